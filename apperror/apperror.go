@@ -0,0 +1,170 @@
+package apperror
+
+import (
+	"errors"
+	"net/http"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// Kind はエラーの種類です。HTTPステータスコードへの対応は Status で決まります。
+type Kind int
+
+const (
+	KindInternal Kind = iota
+	KindBadRequest
+	KindValidation
+	KindNotFound
+	KindConflict
+	KindForbidden
+	KindUnauthorized
+	KindUnavailable
+)
+
+// Error はリポジトリ・ユースケースが返すドメインエラーです。
+// Message はクライアントに返してよい内容のみとし、DBのエラーなどの詳細は Err に保持します。
+type Error struct {
+	Kind    Kind
+	Message string
+	// Fields はバリデーションエラーのフィールドごとのメッセージです。
+	Fields map[string]string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func NotFound(message string) *Error {
+	return &Error{Kind: KindNotFound, Message: message}
+}
+
+func BadRequest(message string) *Error {
+	return &Error{Kind: KindBadRequest, Message: message}
+}
+
+func Conflict(message string) *Error {
+	return &Error{Kind: KindConflict, Message: message}
+}
+
+func Forbidden(message string) *Error {
+	return &Error{Kind: KindForbidden, Message: message}
+}
+
+func Unauthorized(message string) *Error {
+	return &Error{Kind: KindUnauthorized, Message: message}
+}
+
+func Unavailable(message string) *Error {
+	return &Error{Kind: KindUnavailable, Message: message}
+}
+
+// Wrap は原因となるエラーを保持したまま、指定した種類のエラーを作成します。
+func Wrap(kind Kind, message string, err error) *Error {
+	return &Error{Kind: kind, Message: message, Err: err}
+}
+
+// Validation は ozzo-validation のエラーをフィールドごとの詳細を持つバリデーションエラーに変換します。
+// err が nil の場合は nil を返すため、バリデーターの戻り値をそのまま渡せます。
+func Validation(err error) error {
+	if err == nil {
+		return nil
+	}
+	var internal validation.InternalError
+	if errors.As(err, &internal) {
+		return err
+	}
+	var fieldErrs validation.Errors
+	if errors.As(err, &fieldErrs) {
+		fields := map[string]string{}
+		flatten("", fieldErrs, fields)
+		return &Error{Kind: KindValidation, Message: "validation failed", Fields: fields, Err: err}
+	}
+	return &Error{Kind: KindValidation, Message: err.Error(), Err: err}
+}
+
+// Field は単一の値のバリデーションエラーを、指定したフィールドのエラーとして返します。
+func Field(name string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return Validation(validation.Errors{name: err})
+}
+
+func flatten(prefix string, errs validation.Errors, fields map[string]string) {
+	for name, err := range errs {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		var nested validation.Errors
+		if errors.As(err, &nested) {
+			flatten(key, nested, fields)
+			continue
+		}
+		fields[key] = err.Error()
+	}
+}
+
+// KindOf は err の種類を返します。ドメインエラーでない場合は KindInternal です。
+func KindOf(err error) Kind {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Kind
+	}
+	return KindInternal
+}
+
+// Is は err が指定した種類のドメインエラーかを返します。
+func Is(err error, kind Kind) bool {
+	return err != nil && KindOf(err) == kind
+}
+
+// Status は種類に対応するHTTPステータスコードです。
+func (k Kind) Status() int {
+	switch k {
+	case KindBadRequest:
+		return http.StatusBadRequest
+	case KindValidation:
+		return http.StatusUnprocessableEntity
+	case KindNotFound:
+		return http.StatusNotFound
+	case KindConflict:
+		return http.StatusConflict
+	case KindForbidden:
+		return http.StatusForbidden
+	case KindUnauthorized:
+		return http.StatusUnauthorized
+	case KindUnavailable:
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// Slug は problem+json の type に使う種類の名前です。
+func (k Kind) Slug() string {
+	switch k {
+	case KindBadRequest:
+		return "bad-request"
+	case KindValidation:
+		return "validation"
+	case KindNotFound:
+		return "not-found"
+	case KindConflict:
+		return "conflict"
+	case KindForbidden:
+		return "forbidden"
+	case KindUnauthorized:
+		return "unauthorized"
+	case KindUnavailable:
+		return "unavailable"
+	}
+	return "internal"
+}
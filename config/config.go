@@ -0,0 +1,415 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	// タイムゾーンのデータベースがない環境でも SHOP_TIMEZONE を読み込めるよう埋め込む
+	_ "time/tzdata"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/go-ozzo/ozzo-validation/v4/is"
+	"github.com/joho/godotenv"
+)
+
+// Config はアプリケーション全体の設定です。起動時に一度だけ読み込み、各コンポーネントに渡します。
+type Config struct {
+	Env  string
+	Port string
+
+	Secret         string
+	APIDomain      string
+	BuildAPIKey    string
+	AllowedOrigins []string
+	AppURL         string
+	// GeocoderFile は郵便番号と座標の対応表（CSV）です。空の場合はジオコーディングしません。
+	GeocoderFile string
+	// ShopLocation はショップの営業時間のタイムゾーンです。営業中かどうかはこのタイムゾーンの時刻で判定します。
+	ShopLocation *time.Location
+
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+
+	Database Database
+	OIDC     OIDC
+	Mail     Mail
+	Log      Log
+	Media    Media
+	Timeout  Timeout
+
+	// ShutdownTimeout は終了時に処理中のリクエストの完了を待つ時間の上限です。
+	ShutdownTimeout time.Duration
+}
+
+// データベースのドライバー
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+)
+
+type Database struct {
+	// Driver は postgres または sqlite です。POSTGRES_* は postgres の場合のみ使います。
+	Driver   string
+	User     string
+	Password string
+	Host     string
+	Port     string
+	Name     string
+	// SQLitePath は SQLite のファイルです。":memory:" の場合はメモリ上のデータベースを使います。
+	SQLitePath string
+	// MigrateOnStart を指定した場合は、起動時に未適用のマイグレーションを適用します。
+	MigrateOnStart bool
+}
+
+type OIDC struct {
+	Issuer   string
+	ClientID string
+	JWKSURL  string
+}
+
+type Mail struct {
+	SMTPAddr     string
+	SMTPUsername string
+	SMTPPassword string
+	From         string
+	Dir          string
+}
+
+// Media はアップロードした画像の保存先です。
+type Media struct {
+	// Dir は画像を保存するディレクトリ、BaseURL は画像を配信するURLです。
+	// BaseURL が "/" で始まる場合は、このサーバーが Dir のファイルを配信します。
+	Dir      string
+	BaseURL  string
+	MaxBytes int64
+}
+
+// Timeout はリクエストの処理時間の上限です。上限を過ぎるとリクエストのコンテキストが終了し、実行中のクエリを中断します。
+type Timeout struct {
+	// Default は Groups に指定していないルートの上限です。0 の場合は上限を設けません。
+	Default time.Duration
+	// Groups はルートのグループ（パスの最初の要素。"auth"、"admin"、"build" など）ごとの上限です。
+	Groups map[string]time.Duration
+}
+
+// For はルートのグループの処理時間の上限を返します。
+func (t Timeout) For(group string) time.Duration {
+	if d, ok := t.Groups[group]; ok {
+		return d
+	}
+	return t.Default
+}
+
+type Log struct {
+	RequestBody  bool
+	MaxBodyBytes int64
+	// RedactHeaders と RedactFields はアクセスログで値を伏せるヘッダー名とキーです。
+	// 既定で伏せている Authorization や password などに追加します。
+	RedactHeaders []string
+	RedactFields  []string
+}
+
+// defaults は設定されていない場合に使う値です。キーは環境変数名です。
+var defaults = map[string]string{
+	"PORT":               "8080",
+	"ALLOWED_ORIGINS":    "http://localhost:3000,https://ecsite-front.vercel.app",
+	"ACCESS_TOKEN_TTL":   "15m",
+	"REFRESH_TOKEN_TTL":  "720h",
+	"MAIL_DIR":           "mail",
+	"LOG_BODY_MAX_BYTES": "4096",
+	"MEDIA_DIR":          "uploads",
+	"MEDIA_BASE_URL":     "/uploads",
+	"MEDIA_MAX_BYTES":    "5242880",
+	"DB_DRIVER":          "postgres",
+	"SQLITE_PATH":        ":memory:",
+	"REQUEST_TIMEOUT":    "10s",
+	"REQUEST_TIMEOUTS":   "build=60s",
+	"SHUTDOWN_TIMEOUT":   "15s",
+	"SHOP_TIMEZONE":      "Asia/Tokyo",
+}
+
+// Load は既定値・設定ファイル・環境変数・コマンドライン引数の順に上書きして設定を読み込み、検証します。
+// SECRET などサーバーのみで使う設定は検証しないため、サーバーは ValidateServer も呼びます。
+// 設定ファイルは -config または CONFIG_FILE で指定する .env 形式のファイルです。
+// GO_ENV=dev で設定ファイルが指定されていない場合は .env を読み込みます。
+func Load(args []string) (Config, error) {
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	configFile := fs.String("config", os.Getenv("CONFIG_FILE"), "path to a .env style config file")
+	port := fs.String("port", "", "port to listen on (overrides PORT)")
+	logBody := fs.String("log-request-body", "", "log redacted request bodies: true or false (overrides LOG_REQUEST_BODY)")
+	migrate := fs.String("migrate", "", "apply pending migrations on start: true or false (overrides MIGRATE_ON_START)")
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+
+	file := map[string]string{}
+	path := *configFile
+	if path == "" && os.Getenv("GO_ENV") == "dev" {
+		path = ".env"
+	}
+	if path != "" {
+		values, err := godotenv.Read(path)
+		if err != nil {
+			return Config{}, fmt.Errorf("read config file %s: %w", path, err)
+		}
+		file = values
+	}
+
+	flags := map[string]string{}
+	if *port != "" {
+		flags["PORT"] = *port
+	}
+	if *logBody != "" {
+		flags["LOG_REQUEST_BODY"] = *logBody
+	}
+	if *migrate != "" {
+		flags["MIGRATE_ON_START"] = *migrate
+	}
+
+	get := func(key string) string {
+		if v, ok := flags[key]; ok {
+			return v
+		}
+		if v, ok := os.LookupEnv(key); ok {
+			return v
+		}
+		if v, ok := file[key]; ok {
+			return v
+		}
+		return defaults[key]
+	}
+
+	var errs []string
+	duration := func(key string) time.Duration {
+		d, err := time.ParseDuration(get(key))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+		}
+		return d
+	}
+	boolean := func(key string) bool {
+		v := get(key)
+		if v == "" {
+			return false
+		}
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+		}
+		return b
+	}
+	durations := func(key string) map[string]time.Duration {
+		m, err := parseDurations(get(key))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+		}
+		return m
+	}
+	location := func(key string) *time.Location {
+		loc, err := time.LoadLocation(get(key))
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+		}
+		return loc
+	}
+	integer := func(key string) int64 {
+		n, err := strconv.ParseInt(get(key), 10, 64)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %v", key, err))
+		}
+		return n
+	}
+
+	cfg := Config{
+		Env:             get("GO_ENV"),
+		Port:            get("PORT"),
+		Secret:          get("SECRET"),
+		APIDomain:       get("API_DOMAIN"),
+		BuildAPIKey:     get("BUILD_API_KEY"),
+		AllowedOrigins:  splitList(get("ALLOWED_ORIGINS")),
+		AppURL:          get("APP_URL"),
+		GeocoderFile:    get("GEOCODER_FILE"),
+		ShopLocation:    location("SHOP_TIMEZONE"),
+		AccessTokenTTL:  duration("ACCESS_TOKEN_TTL"),
+		RefreshTokenTTL: duration("REFRESH_TOKEN_TTL"),
+		Database: Database{
+			Driver:         get("DB_DRIVER"),
+			User:           get("POSTGRES_USER"),
+			Password:       get("POSTGRES_PW"),
+			Host:           get("POSTGRES_HOST"),
+			Port:           get("POSTGRES_PORT"),
+			Name:           get("POSTGRES_DB"),
+			SQLitePath:     get("SQLITE_PATH"),
+			MigrateOnStart: boolean("MIGRATE_ON_START"),
+		},
+		OIDC: OIDC{
+			Issuer:   get("OIDC_ISSUER"),
+			ClientID: get("OIDC_CLIENT_ID"),
+			JWKSURL:  get("OIDC_JWKS_URL"),
+		},
+		Mail: Mail{
+			SMTPAddr:     get("SMTP_ADDR"),
+			SMTPUsername: get("SMTP_USERNAME"),
+			SMTPPassword: get("SMTP_PASSWORD"),
+			From:         get("MAIL_FROM"),
+			Dir:          get("MAIL_DIR"),
+		},
+		Log: Log{
+			RequestBody:   boolean("LOG_REQUEST_BODY"),
+			MaxBodyBytes:  integer("LOG_BODY_MAX_BYTES"),
+			RedactHeaders: splitList(get("LOG_REDACT_HEADERS")),
+			RedactFields:  splitList(get("LOG_REDACT_FIELDS")),
+		},
+		Media: Media{
+			Dir:      get("MEDIA_DIR"),
+			BaseURL:  get("MEDIA_BASE_URL"),
+			MaxBytes: integer("MEDIA_MAX_BYTES"),
+		},
+		Timeout: Timeout{
+			Default: duration("REQUEST_TIMEOUT"),
+			Groups:  durations("REQUEST_TIMEOUTS"),
+		},
+		ShutdownTimeout: duration("SHUTDOWN_TIMEOUT"),
+	}
+	if len(errs) > 0 {
+		return Config{}, errors.New("invalid config: " + strings.Join(errs, "; "))
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid config: %w", err)
+	}
+	return cfg, nil
+}
+
+// Validate はサーバーとコマンド（migrate、shopio など）に共通の設定を検証します。
+func (c Config) Validate() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Port, validation.Required.Error("PORT is required"), is.Port.Error("PORT must be a valid port number")),
+		validation.Field(&c.AllowedOrigins, validation.Required.Error("ALLOWED_ORIGINS is required"), validation.Each(is.URL.Error("ALLOWED_ORIGINS must be URLs"))),
+		validation.Field(&c.AccessTokenTTL, validation.Required.Error("ACCESS_TOKEN_TTL must be positive"), validation.Min(time.Second).Error("ACCESS_TOKEN_TTL must be positive")),
+		validation.Field(&c.RefreshTokenTTL, validation.Required.Error("REFRESH_TOKEN_TTL must be positive"), validation.Min(time.Second).Error("REFRESH_TOKEN_TTL must be positive")),
+		validation.Field(&c.Database),
+		validation.Field(&c.Mail),
+		validation.Field(&c.Log),
+		validation.Field(&c.Media),
+		validation.Field(&c.Timeout),
+		validation.Field(&c.ShutdownTimeout, validation.Min(time.Duration(0)).Error("SHUTDOWN_TIMEOUT must not be negative")),
+	)
+}
+
+// ValidateServer は API サーバーの起動に必要な設定が揃っているかを検証します。
+func (c Config) ValidateServer() error {
+	return validation.ValidateStruct(&c,
+		validation.Field(&c.Secret, validation.Required.Error("SECRET is required")),
+		// メール内のリンクに使うため、スキームとホストを含む URL に限る
+		validation.Field(&c.AppURL, validation.Required.Error("APP_URL is required"), validation.By(httpURL("APP_URL"))),
+	)
+}
+
+// httpURL は値がスキームとホストを含む http(s) の URL かを確認するルールを返します。
+func httpURL(name string) validation.RuleFunc {
+	return func(value interface{}) error {
+		u, err := url.Parse(value.(string))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("%s must be an absolute http(s) URL", name)
+		}
+		return nil
+	}
+}
+
+func (d Database) Validate() error {
+	postgres := d.Driver == DriverPostgres
+	return validation.ValidateStruct(&d,
+		validation.Field(&d.Driver, validation.In(DriverPostgres, DriverSQLite).Error("DB_DRIVER must be postgres or sqlite")),
+		validation.Field(&d.User, validation.When(postgres, validation.Required.Error("POSTGRES_USER is required"))),
+		validation.Field(&d.Host, validation.When(postgres, validation.Required.Error("POSTGRES_HOST is required"))),
+		validation.Field(&d.Port, validation.When(postgres, validation.Required.Error("POSTGRES_PORT is required"), is.Port.Error("POSTGRES_PORT must be a valid port number"))),
+		validation.Field(&d.Name, validation.When(postgres, validation.Required.Error("POSTGRES_DB is required"))),
+		validation.Field(&d.SQLitePath, validation.When(d.Driver == DriverSQLite, validation.Required.Error("SQLITE_PATH is required"))),
+	)
+}
+
+func (m Mail) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.From, validation.When(m.SMTPAddr != "", validation.Required.Error("MAIL_FROM is required when SMTP_ADDR is set"), is.Email)),
+	)
+}
+
+func (l Log) Validate() error {
+	return validation.ValidateStruct(&l,
+		validation.Field(&l.MaxBodyBytes, validation.Min(int64(0)).Error("LOG_BODY_MAX_BYTES must not be negative")),
+	)
+}
+
+func (m Media) Validate() error {
+	return validation.ValidateStruct(&m,
+		validation.Field(&m.Dir, validation.Required.Error("MEDIA_DIR is required")),
+		validation.Field(&m.BaseURL, validation.Required.Error("MEDIA_BASE_URL is required")),
+		validation.Field(&m.MaxBytes, validation.Min(int64(1)).Error("MEDIA_MAX_BYTES must be positive")),
+	)
+}
+
+func (t Timeout) Validate() error {
+	for group, d := range t.Groups {
+		if d < 0 {
+			return fmt.Errorf("REQUEST_TIMEOUTS: timeout of %s must not be negative", group)
+		}
+	}
+	return validation.ValidateStruct(&t,
+		validation.Field(&t.Default, validation.Min(time.Duration(0)).Error("REQUEST_TIMEOUT must not be negative")),
+	)
+}
+
+// Addr はサーバーが待ち受けるアドレスです。
+func (c Config) Addr() string {
+	return ":" + c.Port
+}
+
+// InMemory はメモリ上の SQLite を使うかどうかです。プロセスの終了とともにデータは失われます。
+func (d Database) InMemory() bool {
+	return d.Driver == DriverSQLite && d.SQLitePath == ":memory:"
+}
+
+// DSN はPostgreSQLの接続文字列です。ユーザー名・パスワード・データベース名に記号が含まれていてもよいよう、URL としてエスケープします。
+func (d Database) DSN() string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(d.User, d.Password),
+		Host:   net.JoinHostPort(d.Host, d.Port),
+		Path:   "/" + d.Name,
+	}
+	return u.String()
+}
+
+// parseDurations は "build=60s,admin=30s" 形式の名前ごとの時間を読み込みます。
+func parseDurations(s string) (map[string]time.Duration, error) {
+	m := map[string]time.Duration{}
+	for _, v := range splitList(s) {
+		name, value, ok := strings.Cut(v, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%q must be NAME=DURATION", v)
+		}
+		d, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, err
+		}
+		m[name] = d
+	}
+	return m, nil
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestLoadWithoutSecret(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("GO_ENV", "")
+	t.Setenv("DB_DRIVER", DriverSQLite)
+	t.Setenv("SECRET", "")
+	t.Setenv("APP_URL", "https://app.example.com")
+
+	// migrate や shopio は SECRET がなくても設定を読み込める
+	cfg, err := Load(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.ValidateServer(); err == nil {
+		t.Fatal("ValidateServer accepted an empty SECRET")
+	}
+
+	t.Setenv("SECRET", "secret")
+	if cfg, err = Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := cfg.ValidateServer(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateServerAppURL(t *testing.T) {
+	for _, tc := range []struct {
+		appURL string
+		ok     bool
+	}{
+		{"https://app.example.com", true},
+		{"http://localhost:3000/", true},
+		{"", false},
+		{"app.example.com", false},
+		{"/verify", false},
+		{"ftp://app.example.com", false},
+	} {
+		cfg := Config{Secret: "secret", AppURL: tc.appURL}
+		if err := cfg.ValidateServer(); (err == nil) != tc.ok {
+			t.Errorf("ValidateServer with APP_URL %q = %v", tc.appURL, err)
+		}
+	}
+}
+
+func TestDSNEscapes(t *testing.T) {
+	d := Database{User: "app@svc", Password: "p@ss:w/rd?#%", Host: "db.internal", Port: "5432", Name: "shop db"}
+	dsn := d.DSN()
+	u, err := url.Parse(dsn)
+	if err != nil {
+		t.Fatalf("DSN %q: %v", dsn, err)
+	}
+	password, _ := u.User.Password()
+	if u.User.Username() != d.User || password != d.Password || u.Hostname() != d.Host || u.Port() != d.Port || u.Path != "/"+d.Name {
+		t.Fatalf("DSN %q parsed as %+v", dsn, u)
+	}
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"go-rest-api/apperror"
+	"go-rest-api/usecase"
+	"net/http"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+type IAccountController interface {
+	RequestEmailVerification(c echo.Context) error
+	VerifyEmail(c echo.Context) error
+	RequestPasswordReset(c echo.Context) error
+	ResetPassword(c echo.Context) error
+}
+
+type accountController struct {
+	au usecase.IAccountUsecase
+}
+
+func NewAccountController(au usecase.IAccountUsecase) IAccountController {
+	return &accountController{au}
+}
+
+// RequestEmailVerificationはログイン中のユーザーに確認メールを再送します。
+func (ac *accountController) RequestEmailVerification(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+
+	if err := ac.au.RequestEmailVerification(c.Request().Context(), uint(userId.(float64))); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (ac *accountController) VerifyEmail(c echo.Context) error {
+	body := struct {
+		Token string `json:"token"`
+	}{}
+	if err := c.Bind(&body); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	if err := ac.au.VerifyEmail(c.Request().Context(), body.Token); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// RequestPasswordResetは登録の有無にかかわらず同じレスポンスを返します。
+func (ac *accountController) RequestPasswordReset(c echo.Context) error {
+	body := struct {
+		Email string `json:"email"`
+	}{}
+	if err := c.Bind(&body); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	if err := ac.au.RequestPasswordReset(c.Request().Context(), body.Email); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusAccepted)
+}
+
+func (ac *accountController) ResetPassword(c echo.Context) error {
+	body := struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}{}
+	if err := c.Bind(&body); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	if err := ac.au.ResetPassword(c.Request().Context(), body.Token, body.Password); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
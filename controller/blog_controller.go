@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"go-rest-api/apperror"
 	"go-rest-api/model"
 	"go-rest-api/usecase"
 	"net/http"
@@ -16,6 +17,8 @@ type IBlogController interface {
 	CreateBlog(c echo.Context) error
 	UpdateBlog(c echo.Context) error
 	DeleteBlog(c echo.Context) error
+	SetBlogCover(c echo.Context) error
+	DeleteBlogCover(c echo.Context) error
 	GetBlogsForBuild(c echo.Context) error
 }
 
@@ -32,11 +35,15 @@ func (bc *blogController) GetAllBlogs(c echo.Context) error {
 	claims := user.Claims.(jwt.MapClaims)
 	userId := claims["user_id"]
 
-	blogsRes, err := bc.bu.GetAllBlogs(uint(userId.(float64)))
+	q, err := bindListQuery(c)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
-	return c.JSON(http.StatusOK, blogsRes)
+	blogsRes, err := bc.bu.GetAllBlogs(c.Request().Context(), uint(userId.(float64)), q)
+	if err != nil {
+		return err
+	}
+	return listResponse(c, blogsRes)
 }
 
 func (bc *blogController) GetBlogById(c echo.Context) error {
@@ -45,9 +52,9 @@ func (bc *blogController) GetBlogById(c echo.Context) error {
 	userId := claims["user_id"]
 	id := c.Param("blogId")
 	blogId, _ := strconv.Atoi(id)
-	blogRes, err := bc.bu.GetBlogById(uint(userId.(float64)), uint(blogId))
+	blogRes, err := bc.bu.GetBlogById(c.Request().Context(), uint(userId.(float64)), uint(blogId))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusOK, blogRes)
 }
@@ -59,12 +66,12 @@ func (bc *blogController) CreateBlog(c echo.Context) error {
 
 	blog := model.Blog{}
 	if err := c.Bind(&blog); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
 	}
 	blog.UserId = uint(userId.(float64))
-	blogRes, err := bc.bu.CreateBlog(blog)
+	blogRes, err := bc.bu.CreateBlog(c.Request().Context(), blog)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusCreated, blogRes)
 }
@@ -78,11 +85,11 @@ func (bc *blogController) UpdateBlog(c echo.Context) error {
 
 	blog := model.Blog{}
 	if err := c.Bind(&blog); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
 	}
-	blogRes, err := bc.bu.UpdateBlog(blog, uint(userId.(float64)), uint(blogId))
+	blogRes, err := bc.bu.UpdateBlog(c.Request().Context(), blog, uint(userId.(float64)), uint(blogId))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusOK, blogRes)
 }
@@ -94,19 +101,61 @@ func (bc *blogController) DeleteBlog(c echo.Context) error {
 	id := c.Param("blogId")
 	blogId, _ := strconv.Atoi(id)
 
-	err := bc.bu.DeleteBlog(uint(userId.(float64)), uint(blogId))
+	err := bc.bu.DeleteBlog(c.Request().Context(), uint(userId.(float64)), uint(blogId))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.NoContent(http.StatusNoContent)
 }
 
 func (bc *blogController) GetBlogsForBuild(c echo.Context) error {
     // すべてのユーザーのブログを取得
-    blogs, err := bc.bu.GetAllBlogsForBuild()
+    q, err := bindListQuery(c)
+    if err != nil {
+        return err
+    }
+    blogs, err := bc.bu.GetAllBlogsForBuild(c.Request().Context(), q)
     if err != nil {
-        return c.JSON(http.StatusInternalServerError, err.Error())
+        return err
     }
-    return c.JSON(http.StatusOK, blogs)
+    return listResponse(c, blogs)
+}
+
+
+// SetBlogCoverはマルチパートのフォームの file（画像）をブログのカバー画像にします。
+func (bc *blogController) SetBlogCover(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	blogId, err := strconv.Atoi(c.Param("blogId"))
+	if err != nil {
+		return apperror.BadRequest("Blog ID must be an integer")
+	}
+
+	file, err := openFormFile(c, "file")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	blogRes, err := bc.bu.SetBlogCover(c.Request().Context(), uint(userId.(float64)), uint(blogId), file)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, blogRes)
 }
 
+func (bc *blogController) DeleteBlogCover(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId := claims["user_id"]
+	blogId, err := strconv.Atoi(c.Param("blogId"))
+	if err != nil {
+		return apperror.BadRequest("Blog ID must be an integer")
+	}
+
+	blogRes, err := bc.bu.DeleteBlogCover(c.Request().Context(), uint(userId.(float64)), uint(blogId))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, blogRes)
+}
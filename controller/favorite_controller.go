@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"go-rest-api/apperror"
 	"fmt"
 	"go-rest-api/model"
 	"go-rest-api/usecase"
@@ -29,12 +30,12 @@ func NewFavoriteController(fu usecase.IFavoriteUsecase) IFavoriteController {
 func (fc *favoriteController) AddFavorite(c echo.Context) error {
 	favorite := model.Favorite{}
 	if err := c.Bind(&favorite); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
 	}
-	favoriteRes, err := fc.fu.AddFavorite(favorite)
+	favoriteRes, err := fc.fu.AddFavorite(c.Request().Context(), favorite)
 	if err != nil {
 		fmt.Println(err)
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusCreated, favoriteRes)
 }
@@ -42,10 +43,10 @@ func (fc *favoriteController) AddFavorite(c echo.Context) error {
 func (fc *favoriteController) RemoveFavorite(c echo.Context) error {
     shopId := c.Param("shopId")
     userId := c.Param("userId")
-    err := fc.fu.RemoveFavorite(shopId, userId)
+    err := fc.fu.RemoveFavorite(c.Request().Context(), shopId, userId)
     if err != nil {
         fmt.Println(err)
-        return c.JSON(http.StatusInternalServerError, err.Error())
+        return err
     }
     return c.NoContent(http.StatusNoContent)
 }
@@ -54,30 +55,34 @@ func (fc *favoriteController) RemoveFavorite(c echo.Context) error {
 func (fc *favoriteController) GetFavorites(c echo.Context) error {
 	userId := c.Param("userId")  // 仮定: userIdはURLパラメータとして提供されます
 	if userId == "" {
-		return c.JSON(http.StatusBadRequest, "User ID is required")
+		return apperror.BadRequest("User ID is required")
 	}
-	favoritesRes, err := fc.fu.GetFavorites(userId)
+	favoritesRes, err := fc.fu.GetFavorites(c.Request().Context(), userId)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusOK, favoritesRes)
 }
 
 func (fc *favoriteController) GetFavoritesForBuild(c echo.Context) error {
-	favoritesRes, err := fc.fu.GetFavoritesForBuild()
+	q, err := bindListQuery(c)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
-	return c.JSON(http.StatusOK, favoritesRes)
+	favoritesRes, err := fc.fu.GetFavoritesForBuild(c.Request().Context(), q)
+	if err != nil {
+		return err
+	}
+	return listResponse(c, favoritesRes)
 }
 
 func (fc *favoriteController) GetFavoriteShops(c echo.Context) error {
 		user := c.Get("user").(*jwt.Token)
     claims := user.Claims.(jwt.MapClaims)
     userID := claims["user_id"].(string)
-	favoriteShopsRes, err := fc.fu.GetFavoriteShops(userID)
+	favoriteShopsRes, err := fc.fu.GetFavoriteShops(c.Request().Context(), userID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusOK, favoriteShopsRes)
 }
\ No newline at end of file
@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"go-rest-api/model"
+	"go-rest-api/usecase"
+	"log"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+type IHealthController interface {
+	Healthz(c echo.Context) error
+	Readyz(c echo.Context) error
+}
+
+type healthController struct {
+	hu usecase.IHealthUsecase
+}
+
+func NewHealthController(hu usecase.IHealthUsecase) IHealthController {
+	return &healthController{hu}
+}
+
+// Healthz はプロセスがリクエストに応答できることを返します（liveness）。データベースは確認しません。
+func (hc *healthController) Healthz(c echo.Context) error {
+	return c.JSON(http.StatusOK, model.Health{Status: model.HealthOK})
+}
+
+// Readyz はリクエストを処理できる状態かを返します（readiness）。準備ができていない場合は 503 です。
+// 原因のエラーはクライアントに返さず、ログにのみ記録します。
+func (hc *healthController) Readyz(c echo.Context) error {
+	readiness, err := hc.hu.Readiness(c.Request().Context())
+	if err != nil {
+		log.Printf("Not ready: %v", err)
+		return c.JSON(http.StatusServiceUnavailable, readiness)
+	}
+	return c.JSON(http.StatusOK, readiness)
+}
@@ -0,0 +1,84 @@
+package controller
+
+import (
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"net/http"
+	"strconv"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/labstack/echo/v4"
+)
+
+// bindListQuery はクエリパラメーターから一覧取得の条件を読み込みます。
+//
+//	limit, offset, cursor, sort, order, from, to (YYYY-MM-DD)
+//
+// filters には絞り込みに使えるクエリパラメーター名を指定します。
+func bindListQuery(c echo.Context, filters ...string) (model.ListQuery, error) {
+	q := model.ListQuery{
+		Cursor: c.QueryParam("cursor"),
+		Sort:   c.QueryParam("sort"),
+		Order:  c.QueryParam("order"),
+	}
+	errs := validation.Errors{}
+	if v := c.QueryParam("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil || limit < 1 || limit > model.MaxListLimit {
+			errs["limit"] = validation.NewError("validation_limit_invalid", "limit must be between 1 and "+strconv.Itoa(model.MaxListLimit))
+		}
+		q.Limit = limit
+	}
+	if v := c.QueryParam("offset"); v != "" {
+		offset, err := strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			errs["offset"] = validation.NewError("validation_offset_invalid", "offset must be a non-negative integer")
+		}
+		if q.Cursor != "" {
+			errs["offset"] = validation.NewError("validation_offset_with_cursor", "offset cannot be used with cursor")
+		}
+		q.Offset = offset
+	}
+	for _, name := range []string{"from", "to"} {
+		v := c.QueryParam(name)
+		if v == "" {
+			continue
+		}
+		date, err := time.Parse("2006-01-02", v)
+		if err != nil {
+			errs[name] = validation.NewError("validation_date_invalid", name+" must be YYYY-MM-DD")
+			continue
+		}
+		if name == "from" {
+			q.From = &date
+		} else {
+			q.To = &date
+		}
+	}
+	for _, name := range filters {
+		if v := c.QueryParam(name); v != "" {
+			if q.Filters == nil {
+				q.Filters = map[string]string{}
+			}
+			q.Filters[name] = v
+		}
+	}
+	if len(errs) > 0 {
+		return q, apperror.Validation(errs)
+	}
+	return q, nil
+}
+
+// listResponse は一覧取得の結果に次のページのURLを付けて返します。
+func listResponse[T any](c echo.Context, page model.Page[T]) error {
+	if page.NextCursor != "" {
+		u := *c.Request().URL
+		query := u.Query()
+		query.Del("offset")
+		query.Set("cursor", page.NextCursor)
+		u.RawQuery = query.Encode()
+		page.Next = u.RequestURI()
+	}
+	return c.JSON(http.StatusOK, page)
+}
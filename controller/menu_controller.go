@@ -0,0 +1,203 @@
+package controller
+
+import (
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"go-rest-api/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+type IMenuController interface {
+	GetMenus(c echo.Context) error
+	GetMenusForOwner(c echo.Context) error
+	CreateMenu(c echo.Context) error
+	UpdateMenu(c echo.Context) error
+	DeleteMenu(c echo.Context) error
+	CreateCourse(c echo.Context) error
+	UpdateCourse(c echo.Context) error
+	DeleteCourse(c echo.Context) error
+}
+
+type menuController struct {
+	mu usecase.IMenuUsecase
+}
+
+func NewMenuController(mu usecase.IMenuUsecase) IMenuController {
+	return &menuController{mu}
+}
+
+// GetMenusはショップのメニューを提供中のコースとともに返します。
+func (mc *menuController) GetMenus(c echo.Context) error {
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+	menusRes, err := mc.mu.GetMenus(c.Request().Context(), uint(shopId))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, menusRes)
+}
+
+// GetMenusForOwnerは提供を停止したコースも含めてメニューを返します（ショップオーナー用）。
+func (mc *menuController) GetMenusForOwner(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+	menusRes, err := mc.mu.GetMenusForOwner(c.Request().Context(), uint(shopId), uint(userId), role)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, menusRes)
+}
+
+func (mc *menuController) CreateMenu(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+
+	menu := model.Menu{}
+	if err := c.Bind(&menu); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	menu.ID = 0
+	menu.ShopID = uint(shopId)
+	menuRes, err := mc.mu.CreateMenu(c.Request().Context(), menu, uint(userId), role)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, menuRes)
+}
+
+func (mc *menuController) UpdateMenu(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+	menuId, err := strconv.Atoi(c.Param("menuId"))
+	if err != nil {
+		return apperror.BadRequest("Menu ID must be an integer")
+	}
+
+	menu := model.Menu{}
+	if err := c.Bind(&menu); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	menuRes, err := mc.mu.UpdateMenu(c.Request().Context(), menu, uint(shopId), uint(menuId), uint(userId), role)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, menuRes)
+}
+
+func (mc *menuController) DeleteMenu(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+	menuId, err := strconv.Atoi(c.Param("menuId"))
+	if err != nil {
+		return apperror.BadRequest("Menu ID must be an integer")
+	}
+
+	if err := mc.mu.DeleteMenu(c.Request().Context(), uint(shopId), uint(menuId), uint(userId), role); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+func (mc *menuController) CreateCourse(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+	menuId, err := strconv.Atoi(c.Param("menuId"))
+	if err != nil {
+		return apperror.BadRequest("Menu ID must be an integer")
+	}
+
+	// 提供中かどうかを省略した場合は提供中とする
+	course := model.Course{Active: true}
+	if err := c.Bind(&course); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	course.ID = 0
+	course.ShopID = uint(shopId)
+	course.MenuID = uint(menuId)
+	courseRes, err := mc.mu.CreateCourse(c.Request().Context(), course, uint(userId), role)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, courseRes)
+}
+
+func (mc *menuController) UpdateCourse(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+	courseId, err := strconv.Atoi(c.Param("courseId"))
+	if err != nil {
+		return apperror.BadRequest("Course ID must be an integer")
+	}
+
+	course := model.Course{Active: true}
+	if err := c.Bind(&course); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	courseRes, err := mc.mu.UpdateCourse(c.Request().Context(), course, uint(shopId), uint(courseId), uint(userId), role)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, courseRes)
+}
+
+func (mc *menuController) DeleteCourse(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+	courseId, err := strconv.Atoi(c.Param("courseId"))
+	if err != nil {
+		return apperror.BadRequest("Course ID must be an integer")
+	}
+
+	if err := mc.mu.DeleteCourse(c.Request().Context(), uint(shopId), uint(courseId), uint(userId), role); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
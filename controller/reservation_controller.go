@@ -1,21 +1,30 @@
 package controller
 
 import (
+	"go-rest-api/apperror"
 	"net/http"
 	"go-rest-api/model"
 	"go-rest-api/usecase"
 	"github.com/labstack/echo/v4"
 	"strconv" 
+	"time"
     "github.com/golang-jwt/jwt/v4"
 )
 
 type IReservationController interface {
     MakeReservation(c echo.Context) error
     CancelReservation(c echo.Context) error
+    ConfirmReservation(c echo.Context) error
+    CheckInReservation(c echo.Context) error
+    MarkNoShow(c echo.Context) error
+    CompleteReservation(c echo.Context) error
+    CancelReservationByShop(c echo.Context) error
     GetReservationByUser(c echo.Context) error
+    GetReservationsByShop(c echo.Context) error
     GetAllReservations(c echo.Context) error
     UpdateReservation(c echo.Context) error
 	GetReservationsForBuild(c echo.Context) error
+	GetAvailability(c echo.Context) error
 }
 
 type reservationController struct {
@@ -32,13 +41,13 @@ func (rc *reservationController) MakeReservation(c echo.Context) error {
 
     // shopIdが空でないことを確認します。
     if shopIdParam == "" {
-        return c.JSON(http.StatusBadRequest, "Shop ID is required")
+        return apperror.BadRequest("Shop ID is required")
     }
 
     // shopIdを整数に変換します。エラーがあれば処理します。
     shopId, err := strconv.Atoi(shopIdParam)
     if err != nil {
-        return c.JSON(http.StatusBadRequest, "Shop ID must be an integer")
+        return apperror.BadRequest("Shop ID must be an integer")
     }
 
     // JWTトークンからユーザー情報を取得
@@ -48,7 +57,7 @@ func (rc *reservationController) MakeReservation(c echo.Context) error {
     // claims["user_id"] は float64 型であるため、型アサーションを適切に行います。
     userIdFloat, ok := claims["user_id"].(float64) // 修正: 型アサーションをfloat64に
     if !ok {
-        return c.JSON(http.StatusInternalServerError, "Invalid user ID")
+        return apperror.Unauthorized("invalid user ID claim")
     }
 
     // float64 から uint への変換
@@ -57,7 +66,7 @@ func (rc *reservationController) MakeReservation(c echo.Context) error {
     // リクエストボディから予約データを取得
     reservation := model.Reservation{}
     if err := c.Bind(&reservation); err != nil {
-        return c.JSON(http.StatusBadRequest, err.Error())
+        return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
     }
 
     // ユーザーIDとショップIDをreservationモデルに設定
@@ -65,60 +74,168 @@ func (rc *reservationController) MakeReservation(c echo.Context) error {
     reservation.ShopID = uint(shopId)
 
     // 予約を作成
-    reservationRes, err := rc.ru.MakeReservation(reservation)
+    role, _ := claims["role"].(string)
+    reservationRes, err := rc.ru.MakeReservation(c.Request().Context(), reservation, role)
     if err != nil {
-        return c.JSON(http.StatusInternalServerError, err.Error())
+        return err
     }
 
     return c.JSON(http.StatusCreated, reservationRes)
 }
 
 func (rc *reservationController) CancelReservation(c echo.Context) error {
-    reservationId := c.Param("reservationId")
-    err := rc.ru.CancelReservation(reservationId)
+    user := c.Get("user").(*jwt.Token)
+    claims := user.Claims.(jwt.MapClaims)
+    userId := claims["user_id"]
+    reservationId, err := strconv.Atoi(c.Param("reservationId"))
+    if err != nil {
+        return apperror.BadRequest("Reservation ID must be an integer")
+    }
+    role, _ := claims["role"].(string)
+    err = rc.ru.CancelReservation(c.Request().Context(), uint(userId.(float64)), uint(reservationId), role)
     if err != nil {
-        return c.JSON(http.StatusInternalServerError, err.Error())
+        return err
     }
     return c.NoContent(http.StatusNoContent)
 }
 
+func (rc *reservationController) ConfirmReservation(c echo.Context) error {
+    return rc.changeStatusByShop(c, model.ReservationConfirmed)
+}
+
+func (rc *reservationController) CheckInReservation(c echo.Context) error {
+    return rc.changeStatusByShop(c, model.ReservationCheckedIn)
+}
+
+func (rc *reservationController) MarkNoShow(c echo.Context) error {
+    return rc.changeStatusByShop(c, model.ReservationNoShow)
+}
+
+func (rc *reservationController) CompleteReservation(c echo.Context) error {
+    return rc.changeStatusByShop(c, model.ReservationCompleted)
+}
+
+func (rc *reservationController) CancelReservationByShop(c echo.Context) error {
+    return rc.changeStatusByShop(c, model.ReservationCancelledByShop)
+}
+
+// changeStatusByShopはショップスタッフによるステータス変更の共通処理です。
+func (rc *reservationController) changeStatusByShop(c echo.Context, status string) error {
+    user := c.Get("user").(*jwt.Token)
+    claims := user.Claims.(jwt.MapClaims)
+    userId := claims["user_id"]
+    role, _ := claims["role"].(string)
+    reservationId, err := strconv.Atoi(c.Param("reservationId"))
+    if err != nil {
+        return apperror.BadRequest("Reservation ID must be an integer")
+    }
+    reservationRes, err := rc.ru.ChangeStatusByShop(c.Request().Context(), uint(reservationId), status, uint(userId.(float64)), role)
+    if err != nil {
+        return err
+    }
+    return c.JSON(http.StatusOK, reservationRes)
+}
+
 func (rc *reservationController) GetReservationByUser(c echo.Context) error {
-    userId := c.Param("userId")
-    if userId == "" {
-        return c.JSON(http.StatusBadRequest, "User ID is required")
+    user := c.Get("user").(*jwt.Token)
+    claims := user.Claims.(jwt.MapClaims)
+    userId := uint(claims["user_id"].(float64))
+
+    // パスのuserIdが指定されている場合は、本人か管理者のみ参照できる
+    if param := c.Param("userId"); param != "" {
+        pathUserId, err := strconv.Atoi(param)
+        if err != nil {
+            return apperror.BadRequest("User ID must be an integer")
+        }
+        role, _ := claims["role"].(string)
+        if uint(pathUserId) != userId && role != model.RoleAdmin {
+            return usecase.ErrForbidden
+        }
+        userId = uint(pathUserId)
     }
-    reservationsRes, err := rc.ru.GetReservationByUser(userId)
+    reservationsRes, err := rc.ru.GetReservationByUser(c.Request().Context(), userId)
     if err != nil {
-        return c.JSON(http.StatusInternalServerError, err.Error())
+        return err
     }
     return c.JSON(http.StatusOK, reservationsRes)
 }
 
-func (rc *reservationController) GetAllReservations(c echo.Context) error {
-    reservationsRes, err := rc.ru.GetAllReservations()
+// GetReservationsByShopはショップスタッフ向けに自分のショップの予約一覧を返します。
+func (rc *reservationController) GetReservationsByShop(c echo.Context) error {
+    user := c.Get("user").(*jwt.Token)
+    claims := user.Claims.(jwt.MapClaims)
+    userId := claims["user_id"]
+    role, _ := claims["role"].(string)
+    shopId, err := strconv.Atoi(c.Param("shopId"))
+    if err != nil {
+        return apperror.BadRequest("Shop ID must be an integer")
+    }
+    reservationsRes, err := rc.ru.GetReservationsByShop(c.Request().Context(), uint(shopId), uint(userId.(float64)), role)
     if err != nil {
-        return c.JSON(http.StatusInternalServerError, err.Error())
+        return err
     }
     return c.JSON(http.StatusOK, reservationsRes)
 }
 
+func (rc *reservationController) GetAllReservations(c echo.Context) error {
+    q, err := bindListQuery(c, "status", "shop_id", "user_id")
+    if err != nil {
+        return err
+    }
+    reservationsRes, err := rc.ru.GetAllReservations(c.Request().Context(), q)
+    if err != nil {
+        return err
+    }
+    return listResponse(c, reservationsRes)
+}
+
 func (rc *reservationController) UpdateReservation(c echo.Context) error {
+    user := c.Get("user").(*jwt.Token)
+    claims := user.Claims.(jwt.MapClaims)
+    userId := claims["user_id"]
+    reservationId, err := strconv.Atoi(c.Param("reservationId"))
+    if err != nil {
+        return apperror.BadRequest("Reservation ID must be an integer")
+    }
     reservation := model.Reservation{}
     if err := c.Bind(&reservation); err != nil {
-        return c.JSON(http.StatusBadRequest, err.Error())
+        return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
     }
-    updatedReservation, err := rc.ru.UpdateReservation(reservation)
+    role, _ := claims["role"].(string)
+    updatedReservation, err := rc.ru.UpdateReservation(c.Request().Context(), reservation, uint(userId.(float64)), uint(reservationId), role)
     if err != nil {
-        return c.JSON(http.StatusInternalServerError, err.Error())
+        return err
     }
     return c.JSON(http.StatusOK, updatedReservation)
 }
 
 func (rc *reservationController) GetReservationsForBuild(c echo.Context) error {
     // ビルドプロセス用に特別に設計されたロジックで予約情報を取得
-    reservations, err := rc.ru.GetReservationsForBuild()
+    q, err := bindListQuery(c, "status", "shop_id")
+    if err != nil {
+        return err
+    }
+    reservations, err := rc.ru.GetReservationsForBuild(c.Request().Context(), q)
+    if err != nil {
+        return err
+    }
+    return listResponse(c, reservations)
+}
+
+// GetAvailabilityは指定した日のショップの空き予約枠を返します。
+func (rc *reservationController) GetAvailability(c echo.Context) error {
+    shopId, err := strconv.Atoi(c.Param("shopId"))
+    if err != nil {
+        return apperror.BadRequest("Shop ID must be an integer")
+    }
+    date, err := time.Parse("2006-01-02", c.QueryParam("date"))
     if err != nil {
-        return c.JSON(http.StatusInternalServerError, err.Error())
+        return apperror.BadRequest("date must be YYYY-MM-DD")
     }
-    return c.JSON(http.StatusOK, reservations)
+    slots, err := rc.ru.GetAvailability(c.Request().Context(), uint(shopId), date)
+    if err != nil {
+        return err
+    }
+    return c.JSON(http.StatusOK, slots)
 }
+
@@ -0,0 +1,207 @@
+package controller
+
+import (
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"go-rest-api/usecase"
+	"net/http"
+	"strconv"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+type IReviewController interface {
+	GetShopReviews(c echo.Context) error
+	GetMyReviews(c echo.Context) error
+	GetReportedReviews(c echo.Context) error
+	CreateReview(c echo.Context) error
+	UpdateReview(c echo.Context) error
+	DeleteReview(c echo.Context) error
+	ReplyReview(c echo.Context) error
+	ReportReview(c echo.Context) error
+	SetReviewHidden(c echo.Context) error
+}
+
+type reviewController struct {
+	ru usecase.IReviewUsecase
+}
+
+func NewReviewController(ru usecase.IReviewUsecase) IReviewController {
+	return &reviewController{ru}
+}
+
+// GetShopReviewsはショップの公開中のレビューを新しい順に返します。
+func (rc *reviewController) GetShopReviews(c echo.Context) error {
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+	q, err := bindListQuery(c, "rating", "verified")
+	if err != nil {
+		return err
+	}
+	reviewsRes, err := rc.ru.GetShopReviews(c.Request().Context(), uint(shopId), q)
+	if err != nil {
+		return err
+	}
+	return listResponse(c, reviewsRes)
+}
+
+func (rc *reviewController) GetMyReviews(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+
+	q, err := bindListQuery(c, "rating", "verified")
+	if err != nil {
+		return err
+	}
+	reviewsRes, err := rc.ru.GetUserReviews(c.Request().Context(), uint(userId), q)
+	if err != nil {
+		return err
+	}
+	return listResponse(c, reviewsRes)
+}
+
+// GetReportedReviewsは通報されたレビューを返します（管理者用）。
+func (rc *reviewController) GetReportedReviews(c echo.Context) error {
+	q, err := bindListQuery(c, "rating", "verified")
+	if err != nil {
+		return err
+	}
+	if q.Sort == "" {
+		q.Sort = "report_count"
+	}
+	reviewsRes, err := rc.ru.GetReportedReviews(c.Request().Context(), q)
+	if err != nil {
+		return err
+	}
+	return listResponse(c, reviewsRes)
+}
+
+func (rc *reviewController) CreateReview(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+
+	review := model.Review{}
+	if err := c.Bind(&review); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	review.ID = 0
+	review.UserID = uint(userId)
+	review.ShopID = uint(shopId)
+	reviewRes, err := rc.ru.CreateReview(c.Request().Context(), review)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, reviewRes)
+}
+
+func (rc *reviewController) UpdateReview(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	reviewId, err := strconv.Atoi(c.Param("reviewId"))
+	if err != nil {
+		return apperror.BadRequest("Review ID must be an integer")
+	}
+
+	review := model.Review{}
+	if err := c.Bind(&review); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	reviewRes, err := rc.ru.UpdateReview(c.Request().Context(), review, uint(userId), uint(reviewId))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, reviewRes)
+}
+
+func (rc *reviewController) DeleteReview(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	reviewId, err := strconv.Atoi(c.Param("reviewId"))
+	if err != nil {
+		return apperror.BadRequest("Review ID must be an integer")
+	}
+
+	if err := rc.ru.DeleteReview(c.Request().Context(), uint(userId), uint(reviewId)); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// ReplyReviewはショップのオーナーがレビューに返信します。空の返信を送ると返信を削除します。
+func (rc *reviewController) ReplyReview(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+	reviewId, err := strconv.Atoi(c.Param("reviewId"))
+	if err != nil {
+		return apperror.BadRequest("Review ID must be an integer")
+	}
+
+	review := model.Review{}
+	if err := c.Bind(&review); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	reviewRes, err := rc.ru.ReplyReview(c.Request().Context(), review, uint(shopId), uint(reviewId), uint(userId), role)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, reviewRes)
+}
+
+func (rc *reviewController) ReportReview(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	reviewId, err := strconv.Atoi(c.Param("reviewId"))
+	if err != nil {
+		return apperror.BadRequest("Review ID must be an integer")
+	}
+
+	report := model.ReviewReport{}
+	if err := c.Bind(&report); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	report.ID = 0
+	report.ReviewID = uint(reviewId)
+	report.UserID = uint(userId)
+	if err := rc.ru.ReportReview(c.Request().Context(), report); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+// SetReviewHiddenはレビューの公開・非公開を切り替えます（管理者用）。リクエストボディは {"hidden": true} です。
+func (rc *reviewController) SetReviewHidden(c echo.Context) error {
+	reviewId, err := strconv.Atoi(c.Param("reviewId"))
+	if err != nil {
+		return apperror.BadRequest("Review ID must be an integer")
+	}
+
+	req := struct {
+		Hidden bool `json:"hidden"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	reviewRes, err := rc.ru.SetReviewHidden(c.Request().Context(), uint(reviewId), req.Hidden)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, reviewRes)
+}
@@ -1,20 +1,35 @@
 package controller
 
 import (
+	"bytes"
+	"encoding/json"
+	"go-rest-api/apperror"
 	"go-rest-api/model"
 	"go-rest-api/usecase"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
 
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/golang-jwt/jwt/v4"
 	"github.com/labstack/echo/v4"
 )
 
 type IShopController interface {
 	GetAllShops(c echo.Context) error
+	SearchShops(c echo.Context) error
+	GetNearbyShops(c echo.Context) error
 	GetShopById(c echo.Context) error
 	CreateShop(c echo.Context) error
 	UpdateShop(c echo.Context) error
 	DeleteShop(c echo.Context) error
+	SetShopHours(c echo.Context) error
+	AddShopClosure(c echo.Context) error
+	DeleteShopClosure(c echo.Context) error
+	AddShopImage(c echo.Context) error
+	ReorderShopImages(c echo.Context) error
+	DeleteShopImage(c echo.Context) error
 }
 
 type shopController struct {
@@ -26,9 +41,84 @@ func NewShopController(su usecase.IShopUsecase) IShopController {
 }
 
 func (sc *shopController) GetAllShops(c echo.Context) error {
-	shopsRes, err := sc.su.GetAllShops()
+	q, err := bindListQuery(c, "area", "genre")
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
+	}
+	shopsRes, err := sc.su.GetAllShops(c.Request().Context(), q)
+	if err != nil {
+		return err
+	}
+	return listResponse(c, shopsRes)
+}
+
+// SearchShopsは検索語（q）、エリア、ジャンル、営業中（open_now=true）でショップを検索します。
+func (sc *shopController) SearchShops(c echo.Context) error {
+	lq, err := bindListQuery(c)
+	if err != nil {
+		return err
+	}
+	if lq.Cursor != "" {
+		return apperror.Field("cursor", validation.NewError("validation_cursor_unsupported", "search supports offset paging only"))
+	}
+	openNow := false
+	if v := c.QueryParam("open_now"); v != "" {
+		openNow, err = strconv.ParseBool(v)
+		if err != nil {
+			return apperror.Field("open_now", validation.NewError("validation_open_now_invalid", "open_now must be true or false"))
+		}
+	}
+	q := model.ShopSearchQuery{
+		Text:   strings.TrimSpace(c.QueryParam("q")),
+		Area:   c.QueryParam("area"),
+		Genre:  c.QueryParam("genre"),
+		Limit:  lq.Limit,
+		Offset: lq.Offset,
+	}
+	res, err := sc.su.SearchShops(c.Request().Context(), q, openNow)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// 周辺検索の半径（メートル）
+const (
+	defaultNearbyRadius = 1000.0
+	maxNearbyRadius     = 50000.0
+)
+
+// GetNearbyShopsは指定した地点（lat, lng）から半径radiusメートル以内のショップを近い順に返します。
+func (sc *shopController) GetNearbyShops(c echo.Context) error {
+	lq, err := bindListQuery(c)
+	if err != nil {
+		return err
+	}
+	if lq.Cursor != "" {
+		return apperror.Field("cursor", validation.NewError("validation_cursor_unsupported", "nearby search supports offset paging only"))
+	}
+	errs := validation.Errors{}
+	lat, err := strconv.ParseFloat(c.QueryParam("lat"), 64)
+	if err != nil || lat < -90 || lat > 90 {
+		errs["lat"] = validation.NewError("validation_lat_invalid", "lat must be between -90 and 90")
+	}
+	lng, err := strconv.ParseFloat(c.QueryParam("lng"), 64)
+	if err != nil || lng < -180 || lng > 180 {
+		errs["lng"] = validation.NewError("validation_lng_invalid", "lng must be between -180 and 180")
+	}
+	radius := defaultNearbyRadius
+	if v := c.QueryParam("radius"); v != "" {
+		radius, err = strconv.ParseFloat(v, 64)
+		if err != nil || radius <= 0 || radius > maxNearbyRadius {
+			errs["radius"] = validation.NewError("validation_radius_invalid", "radius must be between 0 and 50000 meters")
+		}
+	}
+	if len(errs) > 0 {
+		return apperror.Validation(errs)
+	}
+	shopsRes, err := sc.su.GetNearbyShops(c.Request().Context(), lat, lng, radius, lq.Limit, lq.Offset)
+	if err != nil {
+		return err
 	}
 	return c.JSON(http.StatusOK, shopsRes)
 }
@@ -36,9 +126,9 @@ func (sc *shopController) GetAllShops(c echo.Context) error {
 func (sc *shopController) GetShopById(c echo.Context) error {
 	id := c.Param("shopId")
 	shopId, _ := strconv.Atoi(id)
-	shopRes, err := sc.su.GetShopById(uint(shopId))
+	shopRes, err := sc.su.GetShopById(c.Request().Context(), uint(shopId))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusOK, shopRes)
 }
@@ -46,26 +136,40 @@ func (sc *shopController) GetShopById(c echo.Context) error {
 func (sc *shopController) CreateShop(c echo.Context) error {
 	shop := model.Shop{}
 	if err := c.Bind(&shop); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
 	}
-	shopRes, err := sc.su.CreateShop(shop)
+	shopRes, err := sc.su.CreateShop(c.Request().Context(), shop)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusCreated, shopRes)
 }
 
 func (sc *shopController) UpdateShop(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
 	id := c.Param("shopId")
 	shopId, _ := strconv.Atoi(id)
 
+	// owner_id の有無を確認するため、ボディを読み込んでからバインドする
+	raw, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	c.Request().Body = io.NopCloser(bytes.NewReader(raw))
 	shop := model.Shop{}
 	if err := c.Bind(&shop); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
 	}
-	shopRes, err := sc.su.UpdateShop(shop, uint(shopId))
+	// owner_id を省略した場合はオーナーを変更しない（null の場合はオーナーを外す）
+	fields := map[string]json.RawMessage{}
+	json.Unmarshal(raw, &fields)
+	_, updateOwner := fields["owner_id"]
+	shopRes, err := sc.su.UpdateShop(c.Request().Context(), shop, uint(shopId), uint(userId), role, updateOwner)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusOK, shopRes)
 }
@@ -74,10 +178,142 @@ func (sc *shopController) DeleteShop(c echo.Context) error {
 	id := c.Param("shopId")
 	shopId, _ := strconv.Atoi(id)
 
-	err := sc.su.DeleteShop(uint(shopId))
+	err := sc.su.DeleteShop(c.Request().Context(), uint(shopId))
+	if err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
+
+
+// SetShopHoursは曜日ごとの営業時間をまとめて置き換えます。リクエストボディは営業時間帯の配列です。
+func (sc *shopController) SetShopHours(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+
+	hours := []model.ShopHour{}
+	if err := c.Bind(&hours); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	shopRes, err := sc.su.SetShopHours(c.Request().Context(), uint(shopId), hours, uint(userId), role)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, shopRes)
+}
+
+func (sc *shopController) AddShopClosure(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+
+	closure := model.ShopClosure{}
+	if err := c.Bind(&closure); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	closure.ID = 0
+	closure.ShopID = uint(shopId)
+	closureRes, err := sc.su.AddShopClosure(c.Request().Context(), closure, uint(userId), role)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, closureRes)
+}
+
+func (sc *shopController) DeleteShopClosure(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+	closureId, err := strconv.Atoi(c.Param("closureId"))
+	if err != nil {
+		return apperror.BadRequest("Closure ID must be an integer")
+	}
+
+	if err := sc.su.DeleteShopClosure(c.Request().Context(), uint(shopId), uint(closureId), uint(userId), role); err != nil {
+		return err
 	}
 	return c.NoContent(http.StatusNoContent)
 }
 
+// AddShopImageはマルチパートのフォームの file（画像）と caption をギャラリーの最後に追加します。
+func (sc *shopController) AddShopImage(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+
+	file, err := openFormFile(c, "file")
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	imageRes, err := sc.su.AddShopImage(c.Request().Context(), uint(shopId), file, c.FormValue("caption"), uint(userId), role)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusCreated, imageRes)
+}
+
+// ReorderShopImagesはギャラリーの画像を並べ替えます。リクエストボディは {"image_ids": [3, 1, 2]} です。
+func (sc *shopController) ReorderShopImages(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+
+	req := struct {
+		ImageIDs []uint `json:"image_ids"`
+	}{}
+	if err := c.Bind(&req); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	imagesRes, err := sc.su.ReorderShopImages(c.Request().Context(), uint(shopId), req.ImageIDs, uint(userId), role)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, imagesRes)
+}
+
+func (sc *shopController) DeleteShopImage(c echo.Context) error {
+	user := c.Get("user").(*jwt.Token)
+	claims := user.Claims.(jwt.MapClaims)
+	userId, _ := claims["user_id"].(float64)
+	role, _ := claims["role"].(string)
+	shopId, err := strconv.Atoi(c.Param("shopId"))
+	if err != nil {
+		return apperror.BadRequest("Shop ID must be an integer")
+	}
+	imageId, err := strconv.Atoi(c.Param("imageId"))
+	if err != nil {
+		return apperror.BadRequest("Image ID must be an integer")
+	}
+
+	if err := sc.su.DeleteShopImage(c.Request().Context(), uint(shopId), uint(imageId), uint(userId), role); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
+}
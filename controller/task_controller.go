@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"go-rest-api/apperror"
 	"go-rest-api/model"
 	"go-rest-api/usecase"
 	"net/http"
@@ -31,11 +32,15 @@ func (tc *taskController) GetAllTasks(c echo.Context) error {
 	claims := user.Claims.(jwt.MapClaims)
 	userId := claims["user_id"]
 
-	tasksRes, err := tc.tu.GetAllTasks(uint(userId.(float64)))
+	q, err := bindListQuery(c)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
-	return c.JSON(http.StatusOK, tasksRes)
+	tasksRes, err := tc.tu.GetAllTasks(c.Request().Context(), uint(userId.(float64)), q)
+	if err != nil {
+		return err
+	}
+	return listResponse(c, tasksRes)
 }
 
 func (tc *taskController) GetTaskById(c echo.Context) error {
@@ -44,9 +49,9 @@ func (tc *taskController) GetTaskById(c echo.Context) error {
 	userId := claims["user_id"]
 	id := c.Param("taskId")
 	taskId, _ := strconv.Atoi(id)
-	taskRes, err := tc.tu.GetTaskById(uint(userId.(float64)), uint(taskId))
+	taskRes, err := tc.tu.GetTaskById(c.Request().Context(), uint(userId.(float64)), uint(taskId))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusOK, taskRes)
 }
@@ -58,12 +63,12 @@ func (tc *taskController) CreateTask(c echo.Context) error {
 
 	task := model.Task{}
 	if err := c.Bind(&task); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
 	}
 	task.UserId = uint(userId.(float64))
-	taskRes, err := tc.tu.CreateTask(task)
+	taskRes, err := tc.tu.CreateTask(c.Request().Context(), task)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusCreated, taskRes)
 }
@@ -77,11 +82,11 @@ func (tc *taskController) UpdateTask(c echo.Context) error {
 
 	task := model.Task{}
 	if err := c.Bind(&task); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
 	}
-	taskRes, err := tc.tu.UpdateTask(task, uint(userId.(float64)), uint(taskId))
+	taskRes, err := tc.tu.UpdateTask(c.Request().Context(), task, uint(userId.(float64)), uint(taskId))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.JSON(http.StatusOK, taskRes)
 }
@@ -93,9 +98,9 @@ func (tc *taskController) DeleteTask(c echo.Context) error {
 	id := c.Param("taskId")
 	taskId, _ := strconv.Atoi(id)
 
-	err := tc.tu.DeleteTask(uint(userId.(float64)), uint(taskId))
+	err := tc.tu.DeleteTask(c.Request().Context(), uint(userId.(float64)), uint(taskId))
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	return c.NoContent(http.StatusNoContent)
 }
@@ -0,0 +1,27 @@
+package controller
+
+import (
+	"errors"
+	"go-rest-api/apperror"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// openFormFile はマルチパートのフォームからアップロードされたファイルを開きます。
+// 呼び出し側でファイルを閉じる必要があります。
+func openFormFile(c echo.Context, name string) (multipart.File, error) {
+	header, err := c.FormFile(name)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, apperror.Field(name, errors.New(name+" is required"))
+	}
+	if err != nil {
+		return nil, apperror.Wrap(apperror.KindBadRequest, "invalid multipart form", err)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	return file, nil
+}
@@ -1,13 +1,15 @@
 package controller
 
 import (
-	"fmt"
+	"context"
+	"go-rest-api/apperror"
+	"errors"
 	"go-rest-api/model"
 	"go-rest-api/usecase"
 	"net/http"
 	"net/url"
-	"os"
 	"log"
+	"strconv"
 	"time"
 	"encoding/json"
 	"github.com/golang-jwt/jwt/v4"
@@ -25,101 +27,136 @@ type IUserController interface {
 	GetToken(c echo.Context) error 
 	AuthLogin(c echo.Context) error
 	AuthSignup(c echo.Context) error
-    OAuthLogin(c echo.Context, email string, name string) error
     HandleOAuthLogin(c echo.Context) error 
+	UpdateUserRole(c echo.Context) error
+	RefreshToken(c echo.Context) error
+	RevokeUserSessions(c echo.Context) error
 }
 
 type userController struct {
-	uu usecase.IUserUsecase
+	uu           usecase.IUserUsecase
+	tu           usecase.ITokenUsecase
+	au           usecase.IAccountUsecase
+	cookieDomain string
 }
 
-func NewUserController(uu usecase.IUserUsecase) IUserController {
-	return &userController{uu}
+func NewUserController(uu usecase.IUserUsecase, tu usecase.ITokenUsecase, au usecase.IAccountUsecase, cookieDomain string) IUserController {
+	return &userController{uu, tu, au, cookieDomain}
+}
+
+// sendVerificationは新規登録したユーザーに確認メールを送ります。
+// 送信に失敗しても登録自体は成功として扱い、ユーザーは後から再送できます。
+func (uc *userController) sendVerification(ctx context.Context, userId uint) {
+	if err := uc.au.RequestEmailVerification(ctx, userId); err != nil {
+		log.Printf("Failed to send verification email: %v", err)
+	}
+}
+
+// newAuthCookieは認証関連のCookieを共通の属性で作成します。
+func (uc *userController) newAuthCookie(name, value string, expires time.Time) *http.Cookie {
+	cookie := new(http.Cookie)
+	cookie.Name = name
+	cookie.Value = value
+	cookie.Expires = expires
+	cookie.Path = "/"
+	cookie.Domain = uc.cookieDomain
+	cookie.Secure = true
+	cookie.HttpOnly = true
+	cookie.SameSite = http.SameSiteNoneMode
+	return cookie
+}
+
+// issueTokenはトークンを発行してCookieに保存します。
+// 同じトークンをレスポンスボディでも返すため、Cookie・Authorizationヘッダーのどちらでも利用できます。
+func (uc *userController) issueToken(c echo.Context, user model.UserResponse) (model.TokenResponse, error) {
+	tokenRes, err := uc.tu.IssueToken(c.Request().Context(), user)
+	if err != nil {
+		return model.TokenResponse{}, err
+	}
+	uc.setTokenCookies(c, tokenRes)
+	return tokenRes, nil
+}
+
+func (uc *userController) setTokenCookies(c echo.Context, tokenRes model.TokenResponse) {
+	c.SetCookie(uc.newAuthCookie("token", tokenRes.AccessToken, tokenRes.AccessTokenExpiresAt))
+	c.SetCookie(uc.newAuthCookie("refresh_token", tokenRes.RefreshToken, tokenRes.RefreshTokenExpiresAt))
+}
+
+// refreshTokenFromRequestはリクエストボディまたはCookieからリフレッシュトークンを取得します。
+// refresh_tokenのCookieが付いたリクエストはルーターでCSRFチェックを行います。
+func refreshTokenFromRequest(c echo.Context) string {
+	body := struct {
+		RefreshToken string `json:"refresh_token"`
+	}{}
+	if err := c.Bind(&body); err == nil && body.RefreshToken != "" {
+		return body.RefreshToken
+	}
+	if cookie, err := c.Cookie("refresh_token"); err == nil {
+		return cookie.Value
+	}
+	return ""
 }
 
 func (uc *userController) SignUp(c echo.Context) error {
 	user := model.User{}
 	if err := c.Bind(&user); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
 	}
-	userRes, err := uc.uu.SignUp(user)
+	userRes, err := uc.uu.SignUp(c.Request().Context(), user)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
+	uc.sendVerification(c.Request().Context(), userRes.ID)
 	return c.JSON(http.StatusCreated, userRes)
 }
 
 func (uc *userController) LogIn(c echo.Context) error {
 	user := model.User{}
 	if err := c.Bind(&user); err != nil {
-		return c.JSON(http.StatusBadRequest, err.Error())
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
 	}
-	tokenString, userRes, err := uc.uu.Login(user)
+	userRes, err := uc.uu.Login(c.Request().Context(), user)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
-	
+
 	// JWTトークンをCookieに保存
-	cookie := new(http.Cookie)
-	cookie.Name = "token"
-	cookie.Value = tokenString
-	cookie.Expires = time.Now().Add(3 * time.Hour)
-	cookie.Path = "/"
-	cookie.Domain = os.Getenv("API_DOMAIN")
-	cookie.Secure = true
-	cookie.HttpOnly = true
-	cookie.SameSite = http.SameSiteNoneMode
-	c.SetCookie(cookie)
+	tokenRes, err := uc.issueToken(c, userRes)
+	if err != nil {
+		return err
+	}
 
 	// ユーザー情報をCookieに保存
 	userInfo, err := json.Marshal(userRes)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, err.Error())
+		return err
 	}
 	encodedUserInfo := url.QueryEscape(string(userInfo))
-	userInfoCookie := new(http.Cookie)
-	userInfoCookie.Name = "userInfo"
-	userInfoCookie.Value = encodedUserInfo
-	userInfoCookie.Expires = time.Now().Add(3 * time.Hour)
-	userInfoCookie.Path = "/"
-	userInfoCookie.Domain = os.Getenv("API_DOMAIN")
-	userInfoCookie.Secure = true
-	userInfoCookie.HttpOnly = true
-	userInfoCookie.SameSite = http.SameSiteNoneMode
-	c.SetCookie(userInfoCookie)
+	c.SetCookie(uc.newAuthCookie("userInfo", encodedUserInfo, tokenRes.RefreshTokenExpiresAt))
 
 	// JWTトークンとユーザー情報をレスポンスボディに含める
 	return c.JSON(http.StatusOK, echo.Map{
-		"token": tokenString,
-		"user":  userRes,
+		"token":         tokenRes.AccessToken,
+		"refresh_token": tokenRes.RefreshToken,
+		"user":          userRes,
 	})
 }
 
 
 func (uc *userController) LogOut(c echo.Context) error {
+	// リフレッシュトークンのファミリーをサーバー側で失効させる
+	if refreshToken := refreshTokenFromRequest(c); refreshToken != "" {
+		if err := uc.tu.RevokeToken(c.Request().Context(), refreshToken); err != nil && !errors.Is(err, usecase.ErrInvalidRefreshToken) {
+			return err
+		}
+	}
+
 	// トークン用のクッキーを削除
-	cookie := new(http.Cookie)
-	cookie.Name = "token"
-	cookie.Value = ""
-	cookie.Expires = time.Now()
-	cookie.Path = "/"
-	cookie.Domain = os.Getenv("API_DOMAIN")
-	cookie.Secure = true
-	cookie.HttpOnly = true
-	cookie.SameSite = http.SameSiteNoneMode
-	c.SetCookie(cookie)
+	c.SetCookie(uc.newAuthCookie("token", "", time.Now()))
+	c.SetCookie(uc.newAuthCookie("refresh_token", "", time.Now()))
 
 	// ユーザー情報用のクッキーを削除
-	userCookie := new(http.Cookie)
-	userCookie.Name = "userInfo"
-	userCookie.Value = ""
-	userCookie.Expires = time.Now() 
-	userCookie.Path = "/"
-	userCookie.Domain = os.Getenv("API_DOMAIN")
-	userCookie.Secure = true
-	userCookie.HttpOnly = true
-	userCookie.SameSite = http.SameSiteNoneMode
-	c.SetCookie(userCookie)
+	c.SetCookie(uc.newAuthCookie("userInfo", "", time.Now()))
 	return c.NoContent(http.StatusOK)
 }
 
@@ -137,7 +174,6 @@ func (uc *userController) GetCookies(c echo.Context) error {
     // クッキーの情報を格納するためのマップ
     cookieMap := make(map[string]string)
     for _, cookie := range cookies {
-			fmt.Println("Cookie:", cookie.Name, "Value:", cookie.Value) // この行を追加
         cookieMap[cookie.Name] = cookie.Value
     }
 
@@ -154,14 +190,14 @@ func (uc *userController) GetUser(c echo.Context) error {
     // userIDをfloat64として取得し、その後uintに変換
     userIDFloat, ok := claims["user_id"].(float64)
     if !ok {
-        return c.JSON(http.StatusBadRequest, "Invalid user ID format")
+        return apperror.BadRequest("Invalid user ID format")
     }
     userID := uint(userIDFloat)
 
     // データベースからユーザー情報を取得
-    userInfo, err := uc.uu.GetUserByID(userID)
+    userInfo, err := uc.uu.GetUserByID(c.Request().Context(), userID)
     if err != nil {
-        return c.JSON(http.StatusInternalServerError, err.Error())
+        return err
     }
 
     return c.JSON(http.StatusOK, userInfo)
@@ -171,7 +207,7 @@ func (uc *userController) GetToken(c echo.Context) error {
     // トークン自体を取得（トークンはリクエストのクッキーに保存されている）
     token, err := c.Cookie("token")
     if err != nil {
-        return c.JSON(http.StatusInternalServerError, "Token retrieval failed")
+        return apperror.Wrap(apperror.KindUnauthorized, "token cookie is missing", err)
     }
 
     // トークンをレスポンスとして返す
@@ -180,45 +216,26 @@ func (uc *userController) GetToken(c echo.Context) error {
 
 // userControllerに追加するAuthLoginメソッド
 func (uc *userController) AuthLogin(c echo.Context) error {
-    fmt.Println("AuthLogin method called")
     var user model.User
     if err := c.Bind(&user); err != nil {
-        log.Printf("Error in AuthLogin: %v", err)
-        return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+        return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
     }
 
-    authenticatedUser, err := uc.uu.AuthenticateUser(user)
+    authenticatedUser, err := uc.uu.Login(c.Request().Context(), user)
     if err != nil {
-        log.Printf("Authentication failed: %v", err)
-        return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Authentication failed"})
-    }
-
-    // JWTトークンの生成
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-        "user_id": authenticatedUser.ID,
-        "name":  authenticatedUser.Name,
-        "email": authenticatedUser.Email,
-        "exp":   time.Now().Add(time.Hour * 24).Unix(), // トークンの有効期限
-    })
-
-    // 環境変数からJWTシークレットを取得
-    secretKey := os.Getenv("SECRET")
-    if secretKey == "" {
-        log.Printf("JWT secret key is not set")
-        return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal Server Error"})
+        return err
     }
 
-    // トークンに署名
-    tokenString, err := token.SignedString([]byte(secretKey))
+    tokenRes, err := uc.issueToken(c, authenticatedUser)
     if err != nil {
-        log.Printf("Token signing error: %v", err)
-        return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Token signing error"})
+        return err
     }
 
     // JWTトークンを含むレスポンスを返す
     return c.JSON(http.StatusOK, map[string]interface{}{
-        "user": authenticatedUser,
-        "jwt":  tokenString,
+        "user":          authenticatedUser,
+        "jwt":           tokenRes.AccessToken,
+        "refresh_token": tokenRes.RefreshToken,
     })
 }
 
@@ -226,71 +243,102 @@ func (uc *userController) AuthLogin(c echo.Context) error {
 func (uc *userController) AuthSignup(c echo.Context) error {
     var user model.User
     if err := c.Bind(&user); err != nil {
-        log.Printf("Error in AuthSignup: %v", err)
-        return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+        return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
     }
 
-    // 既存のユーザーを検索または新規ユーザーを作成
-    userRes, err := uc.uu.FindOrCreateUser(user.Email, user.Name)
+    // パスワード付きで新規ユーザーを作成（メールアドレスだけでのログインは許可しない）
+    userRes, err := uc.uu.SignUp(c.Request().Context(), user)
     if err != nil {
-        log.Printf("User processing failed: %v", err)
-        return c.JSON(http.StatusInternalServerError, map[string]string{"error": "User processing failed"})
+        return err
     }
+    uc.sendVerification(c.Request().Context(), userRes.ID)
 
-    // JWTトークンの生成
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-        "user_id": userRes.ID,
-        "name":  userRes.Name,
-        "email": userRes.Email,
-        "exp":   time.Now().Add(time.Hour * 24).Unix(),
-    })
-
-    // 環境変数からJWTシークレットを取得
-    secretKey := os.Getenv("SECRET")
-    tokenString, err := token.SignedString([]byte(secretKey))
+    tokenRes, err := uc.issueToken(c, userRes)
     if err != nil {
-        log.Printf("Token signing error: %v", err)
-        return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Token signing error"})
+        return err
     }
 
     return c.JSON(http.StatusOK, map[string]interface{}{
-        "user": userRes,
-        "jwt":  tokenString,
+        "user":          userRes,
+        "jwt":           tokenRes.AccessToken,
+        "refresh_token": tokenRes.RefreshToken,
     })
 }
 
-func (uc *userController) OAuthLogin(c echo.Context, email string, name string) error {
-    // Find or create user based on email
-    userResponse, err := uc.uu.FindOrCreateUser(email, name)
+// HandleOAuthLoginはIDプロバイダーが発行したIDトークンを検証してログインします。
+func (uc *userController) HandleOAuthLogin(c echo.Context) error {
+    body := struct {
+        IDToken string `json:"id_token" form:"id_token"`
+    }{}
+    if err := c.Bind(&body); err != nil || body.IDToken == "" {
+        return apperror.BadRequest("id_token is required")
+    }
+
+    userResponse, err := uc.uu.OAuthLogin(c.Request().Context(), body.IDToken)
     if err != nil {
-        return c.JSON(http.StatusInternalServerError, err.Error())
+        return err
     }
 
-    // Generate JWT token for the user
-    tokenString, err := uc.uu.GenerateJWT(userResponse.ID)
+    // Issue the token and set it in a HttpOnly cookie
+    tokenRes, err := uc.issueToken(c, userResponse)
     if err != nil {
-        return c.JSON(http.StatusInternalServerError, err.Error())
+        return err
     }
 
-    // Set JWT token in a HttpOnly cookie
-    cookie := new(http.Cookie)
-    cookie.Name = "token"
-    cookie.Value = tokenString
-    cookie.Expires = time.Now().Add(3 * time.Hour)
-    cookie.Path = "/"
-    cookie.Domain = os.Getenv("API_DOMAIN")
-    cookie.HttpOnly = true
-    cookie.Secure = true // Set to false if not using HTTPS
-    cookie.SameSite = http.SameSiteNoneMode
-    c.SetCookie(cookie)
-
-    // Optionally, redirect the user or return a success response
-    return c.JSON(http.StatusOK, echo.Map{"success": true})
+    return c.JSON(http.StatusOK, echo.Map{
+        "success":       true,
+        "user":          userResponse,
+        "token":         tokenRes.AccessToken,
+        "refresh_token": tokenRes.RefreshToken,
+    })
 }
 
-func (uc *userController) HandleOAuthLogin(c echo.Context) error {
-    // OAuthログイン処理の実装
-    email := c.FormValue("email")
-    name := c.FormValue("name")
-    return uc.OAuthLogin(c, email, name)
+// UpdateUserRoleは管理者がユーザーのロールを変更するためのハンドラーです。
+func (uc *userController) UpdateUserRole(c echo.Context) error {
+	userId, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		return apperror.BadRequest("User ID must be an integer")
+	}
+	body := struct {
+		Role string `json:"role"`
+	}{}
+	if err := c.Bind(&body); err != nil {
+		return apperror.Wrap(apperror.KindBadRequest, "invalid request body", err)
+	}
+	userRes, err := uc.uu.UpdateUserRole(c.Request().Context(), uint(userId), body.Role)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, userRes)
+}
+
+// RefreshTokenはリフレッシュトークンをローテーションし、新しいアクセストークンを発行します。
+func (uc *userController) RefreshToken(c echo.Context) error {
+	refreshToken := refreshTokenFromRequest(c)
+	if refreshToken == "" {
+		return usecase.ErrInvalidRefreshToken
+	}
+	tokenRes, userRes, err := uc.tu.RefreshToken(c.Request().Context(), refreshToken)
+	if err != nil {
+		return err
+	}
+	uc.setTokenCookies(c, tokenRes)
+	return c.JSON(http.StatusOK, echo.Map{
+		"token":         tokenRes.AccessToken,
+		"refresh_token": tokenRes.RefreshToken,
+		"expires_at":    tokenRes.AccessTokenExpiresAt,
+		"user":          userRes,
+	})
+}
+
+// RevokeUserSessionsは管理者が指定したユーザーの全セッションを失効させるためのハンドラーです。
+func (uc *userController) RevokeUserSessions(c echo.Context) error {
+	userId, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		return apperror.BadRequest("User ID must be an integer")
+	}
+	if err := uc.tu.RevokeAllTokens(c.Request().Context(), uint(userId)); err != nil {
+		return err
+	}
+	return c.NoContent(http.StatusNoContent)
 }
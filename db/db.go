@@ -2,32 +2,58 @@ package db
 
 import (
 	"fmt"
+	"go-rest-api/config"
 	"log"
-	"os"
+	"strings"
 
-	"github.com/joho/godotenv"
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
-func NewDB() *gorm.DB {
-	if os.Getenv("GO_ENV") == "dev" {
-		err := godotenv.Load()
-		if err != nil {
-			log.Fatalln(err)
-		}
-	}
-	url := fmt.Sprintf("postgres://%s:%s@%s:%s/%s", os.Getenv("POSTGRES_USER"),
-		os.Getenv("POSTGRES_PW"), os.Getenv("POSTGRES_HOST"),
-		os.Getenv("POSTGRES_PORT"), os.Getenv("POSTGRES_DB"))
-	db, err := gorm.Open(postgres.Open(url), &gorm.Config{})
+func NewDB(cfg config.Database) *gorm.DB {
+	db, err := Open(cfg)
 	if err != nil {
 		log.Fatalln(err)
 	}
-	fmt.Println("Connceted")
+	// 標準出力はコマンドの出力に使うため、ログとして標準エラー出力に書く
+	log.Println("Connected")
 	return db
 }
 
+// Open は設定されたドライバーでデータベースに接続します。
+func Open(cfg config.Database) (*gorm.DB, error) {
+	switch cfg.Driver {
+	case config.DriverPostgres:
+		return gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{})
+	case config.DriverSQLite:
+		return OpenSQLite(cfg.SQLitePath)
+	}
+	return nil, fmt.Errorf("unknown database driver %q", cfg.Driver)
+}
+
+// OpenSQLite は SQLite のデータベースに接続します。path が ":memory:" の場合はメモリ上のデータベースです。
+// 外部キー制約を有効にし、書き込みの競合を避けるため接続は1つだけにします。
+// メモリ上のデータベースは接続ごとに別のデータベースになるため、この点でも接続を1つにする必要があります。
+func OpenSQLite(path string) (*gorm.DB, error) {
+	dsn := path
+	if !strings.Contains(dsn, "?") {
+		dsn += "?_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)"
+	}
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+	// 接続が閉じられるとメモリ上のデータベースが消えるため、アイドル状態の接続を残す
+	sqlDB.SetMaxIdleConns(1)
+	return db, nil
+}
+
 func CloseDB(db *gorm.DB) {
 	sqlDB, _ := db.DB()
 	if err := sqlDB.Close(); err != nil {
@@ -0,0 +1,65 @@
+package geocoder
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+)
+
+type fileGeocoder struct {
+	locations map[string]Location
+}
+
+// NewFileGeocoder は郵便番号と座標の対応表（CSV）を読み込むジオコーダーです。
+// 各行は "郵便番号,緯度,経度" で、先頭行が見出しの場合は読み飛ばします。
+// 外部サービスを使えない環境やショップの一括登録で使うことを想定しています。
+func NewFileGeocoder(path string) (IGeocoder, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = 3
+	r.TrimLeadingSpace = true
+	locations := map[string]Location{}
+	for line := 1; ; line++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		postalCode, ok := NormalizePostalCode(record[0])
+		if !ok {
+			if line == 1 {
+				continue
+			}
+			return nil, fmt.Errorf("%s:%d: invalid postal code %q", path, line, record[0])
+		}
+		lat, err1 := strconv.ParseFloat(record[1], 64)
+		lng, err2 := strconv.ParseFloat(record[2], 64)
+		if err1 != nil || err2 != nil || lat < -90 || lat > 90 || lng < -180 || lng > 180 {
+			return nil, fmt.Errorf("%s:%d: invalid coordinates", path, line)
+		}
+		locations[postalCode] = Location{Latitude: lat, Longitude: lng}
+	}
+	return &fileGeocoder{locations}, nil
+}
+
+func (g *fileGeocoder) Geocode(postalCode string) (Location, error) {
+	normalized, ok := NormalizePostalCode(postalCode)
+	if !ok {
+		return Location{}, ErrNotFound
+	}
+	location, ok := g.locations[normalized]
+	if !ok {
+		return Location{}, ErrNotFound
+	}
+	return location, nil
+}
@@ -0,0 +1,68 @@
+package geocoder
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+)
+
+// ErrNotFound は郵便番号に対応する座標が見つからない場合に返されます。
+var ErrNotFound = errors.New("location not found for postal code")
+
+// Location は緯度・経度（度）です。
+type Location struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// IGeocoder は郵便番号から座標を求めます。
+// 外部APIを使わない実装に差し替えられるよう、インターフェースにしています。
+type IGeocoder interface {
+	Geocode(postalCode string) (Location, error)
+}
+
+type nopGeocoder struct{}
+
+// NewNopGeocoder は常に ErrNotFound を返すジオコーダーです。ジオコーディングを使わない場合に使います。
+func NewNopGeocoder() IGeocoder {
+	return nopGeocoder{}
+}
+
+func (nopGeocoder) Geocode(string) (Location, error) {
+	return Location{}, ErrNotFound
+}
+
+// digitNormalizer は全角数字やハイフンの異体字を半角に揃えます。
+var digitNormalizer = strings.NewReplacer(
+	"０", "0", "１", "1", "２", "2", "３", "3", "４", "4",
+	"５", "5", "６", "6", "７", "7", "８", "8", "９", "9",
+	"－", "-", "ー", "-", "‐", "-", "−", "-", "―", "-",
+)
+
+var (
+	postalCodePattern   = regexp.MustCompile(`^\s*(\d{3})-?(\d{4})\s*$`)
+	postalCodeInAddress = regexp.MustCompile(`(?:^|\D)(\d{3})-(\d{4})(?:\D|$)|〒\s*(\d{3})-?(\d{4})`)
+)
+
+// NormalizePostalCode は郵便番号を "100-0001" の形式に揃えます。郵便番号として正しくない場合は false を返します。
+func NormalizePostalCode(s string) (string, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(digitNormalizer.Replace(s)), "〒")
+	m := postalCodePattern.FindStringSubmatch(s)
+	if m == nil {
+		return "", false
+	}
+	return m[1] + "-" + m[2], true
+}
+
+// FindPostalCode は住所に含まれる郵便番号を "100-0001" の形式で返します。
+// 電話番号などとの取り違えを避けるため、ハイフン付きか〒付きのものだけを郵便番号とみなします。
+func FindPostalCode(address string) (string, bool) {
+	m := postalCodeInAddress.FindStringSubmatch(digitNormalizer.Replace(address))
+	if m == nil {
+		return "", false
+	}
+	if m[1] != "" {
+		return m[1] + "-" + m[2], true
+	}
+	return m[3] + "-" + m[4], true
+}
@@ -0,0 +1,110 @@
+package mailer
+
+import (
+	"fmt"
+	"mime"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Message は送信するメールです。本文はプレーンテキストです。
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// IMailer はメール送信の抽象です。本番ではSMTP、開発やテストではファイルやメモリを使います。
+type IMailer interface {
+	Send(msg Message) error
+}
+
+type smtpMailer struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+// NewSMTPMailer は addr（host:port）のSMTPサーバー経由で送信します。username が空の場合は認証しません。
+func NewSMTPMailer(addr, from, username, password string) IMailer {
+	var auth smtp.Auth
+	if username != "" {
+		host := addr
+		if i := strings.LastIndex(addr, ":"); i >= 0 {
+			host = addr[:i]
+		}
+		auth = smtp.PlainAuth("", username, password, host)
+	}
+	return &smtpMailer{addr, from, auth}
+}
+
+func (sm *smtpMailer) Send(msg Message) error {
+	return smtp.SendMail(sm.addr, sm.auth, sm.from, []string{msg.To}, format(sm.from, msg))
+}
+
+type fileMailer struct {
+	dir string
+	mu  sync.Mutex
+	seq int
+}
+
+// NewFileMailer はメールを dir 以下に .eml ファイルとして書き出します。
+func NewFileMailer(dir string) IMailer {
+	return &fileMailer{dir: dir}
+}
+
+func (fm *fileMailer) Send(msg Message) error {
+	fm.mu.Lock()
+	defer fm.mu.Unlock()
+	if err := os.MkdirAll(fm.dir, 0o700); err != nil {
+		return err
+	}
+	fm.seq++
+	name := fmt.Sprintf("%s-%03d.eml", time.Now().Format("20060102T150405.000000000"), fm.seq)
+	return os.WriteFile(filepath.Join(fm.dir, name), format("noreply@localhost", msg), 0o600)
+}
+
+// MemoryMailer は送信したメールをメモリに保持します。テストで送信内容を確認するために使います。
+type MemoryMailer struct {
+	mu       sync.Mutex
+	messages []Message
+}
+
+func NewMemoryMailer() *MemoryMailer {
+	return &MemoryMailer{}
+}
+
+func (mm *MemoryMailer) Send(msg Message) error {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	mm.messages = append(mm.messages, msg)
+	return nil
+}
+
+// Messages はこれまでに送信されたメールのコピーを返します。
+func (mm *MemoryMailer) Messages() []Message {
+	mm.mu.Lock()
+	defer mm.mu.Unlock()
+	return append([]Message(nil), mm.messages...)
+}
+
+func format(from string, msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", headerValue(from))
+	fmt.Fprintf(&b, "To: %s\r\n", headerValue(msg.To))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("UTF-8", headerValue(msg.Subject)))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(msg.Body)
+	return []byte(b.String())
+}
+
+// headerValue はヘッダーインジェクションを防ぐため改行を取り除きます。
+func headerValue(v string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(v)
+}
@@ -1,56 +1,168 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"go-rest-api/config"
 	"go-rest-api/controller"
 	"go-rest-api/db"
+	"go-rest-api/geocoder"
+	"go-rest-api/mailer"
+	"go-rest-api/media"
+	"go-rest-api/migration"
+	"go-rest-api/oidc"
 	"go-rest-api/repository"
 	"go-rest-api/router"
+	"go-rest-api/storage"
 	"go-rest-api/usecase"
 	"go-rest-api/validator"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 )
 
 func main() {
-	db := db.NewDB()
+	// 設定を読み込み、不足があれば起動しない
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := cfg.ValidateServer(); err != nil {
+		log.Fatalln("invalid config:", err)
+	}
+
+	database := db.NewDB(cfg.Database)
+	// マイグレーションは起動時に一度だけ読み込み、適用とヘルスチェックで共有する
+	migrator, err := migration.NewMigrator(database)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	// インメモリの SQLite は起動のたびに空になるため、常にマイグレーションを適用する
+	if cfg.Database.MigrateOnStart || cfg.Database.InMemory() {
+		// 複数のインスタンスが同時に起動しても、アドバイザリーロックにより1つずつ適用される
+		if err := migrate(migrator); err != nil {
+			log.Fatalln("Migration failed:", err)
+		}
+	}
+
+	// 複数のリポジトリの操作を1つのトランザクションで実行するためのもの
+	unitOfWork := repository.NewUnitOfWork(database)
 
 	// User related components
 	userValidator := validator.NewUserValidator()
-	userRepository := repository.NewUserRepository(db)
-	userUsecase := usecase.NewUserUsecase(userRepository, userValidator)
-	userController := controller.NewUserController(userUsecase)
+	userRepository := repository.NewUserRepository(database)
+	refreshTokenRepository := repository.NewRefreshTokenRepository(database)
+	tokenUsecase := usecase.NewTokenUsecase(refreshTokenRepository, userRepository, []byte(cfg.Secret), cfg.AccessTokenTTL, cfg.RefreshTokenTTL)
+	identityRepository := repository.NewIdentityRepository(database)
+	oidcVerifier := oidc.NewVerifier(cfg.OIDC.Issuer, cfg.OIDC.ClientID, oidc.NewKeySet(cfg.OIDC.JWKSURL))
+	userUsecase := usecase.NewUserUsecase(userRepository, identityRepository, userValidator, oidcVerifier)
+	userTokenRepository := repository.NewUserTokenRepository(database)
+	accountUsecase := usecase.NewAccountUsecase(userRepository, userTokenRepository, userValidator, newMailer(cfg.Mail), []byte(cfg.Secret), cfg.AppURL)
+	userController := controller.NewUserController(userUsecase, tokenUsecase, accountUsecase, cfg.APIDomain)
+	accountController := controller.NewAccountController(accountUsecase)
+
+	// Media related components
+	mediaStorage := storage.NewLocalStorage(cfg.Media.Dir, cfg.Media.BaseURL)
+	imageProcessor := media.NewProcessor(cfg.Media.MaxBytes, media.DefaultThumbnailSize)
 
 	// Task related components
 	taskValidator := validator.NewTaskValidator()
-	taskRepository := repository.NewTaskRepository(db)
+	taskRepository := repository.NewTaskRepository(database)
 	taskUsecase := usecase.NewTaskUsecase(taskRepository, taskValidator)
 	taskController := controller.NewTaskController(taskUsecase)
 
 	// Blog related components
 	blogValidator := validator.NewBlogValidator()
-	blogRepository := repository.NewBlogRepository(db)
-	blogUsecase := usecase.NewBlogUsecase(blogRepository, blogValidator)
+	blogRepository := repository.NewBlogRepository(database)
+	blogUsecase := usecase.NewBlogUsecase(blogRepository, blogValidator, mediaStorage, imageProcessor)
 	blogController := controller.NewBlogController(blogUsecase)
 
 	// Shop related components
 	shopValidator := validator.NewShopValidator()
-	shopRepository := repository.NewShopRepository(db)
-	shopUsecase := usecase.NewShopUsecase(shopRepository, shopValidator)
+	shopRepository := repository.NewShopRepository(database)
+	shopGeocoder, err := newGeocoder(cfg.GeocoderFile)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	shopUsecase := usecase.NewShopUsecase(shopRepository, shopValidator, shopGeocoder, mediaStorage, imageProcessor, cfg.ShopLocation)
 	shopController := controller.NewShopController(shopUsecase)
 
 	// Favorite related components
 	favoriteValidator := validator.NewFavoriteValidator()
-	favoriteRepository := repository.NewFavoriteRepository(db)
-	favoriteUsecase := usecase.NewFavoriteUsecase(favoriteRepository, shopRepository, userRepository, favoriteValidator)
+	favoriteRepository := repository.NewFavoriteRepository(database)
+	favoriteUsecase := usecase.NewFavoriteUsecase(favoriteRepository, shopRepository, userRepository, unitOfWork, favoriteValidator, cfg.ShopLocation)
 	favoriteController := controller.NewFavoriteController(favoriteUsecase)
 
+	// Menu related components
+	menuValidator := validator.NewMenuValidator()
+	menuRepository := repository.NewMenuRepository(database)
+	menuUsecase := usecase.NewMenuUsecase(menuRepository, shopRepository, menuValidator)
+	menuController := controller.NewMenuController(menuUsecase)
+
 	// Reservation related components
 	reservationValidator := validator.NewReservationValidator()
-	reservationRepository := repository.NewReservationRepository(db)
-	reservationUsecase := usecase.NewReservationUsecase(reservationRepository, reservationValidator)
+	reservationRepository := repository.NewReservationRepository(database)
+	reservationUsecase := usecase.NewReservationUsecase(reservationRepository, shopRepository, unitOfWork, reservationValidator)
 	reservationController := controller.NewReservationController(reservationUsecase)
 
+	// Review related components
+	reviewValidator := validator.NewReviewValidator()
+	reviewRepository := repository.NewReviewRepository(database)
+	reviewUsecase := usecase.NewReviewUsecase(reviewRepository, reservationRepository, shopRepository, reviewValidator)
+	reviewController := controller.NewReviewController(reviewUsecase)
+
+	// Health check related components
+	healthRepository := repository.NewHealthRepository(database, migrator)
+	healthUsecase := usecase.NewHealthUsecase(healthRepository)
+	healthController := controller.NewHealthController(healthUsecase)
+
 	// Initialize the router and start the server
-	e := router.NewRouter(userController, taskController, blogController, shopController, favoriteController, reservationController) // Modify to include the reservationController
-	e.Logger.Fatal(e.Start(":8080"))
+	e := router.NewRouter(cfg, userController, accountController, taskController, blogController, shopController, favoriteController, reservationController, reviewController, menuController, healthController) // Modify to include the reservationController
+
+	// SIGINT・SIGTERM を受け取ったら新しい接続の受け付けをやめ、処理中のリクエストの完了を待ってから終了する
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		if err := e.Start(cfg.Addr()); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.Logger.Fatal(err)
+		}
+	}()
+	<-ctx.Done()
+	stop()
+
+	log.Printf("Shutting down (waiting up to %s for in-flight requests)", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		log.Println("Shutdown:", err)
+	}
+	// リクエストが全て終わってからコネクションプールを閉じる
+	db.CloseDB(database)
+}
+
+// newMailerはSMTP_ADDRが設定されていればSMTPで、そうでなければMAIL_DIRにファイルとしてメールを出力します。
+func newMailer(cfg config.Mail) mailer.IMailer {
+	if cfg.SMTPAddr != "" {
+		return mailer.NewSMTPMailer(cfg.SMTPAddr, cfg.From, cfg.SMTPUsername, cfg.SMTPPassword)
+	}
+	return mailer.NewFileMailer(cfg.Dir)
 }
 
+// newGeocoderはGEOCODER_FILEが設定されていればその対応表で、そうでなければジオコーディングを行わないジオコーダーを返します。
+func newGeocoder(path string) (geocoder.IGeocoder, error) {
+	if path == "" {
+		return geocoder.NewNopGeocoder(), nil
+	}
+	return geocoder.NewFileGeocoder(path)
+}
 
+// migrateは未適用のマイグレーションを適用します。
+func migrate(migrator migration.IMigrator) error {
+	applied, err := migrator.Up()
+	for _, m := range applied {
+		log.Printf("applied migration %04d_%s", m.Version, m.Name)
+	}
+	return err
+}
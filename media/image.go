@@ -0,0 +1,150 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif" // image.Decode で GIF を読み込めるよう登録する
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+)
+
+// DefaultThumbnailSize は縮小画像の長辺のピクセル数です。
+const DefaultThumbnailSize = 320
+
+// maxPixels は受け付ける画像の最大ピクセル数です。展開するとメモリを使い切る画像を読み込む前に拒否します。
+const maxPixels = 40_000_000
+
+var (
+	// ErrTooLarge はファイルが上限のサイズを超えている場合に返されます。
+	ErrTooLarge = errors.New("file is too large")
+	// ErrUnsupportedType は JPEG・PNG・GIF 以外のファイルの場合に返されます。
+	ErrUnsupportedType = errors.New("file must be a JPEG, PNG or GIF image")
+	// ErrInvalidImage は画像として読み込めない場合や、ピクセル数が多すぎる場合に返されます。
+	ErrInvalidImage = errors.New("file is not a valid image")
+)
+
+// extensions は受け付ける画像の Content-Type と拡張子です。
+var extensions = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
+
+// Image はアップロードされた画像と、その縮小画像です。
+type Image struct {
+	Data        []byte
+	ContentType string
+	Ext         string
+	Width       int
+	Height      int
+
+	Thumbnail            []byte
+	ThumbnailContentType string
+	ThumbnailExt         string
+}
+
+// IProcessor はアップロードされたファイルを検証し、縮小画像を作ります。
+type IProcessor interface {
+	Process(r io.Reader) (Image, error)
+}
+
+type processor struct {
+	maxBytes      int64
+	thumbnailSize int
+}
+
+// NewProcessor は maxBytes までの画像を受け付け、長辺が thumbnailSize の縮小画像を作ります。
+func NewProcessor(maxBytes int64, thumbnailSize int) IProcessor {
+	return &processor{maxBytes, thumbnailSize}
+}
+
+// Process は r を読み込み、ファイルの内容から種類を判定します（クライアントが送る Content-Type は使いません）。
+func (p *processor) Process(r io.Reader) (Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, p.maxBytes+1))
+	if err != nil {
+		return Image{}, err
+	}
+	if int64(len(data)) > p.maxBytes {
+		return Image{}, ErrTooLarge
+	}
+	contentType := http.DetectContentType(data)
+	ext, ok := extensions[contentType]
+	if !ok {
+		return Image{}, ErrUnsupportedType
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > maxPixels {
+		return Image{}, ErrInvalidImage
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, ErrInvalidImage
+	}
+
+	img := Image{
+		Data:        data,
+		ContentType: contentType,
+		Ext:         ext,
+		Width:       config.Width,
+		Height:      config.Height,
+	}
+	// 透過を保つため、PNG と GIF の縮小画像は PNG にする（GIF は最初のフレームのみ）
+	thumb := Thumbnail(src, p.thumbnailSize)
+	buf := bytes.Buffer{}
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80})
+		img.ThumbnailContentType, img.ThumbnailExt = "image/jpeg", ".jpg"
+	} else {
+		err = png.Encode(&buf, thumb)
+		img.ThumbnailContentType, img.ThumbnailExt = "image/png", ".png"
+	}
+	if err != nil {
+		return Image{}, err
+	}
+	img.Thumbnail = buf.Bytes()
+	return img, nil
+}
+
+// Thumbnail は縦横比を保って長辺が size 以下になるよう縮小します。縮小元の画素を面積で平均するため、
+// 細かい模様がちらつきにくくなります。size 以下の画像はそのままの大きさで複製します。
+func Thumbnail(src image.Image, size int) image.Image {
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if w > size || h > size {
+		if w >= h {
+			dw, dh = size, max(1, h*size/w)
+		} else {
+			dw, dh = max(1, w*size/h), size
+		}
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := b.Min.Y+y*h/dh, b.Min.Y+(y+1)*h/dh
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < dw; x++ {
+			x0, x1 := b.Min.X+x*w/dw, b.Min.X+(x+1)*w/dw
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+			// 透過した画素の色が混ざらないよう、乗算済みアルファのまま平均する
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			c := color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(bl / n), A: uint16(a / n)}
+			dst.Set(x, y, c)
+		}
+	}
+	return dst
+}
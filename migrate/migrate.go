@@ -1,22 +1,122 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"go-rest-api/config"
 	"go-rest-api/db"
-	"go-rest-api/model"
+	"go-rest-api/migration"
+	"log"
+	"os"
+	"strconv"
+	"strings"
 )
 
+const usage = `usage:
+  migrate [-config file] [up]        apply all pending migrations
+  migrate [-config file] down [N]    roll back the latest N migrations (default 1)
+  migrate [-config file] status      list migrations and whether they are applied
+  migrate [-config file] baseline    mark the baseline as applied on a database created by AutoMigrate
+  migrate create [-dir dir] NAME     create empty NNNN_NAME.up.sql / .down.sql files for each database`
+
 func main() {
-	dbConn := db.NewDB()
-	defer db.CloseDB(dbConn)
+	args := os.Args[1:]
+	if len(args) > 0 && args[0] == "create" {
+		if err := create(args[1:]); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
 
-	// 既存のモデルと新しい Reservation モデルをマイグレートします
-	err := dbConn.AutoMigrate(&model.User{}, &model.Task{}, &model.Blog{}, &model.Shop{}, &model.Favorite{}, &model.Reservation{})
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a .env style config file")
+	fs.Usage = func() { fmt.Fprintln(os.Stderr, usage) }
+	if err := fs.Parse(args); err != nil {
+		os.Exit(2)
+	}
+	command := "up"
+	if fs.NArg() > 0 {
+		command = fs.Arg(0)
+	}
+
+	cfgArgs := []string{}
+	if *configFile != "" {
+		cfgArgs = append(cfgArgs, "-config", *configFile)
+	}
+	cfg, err := config.Load(cfgArgs)
 	if err != nil {
-		fmt.Println("Migration failed:", err)
-		return
+		log.Fatalln(err)
+	}
+	dbConn := db.NewDB(cfg.Database)
+	defer db.CloseDB(dbConn)
+	migrator, err := migration.NewMigrator(dbConn)
+	if err != nil {
+		log.Fatalln(err)
 	}
 
-	fmt.Println("Successfully Migrated")
+	switch command {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalln("Migration failed:", err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("No pending migrations")
+		}
+	case "down":
+		steps := 1
+		if fs.NArg() > 1 {
+			if steps, err = strconv.Atoi(fs.Arg(1)); err != nil {
+				log.Fatalln("N must be an integer")
+			}
+		}
+		reverted, err := migrator.Down(steps)
+		for _, m := range reverted {
+			fmt.Printf("rolled back %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.Fatalln("Rollback failed:", err)
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			if s.Missing {
+				state += " (not in this version)"
+			}
+			fmt.Printf("%04d_%-40s %s\n", s.Version, s.Name, state)
+		}
+	case "baseline":
+		if err := migrator.Baseline(); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Println("Baseline recorded")
+	default:
+		log.Fatalf("unknown command %q\n%s", command, usage)
+	}
 }
 
+func create(args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	dir := fs.String("dir", "migration/sql", "directory of the SQL migrations")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("create requires a NAME\n%s", usage)
+	}
+	files, err := migration.Create(*dir, strings.ToLower(fs.Arg(0)))
+	for _, file := range files {
+		fmt.Println("created", file)
+	}
+	return err
+}
@@ -0,0 +1,290 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrIrreversible は down の処理がないマイグレーションを戻そうとした場合に返されます。
+var ErrIrreversible = errors.New("migration cannot be rolled back")
+
+// BaselineVersion は AutoMigrate で作成していた時点のスキーマのバージョンです。
+const BaselineVersion int64 = 1
+
+// lockKey は同時に複数のプロセスがマイグレーションを実行しないよう取得するアドバイザリーロックのキーです。
+const lockKey int64 = 727100020
+
+// Migration は番号付きのスキーマ変更です。SQL ファイルまたは Go の関数で定義します。
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(tx *gorm.DB) error
+	// Down が nil のマイグレーションは戻せません。
+	Down func(tx *gorm.DB) error
+}
+
+// SchemaMigration は適用済みのマイグレーションの記録です。
+type SchemaMigration struct {
+	Version   int64     `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// Status はマイグレーションの適用状況です。
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt *time.Time
+	// Missing は適用済みとして記録されているが、このバージョンのコードに含まれないマイグレーションです。
+	Missing bool
+}
+
+type IMigrator interface {
+	// Up は未適用のマイグレーションをバージョン順に全て適用し、適用したものを返します。
+	Up() ([]Migration, error)
+	// Down は適用済みのマイグレーションを新しいものから steps 件戻し、戻したものを返します。
+	Down(steps int) ([]Migration, error)
+	Status() ([]Status, error)
+	// Baseline は AutoMigrate で作成済みのデータベースに、ベースラインを実行せずに適用済みとして記録します。
+	Baseline() error
+	// Pending は未適用のマイグレーションの件数です。
+	Pending() (int, error)
+	// WithContext は ctx でクエリを実行するマイグレーターを返します。マイグレーションは読み込み直しません。
+	WithContext(ctx context.Context) IMigrator
+}
+
+type migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// NewMigrator は埋め込みの SQL ファイルと Go で登録したマイグレーションを使うマイグレーターを返します。
+// SQL ファイルは db のデータベース（PostgreSQL または SQLite）用のものを使います。
+func NewMigrator(db *gorm.DB) (IMigrator, error) {
+	migrations, err := loadSQL(db.Dialector.Name())
+	if err != nil {
+		return nil, err
+	}
+	migrations = append(migrations, registered...)
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d (%s, %s)", migrations[i].Version, migrations[i-1].Name, migrations[i].Name)
+		}
+	}
+	return &migrator{db, migrations}, nil
+}
+
+// registered は Go で定義したマイグレーションです。Register で追加します。
+var registered []Migration
+
+// Register は Go で定義したマイグレーションを追加します。SQL では書けないデータの変換などに使います。
+// NewMigrator より前に実行されるよう、migration パッケージのファイルの init から呼びます。
+// Up と Down は SQL のマイグレーションと同じく、それぞれ1つのトランザクションの中で実行します。
+func Register(m Migration) {
+	if m.Version <= 0 || !namePattern.MatchString(m.Name) || m.Up == nil {
+		panic(fmt.Sprintf("migration: invalid migration %04d_%s", m.Version, m.Name))
+	}
+	registered = append(registered, m)
+}
+
+func (m *migrator) Up() ([]Migration, error) {
+	done := []Migration{}
+	err := m.withLock(func(conn *gorm.DB) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, mg := range m.migrations {
+			if _, ok := applied[mg.Version]; ok {
+				continue
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := mg.Up(tx); err != nil {
+					return err
+				}
+				return tx.Create(&SchemaMigration{Version: mg.Version, Name: mg.Name, AppliedAt: time.Now()}).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", mg.Version, mg.Name, err)
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+func (m *migrator) Down(steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, errors.New("steps must be at least 1")
+	}
+	done := []Migration{}
+	err := m.withLock(func(conn *gorm.DB) error {
+		records := []SchemaMigration{}
+		if err := conn.Order("version DESC").Limit(steps).Find(&records).Error; err != nil {
+			return err
+		}
+		for _, record := range records {
+			mg, ok := m.find(record.Version)
+			if !ok {
+				return fmt.Errorf("migration %04d_%s is not known to this version", record.Version, record.Name)
+			}
+			if mg.Down == nil {
+				return fmt.Errorf("migration %04d_%s: %w", mg.Version, mg.Name, ErrIrreversible)
+			}
+			err := conn.Transaction(func(tx *gorm.DB) error {
+				if err := mg.Down(tx); err != nil {
+					return err
+				}
+				return tx.Delete(&SchemaMigration{}, mg.Version).Error
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", mg.Version, mg.Name, err)
+			}
+			done = append(done, mg)
+		}
+		return nil
+	})
+	return done, err
+}
+
+// Status は読み込みのみを行います。schema_migrations テーブルがない場合は、全てのマイグレーションが未適用です。
+// テーブルの作成はロックを取得して行う必要があるため、Up などの変更する操作でのみ行います。
+func (m *migrator) Status() ([]Status, error) {
+	records := []SchemaMigration{}
+	exists, err := hasTable(m.db)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		if err := m.db.Order("version").Find(&records).Error; err != nil {
+			return nil, err
+		}
+	}
+	applied := map[int64]SchemaMigration{}
+	for _, record := range records {
+		applied[record.Version] = record
+	}
+	statuses := []Status{}
+	for _, mg := range m.migrations {
+		status := Status{Version: mg.Version, Name: mg.Name}
+		if record, ok := applied[mg.Version]; ok {
+			status.AppliedAt = &record.AppliedAt
+			delete(applied, mg.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, record := range records {
+		if _, ok := applied[record.Version]; ok {
+			record := record
+			statuses = append(statuses, Status{Version: record.Version, Name: record.Name, AppliedAt: &record.AppliedAt, Missing: true})
+		}
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+func (m *migrator) Baseline() error {
+	mg, ok := m.find(BaselineVersion)
+	if !ok {
+		return errors.New("baseline migration is not defined")
+	}
+	return m.withLock(func(conn *gorm.DB) error {
+		var count int64
+		if err := conn.Model(&SchemaMigration{}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return errors.New("migrations have already been recorded; baseline is only for databases created by AutoMigrate")
+		}
+		return conn.Create(&SchemaMigration{Version: mg.Version, Name: mg.Name, AppliedAt: time.Now()}).Error
+	})
+}
+
+func (m *migrator) Pending() (int, error) {
+	statuses, err := m.Status()
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (m *migrator) WithContext(ctx context.Context) IMigrator {
+	return &migrator{m.db.WithContext(ctx), m.migrations}
+}
+
+func (m *migrator) find(version int64) (Migration, bool) {
+	for _, mg := range m.migrations {
+		if mg.Version == version {
+			return mg, true
+		}
+	}
+	return Migration{}, false
+}
+
+// withLock はアドバイザリーロックを取得した1つの接続で fc を実行します。
+// 他のプロセスがマイグレーション中の場合は終わるまで待ちます。
+// SQLite は書き込みがデータベース全体で直列になるため、ロックは取得しません。
+func (m *migrator) withLock(fc func(conn *gorm.DB) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		if conn.Dialector.Name() == "postgres" {
+			if err := conn.Exec("SELECT pg_advisory_lock(?)", lockKey).Error; err != nil {
+				return err
+			}
+			defer conn.Exec("SELECT pg_advisory_unlock(?)", lockKey)
+		}
+		if err := ensureTable(conn); err != nil {
+			return err
+		}
+		return fc(conn)
+	})
+}
+
+func ensureTable(db *gorm.DB) error {
+	timestamp := "timestamptz"
+	if db.Dialector.Name() != "postgres" {
+		timestamp = "datetime"
+	}
+	return db.Exec(`CREATE TABLE IF NOT EXISTS "schema_migrations" (
+    "version" bigint PRIMARY KEY,
+    "name" text NOT NULL,
+    "applied_at" ` + timestamp + ` NOT NULL
+)`).Error
+}
+
+// hasTable は schema_migrations テーブルがあるかを返します。
+func hasTable(db *gorm.DB) (bool, error) {
+	query := "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'schema_migrations'"
+	if db.Dialector.Name() == "postgres" {
+		query = "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = CURRENT_SCHEMA() AND table_name = 'schema_migrations'"
+	}
+	var count int64
+	if err := db.Raw(query).Scan(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func appliedVersions(db *gorm.DB) (map[int64]struct{}, error) {
+	versions := []int64{}
+	if err := db.Model(&SchemaMigration{}).Pluck("version", &versions).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]struct{}, len(versions))
+	for _, v := range versions {
+		applied[v] = struct{}{}
+	}
+	return applied, nil
+}
@@ -0,0 +1,133 @@
+package migration
+
+import (
+	"context"
+	"go-rest-api/config"
+	"go-rest-api/db"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func newSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	conn, err := db.Open(config.Database{Driver: config.DriverSQLite, SQLitePath: ":memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.CloseDB(conn) })
+	return conn
+}
+
+// withRegistered はテストの間だけ Go のマイグレーションを登録します。
+func withRegistered(t *testing.T, m Migration) {
+	t.Helper()
+	saved := registered
+	t.Cleanup(func() { registered = saved })
+	Register(m)
+}
+
+func TestStatusIsReadOnly(t *testing.T) {
+	conn := newSQLite(t)
+	migrator, err := NewMigrator(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := migrator.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// readiness の確認と同じく ctx を渡したマイグレーターでも読み込みのみを行う
+	pending, err := migrator.WithContext(context.Background()).Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) == 0 || pending != len(statuses) {
+		t.Fatalf("pending = %d of %d", pending, len(statuses))
+	}
+	if conn.Migrator().HasTable(&SchemaMigration{}) {
+		t.Fatal("Status created schema_migrations")
+	}
+
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	if pending, err := migrator.Pending(); err != nil || pending != 0 {
+		t.Fatalf("pending after up = %d, %v", pending, err)
+	}
+}
+
+func TestRegisterGoMigration(t *testing.T) {
+	withRegistered(t, Migration{
+		Version: 9001,
+		Name:    "default_genres",
+		Up: func(tx *gorm.DB) error {
+			if err := tx.Exec("CREATE TABLE genres (name text PRIMARY KEY)").Error; err != nil {
+				return err
+			}
+			for _, name := range []string{"寿司", "ラーメン"} {
+				if err := tx.Exec("INSERT INTO genres (name) VALUES (?)", name).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: func(tx *gorm.DB) error {
+			return tx.Exec("DROP TABLE genres").Error
+		},
+	})
+	conn := newSQLite(t)
+	migrator, err := NewMigrator(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last := applied[len(applied)-1]; last.Version != 9001 {
+		t.Fatalf("last applied migration = %04d_%s", last.Version, last.Name)
+	}
+	var count int64
+	if err := conn.Raw("SELECT COUNT(*) FROM genres").Scan(&count).Error; err != nil || count != 2 {
+		t.Fatalf("genres = %d, %v", count, err)
+	}
+
+	rolledBack, err := migrator.Down(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rolledBack) != 1 || rolledBack[0].Version != 9001 || conn.Migrator().HasTable("genres") {
+		t.Fatalf("rolled back = %+v", rolledBack)
+	}
+	if pending, err := migrator.Pending(); err != nil || pending != 1 {
+		t.Fatalf("pending after down = %d, %v", pending, err)
+	}
+}
+
+func TestRegisterDuplicateVersion(t *testing.T) {
+	withRegistered(t, Migration{Version: BaselineVersion, Name: "duplicate", Up: func(tx *gorm.DB) error { return nil }})
+	if _, err := NewMigrator(newSQLite(t)); err == nil {
+		t.Fatal("NewMigrator accepted a duplicate version")
+	}
+}
+
+func TestCreateAfterRegistered(t *testing.T) {
+	withRegistered(t, Migration{Version: 9001, Name: "default_genres", Up: func(tx *gorm.DB) error { return nil }})
+	dir := t.TempDir()
+	for _, dialect := range dialects {
+		if err := os.Mkdir(filepath.Join(dir, dialect), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := Create(dir, "add_tags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := filepath.Join(dir, "postgres", "9002_add_tags.up.sql"); files[0] != want {
+		t.Fatalf("created %v, want %s first", files, want)
+	}
+}
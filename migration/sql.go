@@ -0,0 +1,118 @@
+package migration
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"gorm.io/gorm"
+)
+
+// sqlFiles は "0002_add_xxx.up.sql" / "0002_add_xxx.down.sql" の形式のマイグレーションです。
+// データベースごとのディレクトリ（sql/postgres, sql/sqlite）に同じ番号・名前のファイルを置きます。
+// 1つのファイルは1回の Exec で実行するため、複数の文を書けます。
+//
+//go:embed sql/postgres/*.sql sql/sqlite/*.sql
+var sqlFiles embed.FS
+
+// dialects は SQL のマイグレーションを用意しているデータベースです。GORM の Dialector の名前と同じです。
+var dialects = []string{"postgres", "sqlite"}
+
+var sqlFilePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+var namePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// loadSQL は dialect の埋め込みの SQL ファイルをマイグレーションとして読み込みます。
+func loadSQL(dialect string) ([]Migration, error) {
+	dir := "sql/" + dialect
+	entries, err := fs.ReadDir(sqlFiles, dir)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("migrations for %s are not available", dialect)
+	}
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		m := sqlFilePattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		body, err := sqlFiles.ReadFile(dir + "/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mg
+		}
+		if mg.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has different names %q and %q", version, mg.Name, m[2])
+		}
+		if m[3] == "up" {
+			mg.Up = execSQL(string(body))
+		} else {
+			mg.Down = execSQL(string(body))
+		}
+	}
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if mg.Up == nil {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", mg.Version, mg.Name)
+		}
+		migrations = append(migrations, *mg)
+	}
+	return migrations, nil
+}
+
+func execSQL(sql string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		return tx.Exec(sql).Error
+	}
+}
+
+// Create は dir の下のデータベースごとのディレクトリに次の番号の up / down の SQL ファイルを作成し、そのパスを返します。
+// 番号は dir のファイルと Go で登録したマイグレーションのうち最大のものの次です。
+func Create(dir, name string) ([]string, error) {
+	if !namePattern.MatchString(name) {
+		return nil, errors.New("name must consist of lowercase letters, digits and underscores")
+	}
+	var latest int64
+	for _, mg := range registered {
+		latest = max(latest, mg.Version)
+	}
+	for _, dialect := range dialects {
+		entries, err := os.ReadDir(filepath.Join(dir, dialect))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if m := sqlFilePattern.FindStringSubmatch(entry.Name()); m != nil {
+				version, _ := strconv.ParseInt(m[1], 10, 64)
+				latest = max(latest, version)
+			}
+		}
+	}
+	base := fmt.Sprintf("%04d_%s", latest+1, name)
+	files := []string{}
+	for _, dialect := range dialects {
+		up := filepath.Join(dir, dialect, base+".up.sql")
+		down := filepath.Join(dir, dialect, base+".down.sql")
+		if err := os.WriteFile(up, []byte("-- "+base+"\n"), 0o644); err != nil {
+			return files, err
+		}
+		files = append(files, up)
+		if err := os.WriteFile(down, []byte("-- "+base+" を戻す\n"), 0o644); err != nil {
+			return files, err
+		}
+		files = append(files, down)
+	}
+	return files, nil
+}
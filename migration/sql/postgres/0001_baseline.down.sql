@@ -0,0 +1,18 @@
+DROP TABLE IF EXISTS "user_tokens";
+DROP TABLE IF EXISTS "identities";
+DROP TABLE IF EXISTS "refresh_tokens";
+DROP TABLE IF EXISTS "review_reports";
+DROP TABLE IF EXISTS "reviews";
+DROP TABLE IF EXISTS "reservation_status_changes";
+DROP TABLE IF EXISTS "reservations";
+DROP TABLE IF EXISTS "favorites";
+DROP TABLE IF EXISTS "course_windows";
+DROP TABLE IF EXISTS "courses";
+DROP TABLE IF EXISTS "menus";
+DROP TABLE IF EXISTS "shop_images";
+DROP TABLE IF EXISTS "shop_closures";
+DROP TABLE IF EXISTS "shop_hours";
+DROP TABLE IF EXISTS "shops";
+DROP TABLE IF EXISTS "blogs";
+DROP TABLE IF EXISTS "tasks";
+DROP TABLE IF EXISTS "users";
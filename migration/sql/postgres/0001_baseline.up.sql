@@ -0,0 +1,287 @@
+-- 0001_baseline は AutoMigrate で作成していた時点のスキーマです。
+-- AutoMigrate で作成済みのデータベースでは、実行せずに適用済みとして記録します（migrate baseline）。
+
+CREATE TABLE "users" (
+    "id" bigserial,
+    "email" text UNIQUE,
+    "password" text,
+    "name" text,
+    "role" text NOT NULL DEFAULT 'customer',
+    "email_verified" boolean NOT NULL DEFAULT false,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE "tasks" (
+    "id" bigserial,
+    "title" text NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "user_id" bigint NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_tasks_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+
+CREATE TABLE "blogs" (
+    "id" bigserial,
+    "title" text NOT NULL,
+    "content" text NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "cover_key" text NOT NULL DEFAULT '',
+    "cover_url" text NOT NULL DEFAULT '',
+    "cover_thumbnail_key" text NOT NULL DEFAULT '',
+    "cover_thumbnail_url" text NOT NULL DEFAULT '',
+    "cover_content_type" text NOT NULL DEFAULT '',
+    "cover_size" bigint NOT NULL DEFAULT 0,
+    "cover_width" bigint NOT NULL DEFAULT 0,
+    "cover_height" bigint NOT NULL DEFAULT 0,
+    "user_id" bigint NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_blogs_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+
+CREATE TABLE "shops" (
+    "id" bigserial,
+    "external_id" text,
+    "name" text NOT NULL,
+    "address" text NOT NULL,
+    "postal_code" text NOT NULL DEFAULT '',
+    "latitude" decimal,
+    "longitude" decimal,
+    "area" text NOT NULL,
+    "genre" text NOT NULL,
+    "description" text NOT NULL,
+    "capacity" bigint NOT NULL DEFAULT 20,
+    "open_time" text NOT NULL DEFAULT '11:00',
+    "close_time" text NOT NULL DEFAULT '22:00',
+    "slot_minutes" bigint NOT NULL DEFAULT 60,
+    "owner_id" bigint,
+    "rating_average" decimal NOT NULL DEFAULT 0.000000,
+    "rating_count" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_shops_owner" FOREIGN KEY ("owner_id") REFERENCES "users"("id") ON DELETE SET NULL
+);
+CREATE UNIQUE INDEX "idx_shops_external_id" ON "shops" ("external_id");
+CREATE INDEX "idx_shops_location" ON "shops" ("latitude","longitude");
+CREATE INDEX "idx_shops_postal_code" ON "shops" ("postal_code");
+
+CREATE TABLE "shop_hours" (
+    "id" bigserial,
+    "shop_id" bigint NOT NULL,
+    "weekday" bigint NOT NULL,
+    "open_time" text NOT NULL,
+    "close_time" text NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_shops_hours" FOREIGN KEY ("shop_id") REFERENCES "shops"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_shop_hours_shop_id" ON "shop_hours" ("shop_id");
+
+CREATE TABLE "shop_closures" (
+    "id" bigserial,
+    "shop_id" bigint NOT NULL,
+    "date" timestamptz NOT NULL,
+    "start_time" text NOT NULL DEFAULT '',
+    "end_time" text NOT NULL DEFAULT '',
+    "reason" text NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_shops_closures" FOREIGN KEY ("shop_id") REFERENCES "shops"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_shop_closures_shop_date" ON "shop_closures" ("shop_id","date");
+
+CREATE TABLE "shop_images" (
+    "id" bigserial,
+    "shop_id" bigint NOT NULL,
+    "position" bigint NOT NULL,
+    "caption" text NOT NULL DEFAULT '',
+    "key" text NOT NULL DEFAULT '',
+    "url" text NOT NULL DEFAULT '',
+    "thumbnail_key" text NOT NULL DEFAULT '',
+    "thumbnail_url" text NOT NULL DEFAULT '',
+    "content_type" text NOT NULL DEFAULT '',
+    "size" bigint NOT NULL DEFAULT 0,
+    "width" bigint NOT NULL DEFAULT 0,
+    "height" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_shops_images" FOREIGN KEY ("shop_id") REFERENCES "shops"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_shop_images_shop_id" ON "shop_images" ("shop_id");
+
+CREATE TABLE "menus" (
+    "id" bigserial,
+    "shop_id" bigint NOT NULL,
+    "name" text NOT NULL,
+    "description" text NOT NULL DEFAULT '',
+    "position" bigint NOT NULL DEFAULT 0,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_menus_shop" FOREIGN KEY ("shop_id") REFERENCES "shops"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_menus_shop_id" ON "menus" ("shop_id");
+
+CREATE TABLE "courses" (
+    "id" bigserial,
+    "menu_id" bigint NOT NULL,
+    "shop_id" bigint NOT NULL,
+    "name" text NOT NULL,
+    "description" text NOT NULL DEFAULT '',
+    "price" bigint NOT NULL,
+    "duration_minutes" bigint NOT NULL,
+    "min_party_size" bigint NOT NULL DEFAULT 1,
+    "max_party_size" bigint NOT NULL DEFAULT 0,
+    "start_date" timestamptz,
+    "end_date" timestamptz,
+    "active" boolean NOT NULL DEFAULT true,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_menus_courses" FOREIGN KEY ("menu_id") REFERENCES "menus"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_courses_shop_id" ON "courses" ("shop_id");
+CREATE INDEX "idx_courses_menu_id" ON "courses" ("menu_id");
+
+CREATE TABLE "course_windows" (
+    "id" bigserial,
+    "course_id" bigint NOT NULL,
+    "weekday" bigint,
+    "start_time" text NOT NULL,
+    "end_time" text NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_courses_windows" FOREIGN KEY ("course_id") REFERENCES "courses"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_course_windows_course_id" ON "course_windows" ("course_id");
+
+CREATE TABLE "favorites" (
+    "id" bigserial,
+    "shop_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "is_favorite" boolean,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_shops_favorites" FOREIGN KEY ("shop_id") REFERENCES "shops"("id"),
+    CONSTRAINT "fk_users_favorites" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+
+CREATE TABLE "reservations" (
+    "id" bigserial,
+    "date" timestamptz NOT NULL,
+    "time" text NOT NULL,
+    "shop_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "num" bigint NOT NULL,
+    "status" text NOT NULL DEFAULT 'pending',
+    "course_id" bigint,
+    "total_price" bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_reservations_course" FOREIGN KEY ("course_id") REFERENCES "courses"("id") ON DELETE SET NULL,
+    CONSTRAINT "fk_shops_reservations" FOREIGN KEY ("shop_id") REFERENCES "shops"("id"),
+    CONSTRAINT "fk_users_reservations" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+CREATE INDEX "idx_reservations_course_id" ON "reservations" ("course_id");
+CREATE INDEX "idx_reservations_status" ON "reservations" ("status");
+
+CREATE TABLE "reservation_status_changes" (
+    "id" bigserial,
+    "reservation_id" bigint NOT NULL,
+    "from_status" text NOT NULL,
+    "to_status" text NOT NULL,
+    "actor_id" bigint NOT NULL,
+    "actor_role" text NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_reservations_status_changes" FOREIGN KEY ("reservation_id") REFERENCES "reservations"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_reservation_status_changes_reservation_id" ON "reservation_status_changes" ("reservation_id");
+
+CREATE TABLE "reviews" (
+    "id" bigserial,
+    "shop_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "reservation_id" bigint,
+    "rating" bigint NOT NULL,
+    "comment" text NOT NULL,
+    "verified" boolean NOT NULL DEFAULT false,
+    "reply" text NOT NULL DEFAULT '',
+    "replied_at" timestamptz,
+    "report_count" bigint NOT NULL DEFAULT 0,
+    "hidden" boolean NOT NULL DEFAULT false,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_reviews_shop" FOREIGN KEY ("shop_id") REFERENCES "shops"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_reviews_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_reviews_hidden" ON "reviews" ("hidden");
+CREATE UNIQUE INDEX "idx_reviews_reservation_id" ON "reviews" ("reservation_id");
+CREATE INDEX "idx_reviews_user_id" ON "reviews" ("user_id");
+CREATE INDEX "idx_reviews_shop_id" ON "reviews" ("shop_id");
+
+CREATE TABLE "review_reports" (
+    "id" bigserial,
+    "review_id" bigint NOT NULL,
+    "user_id" bigint NOT NULL,
+    "reason" text NOT NULL,
+    "created_at" timestamptz,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_review_reports_review" FOREIGN KEY ("review_id") REFERENCES "reviews"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX "idx_review_reports_review_user" ON "review_reports" ("review_id","user_id");
+
+CREATE TABLE "refresh_tokens" (
+    "id" bigserial,
+    "token_hash" text NOT NULL,
+    "family_id" text NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "revoked_at" timestamptz,
+    "created_at" timestamptz,
+    "user_id" bigint NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_refresh_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_refresh_tokens_user_id" ON "refresh_tokens" ("user_id");
+CREATE INDEX "idx_refresh_tokens_family_id" ON "refresh_tokens" ("family_id");
+CREATE UNIQUE INDEX "idx_refresh_tokens_token_hash" ON "refresh_tokens" ("token_hash");
+
+CREATE TABLE "identities" (
+    "id" bigserial,
+    "provider" text NOT NULL,
+    "subject" text NOT NULL,
+    "email" text,
+    "created_at" timestamptz,
+    "updated_at" timestamptz,
+    "user_id" bigint NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_identities_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_identities_user_id" ON "identities" ("user_id");
+CREATE UNIQUE INDEX "idx_identity_provider_subject" ON "identities" ("provider","subject");
+
+CREATE TABLE "user_tokens" (
+    "id" bigserial,
+    "purpose" text NOT NULL,
+    "token_hash" text NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "used_at" timestamptz,
+    "created_at" timestamptz,
+    "user_id" bigint NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_user_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_user_tokens_user_id" ON "user_tokens" ("user_id");
+CREATE UNIQUE INDEX "idx_user_tokens_token_hash" ON "user_tokens" ("token_hash");
+CREATE INDEX "idx_user_tokens_purpose" ON "user_tokens" ("purpose");
+
+-- ショップ検索用の拡張機能とインデックス。日本語は単語に分割されないため、全文検索に加えて pg_trgm による部分一致で検索する。
+-- idx_shops_search の式は repository.shopSearchDocument と同じにする必要がある。
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX "idx_shops_search" ON "shops" USING GIN ((to_tsvector('simple', name || ' ' || description || ' ' || address)));
+CREATE INDEX "idx_shops_name_trgm" ON "shops" USING GIN (name gin_trgm_ops);
+CREATE INDEX "idx_shops_description_trgm" ON "shops" USING GIN (description gin_trgm_ops);
+CREATE INDEX "idx_shops_address_trgm" ON "shops" USING GIN (address gin_trgm_ops);
@@ -0,0 +1,279 @@
+-- 0001_baseline は AutoMigrate で作成していた時点のスキーマの SQLite 版です。
+-- 全文検索のインデックスはなく、ショップの検索は LIKE による部分一致で行います。
+
+CREATE TABLE "users" (
+    "id" integer,
+    "email" text UNIQUE,
+    "password" text,
+    "name" text,
+    "role" text NOT NULL DEFAULT 'customer',
+    "email_verified" numeric NOT NULL DEFAULT false,
+    "created_at" datetime,
+    "updated_at" datetime,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE "tasks" (
+    "id" integer,
+    "title" text NOT NULL,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "user_id" integer NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_tasks_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+
+CREATE TABLE "blogs" (
+    "id" integer,
+    "title" text NOT NULL,
+    "content" text NOT NULL,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "cover_key" text NOT NULL DEFAULT '',
+    "cover_url" text NOT NULL DEFAULT '',
+    "cover_thumbnail_key" text NOT NULL DEFAULT '',
+    "cover_thumbnail_url" text NOT NULL DEFAULT '',
+    "cover_content_type" text NOT NULL DEFAULT '',
+    "cover_size" integer NOT NULL DEFAULT 0,
+    "cover_width" integer NOT NULL DEFAULT 0,
+    "cover_height" integer NOT NULL DEFAULT 0,
+    "user_id" integer NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_blogs_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+
+CREATE TABLE "shops" (
+    "id" integer,
+    "external_id" text,
+    "name" text NOT NULL,
+    "address" text NOT NULL,
+    "postal_code" text NOT NULL DEFAULT '',
+    "latitude" real,
+    "longitude" real,
+    "area" text NOT NULL,
+    "genre" text NOT NULL,
+    "description" text NOT NULL,
+    "capacity" integer NOT NULL DEFAULT 20,
+    "open_time" text NOT NULL DEFAULT '11:00',
+    "close_time" text NOT NULL DEFAULT '22:00',
+    "slot_minutes" integer NOT NULL DEFAULT 60,
+    "owner_id" integer,
+    "rating_average" real NOT NULL DEFAULT 0.000000,
+    "rating_count" integer NOT NULL DEFAULT 0,
+    "created_at" datetime,
+    "updated_at" datetime,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_shops_owner" FOREIGN KEY ("owner_id") REFERENCES "users"("id") ON DELETE SET NULL
+);
+CREATE INDEX "idx_shops_location" ON "shops" ("latitude","longitude");
+CREATE INDEX "idx_shops_postal_code" ON "shops" ("postal_code");
+CREATE UNIQUE INDEX "idx_shops_external_id" ON "shops" ("external_id");
+
+CREATE TABLE "shop_hours" (
+    "id" integer,
+    "shop_id" integer NOT NULL,
+    "weekday" integer NOT NULL,
+    "open_time" text NOT NULL,
+    "close_time" text NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_shops_hours" FOREIGN KEY ("shop_id") REFERENCES "shops"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_shop_hours_shop_id" ON "shop_hours" ("shop_id");
+
+CREATE TABLE "shop_closures" (
+    "id" integer,
+    "shop_id" integer NOT NULL,
+    "date" datetime NOT NULL,
+    "start_time" text NOT NULL DEFAULT '',
+    "end_time" text NOT NULL DEFAULT '',
+    "reason" text NOT NULL,
+    "created_at" datetime,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_shops_closures" FOREIGN KEY ("shop_id") REFERENCES "shops"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_shop_closures_shop_date" ON "shop_closures" ("shop_id","date");
+
+CREATE TABLE "shop_images" (
+    "id" integer,
+    "shop_id" integer NOT NULL,
+    "position" integer NOT NULL,
+    "caption" text NOT NULL DEFAULT '',
+    "key" text NOT NULL DEFAULT '',
+    "url" text NOT NULL DEFAULT '',
+    "thumbnail_key" text NOT NULL DEFAULT '',
+    "thumbnail_url" text NOT NULL DEFAULT '',
+    "content_type" text NOT NULL DEFAULT '',
+    "size" integer NOT NULL DEFAULT 0,
+    "width" integer NOT NULL DEFAULT 0,
+    "height" integer NOT NULL DEFAULT 0,
+    "created_at" datetime,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_shops_images" FOREIGN KEY ("shop_id") REFERENCES "shops"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_shop_images_shop_id" ON "shop_images" ("shop_id");
+
+CREATE TABLE "menus" (
+    "id" integer,
+    "shop_id" integer NOT NULL,
+    "name" text NOT NULL,
+    "description" text NOT NULL DEFAULT '',
+    "position" integer NOT NULL DEFAULT 0,
+    "created_at" datetime,
+    "updated_at" datetime,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_menus_shop" FOREIGN KEY ("shop_id") REFERENCES "shops"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_menus_shop_id" ON "menus" ("shop_id");
+
+CREATE TABLE "courses" (
+    "id" integer,
+    "menu_id" integer NOT NULL,
+    "shop_id" integer NOT NULL,
+    "name" text NOT NULL,
+    "description" text NOT NULL DEFAULT '',
+    "price" integer NOT NULL,
+    "duration_minutes" integer NOT NULL,
+    "min_party_size" integer NOT NULL DEFAULT 1,
+    "max_party_size" integer NOT NULL DEFAULT 0,
+    "start_date" datetime,
+    "end_date" datetime,
+    "active" numeric NOT NULL DEFAULT true,
+    "created_at" datetime,
+    "updated_at" datetime,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_menus_courses" FOREIGN KEY ("menu_id") REFERENCES "menus"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_courses_shop_id" ON "courses" ("shop_id");
+CREATE INDEX "idx_courses_menu_id" ON "courses" ("menu_id");
+
+CREATE TABLE "course_windows" (
+    "id" integer,
+    "course_id" integer NOT NULL,
+    "weekday" integer,
+    "start_time" text NOT NULL,
+    "end_time" text NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_courses_windows" FOREIGN KEY ("course_id") REFERENCES "courses"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_course_windows_course_id" ON "course_windows" ("course_id");
+
+CREATE TABLE "favorites" (
+    "id" integer,
+    "shop_id" integer NOT NULL,
+    "user_id" integer NOT NULL,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "is_favorite" numeric,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_shops_favorites" FOREIGN KEY ("shop_id") REFERENCES "shops"("id"),
+    CONSTRAINT "fk_users_favorites" FOREIGN KEY ("user_id") REFERENCES "users"("id")
+);
+
+CREATE TABLE "reservations" (
+    "id" integer,
+    "date" datetime NOT NULL,
+    "time" text NOT NULL,
+    "shop_id" integer NOT NULL,
+    "user_id" integer NOT NULL,
+    "num" integer NOT NULL,
+    "status" text NOT NULL DEFAULT 'pending',
+    "course_id" integer,
+    "total_price" integer NOT NULL DEFAULT 0,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_shops_reservations" FOREIGN KEY ("shop_id") REFERENCES "shops"("id"),
+    CONSTRAINT "fk_users_reservations" FOREIGN KEY ("user_id") REFERENCES "users"("id"),
+    CONSTRAINT "fk_reservations_course" FOREIGN KEY ("course_id") REFERENCES "courses"("id") ON DELETE SET NULL
+);
+CREATE INDEX "idx_reservations_course_id" ON "reservations" ("course_id");
+CREATE INDEX "idx_reservations_status" ON "reservations" ("status");
+
+CREATE TABLE "reservation_status_changes" (
+    "id" integer,
+    "reservation_id" integer NOT NULL,
+    "from_status" text NOT NULL,
+    "to_status" text NOT NULL,
+    "actor_id" integer NOT NULL,
+    "actor_role" text NOT NULL,
+    "created_at" datetime,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_reservations_status_changes" FOREIGN KEY ("reservation_id") REFERENCES "reservations"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_reservation_status_changes_reservation_id" ON "reservation_status_changes" ("reservation_id");
+
+CREATE TABLE "reviews" (
+    "id" integer,
+    "shop_id" integer NOT NULL,
+    "user_id" integer NOT NULL,
+    "reservation_id" integer,
+    "rating" integer NOT NULL,
+    "comment" text NOT NULL,
+    "verified" numeric NOT NULL DEFAULT false,
+    "reply" text NOT NULL DEFAULT '',
+    "replied_at" datetime,
+    "report_count" integer NOT NULL DEFAULT 0,
+    "hidden" numeric NOT NULL DEFAULT false,
+    "created_at" datetime,
+    "updated_at" datetime,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_reviews_shop" FOREIGN KEY ("shop_id") REFERENCES "shops"("id") ON DELETE CASCADE,
+    CONSTRAINT "fk_reviews_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_reviews_hidden" ON "reviews" ("hidden");
+CREATE UNIQUE INDEX "idx_reviews_reservation_id" ON "reviews" ("reservation_id");
+CREATE INDEX "idx_reviews_user_id" ON "reviews" ("user_id");
+CREATE INDEX "idx_reviews_shop_id" ON "reviews" ("shop_id");
+
+CREATE TABLE "review_reports" (
+    "id" integer,
+    "review_id" integer NOT NULL,
+    "user_id" integer NOT NULL,
+    "reason" text NOT NULL,
+    "created_at" datetime,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_review_reports_review" FOREIGN KEY ("review_id") REFERENCES "reviews"("id") ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX "idx_review_reports_review_user" ON "review_reports" ("review_id","user_id");
+
+CREATE TABLE "refresh_tokens" (
+    "id" integer,
+    "token_hash" text NOT NULL,
+    "family_id" text NOT NULL,
+    "expires_at" datetime NOT NULL,
+    "revoked_at" datetime,
+    "created_at" datetime,
+    "user_id" integer NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_refresh_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_refresh_tokens_user_id" ON "refresh_tokens" ("user_id");
+CREATE INDEX "idx_refresh_tokens_family_id" ON "refresh_tokens" ("family_id");
+CREATE UNIQUE INDEX "idx_refresh_tokens_token_hash" ON "refresh_tokens" ("token_hash");
+
+CREATE TABLE "identities" (
+    "id" integer,
+    "provider" text NOT NULL,
+    "subject" text NOT NULL,
+    "email" text,
+    "created_at" datetime,
+    "updated_at" datetime,
+    "user_id" integer NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_identities_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_identities_user_id" ON "identities" ("user_id");
+CREATE UNIQUE INDEX "idx_identity_provider_subject" ON "identities" ("provider","subject");
+
+CREATE TABLE "user_tokens" (
+    "id" integer,
+    "purpose" text NOT NULL,
+    "token_hash" text NOT NULL,
+    "expires_at" datetime NOT NULL,
+    "used_at" datetime,
+    "created_at" datetime,
+    "user_id" integer NOT NULL,
+    PRIMARY KEY ("id"),
+    CONSTRAINT "fk_user_tokens_user" FOREIGN KEY ("user_id") REFERENCES "users"("id") ON DELETE CASCADE
+);
+CREATE INDEX "idx_user_tokens_user_id" ON "user_tokens" ("user_id");
+CREATE UNIQUE INDEX "idx_user_tokens_token_hash" ON "user_tokens" ("token_hash");
+CREATE INDEX "idx_user_tokens_purpose" ON "user_tokens" ("purpose");
@@ -8,14 +8,17 @@ type Blog struct {
 	Content   string    `json:"content" gorm:"not null"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
-	User      User      `json:"user" gorm:"foreignKey:UserId; constraint:OnDelete:CASCADE"`
-	UserId    uint      `json:"user_id" gorm:"not null"`
+	// Cover はカバー画像です。Key が空の場合は未設定です。
+	Cover  ImageFile `json:"cover" gorm:"embedded;embeddedPrefix:cover_"`
+	User   User      `json:"user" gorm:"foreignKey:UserId; constraint:OnDelete:CASCADE"`
+	UserId uint      `json:"user_id" gorm:"not null"`
 }
 
 type BlogResponse struct {
-	ID        uint      `json:"id" gorm:"primaryKey"`
-	Title     string    `json:"title" gorm:"not null"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	Title     string     `json:"title" gorm:"not null"`
+	Content   string     `json:"content"`
+	Cover     *ImageFile `json:"cover"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
 }
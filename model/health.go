@@ -0,0 +1,27 @@
+package model
+
+// ヘルスチェックの状態
+const (
+	HealthOK          = "ok"
+	HealthUnavailable = "unavailable"
+	// HealthPending は未適用のマイグレーションがある状態です。
+	HealthPending = "pending"
+)
+
+// Health は /healthz のレスポンスです。
+type Health struct {
+	Status string `json:"status"`
+}
+
+// Readiness は /readyz のレスポンスです。全ての項目が ok の場合のみ Status が ok になります。
+type Readiness struct {
+	Status     string              `json:"status"`
+	Database   string              `json:"database"`
+	Migrations MigrationsReadiness `json:"migrations"`
+}
+
+// MigrationsReadiness はマイグレーションの適用状況です。データベースに接続できない場合は Status が unavailable です。
+type MigrationsReadiness struct {
+	Status  string `json:"status"`
+	Pending int    `json:"pending"`
+}
@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// Identity は外部のIDプロバイダー（OpenID Connect）のアカウントとユーザーの紐付けです。
+// Provider には発行者（iss）、Subject にはプロバイダー内のユーザーID（sub）を保存します。
+type Identity struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Provider  string    `json:"provider" gorm:"not null;uniqueIndex:idx_identity_provider_subject"`
+	Subject   string    `json:"subject" gorm:"not null;uniqueIndex:idx_identity_provider_subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	User      User      `json:"-" gorm:"foreignKey:UserID; constraint:OnDelete:CASCADE"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+}
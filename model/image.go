@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// ImageFile はストレージに保存した画像と縮小画像です。キーはストレージ内の場所で、クライアントには URL のみ返します。
+type ImageFile struct {
+	Key          string `json:"-" gorm:"not null;default:''"`
+	URL          string `json:"url" gorm:"not null;default:''"`
+	ThumbnailKey string `json:"-" gorm:"not null;default:''"`
+	ThumbnailURL string `json:"thumbnail_url" gorm:"not null;default:''"`
+	ContentType  string `json:"content_type" gorm:"not null;default:''"`
+	Size         int64  `json:"size" gorm:"not null;default:0"`
+	Width        int    `json:"width" gorm:"not null;default:0"`
+	Height       int    `json:"height" gorm:"not null;default:0"`
+}
+
+// ShopImage はショップのギャラリーの画像です。Position の小さい順に表示します。
+type ShopImage struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	ShopID    uint   `json:"shop_id" gorm:"not null;index"`
+	Position  int    `json:"position" gorm:"not null"`
+	Caption   string `json:"caption" gorm:"not null;default:''"`
+	ImageFile `gorm:"embedded"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,83 @@
+package model
+
+import "time"
+
+// Menu はショップのメニュー（コースのまとまり）です。Position の小さい順に表示します。
+type Menu struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ShopID      uint      `json:"shop_id" gorm:"not null;index"`
+	Name        string    `json:"name" gorm:"not null"`
+	Description string    `json:"description" gorm:"not null;default:''"`
+	Position    int       `json:"position" gorm:"not null;default:0"`
+	Courses     []Course  `json:"courses" gorm:"foreignKey:MenuID; constraint:OnDelete:CASCADE"`
+	Shop        Shop      `json:"-" gorm:"foreignKey:ShopID; constraint:OnDelete:CASCADE"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Course は予約時に選べるコースです。価格は1人あたりの税込みの円です。
+// StartDate と EndDate は提供期間（両端を含む）で、省略した場合は期限なしです。
+// Windows を登録した場合は、いずれかの時間帯に始まる予約のみ受け付けます。
+type Course struct {
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	MenuID          uint           `json:"menu_id" gorm:"not null;index"`
+	ShopID          uint           `json:"shop_id" gorm:"not null;index"`
+	Name            string         `json:"name" gorm:"not null"`
+	Description     string         `json:"description" gorm:"not null;default:''"`
+	Price           int64          `json:"price" gorm:"not null"`
+	DurationMinutes int            `json:"duration_minutes" gorm:"not null"`
+	MinPartySize    int            `json:"min_party_size" gorm:"not null;default:1"`
+	MaxPartySize    int            `json:"max_party_size" gorm:"not null;default:0"` // 0は上限なし
+	StartDate       *time.Time     `json:"start_date"`
+	EndDate         *time.Time     `json:"end_date"`
+	Active          bool           `json:"active" gorm:"not null;default:true"`
+	Windows         []CourseWindow `json:"windows" gorm:"foreignKey:CourseID; constraint:OnDelete:CASCADE"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// CourseWindow はコースを予約できる開始時刻の範囲です（StartTime 以上 EndTime 未満）。
+// Weekday を省略した場合は毎日です。
+type CourseWindow struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	CourseID  uint   `json:"course_id" gorm:"not null;index"`
+	Weekday   *int   `json:"weekday"` // 0が日曜日、6が土曜日
+	StartTime string `json:"start_time" gorm:"not null"`
+	EndTime   string `json:"end_time" gorm:"not null"`
+}
+
+// IsAvailableAt は指定した日の clock に始まる予約でコースを選べるかを返します。date は日付のみを使います。
+func (c Course) IsAvailableAt(date time.Time, clock string) bool {
+	if !c.Active {
+		return false
+	}
+	if c.StartDate != nil && date.Before(startOfDay(*c.StartDate)) {
+		return false
+	}
+	if c.EndDate != nil && !date.Before(startOfDay(*c.EndDate).AddDate(0, 0, 1)) {
+		return false
+	}
+	if len(c.Windows) == 0 {
+		return true
+	}
+	m, ok := ClockMinutes(clock)
+	if !ok {
+		return false
+	}
+	for _, w := range c.Windows {
+		if w.Weekday != nil && *w.Weekday != int(date.Weekday()) {
+			continue
+		}
+		start, ok1 := ClockMinutes(w.StartTime)
+		end, ok2 := ClockMinutes(w.EndTime)
+		if ok1 && ok2 && start <= m && m < end {
+			return true
+		}
+	}
+	return false
+}
+
+// TotalPrice は人数分の合計金額です。
+func (c Course) TotalPrice(num int) int64 {
+	return c.Price * int64(num)
+}
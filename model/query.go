@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// 一覧取得の件数
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+// 並び順
+const (
+	SortAsc  = "asc"
+	SortDesc = "desc"
+)
+
+// ListQuery は一覧取得のページング・並び替え・絞り込みの条件です。
+// Cursor を指定した場合はカーソル方式、指定しない場合は Offset 方式でページングします。
+type ListQuery struct {
+	Limit  int
+	Offset int
+	Cursor string
+	// Sort は並び替えに使う項目名です。使える項目はリポジトリごとに決まっています。
+	Sort  string
+	Order string
+	// Filters は項目名と値の組です（area、genre など）。
+	Filters map[string]string
+	// From と To は日付の範囲です。To の日付も範囲に含みます。
+	From *time.Time
+	To   *time.Time
+}
+
+// Page は一覧取得の結果です。
+type Page[T any] struct {
+	Items []T   `json:"items"`
+	Total int64 `json:"total"`
+	Limit int   `json:"limit"`
+	// Offset はカーソル方式の場合は0です。
+	Offset     int    `json:"offset"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	// Next は次のページのURLです。
+	Next string `json:"next,omitempty"`
+}
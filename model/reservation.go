@@ -2,6 +2,17 @@ package model
 
 import "time"
 
+// 予約のステータス
+const (
+    ReservationPending         = "pending"
+    ReservationConfirmed       = "confirmed"
+    ReservationCancelledByUser = "cancelled_by_user"
+    ReservationCancelledByShop = "cancelled_by_shop"
+    ReservationCheckedIn       = "checked_in"
+    ReservationNoShow          = "no_show"
+    ReservationCompleted       = "completed"
+)
+
 type Reservation struct {
     ID      uint      `json:"id" gorm:"primaryKey"`
     Date    time.Time `json:"date" gorm:"not null"`
@@ -9,6 +20,13 @@ type Reservation struct {
     ShopID  uint      `json:"shop_id" gorm:"not null"`
     UserID  uint      `json:"user_id" gorm:"not null"`
     Num     int       `json:"num" gorm:"not null"`
+    Status  string    `json:"status" gorm:"not null;default:pending;index"`
+    // CourseID は予約時に選んだコースです。コースを選ばない席のみの予約の場合は nil です。
+    CourseID   *uint   `json:"course_id" gorm:"index"`
+    Course     *Course `json:"course,omitempty" gorm:"foreignKey:CourseID; constraint:OnDelete:SET NULL"`
+    // TotalPrice は予約時点のコースの価格と人数から計算した合計金額です。コースを選ばない場合は0です。
+    TotalPrice int64   `json:"total_price" gorm:"not null;default:0"`
+    StatusChanges []ReservationStatusChange `json:"-" gorm:"foreignKey:ReservationID; constraint:OnDelete:CASCADE"`
 }
 
 type ReservationResponse struct {
@@ -18,4 +36,26 @@ type ReservationResponse struct {
     ShopID uint      `json:"shop_id"`
     UserID uint      `json:"user_id"`
     Num    int       `json:"num"`
+    Status string    `json:"status"`
+    CourseID   *uint `json:"course_id"`
+    TotalPrice int64 `json:"total_price"`
+}
+
+// ReservationStatusChange は予約ステータスの変更履歴です。
+type ReservationStatusChange struct {
+    ID            uint      `json:"id" gorm:"primaryKey"`
+    ReservationID uint      `json:"reservation_id" gorm:"not null;index"`
+    FromStatus    string    `json:"from_status" gorm:"not null"`
+    ToStatus      string    `json:"to_status" gorm:"not null"`
+    ActorID       uint      `json:"actor_id" gorm:"not null"`
+    ActorRole     string    `json:"actor_role" gorm:"not null"`
+    CreatedAt     time.Time `json:"created_at"`
+}
+
+// SlotAvailability は予約枠ごとの空き状況を表します。
+type SlotAvailability struct {
+    Time      string `json:"time"`
+    Capacity  int    `json:"capacity"`
+    Booked    int    `json:"booked"`
+    Remaining int    `json:"remaining"`
 }
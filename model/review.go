@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// 評価の範囲
+const (
+	MinRating = 1
+	MaxRating = 5
+)
+
+// Review はショップのレビューです。
+// ReservationID を指定したレビューは、完了した予約に基づく来店済み（Verified）のレビューです。
+type Review struct {
+	ID            uint   `json:"id" gorm:"primaryKey"`
+	ShopID        uint   `json:"shop_id" gorm:"not null;index"`
+	UserID        uint   `json:"user_id" gorm:"not null;index"`
+	ReservationID *uint  `json:"reservation_id" gorm:"uniqueIndex"`
+	Rating        int    `json:"rating" gorm:"not null"`
+	Comment       string `json:"comment" gorm:"not null"`
+	Verified      bool   `json:"verified" gorm:"not null;default:false"`
+	// Reply はショップオーナーからの返信です。
+	Reply     string     `json:"reply" gorm:"not null;default:''"`
+	RepliedAt *time.Time `json:"replied_at"`
+	// ReportCount は通報された回数です。Hidden のレビューは一覧と評価の集計から除きます。
+	ReportCount int       `json:"report_count" gorm:"not null;default:0"`
+	Hidden      bool      `json:"hidden" gorm:"not null;default:false;index"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	Shop        Shop      `json:"-" gorm:"foreignKey:ShopID; constraint:OnDelete:CASCADE"`
+	User        User      `json:"-" gorm:"foreignKey:UserID; constraint:OnDelete:CASCADE"`
+}
+
+type ReviewResponse struct {
+	ID            uint       `json:"id"`
+	ShopID        uint       `json:"shop_id"`
+	UserID        uint       `json:"user_id"`
+	ReservationID *uint      `json:"reservation_id"`
+	Rating        int        `json:"rating"`
+	Comment       string     `json:"comment"`
+	Verified      bool       `json:"verified"`
+	Reply         string     `json:"reply"`
+	RepliedAt     *time.Time `json:"replied_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// ReviewModerationResponse は管理者向けのレビューです。通報の状況を含みます。
+type ReviewModerationResponse struct {
+	ReviewResponse
+	ReportCount int  `json:"report_count"`
+	Hidden      bool `json:"hidden"`
+}
+
+// ReviewReport はレビューの通報です。1人のユーザーが同じレビューを通報できるのは1回のみです。
+type ReviewReport struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ReviewID  uint      `json:"review_id" gorm:"not null;uniqueIndex:idx_review_reports_review_user"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_review_reports_review_user"`
+	Reason    string    `json:"reason" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+	Review    Review    `json:"-" gorm:"foreignKey:ReviewID; constraint:OnDelete:CASCADE"`
+}
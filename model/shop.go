@@ -4,24 +4,99 @@ import "time"
 
 type Shop struct {
 	ID          uint      `json:"id" gorm:"primaryKey"`
+	// ExternalID は一括取り込みで使う外部システムの識別子です。取り込み時はこの値で既存のショップを更新します。
+	ExternalID  *string   `json:"external_id" gorm:"uniqueIndex"`
 	Name        string    `json:"name" gorm:"not null"`
 	Address     string    `json:"address" gorm:"not null"`
+	PostalCode  string    `json:"postal_code" gorm:"not null;default:'';index"`
+	Latitude    *float64  `json:"latitude" gorm:"index:idx_shops_location"`
+	Longitude   *float64  `json:"longitude" gorm:"index:idx_shops_location"`
 	Area        string    `json:"area" gorm:"not null"`
 	Genre       string    `json:"genre" gorm:"not null"`
 	Description string    `json:"description" gorm:"not null"`
+	Capacity    int       `json:"capacity" gorm:"not null;default:20"`
+	OpenTime    string    `json:"open_time" gorm:"not null;default:'11:00'"`
+	CloseTime   string    `json:"close_time" gorm:"not null;default:'22:00'"`
+	SlotMinutes int       `json:"slot_minutes" gorm:"not null;default:60"`
+	OwnerID     *uint     `json:"owner_id"`
+	// RatingAverage と RatingCount は公開中のレビューの集計です。レビューの投稿・更新・削除時に更新します。
+	RatingAverage float64 `json:"rating_average" gorm:"not null;default:0"`
+	RatingCount   int     `json:"rating_count" gorm:"not null;default:0"`
+	Owner       *User     `json:"-" gorm:"foreignKey:OwnerID; constraint:OnDelete:SET NULL"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
+	Hours       []ShopHour    `json:"hours" gorm:"foreignKey:ShopID; constraint:OnDelete:CASCADE"`
+	Closures    []ShopClosure `json:"closures" gorm:"foreignKey:ShopID; constraint:OnDelete:CASCADE"`
+	Images      []ShopImage   `json:"images" gorm:"foreignKey:ShopID; constraint:OnDelete:CASCADE"`
 	Favorites []Favorite `json:"favorites" gorm:"foreignKey:ShopID"`
 	Reservations []Reservation `json:"reservations" gorm:"foreignKey:ShopID"`
 }
 
 type ShopResponse struct {
 	ID          uint      `json:"id"`
+	ExternalID  *string   `json:"external_id"`
 	Name        string    `json:"name"`
 	Address     string    `json:"address"`
+	PostalCode  string    `json:"postal_code"`
+	Latitude    *float64  `json:"latitude"`
+	Longitude   *float64  `json:"longitude"`
 	Area        string    `json:"area"`
 	Genre       string    `json:"genre"`
 	Description string    `json:"description"`
+	Capacity    int       `json:"capacity"`
+	OpenTime    string    `json:"open_time"`
+	CloseTime   string    `json:"close_time"`
+	SlotMinutes int       `json:"slot_minutes"`
+	OwnerID     *uint     `json:"owner_id"`
+	RatingAverage float64 `json:"rating_average"`
+	RatingCount   int     `json:"rating_count"`
+	Hours       []ShopHour    `json:"hours"`
+	Closures    []ShopClosure `json:"closures"`
+	Images      []ShopImage   `json:"images"`
+	IsOpenNow   bool          `json:"is_open_now"`
+	// NextOpeningAt は営業していない場合の次の開店時刻です。
+	NextOpeningAt *time.Time `json:"next_opening_at"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
-}
\ No newline at end of file
+}
+// ShopSearchQuery はショップ検索の条件です。
+type ShopSearchQuery struct {
+	// Text は名前・説明・住所に対する検索語です。
+	Text  string
+	Area  string
+	Genre string
+	// OpenAt を指定した場合は、その時刻に営業しているショップに絞り込みます。
+	OpenAt *time.Time
+	Limit  int
+	Offset int
+}
+
+// FacetCount は絞り込み項目の値ごとの件数です。
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// ShopFacets はエリア・ジャンルごとの件数です。
+// 各項目の件数は、その項目自体の絞り込みを除いた条件で数えます。
+type ShopFacets struct {
+	Area  []FacetCount `json:"area"`
+	Genre []FacetCount `json:"genre"`
+}
+
+type ShopSearchResponse struct {
+	Page[ShopResponse]
+	Facets ShopFacets `json:"facets"`
+}
+
+// NearbyShopResponse は検索地点からの距離（メートル）付きのショップです。
+type NearbyShopResponse struct {
+	ShopResponse
+	Distance float64 `json:"distance"`
+}
+
+// NearbyShop はリポジトリが返す距離付きのショップです。
+type NearbyShop struct {
+	Shop     Shop `gorm:"embedded"`
+	Distance float64
+}
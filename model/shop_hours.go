@@ -0,0 +1,185 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+// ShopHour は曜日ごとの営業時間帯です。1つの曜日に複数の時間帯を登録できます。
+// 閉店時刻が開店時刻以前の場合は翌日の閉店時刻まで営業します（"18:00"〜"02:00" など）。
+type ShopHour struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	ShopID    uint   `json:"shop_id" gorm:"not null;index"`
+	Weekday   int    `json:"weekday" gorm:"not null"` // 0が日曜日、6が土曜日
+	OpenTime  string `json:"open_time" gorm:"not null"`
+	CloseTime string `json:"close_time" gorm:"not null"`
+}
+
+// ShopClosure は祝日や貸切などによる日付指定の休業です。
+// StartTime と EndTime を省略した場合は終日休業です。
+// 休業はその日に開店する営業時間帯に適用します。前日から日付をまたぐ営業時間帯は前日の営業のため、
+// 前日が終日休業の場合は休みになり、当日の休業の影響は受けません。
+type ShopClosure struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ShopID    uint      `json:"shop_id" gorm:"not null;index:idx_shop_closures_shop_date"`
+	Date      time.Time `json:"date" gorm:"not null;index:idx_shop_closures_shop_date"`
+	StartTime string    `json:"start_time" gorm:"not null;default:''"`
+	EndTime   string    `json:"end_time" gorm:"not null;default:''"`
+	Reason    string    `json:"reason" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TimeRange はその日の0時からの分で表した時間帯です。End が 24*60 を超える場合は翌日にまたがります。
+type TimeRange struct {
+	Start int
+	End   int
+}
+
+const minutesPerDay = 24 * 60
+
+// ClockMinutes は "HH:MM" 形式の時刻を0時からの分に変換します。
+func ClockMinutes(clock string) (int, bool) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, false
+	}
+	return t.Hour()*60 + t.Minute(), true
+}
+
+// OpenRanges は指定した日の営業時間帯を開始時刻の順に返します。
+// 曜日ごとの営業時間が登録されていない場合は OpenTime〜CloseTime を毎日の営業時間とし、
+// その日の休業を除いた時間帯を返します。
+func (s Shop) OpenRanges(date time.Time) []TimeRange {
+	ranges := []TimeRange{}
+	if len(s.Hours) == 0 {
+		if r, ok := timeRange(s.OpenTime, s.CloseTime); ok {
+			ranges = append(ranges, r)
+		}
+	}
+	for _, h := range s.Hours {
+		if h.Weekday != int(date.Weekday()) {
+			continue
+		}
+		if r, ok := timeRange(h.OpenTime, h.CloseTime); ok {
+			ranges = append(ranges, r)
+		}
+	}
+	for _, c := range s.Closures {
+		if !SameDate(c.Date, date) {
+			continue
+		}
+		if c.StartTime == "" {
+			return []TimeRange{}
+		}
+		start, ok1 := ClockMinutes(c.StartTime)
+		end, ok2 := ClockMinutes(c.EndTime)
+		if ok1 && ok2 {
+			ranges = subtractRange(ranges, TimeRange{start, end})
+		}
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	return ranges
+}
+
+// IsOpenAt は指定した時刻に営業しているかを返します。前日から日付をまたぐ営業時間帯も考慮します。
+// 休業の扱いは ShopClosure を参照してください。
+func (s Shop) IsOpenAt(t time.Time) bool {
+	day := startOfDay(t)
+	m := t.Hour()*60 + t.Minute()
+	for _, r := range s.OpenRanges(day) {
+		if r.Start <= m && m < r.End {
+			return true
+		}
+	}
+	for _, r := range s.OpenRanges(day.AddDate(0, 0, -1)) {
+		if m+minutesPerDay < r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// NextOpeningAt は指定した時刻より後で最初に開店する時刻を返します。
+// 営業中の場合や、2週間以内に営業日がない場合は nil です。
+func (s Shop) NextOpeningAt(t time.Time) *time.Time {
+	if s.IsOpenAt(t) {
+		return nil
+	}
+	day := startOfDay(t)
+	for i := 0; i <= 14; i++ {
+		date := day.AddDate(0, 0, i)
+		for _, r := range s.OpenRanges(date) {
+			start := date.Add(time.Duration(r.Start) * time.Minute)
+			if start.After(t) {
+				return &start
+			}
+		}
+	}
+	return nil
+}
+
+// IsBookable は指定した日の clock から枠の長さ（SlotMinutes）の間、続けて営業しているかを返します。
+// date は日付のみを使います。
+func (s Shop) IsBookable(date time.Time, clock string) bool {
+	return s.IsOpenFor(date, clock, s.SlotMinutes)
+}
+
+// IsOpenFor は指定した日の clock から minutes 分の間、続けて営業しているかを返します。
+// コースの所要時間が枠の長さより長い場合などに使います。date は日付のみを使います。
+func (s Shop) IsOpenFor(date time.Time, clock string, minutes int) bool {
+	m, ok := ClockMinutes(clock)
+	if !ok {
+		return false
+	}
+	for _, r := range s.OpenRanges(date) {
+		if r.Start <= m && m+minutes <= r.End {
+			return true
+		}
+	}
+	// 前日から日付をまたぐ営業時間帯
+	for _, r := range s.OpenRanges(date.AddDate(0, 0, -1)) {
+		if r.Start <= m+minutesPerDay && m+minutesPerDay+minutes <= r.End {
+			return true
+		}
+	}
+	return false
+}
+
+// SameDate は2つの日時の年月日が同じかを返します。タイムゾーンは変換しません。
+func SameDate(a, b time.Time) bool {
+	return a.Year() == b.Year() && a.Month() == b.Month() && a.Day() == b.Day()
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func timeRange(open, close string) (TimeRange, bool) {
+	start, ok1 := ClockMinutes(open)
+	end, ok2 := ClockMinutes(close)
+	if !ok1 || !ok2 {
+		return TimeRange{}, false
+	}
+	if end <= start {
+		end += minutesPerDay
+	}
+	return TimeRange{start, end}, true
+}
+
+// subtractRange は ranges から closed の時間帯を除きます。
+func subtractRange(ranges []TimeRange, closed TimeRange) []TimeRange {
+	result := []TimeRange{}
+	for _, r := range ranges {
+		if closed.End <= r.Start || r.End <= closed.Start {
+			result = append(result, r)
+			continue
+		}
+		if r.Start < closed.Start {
+			result = append(result, TimeRange{r.Start, closed.Start})
+		}
+		if closed.End < r.End {
+			result = append(result, TimeRange{closed.End, r.End})
+		}
+	}
+	return result
+}
@@ -0,0 +1,49 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsOpenAtOvernightClosures(t *testing.T) {
+	tuesday := time.Date(2030, 1, 8, 0, 0, 0, 0, time.UTC)
+	wednesday, thursday := tuesday.AddDate(0, 0, 1), tuesday.AddDate(0, 0, 2)
+	shop := Shop{
+		Hours: []ShopHour{
+			{Weekday: 2, OpenTime: "18:00", CloseTime: "03:00"},
+			{Weekday: 3, OpenTime: "18:00", CloseTime: "03:00"},
+		},
+		Closures: []ShopClosure{
+			{Date: tuesday},
+			{Date: wednesday, StartTime: "20:00", EndTime: "22:00"},
+			{Date: thursday},
+		},
+	}
+
+	for _, tc := range []struct {
+		at   time.Time
+		want bool
+	}{
+		{tuesday.Add(19 * time.Hour), false},
+		// 日付をまたいだ分は開店した日（火曜日）の終日休業で休み
+		{wednesday.Add(time.Hour), false},
+		{wednesday.Add(19 * time.Hour), true},
+		{wednesday.Add(21 * time.Hour), false},
+		{wednesday.Add(23 * time.Hour), true},
+		// 当日（木曜日）の休業は前日からの営業に影響しない
+		{thursday.Add(time.Hour), true},
+		{thursday.Add(3 * time.Hour), false},
+	} {
+		if got := shop.IsOpenAt(tc.at); got != tc.want {
+			t.Errorf("IsOpenAt(%s) = %v, want %v", tc.at.Format("Mon 15:04"), got, tc.want)
+		}
+	}
+
+	// 予約の判定も同じ規則
+	if !shop.IsOpenFor(thursday, "01:00", 60) || shop.IsOpenFor(wednesday, "01:00", 60) {
+		t.Error("IsOpenFor disagrees with IsOpenAt after midnight")
+	}
+	if next := shop.NextOpeningAt(wednesday.Add(time.Hour)); next == nil || !next.Equal(wednesday.Add(18*time.Hour)) {
+		t.Errorf("NextOpeningAt = %v, want Wed 18:00", next)
+	}
+}
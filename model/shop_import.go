@@ -0,0 +1,87 @@
+package model
+
+import "strconv"
+
+// 取り込み結果の各行の処理内容
+const (
+	ShopImportCreate    = "create"
+	ShopImportUpdate    = "update"
+	ShopImportUnchanged = "unchanged"
+	ShopImportInvalid   = "invalid"
+	ShopImportFailed    = "failed"
+)
+
+// ShopImportRow は取り込むファイルの1行（JSON の場合は配列の1要素）です。
+type ShopImportRow struct {
+	// Line はエラーの報告に使う行番号（JSON の場合は要素の番号）です。
+	Line int
+	Shop Shop
+	// Err は行を読み込めなかった場合のエラーです。
+	Err error
+}
+
+// ShopImportOptions は取り込み方法の指定です。
+type ShopImportOptions struct {
+	// DryRun を指定した場合は差分を報告するだけで保存しません。
+	DryRun bool
+	// Atomic を指定した場合は全ての行を1つのトランザクションで保存し、1行でもエラーがあれば何も保存しません。
+	// 指定しない場合はエラーのない行を1行ずつ保存します。
+	Atomic bool
+}
+
+// FieldChange は既存のショップから変わる項目です。
+type FieldChange struct {
+	Field string `json:"field"`
+	From  string `json:"from"`
+	To    string `json:"to"`
+}
+
+// ShopImportResult は1行分の取り込み結果です。
+type ShopImportResult struct {
+	Line       int           `json:"line"`
+	ExternalID string        `json:"external_id"`
+	Action     string        `json:"action"`
+	ShopID     uint          `json:"shop_id,omitempty"`
+	Changes    []FieldChange `json:"changes,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	// Errors はバリデーションエラーの項目ごとのメッセージです。
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// ShopImportReport は取り込み全体の結果です。
+type ShopImportReport struct {
+	DryRun bool `json:"dry_run"`
+	Atomic bool `json:"atomic"`
+	// Applied は変更を保存したかどうかです。Atomic でエラーがあった場合や DryRun の場合は false です。
+	Applied   bool               `json:"applied"`
+	Created   int                `json:"created"`
+	Updated   int                `json:"updated"`
+	Unchanged int                `json:"unchanged"`
+	Failed    int                `json:"failed"`
+	Results   []ShopImportResult `json:"results"`
+}
+
+// ShopImportColumns は取り込み・書き出しで扱う項目です。CSV の見出し行もこの名前を使います。
+var ShopImportColumns = []string{
+	"external_id", "name", "address", "postal_code", "latitude", "longitude",
+	"area", "genre", "description", "capacity", "open_time", "close_time", "slot_minutes",
+}
+
+// ImportValues は ShopImportColumns の順にショップの値を文字列で返します。未設定の座標は空文字です。
+func (s Shop) ImportValues() []string {
+	externalId := ""
+	if s.ExternalID != nil {
+		externalId = *s.ExternalID
+	}
+	return []string{
+		externalId, s.Name, s.Address, s.PostalCode, formatCoordinate(s.Latitude), formatCoordinate(s.Longitude),
+		s.Area, s.Genre, s.Description, strconv.Itoa(s.Capacity), s.OpenTime, s.CloseTime, strconv.Itoa(s.SlotMinutes),
+	}
+}
+
+func formatCoordinate(v *float64) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*v, 'f', -1, 64)
+}
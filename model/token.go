@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// RefreshToken はリフレッシュトークンです。トークン自体は保存せず、ハッシュのみを保存します。
+// 同じログインから発行されたトークンは同じ FamilyID を持ち、再利用を検知した場合はまとめて失効させます。
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	FamilyID  string     `json:"family_id" gorm:"not null;index"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	User      User       `json:"-" gorm:"foreignKey:UserID; constraint:OnDelete:CASCADE"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+}
+
+// ユーザートークンの用途
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+// UserToken はメールアドレス確認・パスワード再設定用の一度だけ使えるトークンです。
+type UserToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	Purpose   string     `json:"purpose" gorm:"not null;index"`
+	TokenHash string     `json:"-" gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time  `json:"expires_at" gorm:"not null"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	User      User       `json:"-" gorm:"foreignKey:UserID; constraint:OnDelete:CASCADE"`
+	UserID    uint       `json:"user_id" gorm:"not null;index"`
+}
+
+type TokenResponse struct {
+	AccessToken           string    `json:"access_token"`
+	AccessTokenExpiresAt  time.Time `json:"access_token_expires_at"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
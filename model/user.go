@@ -2,11 +2,20 @@ package model
 
 import "time"
 
+// ユーザーのロール
+const (
+	RoleCustomer  = "customer"
+	RoleShopOwner = "shop_owner"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
 	ID        uint      `json:"id" gorm:"primaryKey"`
 	Email     string    `json:"email" gorm:"unique"`
 	Password  string    `json:"password"`
 	Name      string    `json:"name"`
+	Role      string    `json:"role" gorm:"not null;default:customer"`
+	EmailVerified bool  `json:"email_verified" gorm:"not null;default:false"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	Favorites []Favorite `json:"favorites" gorm:"foreignKey:UserID"`
@@ -17,4 +26,6 @@ type UserResponse struct {
 	ID    uint   `json:"id" gorm:"primaryKey"`
 	Email string `json:"email" gorm:"unique"`
 	Name  string `json:"name"`
+	Role  string `json:"role"`
+	EmailVerified bool `json:"email_verified"`
 }
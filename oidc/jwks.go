@@ -0,0 +1,193 @@
+package oidc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrKeyNotFound はJWKSに指定された kid の鍵が存在しない場合に返されます。
+	ErrKeyNotFound = errors.New("signing key not found in JWKS")
+	// ErrKeySetUnavailable はJWKSを取得・解析できない場合に返されます。トークンではなくサーバー側の問題です。
+	ErrKeySetUnavailable = errors.New("JWKS is unavailable")
+)
+
+// minRefreshInterval は未知の kid を受け取った際にJWKSを再取得する最短間隔です。
+// 不正なトークンを大量に送られてもプロバイダーへのリクエストが増えすぎないようにします。
+const minRefreshInterval = time.Minute
+
+// IKeySet は kid から署名検証用の公開鍵を返します。
+type IKeySet interface {
+	Key(kid string) (interface{}, error)
+}
+
+type jwksKeySet struct {
+	fetch     func() ([]byte, error)
+	mu        sync.Mutex
+	keys      map[string]interface{}
+	fetchedAt time.Time
+}
+
+// NewKeySet は location が http(s):// で始まる場合はHTTPから、それ以外はローカルファイルからJWKSを読み込みます。
+func NewKeySet(location string) IKeySet {
+	if strings.HasPrefix(location, "http://") || strings.HasPrefix(location, "https://") {
+		return NewRemoteKeySet(location, &http.Client{Timeout: 10 * time.Second})
+	}
+	return NewFileKeySet(location)
+}
+
+// NewRemoteKeySet はHTTPで公開されているJWKSを取得してキャッシュします。
+func NewRemoteKeySet(url string, client *http.Client) IKeySet {
+	return &jwksKeySet{fetch: func() ([]byte, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("fetch JWKS: unexpected status %d", resp.StatusCode)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	}}
+}
+
+// NewFileKeySet はローカルのJWKSファイルを読み込みます。開発環境やテストで使用します。
+func NewFileKeySet(path string) IKeySet {
+	return &jwksKeySet{fetch: func() ([]byte, error) {
+		if path == "" {
+			return nil, errors.New("JWKS location is not configured")
+		}
+		return os.ReadFile(path)
+	}}
+}
+
+func (ks *jwksKeySet) Key(kid string) (interface{}, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if key, ok := ks.keys[kid]; ok {
+		return key, nil
+	}
+	// 鍵のローテーションに対応するため、未知の kid の場合は再取得する
+	if ks.keys != nil && time.Since(ks.fetchedAt) < minRefreshInterval {
+		return nil, ErrKeyNotFound
+	}
+	data, err := ks.fetch()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrKeySetUnavailable, err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrKeySetUnavailable, err)
+	}
+	ks.keys = keys
+	ks.fetchedAt = time.Now()
+	if key, ok := ks.keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrKeyNotFound
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS は署名用のRSA鍵とEC鍵を kid ごとに取り出します。未対応の鍵は無視します。
+func parseJWKS(data []byte) (map[string]interface{}, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("parse JWKS: %w", err)
+	}
+	keys := make(map[string]interface{}, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var (
+			key interface{}
+			err error
+		)
+		switch k.Kty {
+		case "RSA":
+			key, err = k.rsaPublicKey()
+		case "EC":
+			key, err = k.ecdsaPublicKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("parse JWKS key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeBigInt(k.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA exponent")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k jwk) ecdsaPublicKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+	x, err := decodeBigInt(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBigInt(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point is not on curve")
+	}
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
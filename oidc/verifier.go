@@ -0,0 +1,88 @@
+package oidc
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+var (
+	// ErrNotConfigured はIDトークンの発行者またはクライアントIDが設定されていない場合に返されます。
+	ErrNotConfigured = errors.New("OpenID Connect is not configured")
+	// ErrInvalidIDToken はIDトークンの署名・発行者・対象者・有効期限のいずれかが不正な場合に返されます。
+	ErrInvalidIDToken = errors.New("invalid ID token")
+)
+
+// Claims は検証済みのIDトークンから取り出したユーザー情報です。
+type Claims struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type IVerifier interface {
+	// Verify はIDトークンを検証します。JWKSを取得できない場合は ErrKeySetUnavailable を返します。
+	Verify(rawIDToken string) (Claims, error)
+}
+
+type verifier struct {
+	issuer   string
+	audience string
+	keys     IKeySet
+}
+
+// NewVerifier は issuer が発行し audience（クライアントID）宛てのIDトークンを検証します。
+func NewVerifier(issuer string, audience string, keys IKeySet) IVerifier {
+	return &verifier{issuer, audience, keys}
+}
+
+type idTokenClaims struct {
+	jwt.RegisteredClaims
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+func (v *verifier) Verify(rawIDToken string) (Claims, error) {
+	if v.issuer == "" || v.audience == "" {
+		return Claims{}, ErrNotConfigured
+	}
+	claims := idTokenClaims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}))
+	_, err := parser.ParseWithClaims(rawIDToken, &claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return v.keys.Key(kid)
+	})
+	if errors.Is(err, ErrKeySetUnavailable) {
+		// JWKSを取得できない場合はトークンが不正とは限らない
+		return Claims{}, err
+	}
+	if err != nil {
+		return Claims{}, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+	// 署名と exp・nbf・iat は ParseWithClaims で検証済み。exp は必須とする
+	now := time.Now()
+	if !claims.VerifyExpiresAt(now, true) {
+		return Claims{}, fmt.Errorf("%w: missing exp", ErrInvalidIDToken)
+	}
+	if !claims.VerifyIssuer(v.issuer, true) {
+		return Claims{}, fmt.Errorf("%w: unexpected issuer %q", ErrInvalidIDToken, claims.Issuer)
+	}
+	if !claims.VerifyAudience(v.audience, true) {
+		return Claims{}, fmt.Errorf("%w: unexpected audience", ErrInvalidIDToken)
+	}
+	if claims.Subject == "" {
+		return Claims{}, fmt.Errorf("%w: missing sub", ErrInvalidIDToken)
+	}
+	return Claims{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
@@ -0,0 +1,209 @@
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const (
+	testIssuer   = "https://issuer.example.com"
+	testAudience = "client-id"
+)
+
+// jwksServer は鍵を差し替えられるJWKSのエンドポイントです。
+type jwksServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	keys    map[string]*rsa.PrivateKey
+	status  int
+	fetches int
+}
+
+func newJWKSServer(t *testing.T) *jwksServer {
+	t.Helper()
+	s := &jwksServer{keys: map[string]*rsa.PrivateKey{}, status: http.StatusOK}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.fetches++
+		if s.status != http.StatusOK {
+			w.WriteHeader(s.status)
+			return
+		}
+		w.Write(jwks(s.keys))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) addKey(t *testing.T, kid string) *rsa.PrivateKey {
+	t.Helper()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys[kid] = newKey(t)
+	return s.keys[kid]
+}
+
+func newKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+// jwks は鍵の公開鍵をJWKSにします。
+func jwks(keys map[string]*rsa.PrivateKey) []byte {
+	set := struct {
+		Keys []jwk `json:"keys"`
+	}{}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	b, _ := json.Marshal(set)
+	return b
+}
+
+// sign は標準のクレームを edit で変更したIDトークンを作成します。
+func sign(t *testing.T, key *rsa.PrivateKey, kid string, edit func(c *idTokenClaims)) string {
+	t.Helper()
+	now := time.Now()
+	claims := idTokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    testIssuer,
+			Subject:   "user-1",
+			Audience:  jwt.ClaimStrings{testAudience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Hour)),
+		},
+		Email:         "alice@example.com",
+		EmailVerified: true,
+	}
+	if edit != nil {
+		edit(&claims)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	raw, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+func TestVerify(t *testing.T) {
+	server := newJWKSServer(t)
+	key := server.addKey(t, "k1")
+	v := NewVerifier(testIssuer, testAudience, NewRemoteKeySet(server.URL, server.Client()))
+
+	claims, err := v.Verify(sign(t, key, "k1", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.Issuer != testIssuer || claims.Subject != "user-1" || claims.Email != "alice@example.com" || !claims.EmailVerified {
+		t.Fatalf("claims = %+v", claims)
+	}
+
+	for name, raw := range map[string]string{
+		"bad signature":   sign(t, newKey(t), "k1", nil),
+		"wrong issuer":    sign(t, key, "k1", func(c *idTokenClaims) { c.Issuer = "https://evil.example.com" }),
+		"wrong audience":  sign(t, key, "k1", func(c *idTokenClaims) { c.Audience = jwt.ClaimStrings{"other-client"} }),
+		"expired":         sign(t, key, "k1", func(c *idTokenClaims) { c.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute)) }),
+		"missing exp":     sign(t, key, "k1", func(c *idTokenClaims) { c.ExpiresAt = nil }),
+		"missing subject": sign(t, key, "k1", func(c *idTokenClaims) { c.Subject = "" }),
+		"malformed":       "not-a-token",
+	} {
+		if _, err := v.Verify(raw); !errors.Is(err, ErrInvalidIDToken) {
+			t.Errorf("%s: want ErrInvalidIDToken, got %v", name, err)
+		}
+	}
+}
+
+func TestVerifyNotConfigured(t *testing.T) {
+	v := NewVerifier("", "", NewFileKeySet(""))
+	if _, err := v.Verify("token"); !errors.Is(err, ErrNotConfigured) {
+		t.Fatalf("want ErrNotConfigured, got %v", err)
+	}
+}
+
+func TestVerifyUnknownKidRefetches(t *testing.T) {
+	server := newJWKSServer(t)
+	old := server.addKey(t, "old")
+	keys := NewRemoteKeySet(server.URL, server.Client())
+	v := NewVerifier(testIssuer, testAudience, keys)
+	if _, err := v.Verify(sign(t, old, "old", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	// プロバイダーが鍵をローテーションした
+	rotated := server.addKey(t, "new")
+	token := sign(t, rotated, "new", nil)
+	// 最短間隔が経過するまでは再取得しない
+	if _, err := v.Verify(token); !errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("want ErrInvalidIDToken before the refresh interval, got %v", err)
+	}
+	if server.fetches != 1 {
+		t.Fatalf("fetches = %d, want 1", server.fetches)
+	}
+
+	keys.(*jwksKeySet).fetchedAt = time.Now().Add(-minRefreshInterval)
+	if _, err := v.Verify(token); err != nil {
+		t.Fatalf("token signed with the rotated key: %v", err)
+	}
+	if server.fetches != 2 {
+		t.Fatalf("fetches = %d, want 2", server.fetches)
+	}
+	// 既知の kid は再取得しない
+	if _, err := v.Verify(sign(t, old, "old", nil)); err != nil || server.fetches != 2 {
+		t.Fatalf("cached key: err = %v, fetches = %d", err, server.fetches)
+	}
+}
+
+func TestVerifyKeySetUnavailable(t *testing.T) {
+	server := newJWKSServer(t)
+	key := server.addKey(t, "k1")
+	server.status = http.StatusInternalServerError
+	v := NewVerifier(testIssuer, testAudience, NewRemoteKeySet(server.URL, server.Client()))
+	_, err := v.Verify(sign(t, key, "k1", nil))
+	if !errors.Is(err, ErrKeySetUnavailable) || errors.Is(err, ErrInvalidIDToken) {
+		t.Fatalf("provider error: want ErrKeySetUnavailable, got %v", err)
+	}
+
+	server.Close()
+	v = NewVerifier(testIssuer, testAudience, NewRemoteKeySet(server.URL, server.Client()))
+	if _, err := v.Verify(sign(t, key, "k1", nil)); !errors.Is(err, ErrKeySetUnavailable) {
+		t.Fatalf("network error: want ErrKeySetUnavailable, got %v", err)
+	}
+}
+
+func TestVerifyFileKeySet(t *testing.T) {
+	key := newKey(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, jwks(map[string]*rsa.PrivateKey{"k1": key}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	v := NewVerifier(testIssuer, testAudience, NewKeySet(path))
+	if _, err := v.Verify(sign(t, key, "k1", nil)); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,20 +1,21 @@
 package repository
 
 import (
-	"fmt"
+	"context"
+	"go-rest-api/apperror"
 	"go-rest-api/model"
 
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 type IBlogRepository interface {
-	GetAllBlogs(blogs *[]model.Blog, userId uint) error
-	GetBlogById(blog *model.Blog, userId uint, blogId uint) error
-	CreateBlog(blog *model.Blog) error
-	UpdateBlog(blog *model.Blog, userId uint, blogId uint) error
-	DeleteBlog(userId uint, blogId uint) error
-	GetAllBlogsForBuild() ([]model.Blog, error)
+	GetAllBlogs(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.Blog], error)
+	GetBlogById(ctx context.Context, blog *model.Blog, userId uint, blogId uint) error
+	CreateBlog(ctx context.Context, blog *model.Blog) error
+	UpdateBlog(ctx context.Context, blog *model.Blog, userId uint, blogId uint) error
+	UpdateBlogCover(ctx context.Context, blog *model.Blog, userId uint, blogId uint) error
+	DeleteBlog(ctx context.Context, userId uint, blogId uint) error
+	GetAllBlogsForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.Blog], error)
 }
 
 type blogRepository struct {
@@ -25,57 +26,77 @@ func NewBlogRepository(db *gorm.DB) IBlogRepository {
 	return &blogRepository{db}
 }
 
-func (br *blogRepository) GetAllBlogs(blogs *[]model.Blog, userId uint) error {
-	if err := br.db.Joins("User").Where("user_id=?", userId).Order("created_at").Find(blogs).Error; err != nil {
-		return err
-	}
-	return nil
+// blogListSpec はブログ一覧で使える並び替え・絞り込みの項目です。
+var blogListSpec = listSpec{
+	sorts: map[string]string{
+		"id":         "id",
+		"title":      "title",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	defaultSort: "created_at",
+	dateColumn:  "created_at",
 }
 
-func (br *blogRepository) GetBlogById(blog *model.Blog, userId uint, blogId uint) error {
-	if err := br.db.Joins("User").Where("user_id=?", userId).First(blog, blogId).Error; err != nil {
-		return err
+func (br *blogRepository) GetAllBlogs(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.Blog], error) {
+	return paginate[model.Blog](br.db.WithContext(ctx).Joins("User").Where("user_id=?", userId), blogListSpec, q)
+}
+
+func (br *blogRepository) GetBlogById(ctx context.Context, blog *model.Blog, userId uint, blogId uint) error {
+	if err := br.db.WithContext(ctx).Joins("User").Where("user_id=?", userId).First(blog, blogId).Error; err != nil {
+		return translateError(err)
 	}
 	return nil
 }
 
-func (br *blogRepository) CreateBlog(blog *model.Blog) error {
-	if err := br.db.Create(blog).Error; err != nil {
-		return err
+func (br *blogRepository) CreateBlog(ctx context.Context, blog *model.Blog) error {
+	if err := br.db.WithContext(ctx).Create(blog).Error; err != nil {
+		return translateError(err)
 	}
 	return nil
 }
 
-func (br *blogRepository) UpdateBlog(blog *model.Blog, userId uint, blogId uint) error {
-	result := br.db.Model(blog).Clauses(clause.Returning{}).Where("id=? AND user_id=?", blogId, userId).Updates(map[string]interface{}{
+func (br *blogRepository) UpdateBlog(ctx context.Context, blog *model.Blog, userId uint, blogId uint) error {
+	err := updateReturning(br.db.WithContext(ctx), blog, map[string]interface{}{
 		"title":   blog.Title,
 		"content": blog.Content,
-	})
-	if result.Error != nil {
-		return result.Error
+	}, "id=? AND user_id=?", blogId, userId)
+	if err != nil {
+		return translateError(err)
 	}
-	if result.RowsAffected < 1 {
-		return fmt.Errorf("object does not exist")
+	return nil
+}
+
+// UpdateBlogCover はブログのカバー画像を blog.Cover に置き換えます。Key が空の場合はカバー画像を外します。
+func (br *blogRepository) UpdateBlogCover(ctx context.Context, blog *model.Blog, userId uint, blogId uint) error {
+	err := updateReturning(br.db.WithContext(ctx), blog, map[string]interface{}{
+		"cover_key":           blog.Cover.Key,
+		"cover_url":           blog.Cover.URL,
+		"cover_thumbnail_key": blog.Cover.ThumbnailKey,
+		"cover_thumbnail_url": blog.Cover.ThumbnailURL,
+		"cover_content_type":  blog.Cover.ContentType,
+		"cover_size":          blog.Cover.Size,
+		"cover_width":         blog.Cover.Width,
+		"cover_height":        blog.Cover.Height,
+	}, "id=? AND user_id=?", blogId, userId)
+	if err != nil {
+		return translateError(err)
 	}
 	return nil
 }
 
-func (br *blogRepository) DeleteBlog(userId uint, blogId uint) error {
-	result := br.db.Where("id=? AND user_id=?", blogId, userId).Delete(&model.Blog{})
+func (br *blogRepository) DeleteBlog(ctx context.Context, userId uint, blogId uint) error {
+	result := br.db.WithContext(ctx).Where("id=? AND user_id=?", blogId, userId).Delete(&model.Blog{})
 	if result.Error != nil {
-		return result.Error
+		return translateError(result.Error)
 	}
 	if result.RowsAffected < 1 {
-		return fmt.Errorf("object does not exist")
+		return apperror.NotFound("object does not exist")
 	}
 	return nil
 }
 
-func (br *blogRepository) GetAllBlogsForBuild() ([]model.Blog, error) {
-    var blogs []model.Blog
-    if err := br.db.Joins("User").Order("created_at").Find(&blogs).Error; err != nil {
-        return nil, err
-    }
-    return blogs, nil
+func (br *blogRepository) GetAllBlogsForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.Blog], error) {
+    return paginate[model.Blog](br.db.WithContext(ctx).Joins("User"), blogListSpec, q)
 }
 
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"go-rest-api/apperror"
+
+	"gorm.io/gorm"
+)
+
+// isPostgres はデータベースが PostgreSQL かどうかを返します。
+// 全文検索などの PostgreSQL 固有の機能はリポジトリの中だけで使い分け、SQLite では代わりの方法で処理します。
+func isPostgres(db *gorm.DB) bool {
+	return db.Dialector.Name() == "postgres"
+}
+
+// updateReturning は query に一致する行を values で更新し、更新後の行を dest に読み込みます。
+// RETURNING 句に対応しないデータベースでも同じ結果になるよう、同じトランザクションの中で読み直します。
+// 一致する行がない場合は NotFound を返します。
+func updateReturning(db *gorm.DB, dest interface{}, values map[string]interface{}, query string, args ...interface{}) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(dest).Where(query, args...).Updates(values)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected < 1 {
+			return apperror.NotFound("object does not exist")
+		}
+		return tx.Where(query, args...).First(dest).Error
+	})
+}
+
+// deleteReturning は query に一致する行を削除し、削除した行を dest に読み込みます。
+// 一致する行がない場合は NotFound を返します。
+func deleteReturning(db *gorm.DB, dest interface{}, query string, args ...interface{}) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(query, args...).First(dest).Error; err != nil {
+			return err
+		}
+		return tx.Delete(dest).Error
+	})
+}
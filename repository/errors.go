@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-rest-api/apperror"
+
+	gosqlite "github.com/glebarez/go-sqlite"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// uniqueViolation は PostgreSQL の一意制約違反のエラーコードです。
+const uniqueViolation = "23505"
+
+// sqliteConstraintUnique は SQLite の一意制約違反の拡張エラーコード (SQLITE_CONSTRAINT_UNIQUE) です。
+const sqliteConstraintUnique = 2067
+
+// translateError はGORMのエラーをドメインエラーに変換します。
+// DBのエラーメッセージはクライアントに返さないよう、原因として保持するだけにします。
+func translateError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return apperror.Wrap(apperror.KindNotFound, "object does not exist", err)
+	case errors.Is(err, gorm.ErrDuplicatedKey), isUniqueViolation(err):
+		return apperror.Wrap(apperror.KindConflict, "object already exists", err)
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		// リクエストの処理時間の上限を超えたか、クライアントが切断してクエリを中断した
+		return apperror.Wrap(apperror.KindUnavailable, "request timed out", err)
+	}
+	return err
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.Code == uniqueViolation
+	}
+	var sqliteErr *gosqlite.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.Code() == sqliteConstraintUnique
+}
@@ -1,17 +1,18 @@
 package repository
 
 import (
-	"fmt"
+	"context"
+	"go-rest-api/apperror"
 	"go-rest-api/model"
 	"gorm.io/gorm"
 )
 
 type IFavoriteRepository interface {
-	AddFavorite(favorite *model.Favorite) error
-	RemoveFavorite(shopId, userId string) error
-	GetFavorites(userId string, favorites *[]model.Favorite) error
-	GetFavoriteShops(userId string, shops *[]model.Shop) error
-	GetFavoritesForBuild(favorites *[]model.Favorite) error
+	AddFavorite(ctx context.Context, favorite *model.Favorite) error
+	RemoveFavorite(ctx context.Context, shopId, userId string) error
+	GetFavorites(ctx context.Context, userId string, favorites *[]model.Favorite) error
+	GetFavoriteShops(ctx context.Context, userId string, shops *[]model.Shop) error
+	GetFavoritesForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.Favorite], error)
 }
 
 type favoriteRepository struct {
@@ -22,51 +23,58 @@ func NewFavoriteRepository(db *gorm.DB) IFavoriteRepository {
 	return &favoriteRepository{db}
 }
 
-func (fr *favoriteRepository) AddFavorite(favorite *model.Favorite) error {
-	if err := fr.db.Create(favorite).Error; err != nil {
-		return err
+func (fr *favoriteRepository) AddFavorite(ctx context.Context, favorite *model.Favorite) error {
+	if err := fr.db.WithContext(ctx).Create(favorite).Error; err != nil {
+		return translateError(err)
 	}
 	return nil
 }
 
-func (fr *favoriteRepository) RemoveFavorite(shopId, userId string) error {
-    result := fr.db.Where("shop_id=? AND user_id=?", shopId, userId).Delete(&model.Favorite{})
+func (fr *favoriteRepository) RemoveFavorite(ctx context.Context, shopId, userId string) error {
+    result := fr.db.WithContext(ctx).Where("shop_id=? AND user_id=?", shopId, userId).Delete(&model.Favorite{})
     if result.Error != nil {
-        return result.Error
+        return translateError(result.Error)
     }
     if result.RowsAffected < 1 {
-        return fmt.Errorf("object does not exist")
+        return apperror.NotFound("object does not exist")
     }
     return nil
 }
 
 
-func (fr *favoriteRepository) GetFavorites(userId string, favorites *[]model.Favorite) error {
-	if err := fr.db.Where("user_id=?", userId).Find(favorites).Error; err != nil {
-		return err
+func (fr *favoriteRepository) GetFavorites(ctx context.Context, userId string, favorites *[]model.Favorite) error {
+	if err := fr.db.WithContext(ctx).Where("user_id=?", userId).Find(favorites).Error; err != nil {
+		return translateError(err)
 	}
 	return nil
 }
 
-func (fr *favoriteRepository) GetFavoriteShops(userId string, shops *[]model.Shop) error {
+func (fr *favoriteRepository) GetFavoriteShops(ctx context.Context, userId string, shops *[]model.Shop) error {
 	// SQLクエリを実行してお気に入りのショップを取得します。
 	// このクエリは、お気に入りテーブルとショップテーブルを結合し、指定されたユーザーIDに関連するお気に入りのショップを取得します。
-	err := fr.db.Joins("JOIN favorites ON favorites.shop_id = shops.id").
+	err := fr.db.WithContext(ctx).Joins("JOIN favorites ON favorites.shop_id = shops.id").
 		Where("favorites.user_id = ?", userId).
 		Find(shops).Error
 
 	// エラーが発生した場合はエラーを返します。
 	if err != nil {
-		return err
+		return translateError(err)
 	}
 
 	// 成功した場合はnilを返します。
 	return nil
 }
 
-func (fr *favoriteRepository) GetFavoritesForBuild(favorites *[]model.Favorite) error {
-    if err := fr.db.Preload("Shop").Preload("User").Find(favorites).Error; err != nil {
-        return err
-    }
-    return nil
+// favoriteListSpec はお気に入り一覧で使える並び替えの項目です。
+var favoriteListSpec = listSpec{
+    sorts: map[string]string{
+        "id":         "id",
+        "created_at": "created_at",
+    },
+    defaultSort: "id",
+    dateColumn:  "created_at",
+}
+
+func (fr *favoriteRepository) GetFavoritesForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.Favorite], error) {
+    return paginate[model.Favorite](fr.db.WithContext(ctx).Preload("Shop").Preload("User"), favoriteListSpec, q)
 }
\ No newline at end of file
@@ -0,0 +1,8 @@
+package repository_test
+
+import (
+	"go-rest-api/repository/repositorytest"
+	"testing"
+)
+
+func TestSQLite(t *testing.T) { repositorytest.Run(t, repositorytest.SQLite) }
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"go-rest-api/migration"
+
+	"gorm.io/gorm"
+)
+
+type IHealthRepository interface {
+	// Ping はデータベースに接続できるかを確認します。
+	Ping(ctx context.Context) error
+	// PendingMigrations は未適用のマイグレーションの件数です。
+	PendingMigrations(ctx context.Context) (int, error)
+}
+
+type healthRepository struct {
+	db       *gorm.DB
+	migrator migration.IMigrator
+}
+
+// NewHealthRepository は起動時に作成したマイグレーターで未適用のマイグレーションを数えます。
+// 確認のたびにマイグレーションを読み込み直さず、schema_migrations の読み込みのみを行います。
+func NewHealthRepository(db *gorm.DB, migrator migration.IMigrator) IHealthRepository {
+	return &healthRepository{db, migrator}
+}
+
+func (hr *healthRepository) Ping(ctx context.Context) error {
+	sqlDB, err := hr.db.DB()
+	if err != nil {
+		return err
+	}
+	return translateError(sqlDB.PingContext(ctx))
+}
+
+func (hr *healthRepository) PendingMigrations(ctx context.Context) (int, error) {
+	pending, err := hr.migrator.WithContext(ctx).Pending()
+	return pending, translateError(err)
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"go-rest-api/model"
+
+	"gorm.io/gorm"
+)
+
+type IIdentityRepository interface {
+	GetIdentity(ctx context.Context, identity *model.Identity, provider string, subject string) error
+	CreateIdentity(ctx context.Context, identity *model.Identity) error
+	// CreateUserWithIdentity はユーザーと外部アカウントの紐付けを同じトランザクションで作成します。
+	CreateUserWithIdentity(ctx context.Context, user *model.User, identity *model.Identity) error
+}
+
+type identityRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepository(db *gorm.DB) IIdentityRepository {
+	return &identityRepository{db}
+}
+
+func (ir *identityRepository) GetIdentity(ctx context.Context, identity *model.Identity, provider string, subject string) error {
+	if err := ir.db.WithContext(ctx).Where("provider=? AND subject=?", provider, subject).First(identity).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (ir *identityRepository) CreateIdentity(ctx context.Context, identity *model.Identity) error {
+	if err := ir.db.WithContext(ctx).Create(identity).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (ir *identityRepository) CreateUserWithIdentity(ctx context.Context, user *model.User, identity *model.Identity) error {
+	return ir.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return translateError(err)
+		}
+		identity.UserID = user.ID
+		return translateError(tx.Create(identity).Error)
+	})
+}
@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"reflect"
+	"strconv"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// listSpec は一覧取得で使える並び替え・絞り込みの項目です。
+// クエリで指定された項目名はここに定義したカラムにのみ変換するため、任意のカラムは指定できません。
+type listSpec struct {
+	// sorts は並び替えの項目名とカラム名の対応です。
+	sorts       map[string]string
+	defaultSort string
+	// defaultOrder が空の場合は昇順です。
+	defaultOrder string
+	// filters は絞り込みの項目名とカラム名の対応です（値の完全一致）。
+	filters map[string]string
+	// dateColumn は From / To を適用するカラムです。空の場合は日付で絞り込めません。
+	dateColumn string
+}
+
+// cursor は最後に返した行の並び替えの値とIDです。
+// 並び替えの条件が変わった場合に検出できるよう、項目名と順序も含めます。
+type cursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	// Type は Value の型です。空の場合は文字列です。
+	Type  string `json:"t,omitempty"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// カーソルに保存する並び替えの値の型
+const (
+	cursorTime  = "time"
+	cursorInt   = "int"
+	cursorUint  = "uint"
+	cursorFloat = "float"
+)
+
+// value はカーソルに保存した並び替えの値を元の型に戻します。
+// データベースは日時を文字列とは異なる形式で保存するため、文字列のまま比較すると順序が正しくなりません。
+func (c cursor) value() (interface{}, error) {
+	switch c.Type {
+	case "":
+		return c.Value, nil
+	case cursorTime:
+		return time.Parse(time.RFC3339Nano, c.Value)
+	case cursorInt:
+		return strconv.ParseInt(c.Value, 10, 64)
+	case cursorUint:
+		return strconv.ParseUint(c.Value, 10, 64)
+	case cursorFloat:
+		return strconv.ParseFloat(c.Value, 64)
+	}
+	return nil, errors.New("unknown cursor type: " + c.Type)
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	c := cursor{}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
+
+// paginate は db に listSpec で許可した条件を適用し、1ページ分の結果と件数を返します。
+// db には呼び出し側でユーザーIDなどの条件を指定しておきます。
+func paginate[T any](db *gorm.DB, spec listSpec, q model.ListQuery) (model.Page[T], error) {
+	page := model.Page[T]{Items: []T{}, Limit: q.Limit}
+	if page.Limit <= 0 {
+		page.Limit = model.DefaultListLimit
+	}
+
+	sort := q.Sort
+	if sort == "" {
+		sort = spec.defaultSort
+	}
+	column, ok := spec.sorts[sort]
+	if !ok {
+		return page, apperror.Field("sort", validation.NewError("validation_sort_unsupported", "unsupported sort field"))
+	}
+	order := q.Order
+	if order == "" {
+		order = spec.defaultOrder
+	}
+	if order == "" {
+		order = model.SortAsc
+	}
+	if order != model.SortAsc && order != model.SortDesc {
+		return page, apperror.Field("order", validation.NewError("validation_order_invalid", "order must be asc or desc"))
+	}
+	desc := order == model.SortDesc
+
+	query := db.Model(new(T))
+	for name, value := range q.Filters {
+		filterColumn, ok := spec.filters[name]
+		if !ok {
+			return page, apperror.Field(name, validation.NewError("validation_filter_unsupported", "unsupported filter"))
+		}
+		query = query.Where(clause.Eq{Column: tableColumn(filterColumn), Value: value})
+	}
+	if q.From != nil || q.To != nil {
+		if spec.dateColumn == "" {
+			return page, apperror.Field("from", validation.NewError("validation_filter_unsupported", "date range is not supported"))
+		}
+		if q.From != nil {
+			query = query.Where(clause.Gte{Column: tableColumn(spec.dateColumn), Value: *q.From})
+		}
+		if q.To != nil {
+			query = query.Where(clause.Lt{Column: tableColumn(spec.dateColumn), Value: q.To.AddDate(0, 0, 1)})
+		}
+	}
+
+	if err := query.Session(&gorm.Session{}).Count(&page.Total).Error; err != nil {
+		return page, translateError(err)
+	}
+
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil || c.Sort != sort || c.Order != order {
+			return page, apperror.Field("cursor", validation.NewError("validation_cursor_invalid", "invalid cursor"))
+		}
+		value, err := c.value()
+		if err != nil {
+			return page, apperror.Field("cursor", validation.NewError("validation_cursor_invalid", "invalid cursor"))
+		}
+		query = query.Where(keyset(column, desc, value, c.ID))
+	} else {
+		page.Offset = q.Offset
+		query = query.Offset(q.Offset)
+	}
+
+	// 次のページの有無を判定するため、1件多く取得する
+	rows := []T{}
+	err := query.
+		Order(clause.OrderByColumn{Column: tableColumn(column), Desc: desc}).
+		Order(clause.OrderByColumn{Column: tableColumn("id"), Desc: desc}).
+		Limit(page.Limit + 1).
+		Find(&rows).Error
+	if err != nil {
+		return page, translateError(err)
+	}
+	if len(rows) > page.Limit {
+		rows = rows[:page.Limit]
+		next, err := cursorOf(db, rows[len(rows)-1], column)
+		if err != nil {
+			return page, err
+		}
+		next.Sort = sort
+		next.Order = order
+		page.NextCursor = encodeCursor(next)
+	}
+	page.Items = rows
+	return page, nil
+}
+
+// keyset はカーソルの行より後ろの行を返す条件です。並び替えの値が同じ行はIDで順序を決めます。
+func keyset(column string, desc bool, value interface{}, id uint) clause.Expression {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	if column == "id" {
+		return clause.Expr{SQL: "? " + op + " ?", Vars: []interface{}{tableColumn("id"), id}}
+	}
+	return clause.Expr{
+		SQL:  "(? " + op + " ? OR (? = ? AND ? " + op + " ?))",
+		Vars: []interface{}{tableColumn(column), value, tableColumn(column), value, tableColumn("id"), id},
+	}
+}
+
+// cursorOf は行から並び替えの値とIDを取り出します。
+func cursorOf(db *gorm.DB, row interface{}, column string) (cursor, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(row); err != nil {
+		return cursor{}, err
+	}
+	value := reflect.ValueOf(row)
+	idField := stmt.Schema.LookUpField("id")
+	sortField := stmt.Schema.LookUpField(column)
+	if idField == nil || sortField == nil {
+		return cursor{}, errors.New("cursor field not found: " + column)
+	}
+	ctx := context.Background()
+	id, _ := idField.ValueOf(ctx, value)
+	v, _ := sortField.ValueOf(ctx, value)
+	c := cursor{}
+	c.ID, _ = id.(uint)
+	// 日時はデータベースから読み込んだオフセットのまま保存し、同じ形式で比較されるようにする
+	if t, ok := v.(time.Time); ok {
+		c.Type, c.Value = cursorTime, t.Format(time.RFC3339Nano)
+		return c, nil
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		c.Type, c.Value = cursorInt, strconv.FormatInt(rv.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		c.Type, c.Value = cursorUint, strconv.FormatUint(rv.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		c.Type, c.Value = cursorFloat, strconv.FormatFloat(rv.Float(), 'g', -1, 64)
+	default:
+		c.Value = fmt.Sprint(v)
+	}
+	return c, nil
+}
+
+func tableColumn(name string) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: name}
+}
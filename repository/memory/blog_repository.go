@@ -0,0 +1,109 @@
+package memory
+
+import (
+	"context"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"time"
+)
+
+type blogRepository struct {
+	s *Store
+}
+
+func NewBlogRepository(s *Store) repository.IBlogRepository {
+	return &blogRepository{s}
+}
+
+// blogListSpec はブログ一覧で使える並び替え・絞り込みの項目です。
+var blogListSpec = listSpec[model.Blog]{
+	sorts: map[string]func(b model.Blog) interface{}{
+		"id":         func(b model.Blog) interface{} { return b.ID },
+		"title":      func(b model.Blog) interface{} { return b.Title },
+		"created_at": func(b model.Blog) interface{} { return b.CreatedAt },
+		"updated_at": func(b model.Blog) interface{} { return b.UpdatedAt },
+	},
+	defaultSort: "created_at",
+	date:        func(b model.Blog) time.Time { return b.CreatedAt },
+	id:          func(b model.Blog) uint { return b.ID },
+}
+
+func (br *blogRepository) GetAllBlogs(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.Blog], error) {
+	br.s.rlock()
+	defer br.s.runlock()
+	return paginate(br.withUsers(func(b model.Blog) bool { return b.UserId == userId }), blogListSpec, q)
+}
+
+func (br *blogRepository) GetBlogById(ctx context.Context, blog *model.Blog, userId uint, blogId uint) error {
+	br.s.rlock()
+	defer br.s.runlock()
+	b, ok := br.s.blogs.get(blogId)
+	if !ok || b.UserId != userId {
+		return errNotFound()
+	}
+	b.User, _ = br.s.users.get(b.UserId)
+	*blog = b
+	return nil
+}
+
+func (br *blogRepository) CreateBlog(ctx context.Context, blog *model.Blog) error {
+	br.s.lock()
+	defer br.s.unlock()
+	touch(&blog.CreatedAt, &blog.UpdatedAt)
+	row := *blog
+	row.User = model.User{}
+	blog.ID = br.s.blogs.insert(&row, func(b *model.Blog, id uint) { b.ID = id })
+	return nil
+}
+
+func (br *blogRepository) UpdateBlog(ctx context.Context, blog *model.Blog, userId uint, blogId uint) error {
+	return br.update(blog, userId, blogId, func(b *model.Blog) {
+		b.Title = blog.Title
+		b.Content = blog.Content
+	})
+}
+
+// UpdateBlogCover はブログのカバー画像を blog.Cover に置き換えます。Key が空の場合はカバー画像を外します。
+func (br *blogRepository) UpdateBlogCover(ctx context.Context, blog *model.Blog, userId uint, blogId uint) error {
+	return br.update(blog, userId, blogId, func(b *model.Blog) { b.Cover = blog.Cover })
+}
+
+func (br *blogRepository) update(blog *model.Blog, userId uint, blogId uint, fc func(b *model.Blog)) error {
+	br.s.lock()
+	defer br.s.unlock()
+	b, ok := br.s.blogs.get(blogId)
+	if !ok || b.UserId != userId {
+		return errNotFound()
+	}
+	fc(&b)
+	b.UpdatedAt = time.Now()
+	br.s.blogs.put(blogId, b)
+	*blog = b
+	return nil
+}
+
+func (br *blogRepository) DeleteBlog(ctx context.Context, userId uint, blogId uint) error {
+	br.s.lock()
+	defer br.s.unlock()
+	b, ok := br.s.blogs.get(blogId)
+	if !ok || b.UserId != userId {
+		return errNotFound()
+	}
+	br.s.blogs.delete(blogId)
+	return nil
+}
+
+func (br *blogRepository) GetAllBlogsForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.Blog], error) {
+	br.s.rlock()
+	defer br.s.runlock()
+	return paginate(br.withUsers(nil), blogListSpec, q)
+}
+
+// withUsers は条件に一致するブログを、書いたユーザーを設定して返します。
+func (br *blogRepository) withUsers(match func(b model.Blog) bool) []model.Blog {
+	blogs := br.s.blogs.list(match)
+	for i := range blogs {
+		blogs[i].User, _ = br.s.users.get(blogs[i].UserId)
+	}
+	return blogs
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"strconv"
+	"time"
+)
+
+type favoriteRepository struct {
+	s *Store
+}
+
+func NewFavoriteRepository(s *Store) repository.IFavoriteRepository {
+	return &favoriteRepository{s}
+}
+
+func (fr *favoriteRepository) AddFavorite(ctx context.Context, favorite *model.Favorite) error {
+	fr.s.lock()
+	defer fr.s.unlock()
+	touch(&favorite.CreatedAt, &favorite.UpdatedAt)
+	row := *favorite
+	row.Shop, row.User = model.Shop{}, model.User{}
+	favorite.ID = fr.s.favorites.insert(&row, func(f *model.Favorite, id uint) { f.ID = id })
+	return nil
+}
+
+func (fr *favoriteRepository) RemoveFavorite(ctx context.Context, shopId, userId string) error {
+	fr.s.lock()
+	defer fr.s.unlock()
+	favorites := fr.s.favorites.list(func(f model.Favorite) bool {
+		return idEquals(f.ShopID, shopId) && idEquals(f.UserID, userId)
+	})
+	if len(favorites) == 0 {
+		return errNotFound()
+	}
+	for _, f := range favorites {
+		fr.s.favorites.delete(f.ID)
+	}
+	return nil
+}
+
+func (fr *favoriteRepository) GetFavorites(ctx context.Context, userId string, favorites *[]model.Favorite) error {
+	fr.s.rlock()
+	defer fr.s.runlock()
+	*favorites = fr.s.favorites.list(func(f model.Favorite) bool { return idEquals(f.UserID, userId) })
+	return nil
+}
+
+// GetFavoriteShops はユーザーがお気に入りに登録したショップを、登録した順に返します。
+func (fr *favoriteRepository) GetFavoriteShops(ctx context.Context, userId string, shops *[]model.Shop) error {
+	fr.s.rlock()
+	defer fr.s.runlock()
+	*shops = []model.Shop{}
+	for _, f := range fr.s.favorites.list(func(f model.Favorite) bool { return idEquals(f.UserID, userId) }) {
+		if shop, ok := fr.s.shops.get(f.ShopID); ok {
+			*shops = append(*shops, shop)
+		}
+	}
+	return nil
+}
+
+// favoriteListSpec はお気に入り一覧で使える並び替えの項目です。
+var favoriteListSpec = listSpec[model.Favorite]{
+	sorts: map[string]func(f model.Favorite) interface{}{
+		"id":         func(f model.Favorite) interface{} { return f.ID },
+		"created_at": func(f model.Favorite) interface{} { return f.CreatedAt },
+	},
+	defaultSort: "id",
+	date:        func(f model.Favorite) time.Time { return f.CreatedAt },
+	id:          func(f model.Favorite) uint { return f.ID },
+}
+
+func (fr *favoriteRepository) GetFavoritesForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.Favorite], error) {
+	fr.s.rlock()
+	defer fr.s.runlock()
+	favorites := fr.s.favorites.list(nil)
+	for i := range favorites {
+		favorites[i].Shop, _ = fr.s.shops.get(favorites[i].ShopID)
+		favorites[i].User, _ = fr.s.users.get(favorites[i].UserID)
+	}
+	return paginate(favorites, favoriteListSpec, q)
+}
+
+// idEquals はパスパラメーターなどの文字列のIDが id と等しいかを返します。数値でない場合は一致しません。
+func idEquals(id uint, s string) bool {
+	n, err := strconv.ParseUint(s, 10, 64)
+	return err == nil && uint(n) == id
+}
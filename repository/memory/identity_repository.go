@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"context"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+)
+
+type identityRepository struct {
+	s *Store
+}
+
+func NewIdentityRepository(s *Store) repository.IIdentityRepository {
+	return &identityRepository{s}
+}
+
+func (ir *identityRepository) GetIdentity(ctx context.Context, identity *model.Identity, provider string, subject string) error {
+	ir.s.rlock()
+	defer ir.s.runlock()
+	identities := ir.s.identities.list(func(i model.Identity) bool { return i.Provider == provider && i.Subject == subject })
+	if len(identities) == 0 {
+		return errNotFound()
+	}
+	*identity = identities[0]
+	return nil
+}
+
+func (ir *identityRepository) CreateIdentity(ctx context.Context, identity *model.Identity) error {
+	ir.s.lock()
+	defer ir.s.unlock()
+	return createIdentity(ir.s, identity)
+}
+
+func (ir *identityRepository) CreateUserWithIdentity(ctx context.Context, user *model.User, identity *model.Identity) error {
+	ir.s.lock()
+	defer ir.s.unlock()
+	// どちらかが失敗した場合はユーザーも作成しない
+	saved := ir.s.users.clone()
+	if err := createUser(ir.s, user); err != nil {
+		return err
+	}
+	identity.UserID = user.ID
+	if err := createIdentity(ir.s, identity); err != nil {
+		ir.s.users = saved
+		return err
+	}
+	return nil
+}
+
+// createIdentity は外部アカウントの紐付けを追加します。Store のロックを取得した状態で呼びます。
+func createIdentity(s *Store, identity *model.Identity) error {
+	// identities(provider, subject) の一意制約
+	if len(s.identities.list(func(i model.Identity) bool {
+		return i.Provider == identity.Provider && i.Subject == identity.Subject
+	})) > 0 {
+		return errConflict()
+	}
+	touch(&identity.CreatedAt, &identity.UpdatedAt)
+	row := *identity
+	row.User = model.User{}
+	identity.ID = s.identities.insert(&row, func(i *model.Identity, id uint) { i.ID = id })
+	return nil
+}
@@ -0,0 +1,227 @@
+package memory
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// listSpec は一覧取得で使える並び替え・絞り込みの項目です。GORM の実装の listSpec と同じ項目名を定義します。
+type listSpec[T any] struct {
+	// sorts は並び替えの項目名と値の取り出し方の対応です。
+	sorts       map[string]func(row T) interface{}
+	defaultSort string
+	// defaultOrder が空の場合は昇順です。
+	defaultOrder string
+	// filters は絞り込みの項目名と値の取り出し方の対応です（文字列にした値の完全一致）。
+	filters map[string]func(row T) interface{}
+	// date は From / To を適用する値です。nil の場合は日付で絞り込めません。
+	date func(row T) time.Time
+	id   func(row T) uint
+}
+
+// cursor は最後に返した行の並び替えの値とIDです。
+type cursor struct {
+	Sort  string `json:"s"`
+	Order string `json:"o"`
+	Type  string `json:"t,omitempty"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+// paginate は rows に listSpec で許可した条件を適用し、1ページ分の結果と件数を返します。
+// rows には呼び出し側でユーザーIDなどの条件を適用しておきます。
+func paginate[T any](rows []T, spec listSpec[T], q model.ListQuery) (model.Page[T], error) {
+	page := model.Page[T]{Items: []T{}, Limit: q.Limit}
+	if page.Limit <= 0 {
+		page.Limit = model.DefaultListLimit
+	}
+
+	sortName := q.Sort
+	if sortName == "" {
+		sortName = spec.defaultSort
+	}
+	value, ok := spec.sorts[sortName]
+	if !ok {
+		return page, apperror.Field("sort", validation.NewError("validation_sort_unsupported", "unsupported sort field"))
+	}
+	order := q.Order
+	if order == "" {
+		order = spec.defaultOrder
+	}
+	if order == "" {
+		order = model.SortAsc
+	}
+	if order != model.SortAsc && order != model.SortDesc {
+		return page, apperror.Field("order", validation.NewError("validation_order_invalid", "order must be asc or desc"))
+	}
+	desc := order == model.SortDesc
+
+	for name := range q.Filters {
+		if _, ok := spec.filters[name]; !ok {
+			return page, apperror.Field(name, validation.NewError("validation_filter_unsupported", "unsupported filter"))
+		}
+	}
+	if (q.From != nil || q.To != nil) && spec.date == nil {
+		return page, apperror.Field("from", validation.NewError("validation_filter_unsupported", "date range is not supported"))
+	}
+	matched := []T{}
+	for _, row := range rows {
+		if matchList(row, spec, q) {
+			matched = append(matched, row)
+		}
+	}
+	page.Total = int64(len(matched))
+
+	// 並び替えの値が同じ行はIDで順序を決める
+	less := func(a, b T) bool {
+		if c := compare(value(a), value(b)); c != 0 {
+			return (c < 0) != desc
+		}
+		return (spec.id(a) < spec.id(b)) != desc
+	}
+	sort.SliceStable(matched, func(i, j int) bool { return less(matched[i], matched[j]) })
+
+	if q.Cursor != "" {
+		c, err := decodeCursor(q.Cursor)
+		if err != nil || c.Sort != sortName || c.Order != order {
+			return page, apperror.Field("cursor", validation.NewError("validation_cursor_invalid", "invalid cursor"))
+		}
+		start := len(matched)
+		for i, row := range matched {
+			if afterCursor(value(row), spec.id(row), c, desc) {
+				start = i
+				break
+			}
+		}
+		matched = matched[start:]
+	} else {
+		page.Offset = q.Offset
+		matched = matched[min(max(q.Offset, 0), len(matched)):]
+	}
+
+	if len(matched) > page.Limit {
+		matched = matched[:page.Limit]
+		last := matched[len(matched)-1]
+		typ, v := formatValue(value(last))
+		page.NextCursor = encodeCursor(cursor{Sort: sortName, Order: order, Type: typ, Value: v, ID: spec.id(last)})
+	}
+	page.Items = append(page.Items, matched...)
+	return page, nil
+}
+
+func matchList[T any](row T, spec listSpec[T], q model.ListQuery) bool {
+	for name, want := range q.Filters {
+		if fmt.Sprint(spec.filters[name](row)) != want {
+			return false
+		}
+	}
+	if q.From != nil && spec.date(row).Before(*q.From) {
+		return false
+	}
+	if q.To != nil && !spec.date(row).Before(q.To.AddDate(0, 0, 1)) {
+		return false
+	}
+	return true
+}
+
+// afterCursor はカーソルの行より後ろの行かを返します。
+func afterCursor(v interface{}, id uint, c cursor, desc bool) bool {
+	cmp := compare(v, parseValue(v, c.Value))
+	if cmp == 0 {
+		cmp = compare(id, c.ID)
+	}
+	if desc {
+		return cmp < 0
+	}
+	return cmp > 0
+}
+
+// compare は同じ型の並び替えの値を比較します。
+func compare(a, b interface{}) int {
+	switch a := a.(type) {
+	case uint:
+		return compareOrdered(a, b.(uint))
+	case int:
+		return compareOrdered(a, b.(int))
+	case int64:
+		return compareOrdered(a, b.(int64))
+	case float64:
+		return compareOrdered(a, b.(float64))
+	case string:
+		return strings.Compare(a, b.(string))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	}
+	panic(fmt.Sprintf("memory: unsupported sort value %T", a))
+}
+
+func compareOrdered[V uint | int | int64 | float64](a, b V) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// formatValue と parseValue はカーソルに保存する並び替えの値の型と値の変換です。GORM の実装のカーソルと同じ形式です。
+func formatValue(v interface{}) (string, string) {
+	switch v := v.(type) {
+	case time.Time:
+		return "time", v.Format(time.RFC3339Nano)
+	case int, int64:
+		return "int", fmt.Sprint(v)
+	case uint:
+		return "uint", fmt.Sprint(v)
+	case float64:
+		return "float", strconv.FormatFloat(v, 'g', -1, 64)
+	}
+	return "", fmt.Sprint(v)
+}
+
+// parseValue は s を like と同じ型の値に変換します。変換できない場合はゼロ値です。
+func parseValue(like interface{}, s string) interface{} {
+	switch like.(type) {
+	case uint:
+		n, _ := strconv.ParseUint(s, 10, 64)
+		return uint(n)
+	case int:
+		n, _ := strconv.Atoi(s)
+		return n
+	case int64:
+		n, _ := strconv.ParseInt(s, 10, 64)
+		return n
+	case float64:
+		n, _ := strconv.ParseFloat(s, 64)
+		return n
+	case time.Time:
+		t, _ := time.Parse(time.RFC3339Nano, s)
+		return t
+	}
+	return s
+}
+
+func encodeCursor(c cursor) string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	c := cursor{}
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, err
+	}
+	err = json.Unmarshal(b, &c)
+	return c, err
+}
@@ -0,0 +1,8 @@
+package memory_test
+
+import (
+	"go-rest-api/repository/repositorytest"
+	"testing"
+)
+
+func TestMemory(t *testing.T) { repositorytest.Run(t, repositorytest.Memory) }
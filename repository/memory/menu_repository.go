@@ -0,0 +1,195 @@
+package memory
+
+import (
+	"context"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"sort"
+	"time"
+)
+
+type menuRepository struct {
+	s *Store
+}
+
+func NewMenuRepository(s *Store) repository.IMenuRepository {
+	return &menuRepository{s}
+}
+
+// GetMenusByShop はショップのメニューをコースと予約できる時間帯とともに返します。
+// activeOnly の場合は提供中のコースのみ含めます。
+func (mr *menuRepository) GetMenusByShop(ctx context.Context, shopId uint, activeOnly bool) ([]model.Menu, error) {
+	mr.s.rlock()
+	defer mr.s.runlock()
+	menus := mr.s.menus.list(func(m model.Menu) bool { return m.ShopID == shopId })
+	sort.SliceStable(menus, func(i, j int) bool { return menus[i].Position < menus[j].Position })
+	for i := range menus {
+		menuId := menus[i].ID
+		courses := mr.s.courses.list(func(c model.Course) bool {
+			return c.MenuID == menuId && (!activeOnly || c.Active)
+		})
+		sort.SliceStable(courses, func(i, j int) bool { return courses[i].Price < courses[j].Price })
+		for j := range courses {
+			courses[j].Windows = mr.windows(courses[j].ID)
+		}
+		menus[i].Courses = courses
+	}
+	return menus, nil
+}
+
+// windows はコースの予約できる時間帯を開始時刻の順に返します。
+func (mr *menuRepository) windows(courseId uint) []model.CourseWindow {
+	windows := mr.s.courseWindows.list(func(w model.CourseWindow) bool { return w.CourseID == courseId })
+	sort.SliceStable(windows, func(i, j int) bool { return windows[i].StartTime < windows[j].StartTime })
+	return windows
+}
+
+func (mr *menuRepository) GetMenuById(ctx context.Context, menu *model.Menu, shopId uint, menuId uint) error {
+	mr.s.rlock()
+	defer mr.s.runlock()
+	m, ok := mr.s.menus.get(menuId)
+	if !ok || m.ShopID != shopId {
+		return errNotFound()
+	}
+	*menu = m
+	return nil
+}
+
+func (mr *menuRepository) CreateMenu(ctx context.Context, menu *model.Menu) error {
+	mr.s.lock()
+	defer mr.s.unlock()
+	touch(&menu.CreatedAt, &menu.UpdatedAt)
+	row := *menu
+	row.Courses = nil
+	menu.ID = mr.s.menus.insert(&row, func(m *model.Menu, id uint) { m.ID = id })
+	return nil
+}
+
+func (mr *menuRepository) UpdateMenu(ctx context.Context, menu *model.Menu, shopId uint, menuId uint) error {
+	mr.s.lock()
+	defer mr.s.unlock()
+	m, ok := mr.s.menus.get(menuId)
+	if !ok || m.ShopID != shopId {
+		return errNotFound()
+	}
+	m.Name = menu.Name
+	m.Description = menu.Description
+	m.Position = menu.Position
+	m.UpdatedAt = time.Now()
+	mr.s.menus.put(menuId, m)
+	*menu = m
+	return nil
+}
+
+// DeleteMenu はメニューを、コースと予約できる時間帯とともに削除します。
+func (mr *menuRepository) DeleteMenu(ctx context.Context, shopId uint, menuId uint) error {
+	mr.s.lock()
+	defer mr.s.unlock()
+	m, ok := mr.s.menus.get(menuId)
+	if !ok || m.ShopID != shopId {
+		return errNotFound()
+	}
+	deleteMenus(mr.s, func(m model.Menu) bool { return m.ID == menuId })
+	return nil
+}
+
+func (mr *menuRepository) GetCourseById(ctx context.Context, course *model.Course, courseId uint) error {
+	mr.s.rlock()
+	defer mr.s.runlock()
+	c, ok := mr.s.courses.get(courseId)
+	if !ok {
+		return errNotFound()
+	}
+	c.Windows = mr.s.courseWindows.list(func(w model.CourseWindow) bool { return w.CourseID == courseId })
+	*course = c
+	return nil
+}
+
+// CreateCourse はコースを予約できる時間帯とともに作成します。
+// 提供中かどうかと最少人数は、GORM と同じくゼロ値の場合にカラムの既定値になります。
+func (mr *menuRepository) CreateCourse(ctx context.Context, course *model.Course) error {
+	mr.s.lock()
+	defer mr.s.unlock()
+	if !course.Active {
+		course.Active = true
+	}
+	if course.MinPartySize == 0 {
+		course.MinPartySize = 1
+	}
+	touch(&course.CreatedAt, &course.UpdatedAt)
+	row := *course
+	row.Windows = nil
+	course.ID = mr.s.courses.insert(&row, func(c *model.Course, id uint) { c.ID = id })
+	mr.insertWindows(course.ID, course.Windows)
+	return nil
+}
+
+// UpdateCourse はコースを更新し、予約できる時間帯を course.Windows に置き換えます。
+func (mr *menuRepository) UpdateCourse(ctx context.Context, course *model.Course, shopId uint, courseId uint) error {
+	mr.s.lock()
+	defer mr.s.unlock()
+	c, ok := mr.s.courses.get(courseId)
+	if !ok || c.ShopID != shopId {
+		return errNotFound()
+	}
+	c.Name = course.Name
+	c.Description = course.Description
+	c.Price = course.Price
+	c.DurationMinutes = course.DurationMinutes
+	c.MinPartySize = course.MinPartySize
+	c.MaxPartySize = course.MaxPartySize
+	c.StartDate = clonePtr(course.StartDate)
+	c.EndDate = clonePtr(course.EndDate)
+	c.Active = course.Active
+	c.UpdatedAt = time.Now()
+	mr.s.courses.put(courseId, c)
+	windows := course.Windows
+	for _, w := range mr.s.courseWindows.list(func(w model.CourseWindow) bool { return w.CourseID == courseId }) {
+		mr.s.courseWindows.delete(w.ID)
+	}
+	for i := range windows {
+		windows[i].ID = 0
+	}
+	mr.insertWindows(courseId, windows)
+	c.Windows = windows
+	*course = c
+	return nil
+}
+
+func (mr *menuRepository) insertWindows(courseId uint, windows []model.CourseWindow) {
+	for i := range windows {
+		windows[i].CourseID = courseId
+		mr.s.courseWindows.insert(&windows[i], func(w *model.CourseWindow, id uint) { w.ID = id })
+	}
+}
+
+func (mr *menuRepository) DeleteCourse(ctx context.Context, shopId uint, courseId uint) error {
+	mr.s.lock()
+	defer mr.s.unlock()
+	c, ok := mr.s.courses.get(courseId)
+	if !ok || c.ShopID != shopId {
+		return errNotFound()
+	}
+	deleteCourses(mr.s, func(c model.Course) bool { return c.ID == courseId })
+	return nil
+}
+
+// deleteMenus は一致するメニューを、コースと予約できる時間帯とともに削除します。Store のロックを取得した状態で呼びます。
+func deleteMenus(s *Store, match func(m model.Menu) bool) {
+	for _, m := range s.menus.list(match) {
+		s.menus.delete(m.ID)
+		menuId := m.ID
+		deleteCourses(s, func(c model.Course) bool { return c.MenuID == menuId })
+	}
+}
+
+// deleteCourses は一致するコースを予約できる時間帯とともに削除します。Store のロックを取得した状態で呼びます。
+func deleteCourses(s *Store, match func(c model.Course) bool) {
+	for _, c := range s.courses.list(match) {
+		s.courses.delete(c.ID)
+		courseId := c.ID
+		for _, w := range s.courseWindows.list(func(w model.CourseWindow) bool { return w.CourseID == courseId }) {
+			s.courseWindows.delete(w.ID)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package memory
+
+import (
+	"context"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"time"
+)
+
+type refreshTokenRepository struct {
+	s *Store
+}
+
+func NewRefreshTokenRepository(s *Store) repository.IRefreshTokenRepository {
+	return &refreshTokenRepository{s}
+}
+
+func (rtr *refreshTokenRepository) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	rtr.s.lock()
+	defer rtr.s.unlock()
+	return createRefreshToken(rtr.s, token)
+}
+
+func (rtr *refreshTokenRepository) GetRefreshTokenByHash(ctx context.Context, token *model.RefreshToken, tokenHash string) error {
+	rtr.s.rlock()
+	defer rtr.s.runlock()
+	tokens := rtr.s.refreshTokens.list(func(t model.RefreshToken) bool { return t.TokenHash == tokenHash })
+	if len(tokens) == 0 {
+		return errNotFound()
+	}
+	*token = tokens[0]
+	return nil
+}
+
+// RotateRefreshToken は現在のトークンを失効させ、次のトークンを作成します。
+// 失効済みの場合は ErrTokenAlreadyRevoked を返し、次のトークンは作成しません。
+func (rtr *refreshTokenRepository) RotateRefreshToken(ctx context.Context, current *model.RefreshToken, next *model.RefreshToken) error {
+	rtr.s.lock()
+	defer rtr.s.unlock()
+	t, ok := rtr.s.refreshTokens.get(current.ID)
+	if !ok || t.RevokedAt != nil {
+		return repository.ErrTokenAlreadyRevoked
+	}
+	if err := createRefreshToken(rtr.s, next); err != nil {
+		return err
+	}
+	now := time.Now()
+	t.RevokedAt = &now
+	rtr.s.refreshTokens.put(t.ID, t)
+	return nil
+}
+
+func (rtr *refreshTokenRepository) RevokeFamily(ctx context.Context, familyId string) error {
+	rtr.s.lock()
+	defer rtr.s.unlock()
+	revokeRefreshTokens(rtr.s, func(t model.RefreshToken) bool { return t.FamilyID == familyId })
+	return nil
+}
+
+func (rtr *refreshTokenRepository) RevokeAllForUser(ctx context.Context, userId uint) error {
+	rtr.s.lock()
+	defer rtr.s.unlock()
+	revokeRefreshTokens(rtr.s, func(t model.RefreshToken) bool { return t.UserID == userId })
+	return nil
+}
+
+// createRefreshToken はリフレッシュトークンを追加します。Store のロックを取得した状態で呼びます。
+func createRefreshToken(s *Store, token *model.RefreshToken) error {
+	// refresh_tokens.token_hash の一意制約
+	if len(s.refreshTokens.list(func(t model.RefreshToken) bool { return t.TokenHash == token.TokenHash })) > 0 {
+		return errConflict()
+	}
+	touch(&token.CreatedAt, nil)
+	row := *token
+	row.User = model.User{}
+	token.ID = s.refreshTokens.insert(&row, func(t *model.RefreshToken, id uint) { t.ID = id })
+	return nil
+}
+
+// revokeRefreshTokens は一致する未失効のリフレッシュトークンを失効させます。Store のロックを取得した状態で呼びます。
+func revokeRefreshTokens(s *Store, match func(t model.RefreshToken) bool) {
+	now := time.Now()
+	for _, t := range s.refreshTokens.list(func(t model.RefreshToken) bool { return t.RevokedAt == nil && match(t) }) {
+		t.RevokedAt = &now
+		s.refreshTokens.put(t.ID, t)
+	}
+}
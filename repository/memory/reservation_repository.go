@@ -0,0 +1,199 @@
+package memory
+
+import (
+	"context"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"sort"
+	"time"
+)
+
+// releasedStatuses は席を確保しないステータスです。残り席数の計算から除外します。
+var releasedStatuses = map[string]bool{
+	model.ReservationCancelledByUser: true,
+	model.ReservationCancelledByShop: true,
+	model.ReservationNoShow:          true,
+}
+
+type reservationRepository struct {
+	s *Store
+}
+
+func NewReservationRepository(s *Store) repository.IReservationRepository {
+	return &reservationRepository{s}
+}
+
+// MakeReservation は予約を作成し、最初のステータスを変更履歴に記録します。
+func (rr *reservationRepository) MakeReservation(ctx context.Context, reservation *model.Reservation, change *model.ReservationStatusChange) (model.Reservation, error) {
+	rr.s.lock()
+	defer rr.s.unlock()
+	if err := rr.checkCapacity(reservation); err != nil {
+		return *reservation, err
+	}
+	if reservation.Status == "" {
+		reservation.Status = model.ReservationPending
+	}
+	row := *reservation
+	row.Course, row.StatusChanges = nil, nil
+	reservation.ID = rr.s.reservations.insert(&row, func(r *model.Reservation, id uint) { r.ID = id })
+	rr.recordStatusChange(change, reservation.ID, "", reservation.Status)
+	return *reservation, nil
+}
+
+// ChangeStatus は予約のステータスを変更し、変更履歴を記録します。
+// 読み込み時のステータスから変わっていた場合は ErrStatusConflict を返します。
+func (rr *reservationRepository) ChangeStatus(ctx context.Context, reservation *model.Reservation, status string, change *model.ReservationStatusChange) error {
+	rr.s.lock()
+	defer rr.s.unlock()
+	r, ok := rr.s.reservations.get(reservation.ID)
+	if !ok || r.Status != reservation.Status {
+		return repository.ErrStatusConflict
+	}
+	r.Status = status
+	rr.s.reservations.put(r.ID, r)
+	rr.recordStatusChange(change, reservation.ID, reservation.Status, status)
+	reservation.Status = status
+	return nil
+}
+
+// recordStatusChange はステータスの変更履歴を追加します。Store のロックを取得した状態で呼びます。
+func (rr *reservationRepository) recordStatusChange(change *model.ReservationStatusChange, reservationId uint, from, to string) {
+	change.ReservationID = reservationId
+	change.FromStatus = from
+	change.ToStatus = to
+	touch(&change.CreatedAt, nil)
+	change.ID = rr.s.statusChanges.insert(change, func(c *model.ReservationStatusChange, id uint) { c.ID = id })
+}
+
+// GetStatusChanges は予約のステータスの変更履歴を古い順に返します。
+func (rr *reservationRepository) GetStatusChanges(ctx context.Context, reservationId uint) ([]model.ReservationStatusChange, error) {
+	rr.s.rlock()
+	defer rr.s.runlock()
+	return rr.s.statusChanges.list(func(c model.ReservationStatusChange) bool { return c.ReservationID == reservationId }), nil
+}
+
+func (rr *reservationRepository) GetReservation(ctx context.Context, reservation *model.Reservation, reservationId uint) error {
+	rr.s.rlock()
+	defer rr.s.runlock()
+	r, ok := rr.s.reservations.get(reservationId)
+	if !ok {
+		return errNotFound()
+	}
+	*reservation = r
+	return nil
+}
+
+func (rr *reservationRepository) GetReservationById(ctx context.Context, reservation *model.Reservation, userId uint, reservationId uint) error {
+	rr.s.rlock()
+	defer rr.s.runlock()
+	r, ok := rr.s.reservations.get(reservationId)
+	if !ok || r.UserID != userId {
+		return errNotFound()
+	}
+	*reservation = r
+	return nil
+}
+
+func (rr *reservationRepository) GetReservationByUser(ctx context.Context, userId uint) ([]model.Reservation, error) {
+	rr.s.rlock()
+	defer rr.s.runlock()
+	return rr.byDate(func(r model.Reservation) bool { return r.UserID == userId }), nil
+}
+
+func (rr *reservationRepository) GetReservationsByShop(ctx context.Context, shopId uint) ([]model.Reservation, error) {
+	rr.s.rlock()
+	defer rr.s.runlock()
+	return rr.byDate(func(r model.Reservation) bool { return r.ShopID == shopId }), nil
+}
+
+// byDate は条件に一致する予約を日時の順に返します。
+func (rr *reservationRepository) byDate(match func(r model.Reservation) bool) []model.Reservation {
+	reservations := rr.s.reservations.list(match)
+	sort.SliceStable(reservations, func(i, j int) bool {
+		a, b := reservations[i], reservations[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		return a.Time < b.Time
+	})
+	return reservations
+}
+
+// reservationListSpec は予約一覧で使える並び替え・絞り込みの項目です。
+var reservationListSpec = listSpec[model.Reservation]{
+	sorts: map[string]func(r model.Reservation) interface{}{
+		"id":   func(r model.Reservation) interface{} { return r.ID },
+		"date": func(r model.Reservation) interface{} { return r.Date },
+	},
+	defaultSort: "date",
+	filters: map[string]func(r model.Reservation) interface{}{
+		"status":  func(r model.Reservation) interface{} { return r.Status },
+		"shop_id": func(r model.Reservation) interface{} { return r.ShopID },
+		"user_id": func(r model.Reservation) interface{} { return r.UserID },
+	},
+	date: func(r model.Reservation) time.Time { return r.Date },
+	id:   func(r model.Reservation) uint { return r.ID },
+}
+
+func (rr *reservationRepository) GetAllReservations(ctx context.Context, q model.ListQuery) (model.Page[model.Reservation], error) {
+	rr.s.rlock()
+	defer rr.s.runlock()
+	return paginate(rr.s.reservations.list(nil), reservationListSpec, q)
+}
+
+func (rr *reservationRepository) UpdateReservation(ctx context.Context, reservation *model.Reservation, userId uint, reservationId uint) (model.Reservation, error) {
+	rr.s.lock()
+	defer rr.s.unlock()
+	if err := rr.checkCapacity(reservation); err != nil {
+		return *reservation, err
+	}
+	r, ok := rr.s.reservations.get(reservationId)
+	if !ok || r.UserID != userId {
+		return *reservation, errNotFound()
+	}
+	r.Date = reservation.Date
+	r.Time = reservation.Time
+	r.Num = reservation.Num
+	r.CourseID = reservation.CourseID
+	r.TotalPrice = reservation.TotalPrice
+	rr.s.reservations.put(r.ID, r)
+	return *reservation, nil
+}
+
+func (rr *reservationRepository) GetReservationsForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.Reservation], error) {
+	rr.s.rlock()
+	defer rr.s.runlock()
+	return paginate(rr.s.reservations.list(nil), reservationListSpec, q)
+}
+
+// GetBookedSeats は指定した日のショップの予約済み席数を時刻ごとに返します。
+func (rr *reservationRepository) GetBookedSeats(ctx context.Context, shopId uint, date time.Time) (map[string]int, error) {
+	rr.s.rlock()
+	defer rr.s.runlock()
+	booked := map[string]int{}
+	for _, r := range rr.s.reservations.list(func(r model.Reservation) bool {
+		return r.ShopID == shopId && r.Date.Equal(date) && !releasedStatuses[r.Status]
+	}) {
+		booked[r.Time] += r.Num
+	}
+	return booked, nil
+}
+
+// checkCapacity は予約枠の残り席数が足りるかを確認します。Store のロックを取得した状態で呼びます。
+func (rr *reservationRepository) checkCapacity(reservation *model.Reservation) error {
+	shop, ok := rr.s.shops.get(reservation.ShopID)
+	if !ok {
+		return errNotFound()
+	}
+	booked := 0
+	for _, r := range rr.s.reservations.list(func(r model.Reservation) bool {
+		return r.ShopID == reservation.ShopID && r.Date.Equal(reservation.Date) && r.Time == reservation.Time &&
+			!releasedStatuses[r.Status] && r.ID != reservation.ID
+	}) {
+		booked += r.Num
+	}
+	if booked+reservation.Num > shop.Capacity {
+		return repository.ErrCapacityExceeded
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package memory
+
+import (
+	"context"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"time"
+)
+
+type reviewRepository struct {
+	s *Store
+}
+
+func NewReviewRepository(s *Store) repository.IReviewRepository {
+	return &reviewRepository{s}
+}
+
+// reviewListSpec はレビュー一覧で使える並び替え・絞り込みの項目です。
+var reviewListSpec = listSpec[model.Review]{
+	sorts: map[string]func(r model.Review) interface{}{
+		"id":           func(r model.Review) interface{} { return r.ID },
+		"rating":       func(r model.Review) interface{} { return r.Rating },
+		"created_at":   func(r model.Review) interface{} { return r.CreatedAt },
+		"report_count": func(r model.Review) interface{} { return r.ReportCount },
+	},
+	defaultSort:  "created_at",
+	defaultOrder: model.SortDesc,
+	filters: map[string]func(r model.Review) interface{}{
+		"rating":   func(r model.Review) interface{} { return r.Rating },
+		"verified": func(r model.Review) interface{} { return r.Verified },
+	},
+	date: func(r model.Review) time.Time { return r.CreatedAt },
+	id:   func(r model.Review) uint { return r.ID },
+}
+
+// GetShopReviews はショップの公開中のレビューを返します。
+func (rr *reviewRepository) GetShopReviews(ctx context.Context, shopId uint, q model.ListQuery) (model.Page[model.Review], error) {
+	return rr.paginate(func(r model.Review) bool { return r.ShopID == shopId && !r.Hidden }, q)
+}
+
+func (rr *reviewRepository) GetUserReviews(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.Review], error) {
+	return rr.paginate(func(r model.Review) bool { return r.UserID == userId }, q)
+}
+
+// GetReportedReviews は通報されたレビューを非公開のものも含めて返します。
+func (rr *reviewRepository) GetReportedReviews(ctx context.Context, q model.ListQuery) (model.Page[model.Review], error) {
+	return rr.paginate(func(r model.Review) bool { return r.ReportCount > 0 }, q)
+}
+
+func (rr *reviewRepository) paginate(match func(r model.Review) bool, q model.ListQuery) (model.Page[model.Review], error) {
+	rr.s.rlock()
+	defer rr.s.runlock()
+	return paginate(rr.s.reviews.list(match), reviewListSpec, q)
+}
+
+func (rr *reviewRepository) GetReviewById(ctx context.Context, review *model.Review, reviewId uint) error {
+	rr.s.rlock()
+	defer rr.s.runlock()
+	r, ok := rr.s.reviews.get(reviewId)
+	if !ok {
+		return errNotFound()
+	}
+	*review = r
+	return nil
+}
+
+func (rr *reviewRepository) CreateReview(ctx context.Context, review *model.Review) error {
+	rr.s.lock()
+	defer rr.s.unlock()
+	// reviews.reservation_id の一意制約
+	if review.ReservationID != nil && len(rr.s.reviews.list(func(r model.Review) bool {
+		return r.ReservationID != nil && *r.ReservationID == *review.ReservationID
+	})) > 0 {
+		return errConflict()
+	}
+	touch(&review.CreatedAt, &review.UpdatedAt)
+	row := *review
+	row.Shop, row.User = model.Shop{}, model.User{}
+	review.ID = rr.s.reviews.insert(&row, func(r *model.Review, id uint) { r.ID = id })
+	refreshShopRating(rr.s, review.ShopID)
+	return nil
+}
+
+func (rr *reviewRepository) UpdateReview(ctx context.Context, review *model.Review, userId uint, reviewId uint) error {
+	return rr.update(review, reviewId, func(r model.Review) bool { return r.UserID == userId }, func(r *model.Review) {
+		r.Rating = review.Rating
+		r.Comment = review.Comment
+	})
+}
+
+func (rr *reviewRepository) DeleteReview(ctx context.Context, userId uint, reviewId uint) error {
+	rr.s.lock()
+	defer rr.s.unlock()
+	r, ok := rr.s.reviews.get(reviewId)
+	if !ok || r.UserID != userId {
+		return errNotFound()
+	}
+	rr.s.reviews.delete(reviewId)
+	deleteReviewReports(rr.s, func(rp model.ReviewReport) bool { return rp.ReviewID == reviewId })
+	refreshShopRating(rr.s, r.ShopID)
+	return nil
+}
+
+// ReplyReview はショップのレビューにオーナーの返信を記録します。空の返信は返信の削除です。
+func (rr *reviewRepository) ReplyReview(ctx context.Context, review *model.Review, shopId uint, reviewId uint) error {
+	return rr.update(review, reviewId, func(r model.Review) bool { return r.ShopID == shopId }, func(r *model.Review) {
+		r.Reply = review.Reply
+		r.RepliedAt = review.RepliedAt
+	})
+}
+
+// ReportReview は通報を記録し、レビューの通報回数を増やします。
+func (rr *reviewRepository) ReportReview(ctx context.Context, report *model.ReviewReport) error {
+	rr.s.lock()
+	defer rr.s.unlock()
+	r, ok := rr.s.reviews.get(report.ReviewID)
+	if !ok {
+		return errNotFound()
+	}
+	// review_reports(review_id, user_id) の一意制約
+	if len(rr.s.reviewReports.list(func(rp model.ReviewReport) bool {
+		return rp.ReviewID == report.ReviewID && rp.UserID == report.UserID
+	})) > 0 {
+		return repository.ErrAlreadyReported
+	}
+	touch(&report.CreatedAt, nil)
+	row := *report
+	row.Review = model.Review{}
+	report.ID = rr.s.reviewReports.insert(&row, func(rp *model.ReviewReport, id uint) { rp.ID = id })
+	r.ReportCount++
+	rr.s.reviews.put(r.ID, r)
+	return nil
+}
+
+// SetReviewHidden はレビューの公開・非公開を切り替え、ショップの評価を集計し直します。
+func (rr *reviewRepository) SetReviewHidden(ctx context.Context, review *model.Review, reviewId uint, hidden bool) error {
+	return rr.update(review, reviewId, nil, func(r *model.Review) { r.Hidden = hidden })
+}
+
+// update は条件に一致するレビューを変更し、変更後のレビューを review に読み込んでショップの評価を集計し直します。
+func (rr *reviewRepository) update(review *model.Review, reviewId uint, match func(r model.Review) bool, fc func(r *model.Review)) error {
+	rr.s.lock()
+	defer rr.s.unlock()
+	r, ok := rr.s.reviews.get(reviewId)
+	if !ok || (match != nil && !match(r)) {
+		return errNotFound()
+	}
+	fc(&r)
+	r.UpdatedAt = time.Now()
+	rr.s.reviews.put(reviewId, r)
+	refreshShopRating(rr.s, r.ShopID)
+	*review = r
+	return nil
+}
+
+// refreshShopRating はショップの公開中のレビューから平均評価と件数を集計し直します。Store のロックを取得した状態で呼びます。
+func refreshShopRating(s *Store, shopId uint) {
+	shop, ok := s.shops.get(shopId)
+	if !ok {
+		return
+	}
+	total := 0
+	reviews := s.reviews.list(func(r model.Review) bool { return r.ShopID == shopId && !r.Hidden })
+	for _, r := range reviews {
+		total += r.Rating
+	}
+	shop.RatingCount = len(reviews)
+	shop.RatingAverage = 0
+	if len(reviews) > 0 {
+		shop.RatingAverage = float64(total) / float64(len(reviews))
+	}
+	s.shops.put(shopId, shop)
+}
+
+// deleteReviews は一致するレビューを通報とともに削除します。Store のロックを取得した状態で呼びます。
+func deleteReviews(s *Store, match func(r model.Review) bool) {
+	for _, r := range s.reviews.list(match) {
+		s.reviews.delete(r.ID)
+		reviewId := r.ID
+		deleteReviewReports(s, func(rp model.ReviewReport) bool { return rp.ReviewID == reviewId })
+	}
+}
+
+func deleteReviewReports(s *Store, match func(rp model.ReviewReport) bool) {
+	for _, rp := range s.reviewReports.list(match) {
+		s.reviewReports.delete(rp.ID)
+	}
+}
@@ -0,0 +1,482 @@
+package memory
+
+import (
+	"context"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+type shopRepository struct {
+	s *Store
+}
+
+func NewShopRepository(s *Store) repository.IShopRepository {
+	return &shopRepository{s}
+}
+
+// shopListSpec はショップ一覧で使える並び替え・絞り込みの項目です。
+var shopListSpec = listSpec[model.Shop]{
+	sorts: map[string]func(s model.Shop) interface{}{
+		"id":         func(s model.Shop) interface{} { return s.ID },
+		"name":       func(s model.Shop) interface{} { return s.Name },
+		"area":       func(s model.Shop) interface{} { return s.Area },
+		"genre":      func(s model.Shop) interface{} { return s.Genre },
+		"created_at": func(s model.Shop) interface{} { return s.CreatedAt },
+		"rating":     func(s model.Shop) interface{} { return s.RatingAverage },
+	},
+	defaultSort: "created_at",
+	filters: map[string]func(s model.Shop) interface{}{
+		"area":  func(s model.Shop) interface{} { return s.Area },
+		"genre": func(s model.Shop) interface{} { return s.Genre },
+	},
+	date: func(s model.Shop) time.Time { return s.CreatedAt },
+	id:   func(s model.Shop) uint { return s.ID },
+}
+
+func (sr *shopRepository) GetAllShops(ctx context.Context, q model.ListQuery) (model.Page[model.Shop], error) {
+	sr.s.rlock()
+	defer sr.s.runlock()
+	page, err := paginate(sr.s.shops.list(nil), shopListSpec, q)
+	if err != nil {
+		return page, err
+	}
+	sr.loadAssociations(page.Items)
+	return page, nil
+}
+
+// SearchShops は検索語・エリア・ジャンル・営業時間で絞り込んだショップと、エリア・ジャンルごとの件数を返します。
+// 検索語は名前・説明・住所に対する大文字・小文字を区別しない部分一致です。
+// 検索語を指定した場合は名前に一致するショップを先に、指定しない場合は登録順に並べます。
+func (sr *shopRepository) SearchShops(ctx context.Context, q model.ShopSearchQuery) (model.Page[model.Shop], model.ShopFacets, error) {
+	sr.s.rlock()
+	defer sr.s.runlock()
+	page := model.Page[model.Shop]{Items: []model.Shop{}, Limit: q.Limit, Offset: q.Offset}
+	facets := model.ShopFacets{Area: []model.FacetCount{}, Genre: []model.FacetCount{}}
+	if page.Limit <= 0 {
+		page.Limit = model.DefaultListLimit
+	}
+
+	shops := sr.search(q, "")
+	page.Total = int64(len(shops))
+	text := strings.ToLower(q.Text)
+	sort.SliceStable(shops, func(i, j int) bool {
+		if text != "" {
+			a, b := strings.Contains(strings.ToLower(shops[i].Name), text), strings.Contains(strings.ToLower(shops[j].Name), text)
+			if a != b {
+				return a
+			}
+			return shops[i].ID < shops[j].ID
+		}
+		if !shops[i].CreatedAt.Equal(shops[j].CreatedAt) {
+			return shops[i].CreatedAt.Before(shops[j].CreatedAt)
+		}
+		return shops[i].ID < shops[j].ID
+	})
+	shops = shops[min(max(page.Offset, 0), len(shops)):]
+	shops = shops[:min(page.Limit, len(shops))]
+	page.Items = append(page.Items, shops...)
+	sr.loadAssociations(page.Items)
+
+	facets.Area = facetCounts(sr.search(q, "area"), func(s model.Shop) string { return s.Area })
+	facets.Genre = facetCounts(sr.search(q, "genre"), func(s model.Shop) string { return s.Genre })
+	return page, facets, nil
+}
+
+// search は検索条件に一致するショップを返します。except に指定した項目の絞り込みは適用しません。
+func (sr *shopRepository) search(q model.ShopSearchQuery, except string) []model.Shop {
+	text := strings.ToLower(q.Text)
+	return sr.s.shops.list(func(s model.Shop) bool {
+		if text != "" && !strings.Contains(strings.ToLower(s.Name), text) &&
+			!strings.Contains(strings.ToLower(s.Description), text) &&
+			!strings.Contains(strings.ToLower(s.Address), text) {
+			return false
+		}
+		if q.Area != "" && except != "area" && s.Area != q.Area {
+			return false
+		}
+		if q.Genre != "" && except != "genre" && s.Genre != q.Genre {
+			return false
+		}
+		if q.OpenAt != nil {
+			s.Hours = sr.s.shopHours.list(func(h model.ShopHour) bool { return h.ShopID == s.ID })
+			s.Closures = sr.s.shopClosures.list(func(c model.ShopClosure) bool { return c.ShopID == s.ID })
+			if !s.IsOpenAt(*q.OpenAt) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// facetCounts は値ごとの件数を、件数の多い順・値の順に返します。
+func facetCounts(shops []model.Shop, value func(s model.Shop) string) []model.FacetCount {
+	counts := map[string]int64{}
+	for _, s := range shops {
+		counts[value(s)]++
+	}
+	facets := make([]model.FacetCount, 0, len(counts))
+	for v, n := range counts {
+		facets = append(facets, model.FacetCount{Value: v, Count: n})
+	}
+	sort.Slice(facets, func(i, j int) bool {
+		if facets[i].Count != facets[j].Count {
+			return facets[i].Count > facets[j].Count
+		}
+		return facets[i].Value < facets[j].Value
+	})
+	return facets
+}
+
+// earthRadius は地球の平均半径（メートル）です。
+const earthRadius = 6371000.0
+
+// GetNearbyShops は指定地点から radius メートル以内のショップを近い順に返します。
+// 距離は GORM の実装と同じく球面上の距離（ハーバーサインの公式）です。
+func (sr *shopRepository) GetNearbyShops(ctx context.Context, lat, lng, radius float64, limit, offset int) (model.Page[model.NearbyShop], error) {
+	sr.s.rlock()
+	defer sr.s.runlock()
+	page := model.Page[model.NearbyShop]{Items: []model.NearbyShop{}, Limit: limit, Offset: offset}
+	if page.Limit <= 0 {
+		page.Limit = model.DefaultListLimit
+	}
+
+	nearby := []model.NearbyShop{}
+	for _, s := range sr.s.shops.list(func(s model.Shop) bool { return s.Latitude != nil && s.Longitude != nil }) {
+		if d := distance(lat, lng, *s.Latitude, *s.Longitude); d <= radius {
+			nearby = append(nearby, model.NearbyShop{Shop: s, Distance: d})
+		}
+	}
+	page.Total = int64(len(nearby))
+	sort.SliceStable(nearby, func(i, j int) bool {
+		if nearby[i].Distance != nearby[j].Distance {
+			return nearby[i].Distance < nearby[j].Distance
+		}
+		return nearby[i].Shop.ID < nearby[j].Shop.ID
+	})
+	nearby = nearby[min(max(offset, 0), len(nearby)):]
+	nearby = nearby[:min(page.Limit, len(nearby))]
+	for _, n := range nearby {
+		shops := []model.Shop{n.Shop}
+		sr.loadAssociations(shops)
+		page.Items = append(page.Items, model.NearbyShop{Shop: shops[0], Distance: n.Distance})
+	}
+	return page, nil
+}
+
+func distance(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := func(deg float64) float64 { return deg * math.Pi / 180 }
+	h := math.Pow(math.Sin(rad(lat2-lat1)/2), 2) + math.Cos(rad(lat1))*math.Cos(rad(lat2))*math.Pow(math.Sin(rad(lng2-lng1)/2), 2)
+	return earthRadius * 2 * math.Asin(math.Sqrt(math.Min(1, h)))
+}
+
+// loadAssociations はショップの曜日ごとの営業時間、今日以降の休業、ギャラリーの画像を設定します。
+// Store のロックを取得した状態で呼びます。
+func (sr *shopRepository) loadAssociations(shops []model.Shop) {
+	// 前日から日付をまたぐ営業時間帯の判定のため、前日の休業から読み込む。
+	// ショップのタイムゾーンでの前日は UTC の2日前になることがあるため、2日前から読み込む
+	t := time.Now()
+	from := time.Date(t.Year(), t.Month(), t.Day()-2, 0, 0, 0, 0, time.UTC)
+	for i := range shops {
+		id := shops[i].ID
+		shops[i].Hours = sr.s.shopHours.list(func(h model.ShopHour) bool { return h.ShopID == id })
+		sort.SliceStable(shops[i].Hours, func(a, b int) bool {
+			ha, hb := shops[i].Hours[a], shops[i].Hours[b]
+			if ha.Weekday != hb.Weekday {
+				return ha.Weekday < hb.Weekday
+			}
+			return ha.OpenTime < hb.OpenTime
+		})
+		shops[i].Closures = sr.s.shopClosures.list(func(c model.ShopClosure) bool {
+			return c.ShopID == id && !c.Date.Before(from)
+		})
+		sort.SliceStable(shops[i].Closures, func(a, b int) bool {
+			ca, cb := shops[i].Closures[a], shops[i].Closures[b]
+			if !ca.Date.Equal(cb.Date) {
+				return ca.Date.Before(cb.Date)
+			}
+			return ca.StartTime < cb.StartTime
+		})
+		shops[i].Images = sr.shopImages(id)
+	}
+}
+
+// shopImages はショップの画像を表示順に返します。
+func (sr *shopRepository) shopImages(shopId uint) []model.ShopImage {
+	images := sr.s.shopImages.list(func(img model.ShopImage) bool { return img.ShopID == shopId })
+	sort.SliceStable(images, func(i, j int) bool { return images[i].Position < images[j].Position })
+	return images
+}
+
+// ReplaceShopHours はショップの曜日ごとの営業時間をまとめて置き換えます。
+func (sr *shopRepository) ReplaceShopHours(ctx context.Context, shopId uint, hours []model.ShopHour) error {
+	sr.s.lock()
+	defer sr.s.unlock()
+	for _, h := range sr.s.shopHours.list(func(h model.ShopHour) bool { return h.ShopID == shopId }) {
+		sr.s.shopHours.delete(h.ID)
+	}
+	for i := range hours {
+		hours[i].ShopID = shopId
+		sr.s.shopHours.insert(&hours[i], func(h *model.ShopHour, id uint) { h.ID = id })
+	}
+	return nil
+}
+
+func (sr *shopRepository) CreateShopClosure(ctx context.Context, closure *model.ShopClosure) error {
+	sr.s.lock()
+	defer sr.s.unlock()
+	touch(&closure.CreatedAt, nil)
+	sr.s.shopClosures.insert(closure, func(c *model.ShopClosure, id uint) { c.ID = id })
+	return nil
+}
+
+func (sr *shopRepository) DeleteShopClosure(ctx context.Context, shopId uint, closureId uint) error {
+	sr.s.lock()
+	defer sr.s.unlock()
+	c, ok := sr.s.shopClosures.get(closureId)
+	if !ok || c.ShopID != shopId {
+		return errNotFound()
+	}
+	sr.s.shopClosures.delete(closureId)
+	return nil
+}
+
+// CreateShopImage は画像をギャラリーの最後に追加します。
+func (sr *shopRepository) CreateShopImage(ctx context.Context, image *model.ShopImage) error {
+	sr.s.lock()
+	defer sr.s.unlock()
+	image.Position = 0
+	for _, img := range sr.shopImages(image.ShopID) {
+		image.Position = max(image.Position, img.Position+1)
+	}
+	touch(&image.CreatedAt, nil)
+	sr.s.shopImages.insert(image, func(img *model.ShopImage, id uint) { img.ID = id })
+	return nil
+}
+
+// ReorderShopImages はギャラリーの画像を imageIds の順に並べ替えます。imageIds にはショップの全ての画像を指定します。
+func (sr *shopRepository) ReorderShopImages(ctx context.Context, shopId uint, imageIds []uint) error {
+	sr.s.lock()
+	defer sr.s.unlock()
+	// 1つでも見つからない場合は何も変更しない
+	for _, id := range imageIds {
+		if img, ok := sr.s.shopImages.get(id); !ok || img.ShopID != shopId {
+			return errNotFound()
+		}
+	}
+	for i, id := range imageIds {
+		img, _ := sr.s.shopImages.get(id)
+		img.Position = i
+		sr.s.shopImages.put(id, img)
+	}
+	return nil
+}
+
+func (sr *shopRepository) DeleteShopImage(ctx context.Context, image *model.ShopImage, shopId uint, imageId uint) error {
+	sr.s.lock()
+	defer sr.s.unlock()
+	img, ok := sr.s.shopImages.get(imageId)
+	if !ok || img.ShopID != shopId {
+		return errNotFound()
+	}
+	sr.s.shopImages.delete(imageId)
+	*image = img
+	return nil
+}
+
+func (sr *shopRepository) GetShopById(ctx context.Context, shop *model.Shop, shopId uint) error {
+	sr.s.rlock()
+	defer sr.s.runlock()
+	s, ok := sr.s.shops.get(shopId)
+	if !ok {
+		return errNotFound()
+	}
+	shops := []model.Shop{s}
+	sr.loadAssociations(shops)
+	*shop = shops[0]
+	return nil
+}
+
+func (sr *shopRepository) CreateShop(ctx context.Context, shop *model.Shop) error {
+	sr.s.lock()
+	defer sr.s.unlock()
+	return sr.create(shop)
+}
+
+// create はカラムの既定値を設定してショップを追加します。Store のロックを取得した状態で呼びます。
+func (sr *shopRepository) create(shop *model.Shop) error {
+	if sr.externalIDTaken(shop.ExternalID, 0) {
+		return errConflict()
+	}
+	if shop.Capacity == 0 {
+		shop.Capacity = 20
+	}
+	if shop.OpenTime == "" {
+		shop.OpenTime = "11:00"
+	}
+	if shop.CloseTime == "" {
+		shop.CloseTime = "22:00"
+	}
+	if shop.SlotMinutes == 0 {
+		shop.SlotMinutes = 60
+	}
+	touch(&shop.CreatedAt, &shop.UpdatedAt)
+	row := shopRow(*shop)
+	shop.ID = sr.s.shops.insert(&row, func(s *model.Shop, id uint) { s.ID = id })
+	return nil
+}
+
+func (sr *shopRepository) UpdateShop(ctx context.Context, shop *model.Shop, shopId uint) error {
+	sr.s.lock()
+	defer sr.s.unlock()
+	s, ok := sr.s.shops.get(shopId)
+	if !ok {
+		return errNotFound()
+	}
+	s.Name = shop.Name
+	s.Address = shop.Address
+	s.PostalCode = shop.PostalCode
+	s.Latitude = shop.Latitude
+	s.Longitude = shop.Longitude
+	s.Area = shop.Area
+	s.Genre = shop.Genre
+	s.Description = shop.Description
+	s.Capacity = shop.Capacity
+	s.OpenTime = shop.OpenTime
+	s.CloseTime = shop.CloseTime
+	s.SlotMinutes = shop.SlotMinutes
+	s.OwnerID = shop.OwnerID
+	s.UpdatedAt = time.Now()
+	sr.s.shops.put(shopId, shopRow(s))
+	shops := []model.Shop{s}
+	sr.loadAssociations(shops)
+	*shop = shops[0]
+	return nil
+}
+
+// DeleteShop はショップと、営業時間・休業・画像・メニューを削除します。
+func (sr *shopRepository) DeleteShop(ctx context.Context, shopId uint) error {
+	sr.s.lock()
+	defer sr.s.unlock()
+	if _, ok := sr.s.shops.get(shopId); !ok {
+		return errNotFound()
+	}
+	sr.s.shops.delete(shopId)
+	for _, h := range sr.s.shopHours.list(func(h model.ShopHour) bool { return h.ShopID == shopId }) {
+		sr.s.shopHours.delete(h.ID)
+	}
+	for _, c := range sr.s.shopClosures.list(func(c model.ShopClosure) bool { return c.ShopID == shopId }) {
+		sr.s.shopClosures.delete(c.ID)
+	}
+	for _, img := range sr.s.shopImages.list(func(img model.ShopImage) bool { return img.ShopID == shopId }) {
+		sr.s.shopImages.delete(img.ID)
+	}
+	deleteMenus(sr.s, func(m model.Menu) bool { return m.ShopID == shopId })
+	deleteReviews(sr.s, func(r model.Review) bool { return r.ShopID == shopId })
+	return nil
+}
+
+// GetShopsByExternalIDs は外部IDが一致するショップを返します。
+func (sr *shopRepository) GetShopsByExternalIDs(ctx context.Context, externalIds []string) ([]model.Shop, error) {
+	sr.s.rlock()
+	defer sr.s.runlock()
+	wanted := map[string]bool{}
+	for _, id := range externalIds {
+		wanted[id] = true
+	}
+	return sr.s.shops.list(func(s model.Shop) bool { return s.ExternalID != nil && wanted[*s.ExternalID] }), nil
+}
+
+// GetShopsForExport は書き出し用に全てのショップを ID 順に返します。
+func (sr *shopRepository) GetShopsForExport(ctx context.Context) ([]model.Shop, error) {
+	sr.s.rlock()
+	defer sr.s.runlock()
+	return sr.s.shops.list(nil), nil
+}
+
+// SaveImportedShops は取り込んだショップをまとめて保存します。途中で失敗した場合は何も保存しません。
+// ID が設定されたショップは取り込みの対象項目のみを更新し、オーナーや評価の集計は変更しません。
+func (sr *shopRepository) SaveImportedShops(ctx context.Context, shops []*model.Shop) error {
+	sr.s.lock()
+	defer sr.s.unlock()
+	saved := sr.s.shops.clone()
+	ids := make([]uint, len(shops))
+	for i, shop := range shops {
+		ids[i] = shop.ID
+	}
+	err := sr.saveImportedShops(shops)
+	if err != nil {
+		sr.s.shops = saved
+		for i, shop := range shops {
+			shop.ID = ids[i]
+		}
+	}
+	return err
+}
+
+func (sr *shopRepository) saveImportedShops(shops []*model.Shop) error {
+	for _, shop := range shops {
+		if shop.ID == 0 {
+			if err := sr.create(shop); err != nil {
+				return err
+			}
+			continue
+		}
+		s, ok := sr.s.shops.get(shop.ID)
+		if !ok {
+			return errNotFound()
+		}
+		if sr.externalIDTaken(shop.ExternalID, shop.ID) {
+			return errConflict()
+		}
+		s.ExternalID = shop.ExternalID
+		s.Name = shop.Name
+		s.Address = shop.Address
+		s.PostalCode = shop.PostalCode
+		s.Latitude = shop.Latitude
+		s.Longitude = shop.Longitude
+		s.Area = shop.Area
+		s.Genre = shop.Genre
+		s.Description = shop.Description
+		s.Capacity = shop.Capacity
+		s.OpenTime = shop.OpenTime
+		s.CloseTime = shop.CloseTime
+		s.SlotMinutes = shop.SlotMinutes
+		s.UpdatedAt = time.Now()
+		shop.UpdatedAt = s.UpdatedAt
+		sr.s.shops.put(s.ID, shopRow(s))
+	}
+	return nil
+}
+
+// externalIDTaken は shops.external_id の一意制約に反するかを返します。exceptId のショップは除きます。
+func (sr *shopRepository) externalIDTaken(externalId *string, exceptId uint) bool {
+	if externalId == nil {
+		return false
+	}
+	return len(sr.s.shops.list(func(s model.Shop) bool {
+		return s.ID != exceptId && s.ExternalID != nil && *s.ExternalID == *externalId
+	})) > 0
+}
+
+// shopRow は保存用のショップです。関連は別のテーブルに保存し、ポインターの項目は呼び出し側と共有しないよう複製します。
+func shopRow(s model.Shop) model.Shop {
+	s.Owner = nil
+	s.Hours, s.Closures, s.Images, s.Favorites, s.Reservations = nil, nil, nil, nil, nil
+	s.ExternalID = clonePtr(s.ExternalID)
+	s.Latitude = clonePtr(s.Latitude)
+	s.Longitude = clonePtr(s.Longitude)
+	s.OwnerID = clonePtr(s.OwnerID)
+	return s
+}
+
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
@@ -0,0 +1,171 @@
+// Package memory はリポジトリのインターフェースをメモリ上のデータで実装します。
+// データベースなしでユースケースやコントローラーを動かすためのもので、GORM の実装と同じエラーを返します。
+// 外部キー制約は確認しません。メモリ上の操作は待つことがないため、メソッドの ctx は使いません。
+package memory
+
+import (
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store はリポジトリが共有するメモリ上のデータです。
+// 同じ Store から作ったリポジトリは、同じデータベースを使う GORM のリポジトリと同じように互いの変更が見えます。
+type Store struct {
+	mu sync.RWMutex
+	// inTx はトランザクションの中で使う Store であることを示します。
+	// ロックはトランザクションを開始した Store が持っているため、リポジトリの操作ではロックしません。
+	inTx bool
+	tables
+}
+
+// tables は Store の全てのテーブルです。トランザクションではまとめて複製し、成功した場合に元の Store に戻します。
+type tables struct {
+	users         table[model.User]
+	identities    table[model.Identity]
+	refreshTokens table[model.RefreshToken]
+	userTokens    table[model.UserToken]
+	tasks         table[model.Task]
+	blogs         table[model.Blog]
+	shops         table[model.Shop]
+	shopHours     table[model.ShopHour]
+	shopClosures  table[model.ShopClosure]
+	shopImages    table[model.ShopImage]
+	menus         table[model.Menu]
+	courses       table[model.Course]
+	courseWindows table[model.CourseWindow]
+	favorites     table[model.Favorite]
+	reservations  table[model.Reservation]
+	statusChanges table[model.ReservationStatusChange]
+	reviews       table[model.Review]
+	reviewReports table[model.ReviewReport]
+}
+
+// NewStore は空の Store を返します。
+func NewStore() *Store {
+	return &Store{}
+}
+
+func (s *Store) lock() {
+	if !s.inTx {
+		s.mu.Lock()
+	}
+}
+
+func (s *Store) unlock() {
+	if !s.inTx {
+		s.mu.Unlock()
+	}
+}
+
+func (s *Store) rlock() {
+	if !s.inTx {
+		s.mu.RLock()
+	}
+}
+
+func (s *Store) runlock() {
+	if !s.inTx {
+		s.mu.RUnlock()
+	}
+}
+
+func (t *tables) clone() tables {
+	return tables{
+		users:         t.users.clone(),
+		identities:    t.identities.clone(),
+		refreshTokens: t.refreshTokens.clone(),
+		userTokens:    t.userTokens.clone(),
+		tasks:         t.tasks.clone(),
+		blogs:         t.blogs.clone(),
+		shops:         t.shops.clone(),
+		shopHours:     t.shopHours.clone(),
+		shopClosures:  t.shopClosures.clone(),
+		shopImages:    t.shopImages.clone(),
+		menus:         t.menus.clone(),
+		courses:       t.courses.clone(),
+		courseWindows: t.courseWindows.clone(),
+		favorites:     t.favorites.clone(),
+		reservations:  t.reservations.clone(),
+		statusChanges: t.statusChanges.clone(),
+		reviews:       t.reviews.clone(),
+		reviewReports: t.reviewReports.clone(),
+	}
+}
+
+// table は ID をキーにした行の集まりです。ID はデータベースの連番と同じく 1 から振ります。
+type table[T any] struct {
+	rows   map[uint]T
+	lastID uint
+}
+
+func (t *table[T]) get(id uint) (T, bool) {
+	row, ok := t.rows[id]
+	return row, ok
+}
+
+// insert は新しい ID を振って行を追加し、その ID を返します。setID で行に ID を設定します。
+func (t *table[T]) insert(row *T, setID func(row *T, id uint)) uint {
+	if t.rows == nil {
+		t.rows = map[uint]T{}
+	}
+	t.lastID++
+	setID(row, t.lastID)
+	t.rows[t.lastID] = *row
+	return t.lastID
+}
+
+func (t *table[T]) put(id uint, row T) {
+	t.rows[id] = row
+}
+
+func (t *table[T]) delete(id uint) {
+	delete(t.rows, id)
+}
+
+// list は条件に一致する行を ID の順に返します。match が nil の場合は全ての行を返します。
+func (t *table[T]) list(match func(row T) bool) []T {
+	ids := make([]uint, 0, len(t.rows))
+	for id, row := range t.rows {
+		if match == nil || match(row) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	rows := make([]T, 0, len(ids))
+	for _, id := range ids {
+		rows = append(rows, t.rows[id])
+	}
+	return rows
+}
+
+// clone はテーブルの複製です。複数の行を変更する処理を途中で失敗した場合に元に戻すために使います。
+func (t *table[T]) clone() table[T] {
+	c := table[T]{rows: make(map[uint]T, len(t.rows)), lastID: t.lastID}
+	for id, row := range t.rows {
+		c.rows[id] = row
+	}
+	return c
+}
+
+// errNotFound と errConflict は GORM の実装が返すエラーと同じ種類・メッセージのエラーです。
+func errNotFound() error {
+	return apperror.NotFound("object does not exist")
+}
+
+func errConflict() error {
+	return apperror.Conflict("object already exists")
+}
+
+// touch は GORM と同じく、作成日時・更新日時が未設定の場合に現在時刻を設定します。
+func touch(createdAt, updatedAt *time.Time) {
+	t := time.Now()
+	if createdAt != nil && createdAt.IsZero() {
+		*createdAt = t
+	}
+	if updatedAt != nil && updatedAt.IsZero() {
+		*updatedAt = t
+	}
+}
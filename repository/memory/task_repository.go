@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"time"
+)
+
+type taskRepository struct {
+	s *Store
+}
+
+func NewTaskRepository(s *Store) repository.ITaskRepository {
+	return &taskRepository{s}
+}
+
+// taskListSpec はタスク一覧で使える並び替え・絞り込みの項目です。
+var taskListSpec = listSpec[model.Task]{
+	sorts: map[string]func(t model.Task) interface{}{
+		"id":         func(t model.Task) interface{} { return t.ID },
+		"title":      func(t model.Task) interface{} { return t.Title },
+		"created_at": func(t model.Task) interface{} { return t.CreatedAt },
+		"updated_at": func(t model.Task) interface{} { return t.UpdatedAt },
+	},
+	defaultSort: "created_at",
+	date:        func(t model.Task) time.Time { return t.CreatedAt },
+	id:          func(t model.Task) uint { return t.ID },
+}
+
+func (tr *taskRepository) GetAllTasks(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.Task], error) {
+	tr.s.rlock()
+	defer tr.s.runlock()
+	tasks := tr.s.tasks.list(func(t model.Task) bool { return t.UserId == userId })
+	for i := range tasks {
+		tasks[i].User, _ = tr.s.users.get(tasks[i].UserId)
+	}
+	return paginate(tasks, taskListSpec, q)
+}
+
+func (tr *taskRepository) GetTaskById(ctx context.Context, task *model.Task, userId uint, taskId uint) error {
+	tr.s.rlock()
+	defer tr.s.runlock()
+	t, ok := tr.s.tasks.get(taskId)
+	if !ok || t.UserId != userId {
+		return errNotFound()
+	}
+	t.User, _ = tr.s.users.get(t.UserId)
+	*task = t
+	return nil
+}
+
+func (tr *taskRepository) CreateTask(ctx context.Context, task *model.Task) error {
+	tr.s.lock()
+	defer tr.s.unlock()
+	touch(&task.CreatedAt, &task.UpdatedAt)
+	row := *task
+	row.User = model.User{}
+	task.ID = tr.s.tasks.insert(&row, func(t *model.Task, id uint) { t.ID = id })
+	return nil
+}
+
+func (tr *taskRepository) UpdateTask(ctx context.Context, task *model.Task, userId uint, taskId uint) error {
+	tr.s.lock()
+	defer tr.s.unlock()
+	t, ok := tr.s.tasks.get(taskId)
+	if !ok || t.UserId != userId {
+		return errNotFound()
+	}
+	t.Title = task.Title
+	t.UpdatedAt = time.Now()
+	tr.s.tasks.put(taskId, t)
+	*task = t
+	return nil
+}
+
+func (tr *taskRepository) DeleteTask(ctx context.Context, userId uint, taskId uint) error {
+	tr.s.lock()
+	defer tr.s.unlock()
+	t, ok := tr.s.tasks.get(taskId)
+	if !ok || t.UserId != userId {
+		return errNotFound()
+	}
+	tr.s.tasks.delete(taskId)
+	return nil
+}
@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"go-rest-api/repository"
+)
+
+type unitOfWork struct {
+	s *Store
+}
+
+func NewUnitOfWork(s *Store) repository.IUnitOfWork {
+	return &unitOfWork{s}
+}
+
+// Do は全てのテーブルの複製に対して fc を実行し、成功した場合のみ複製を元の Store に戻します。
+// 最も外側のトランザクションは終了するまで Store をロックするため、トランザクションは他の操作と直列に実行されます。
+// 入れ子の Do はトランザクションの中の Store をさらに複製するため、セーブポイントと同じく内側の変更のみ取り消せます。
+func (u *unitOfWork) Do(ctx context.Context, fc func(r repository.Repositories) error) error {
+	u.s.lock()
+	defer u.s.unlock()
+	tx := &Store{inTx: true, tables: u.s.tables.clone()}
+	if err := fc(&unitOfWork{tx}); err != nil {
+		return err
+	}
+	u.s.tables = tx.tables
+	return nil
+}
+
+func (u *unitOfWork) Users() repository.IUserRepository {
+	return NewUserRepository(u.s)
+}
+
+func (u *unitOfWork) Tasks() repository.ITaskRepository {
+	return NewTaskRepository(u.s)
+}
+
+func (u *unitOfWork) Blogs() repository.IBlogRepository {
+	return NewBlogRepository(u.s)
+}
+
+func (u *unitOfWork) Shops() repository.IShopRepository {
+	return NewShopRepository(u.s)
+}
+
+func (u *unitOfWork) Menus() repository.IMenuRepository {
+	return NewMenuRepository(u.s)
+}
+
+func (u *unitOfWork) Favorites() repository.IFavoriteRepository {
+	return NewFavoriteRepository(u.s)
+}
+
+func (u *unitOfWork) Reservations() repository.IReservationRepository {
+	return NewReservationRepository(u.s)
+}
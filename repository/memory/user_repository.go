@@ -0,0 +1,81 @@
+package memory
+
+import (
+	"context"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"time"
+)
+
+type userRepository struct {
+	s *Store
+}
+
+func NewUserRepository(s *Store) repository.IUserRepository {
+	return &userRepository{s}
+}
+
+func (ur *userRepository) GetUserByEmail(ctx context.Context, user *model.User, email string) error {
+	ur.s.rlock()
+	defer ur.s.runlock()
+	users := ur.s.users.list(func(u model.User) bool { return u.Email == email })
+	if len(users) == 0 {
+		return errNotFound()
+	}
+	*user = users[0]
+	return nil
+}
+
+func (ur *userRepository) CreateUser(ctx context.Context, user *model.User) error {
+	ur.s.lock()
+	defer ur.s.unlock()
+	return createUser(ur.s, user)
+}
+
+func (ur *userRepository) GetUserById(ctx context.Context, user *model.User, userId uint) error {
+	ur.s.rlock()
+	defer ur.s.runlock()
+	u, ok := ur.s.users.get(userId)
+	if !ok {
+		return errNotFound()
+	}
+	*user = u
+	return nil
+}
+
+func (ur *userRepository) UpdateUserRole(ctx context.Context, userId uint, role string) error {
+	return ur.update(userId, func(u *model.User) { u.Role = role })
+}
+
+func (ur *userRepository) MarkEmailVerified(ctx context.Context, userId uint) error {
+	return ur.update(userId, func(u *model.User) { u.EmailVerified = true })
+}
+
+func (ur *userRepository) update(userId uint, fc func(u *model.User)) error {
+	ur.s.lock()
+	defer ur.s.unlock()
+	u, ok := ur.s.users.get(userId)
+	if !ok {
+		return errNotFound()
+	}
+	fc(&u)
+	u.UpdatedAt = time.Now()
+	ur.s.users.put(userId, u)
+	return nil
+}
+
+// createUser はユーザーを追加します。Store のロックを取得した状態で呼びます。
+func createUser(s *Store, user *model.User) error {
+	// users.email の一意制約
+	if len(s.users.list(func(u model.User) bool { return u.Email == user.Email })) > 0 {
+		return errConflict()
+	}
+	if user.Role == "" {
+		user.Role = model.RoleCustomer
+	}
+	touch(&user.CreatedAt, &user.UpdatedAt)
+	row := *user
+	row.Favorites, row.Reservations = nil, nil
+	user.ID = s.users.insert(&row, func(u *model.User, id uint) { u.ID = id })
+	return nil
+}
@@ -0,0 +1,83 @@
+package memory
+
+import (
+	"context"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"time"
+)
+
+type userTokenRepository struct {
+	s *Store
+}
+
+func NewUserTokenRepository(s *Store) repository.IUserTokenRepository {
+	return &userTokenRepository{s}
+}
+
+func (utr *userTokenRepository) CreateUserToken(ctx context.Context, token *model.UserToken) error {
+	utr.s.lock()
+	defer utr.s.unlock()
+	// user_tokens.token_hash の一意制約
+	if len(utr.s.userTokens.list(func(t model.UserToken) bool { return t.TokenHash == token.TokenHash })) > 0 {
+		return errConflict()
+	}
+	now := time.Now()
+	for _, t := range utr.s.userTokens.list(func(t model.UserToken) bool {
+		return t.UserID == token.UserID && t.Purpose == token.Purpose && t.UsedAt == nil
+	}) {
+		t.UsedAt = &now
+		utr.s.userTokens.put(t.ID, t)
+	}
+	touch(&token.CreatedAt, nil)
+	row := *token
+	row.User = model.User{}
+	token.ID = utr.s.userTokens.insert(&row, func(t *model.UserToken, id uint) { t.ID = id })
+	return nil
+}
+
+func (utr *userTokenRepository) VerifyEmail(ctx context.Context, tokenHash string) (uint, error) {
+	utr.s.lock()
+	defer utr.s.unlock()
+	token, err := consumeUserToken(utr.s, tokenHash, model.TokenPurposeVerifyEmail)
+	if err != nil {
+		return 0, err
+	}
+	if u, ok := utr.s.users.get(token.UserID); ok {
+		u.EmailVerified = true
+		u.UpdatedAt = time.Now()
+		utr.s.users.put(u.ID, u)
+	}
+	return token.UserID, nil
+}
+
+func (utr *userTokenRepository) ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (uint, error) {
+	utr.s.lock()
+	defer utr.s.unlock()
+	token, err := consumeUserToken(utr.s, tokenHash, model.TokenPurposeResetPassword)
+	if err != nil {
+		return 0, err
+	}
+	if u, ok := utr.s.users.get(token.UserID); ok {
+		u.Password = passwordHash
+		u.UpdatedAt = time.Now()
+		utr.s.users.put(u.ID, u)
+	}
+	revokeRefreshTokens(utr.s, func(t model.RefreshToken) bool { return t.UserID == token.UserID })
+	return token.UserID, nil
+}
+
+// consumeUserToken は有効なトークンを使用済みにします。Store のロックを取得した状態で呼びます。
+func consumeUserToken(s *Store, tokenHash string, purpose string) (model.UserToken, error) {
+	now := time.Now()
+	tokens := s.userTokens.list(func(t model.UserToken) bool {
+		return t.TokenHash == tokenHash && t.Purpose == purpose && t.UsedAt == nil && t.ExpiresAt.After(now)
+	})
+	if len(tokens) == 0 {
+		return model.UserToken{}, repository.ErrInvalidUserToken
+	}
+	token := tokens[0]
+	token.UsedAt = &now
+	s.userTokens.put(token.ID, token)
+	return token, nil
+}
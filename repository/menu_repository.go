@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+
+	"gorm.io/gorm"
+)
+
+type IMenuRepository interface {
+	GetMenusByShop(ctx context.Context, shopId uint, activeOnly bool) ([]model.Menu, error)
+	GetMenuById(ctx context.Context, menu *model.Menu, shopId uint, menuId uint) error
+	CreateMenu(ctx context.Context, menu *model.Menu) error
+	UpdateMenu(ctx context.Context, menu *model.Menu, shopId uint, menuId uint) error
+	DeleteMenu(ctx context.Context, shopId uint, menuId uint) error
+	GetCourseById(ctx context.Context, course *model.Course, courseId uint) error
+	CreateCourse(ctx context.Context, course *model.Course) error
+	UpdateCourse(ctx context.Context, course *model.Course, shopId uint, courseId uint) error
+	DeleteCourse(ctx context.Context, shopId uint, courseId uint) error
+}
+
+type menuRepository struct {
+	db *gorm.DB
+}
+
+func NewMenuRepository(db *gorm.DB) IMenuRepository {
+	return &menuRepository{db}
+}
+
+// GetMenusByShop はショップのメニューをコースと予約できる時間帯とともに返します。
+// activeOnly の場合は提供中のコースのみ含めます。
+func (mr *menuRepository) GetMenusByShop(ctx context.Context, shopId uint, activeOnly bool) ([]model.Menu, error) {
+	menus := []model.Menu{}
+	err := mr.db.WithContext(ctx).
+		Preload("Courses", func(db *gorm.DB) *gorm.DB {
+			if activeOnly {
+				db = db.Where("active = ?", true)
+			}
+			return db.Order("price, id")
+		}).
+		Preload("Courses.Windows", func(db *gorm.DB) *gorm.DB {
+			return db.Order("start_time, id")
+		}).
+		Where("shop_id=?", shopId).
+		Order("position, id").
+		Find(&menus).Error
+	return menus, translateError(err)
+}
+
+func (mr *menuRepository) GetMenuById(ctx context.Context, menu *model.Menu, shopId uint, menuId uint) error {
+	if err := mr.db.WithContext(ctx).Where("shop_id=?", shopId).First(menu, menuId).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (mr *menuRepository) CreateMenu(ctx context.Context, menu *model.Menu) error {
+	if err := mr.db.WithContext(ctx).Omit("Courses").Create(menu).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (mr *menuRepository) UpdateMenu(ctx context.Context, menu *model.Menu, shopId uint, menuId uint) error {
+	err := updateReturning(mr.db.WithContext(ctx), menu, map[string]interface{}{
+		"name":        menu.Name,
+		"description": menu.Description,
+		"position":    menu.Position,
+	}, "id=? AND shop_id=?", menuId, shopId)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (mr *menuRepository) DeleteMenu(ctx context.Context, shopId uint, menuId uint) error {
+	result := mr.db.WithContext(ctx).Where("id=? AND shop_id=?", menuId, shopId).Delete(&model.Menu{})
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected < 1 {
+		return apperror.NotFound("object does not exist")
+	}
+	return nil
+}
+
+func (mr *menuRepository) GetCourseById(ctx context.Context, course *model.Course, courseId uint) error {
+	if err := mr.db.WithContext(ctx).Preload("Windows").First(course, courseId).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// CreateCourse はコースを予約できる時間帯とともに作成します。
+func (mr *menuRepository) CreateCourse(ctx context.Context, course *model.Course) error {
+	if err := mr.db.WithContext(ctx).Create(course).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// UpdateCourse はコースを更新し、予約できる時間帯を course.Windows に置き換えます。
+func (mr *menuRepository) UpdateCourse(ctx context.Context, course *model.Course, shopId uint, courseId uint) error {
+	err := mr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		windows := course.Windows
+		err := updateReturning(tx, course, map[string]interface{}{
+			"name":             course.Name,
+			"description":      course.Description,
+			"price":            course.Price,
+			"duration_minutes": course.DurationMinutes,
+			"min_party_size":   course.MinPartySize,
+			"max_party_size":   course.MaxPartySize,
+			"start_date":       course.StartDate,
+			"end_date":         course.EndDate,
+			"active":           course.Active,
+		}, "id=? AND shop_id=?", courseId, shopId)
+		if err != nil {
+			return err
+		}
+		if err := tx.Where("course_id=?", courseId).Delete(&model.CourseWindow{}).Error; err != nil {
+			return err
+		}
+		for i := range windows {
+			windows[i].ID = 0
+			windows[i].CourseID = courseId
+		}
+		if len(windows) > 0 {
+			if err := tx.Create(&windows).Error; err != nil {
+				return err
+			}
+		}
+		course.Windows = windows
+		return nil
+	})
+	return translateError(err)
+}
+
+func (mr *menuRepository) DeleteCourse(ctx context.Context, shopId uint, courseId uint) error {
+	result := mr.db.WithContext(ctx).Where("id=? AND shop_id=?", courseId, shopId).Delete(&model.Course{})
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected < 1 {
+		return apperror.NotFound("object does not exist")
+	}
+	return nil
+}
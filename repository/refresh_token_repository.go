@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrTokenAlreadyRevoked は失効済み（使用済み）のリフレッシュトークンをローテーションしようとした場合に返されます。
+var ErrTokenAlreadyRevoked = apperror.Unauthorized("refresh token already revoked")
+
+type IRefreshTokenRepository interface {
+	CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error
+	GetRefreshTokenByHash(ctx context.Context, token *model.RefreshToken, tokenHash string) error
+	RotateRefreshToken(ctx context.Context, current *model.RefreshToken, next *model.RefreshToken) error
+	RevokeFamily(ctx context.Context, familyId string) error
+	RevokeAllForUser(ctx context.Context, userId uint) error
+}
+
+type refreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) IRefreshTokenRepository {
+	return &refreshTokenRepository{db}
+}
+
+func (rtr *refreshTokenRepository) CreateRefreshToken(ctx context.Context, token *model.RefreshToken) error {
+	if err := rtr.db.WithContext(ctx).Create(token).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (rtr *refreshTokenRepository) GetRefreshTokenByHash(ctx context.Context, token *model.RefreshToken, tokenHash string) error {
+	if err := rtr.db.WithContext(ctx).Where("token_hash=?", tokenHash).First(token).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// RotateRefreshToken は現在のトークンを失効させ、同じトランザクションで次のトークンを作成します。
+// 同時に同じトークンが使われた場合、後から来た方は ErrTokenAlreadyRevoked になります。
+func (rtr *refreshTokenRepository) RotateRefreshToken(ctx context.Context, current *model.RefreshToken, next *model.RefreshToken) error {
+	return rtr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.RefreshToken{}).
+			Where("id=? AND revoked_at IS NULL", current.ID).
+			Update("revoked_at", time.Now())
+		if result.Error != nil {
+			return translateError(result.Error)
+		}
+		if result.RowsAffected < 1 {
+			return ErrTokenAlreadyRevoked
+		}
+		return translateError(tx.Create(next).Error)
+	})
+}
+
+func (rtr *refreshTokenRepository) RevokeFamily(ctx context.Context, familyId string) error {
+	err := rtr.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("family_id=? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", time.Now()).Error
+	return translateError(err)
+}
+
+func (rtr *refreshTokenRepository) RevokeAllForUser(ctx context.Context, userId uint) error {
+	err := rtr.db.WithContext(ctx).Model(&model.RefreshToken{}).
+		Where("user_id=? AND revoked_at IS NULL", userId).
+		Update("revoked_at", time.Now()).Error
+	return translateError(err)
+}
@@ -0,0 +1,81 @@
+package repositorytest
+
+import (
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"testing"
+)
+
+// BlogRepository は IBlogRepository の契約テストです。ブログはユーザーごとに分かれ、ビルド用の一覧のみ全てのユーザーのブログを返します。
+func BlogRepository(t *testing.T, newRepositories Factory) {
+	t.Run("UserScope", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		bob := createUser(t, r, "bob@example.com")
+		blog := model.Blog{Title: "a", Content: "content", UserId: alice.ID}
+		mustNil(t, r.Blogs.CreateBlog(ctx, &blog))
+
+		got := model.Blog{}
+		mustNil(t, r.Blogs.GetBlogById(ctx, &got, alice.ID, blog.ID))
+		if got.Title != "a" || got.User.Email != alice.Email {
+			t.Fatalf("GetBlogById = %+v", got)
+		}
+		wantKind(t, r.Blogs.GetBlogById(ctx, &model.Blog{}, bob.ID, blog.ID), apperror.KindNotFound)
+		wantKind(t, r.Blogs.UpdateBlog(ctx, &model.Blog{Title: "b"}, bob.ID, blog.ID), apperror.KindNotFound)
+		wantKind(t, r.Blogs.UpdateBlogCover(ctx, &model.Blog{}, bob.ID, blog.ID), apperror.KindNotFound)
+		wantKind(t, r.Blogs.DeleteBlog(ctx, bob.ID, blog.ID), apperror.KindNotFound)
+		page, err := r.Blogs.GetAllBlogs(ctx, bob.ID, model.ListQuery{})
+		mustNil(t, err)
+		if page.Total != 0 {
+			t.Fatalf("bob's blogs = %+v", page)
+		}
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		blog := model.Blog{Title: "a", Content: "content", UserId: alice.ID}
+		mustNil(t, r.Blogs.CreateBlog(ctx, &blog))
+
+		updated := model.Blog{Title: "b", Content: "new content"}
+		mustNil(t, r.Blogs.UpdateBlog(ctx, &updated, alice.ID, blog.ID))
+		if updated.ID != blog.ID || updated.Title != "b" || updated.Content != "new content" {
+			t.Fatalf("UpdateBlog = %+v", updated)
+		}
+
+		cover := model.Blog{Cover: model.ImageFile{Key: "blogs/1.jpg", URL: "/uploads/blogs/1.jpg", ContentType: "image/jpeg", Size: 10, Width: 4, Height: 3}}
+		mustNil(t, r.Blogs.UpdateBlogCover(ctx, &cover, alice.ID, blog.ID))
+		got := model.Blog{}
+		mustNil(t, r.Blogs.GetBlogById(ctx, &got, alice.ID, blog.ID))
+		if got.Title != "b" || got.Cover != cover.Cover {
+			t.Fatalf("blog after cover update = %+v", got)
+		}
+		mustNil(t, r.Blogs.UpdateBlogCover(ctx, &model.Blog{}, alice.ID, blog.ID))
+		mustNil(t, r.Blogs.GetBlogById(ctx, &got, alice.ID, blog.ID))
+		if got.Cover != (model.ImageFile{}) {
+			t.Fatalf("cover after removal = %+v", got.Cover)
+		}
+
+		mustNil(t, r.Blogs.DeleteBlog(ctx, alice.ID, blog.ID))
+		wantKind(t, r.Blogs.DeleteBlog(ctx, alice.ID, blog.ID), apperror.KindNotFound)
+	})
+
+	t.Run("ListForBuild", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		bob := createUser(t, r, "bob@example.com")
+		for _, user := range []model.User{alice, bob, alice} {
+			mustNil(t, r.Blogs.CreateBlog(ctx, &model.Blog{Title: "t", Content: "c", UserId: user.ID}))
+		}
+		page, err := r.Blogs.GetAllBlogsForBuild(ctx, model.ListQuery{Sort: "id"})
+		mustNil(t, err)
+		if page.Total != 3 || page.Items[1].User.Email != bob.Email {
+			t.Fatalf("GetAllBlogsForBuild = %+v", page)
+		}
+		page, err = r.Blogs.GetAllBlogs(ctx, alice.ID, model.ListQuery{Sort: "id"})
+		mustNil(t, err)
+		if page.Total != 2 {
+			t.Fatalf("alice's blogs = %+v", page)
+		}
+	})
+}
@@ -0,0 +1,77 @@
+package repositorytest
+
+import (
+	"fmt"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"testing"
+)
+
+// FavoriteRepository は IFavoriteRepository の契約テストです。
+func FavoriteRepository(t *testing.T, newRepositories Factory) {
+	t.Run("AddAndRemove", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		bob := createUser(t, r, "bob@example.com")
+		sushi := createShop(t, r, model.Shop{Name: "Sushi"})
+		ramen := createShop(t, r, model.Shop{Name: "Ramen"})
+		for _, f := range []model.Favorite{
+			{ShopID: sushi.ID, UserID: alice.ID, IsFavorite: true},
+			{ShopID: ramen.ID, UserID: alice.ID, IsFavorite: true},
+			{ShopID: ramen.ID, UserID: bob.ID, IsFavorite: true},
+		} {
+			f := f
+			mustNil(t, r.Favorites.AddFavorite(ctx, &f))
+			if f.ID == 0 {
+				t.Fatal("favorite ID is not set")
+			}
+		}
+		aliceID := fmt.Sprint(alice.ID)
+
+		favorites := []model.Favorite{}
+		mustNil(t, r.Favorites.GetFavorites(ctx, aliceID, &favorites))
+		if len(favorites) != 2 {
+			t.Fatalf("alice's favorites = %+v", favorites)
+		}
+		shops := []model.Shop{}
+		mustNil(t, r.Favorites.GetFavoriteShops(ctx, aliceID, &shops))
+		if !sameIDs(ids(shops, func(s model.Shop) uint { return s.ID }), []uint{sushi.ID, ramen.ID}) {
+			t.Fatalf("alice's favorite shops = %+v", shops)
+		}
+
+		mustNil(t, r.Favorites.RemoveFavorite(ctx, fmt.Sprint(ramen.ID), aliceID))
+		wantKind(t, r.Favorites.RemoveFavorite(ctx, fmt.Sprint(ramen.ID), aliceID), apperror.KindNotFound)
+		mustNil(t, r.Favorites.GetFavoriteShops(ctx, aliceID, &shops))
+		if len(shops) != 1 || shops[0].ID != sushi.ID {
+			t.Fatalf("alice's favorite shops after removal = %+v", shops)
+		}
+		// 他のユーザーのお気に入りは削除されない
+		mustNil(t, r.Favorites.GetFavorites(ctx, fmt.Sprint(bob.ID), &favorites))
+		if len(favorites) != 1 {
+			t.Fatalf("bob's favorites = %+v", favorites)
+		}
+	})
+
+	t.Run("ListForBuild", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		sushi := createShop(t, r, model.Shop{Name: "Sushi"})
+		ramen := createShop(t, r, model.Shop{Name: "Ramen"})
+		mustNil(t, r.Favorites.AddFavorite(ctx, &model.Favorite{ShopID: sushi.ID, UserID: alice.ID}))
+		mustNil(t, r.Favorites.AddFavorite(ctx, &model.Favorite{ShopID: ramen.ID, UserID: alice.ID}))
+
+		page, err := r.Favorites.GetFavoritesForBuild(ctx, model.ListQuery{Limit: 1})
+		mustNil(t, err)
+		if page.Total != 2 || len(page.Items) != 1 || page.NextCursor == "" {
+			t.Fatalf("first page = %+v", page)
+		}
+		if page.Items[0].Shop.Name != "Sushi" || page.Items[0].User.Email != alice.Email {
+			t.Fatalf("favorite associations = %+v", page.Items[0])
+		}
+		page, err = r.Favorites.GetFavoritesForBuild(ctx, model.ListQuery{Limit: 1, Cursor: page.NextCursor})
+		mustNil(t, err)
+		if len(page.Items) != 1 || page.Items[0].Shop.Name != "Ramen" || page.NextCursor != "" {
+			t.Fatalf("second page = %+v", page)
+		}
+	})
+}
@@ -0,0 +1,40 @@
+package repositorytest
+
+import (
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"testing"
+)
+
+// IdentityRepository は IIdentityRepository の契約テストです。
+func IdentityRepository(t *testing.T, newRepositories Factory) {
+	t.Run("CreateAndGet", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		identity := model.Identity{Provider: "google", Subject: "123", Email: alice.Email, UserID: alice.ID}
+		mustNil(t, r.Identities.CreateIdentity(ctx, &identity))
+		wantKind(t, r.Identities.CreateIdentity(ctx, &model.Identity{Provider: "google", Subject: "123", UserID: alice.ID}), apperror.KindConflict)
+
+		got := model.Identity{}
+		mustNil(t, r.Identities.GetIdentity(ctx, &got, "google", "123"))
+		if got.ID != identity.ID || got.UserID != alice.ID {
+			t.Fatalf("GetIdentity = %+v", got)
+		}
+		wantKind(t, r.Identities.GetIdentity(ctx, &model.Identity{}, "github", "123"), apperror.KindNotFound)
+	})
+
+	t.Run("CreateUserWithIdentity", func(t *testing.T) {
+		r := newRepositories(t)
+		user := model.User{Email: "alice@example.com", Name: "alice"}
+		identity := model.Identity{Provider: "google", Subject: "123"}
+		mustNil(t, r.Identities.CreateUserWithIdentity(ctx, &user, &identity))
+		if user.ID == 0 || identity.UserID != user.ID {
+			t.Fatalf("user = %+v, identity = %+v", user, identity)
+		}
+
+		// 紐付けが作成できない場合はユーザーも作成しない
+		bob := model.User{Email: "bob@example.com", Name: "bob"}
+		wantKind(t, r.Identities.CreateUserWithIdentity(ctx, &bob, &model.Identity{Provider: "google", Subject: "123"}), apperror.KindConflict)
+		wantKind(t, r.Users.GetUserByEmail(ctx, &model.User{}, "bob@example.com"), apperror.KindNotFound)
+	})
+}
@@ -0,0 +1,80 @@
+package repositorytest
+
+import (
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"testing"
+)
+
+// MenuRepository は IMenuRepository の契約テストです。メニューとコースはショップごとに分かれます。
+func MenuRepository(t *testing.T, newRepositories Factory) {
+	t.Run("MenusAndCourses", func(t *testing.T) {
+		r := newRepositories(t)
+		shop := createShop(t, r, model.Shop{Name: "Sushi"})
+		other := createShop(t, r, model.Shop{Name: "Ramen"})
+		dinner := model.Menu{ShopID: shop.ID, Name: "Dinner", Position: 1}
+		lunch := model.Menu{ShopID: shop.ID, Name: "Lunch"}
+		mustNil(t, r.Menus.CreateMenu(ctx, &dinner))
+		mustNil(t, r.Menus.CreateMenu(ctx, &lunch))
+
+		monday := 1
+		omakase := model.Course{MenuID: dinner.ID, ShopID: shop.ID, Name: "Omakase", Price: 12000, DurationMinutes: 90, Windows: []model.CourseWindow{
+			{Weekday: &monday, StartTime: "18:00", EndTime: "20:00"},
+			{StartTime: "12:00", EndTime: "13:00"},
+		}}
+		mustNil(t, r.Menus.CreateCourse(ctx, &omakase))
+		if !omakase.Active || omakase.MinPartySize != 1 || omakase.Windows[0].ID == 0 || omakase.Windows[0].CourseID != omakase.ID {
+			t.Fatalf("created course = %+v", omakase)
+		}
+		nigiri := model.Course{MenuID: dinner.ID, ShopID: shop.ID, Name: "Nigiri", Price: 8000, DurationMinutes: 60}
+		mustNil(t, r.Menus.CreateCourse(ctx, &nigiri))
+
+		menus, err := r.Menus.GetMenusByShop(ctx, shop.ID, false)
+		mustNil(t, err)
+		if len(menus) != 2 || menus[0].ID != lunch.ID || menus[1].ID != dinner.ID {
+			t.Fatalf("menus = %+v", menus)
+		}
+		courses := menus[1].Courses
+		if len(courses) != 2 || courses[0].ID != nigiri.ID || len(courses[1].Windows) != 2 || courses[1].Windows[0].StartTime != "12:00" {
+			t.Fatalf("dinner courses = %+v", courses)
+		}
+		wantKind(t, r.Menus.GetMenuById(ctx, &model.Menu{}, other.ID, dinner.ID), apperror.KindNotFound)
+
+		// 提供を終えたコースは activeOnly の場合に含まない
+		nigiri.Active = false
+		mustNil(t, r.Menus.UpdateCourse(ctx, &nigiri, shop.ID, nigiri.ID))
+		menus, err = r.Menus.GetMenusByShop(ctx, shop.ID, true)
+		mustNil(t, err)
+		if len(menus[1].Courses) != 1 || menus[1].Courses[0].ID != omakase.ID {
+			t.Fatalf("active dinner courses = %+v", menus[1].Courses)
+		}
+
+		// 時間帯は置き換える
+		omakase.Windows = []model.CourseWindow{{StartTime: "17:00", EndTime: "19:00"}}
+		mustNil(t, r.Menus.UpdateCourse(ctx, &omakase, shop.ID, omakase.ID))
+		got := model.Course{}
+		mustNil(t, r.Menus.GetCourseById(ctx, &got, omakase.ID))
+		if got.Name != "Omakase" || len(got.Windows) != 1 || got.Windows[0].StartTime != "17:00" {
+			t.Fatalf("course after update = %+v", got)
+		}
+		wantKind(t, r.Menus.UpdateCourse(ctx, &omakase, other.ID, omakase.ID), apperror.KindNotFound)
+		wantKind(t, r.Menus.DeleteCourse(ctx, other.ID, omakase.ID), apperror.KindNotFound)
+
+		lunch.Name = "Weekday lunch"
+		mustNil(t, r.Menus.UpdateMenu(ctx, &lunch, shop.ID, lunch.ID))
+		if lunch.Name != "Weekday lunch" || lunch.ShopID != shop.ID {
+			t.Fatalf("updated menu = %+v", lunch)
+		}
+		wantKind(t, r.Menus.UpdateMenu(ctx, &lunch, other.ID, lunch.ID), apperror.KindNotFound)
+
+		// メニューを削除するとコースも削除される
+		mustNil(t, r.Menus.DeleteMenu(ctx, shop.ID, dinner.ID))
+		wantKind(t, r.Menus.DeleteMenu(ctx, shop.ID, dinner.ID), apperror.KindNotFound)
+		wantKind(t, r.Menus.GetCourseById(ctx, &model.Course{}, omakase.ID), apperror.KindNotFound)
+		menus, err = r.Menus.GetMenusByShop(ctx, shop.ID, false)
+		mustNil(t, err)
+		if len(menus) != 1 || len(menus[0].Courses) != 0 {
+			t.Fatalf("menus after delete = %+v", menus)
+		}
+	})
+}
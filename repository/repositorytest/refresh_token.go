@@ -0,0 +1,70 @@
+package repositorytest
+
+import (
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"testing"
+	"time"
+)
+
+// RefreshTokenRepository は IRefreshTokenRepository の契約テストです。
+func RefreshTokenRepository(t *testing.T, newRepositories Factory) {
+	expiresAt := time.Now().Add(time.Hour)
+
+	t.Run("Rotate", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		current := model.RefreshToken{TokenHash: "h1", FamilyID: "f", ExpiresAt: expiresAt, UserID: alice.ID}
+		mustNil(t, r.RefreshTokens.CreateRefreshToken(ctx, &current))
+		wantKind(t, r.RefreshTokens.CreateRefreshToken(ctx, &model.RefreshToken{TokenHash: "h1", FamilyID: "f", ExpiresAt: expiresAt, UserID: alice.ID}), apperror.KindConflict)
+
+		next := model.RefreshToken{TokenHash: "h2", FamilyID: "f", ExpiresAt: expiresAt, UserID: alice.ID}
+		mustNil(t, r.RefreshTokens.RotateRefreshToken(ctx, &current, &next))
+		got := model.RefreshToken{}
+		mustNil(t, r.RefreshTokens.GetRefreshTokenByHash(ctx, &got, "h1"))
+		if got.RevokedAt == nil {
+			t.Fatalf("rotated token = %+v", got)
+		}
+		got = model.RefreshToken{}
+		mustNil(t, r.RefreshTokens.GetRefreshTokenByHash(ctx, &got, "h2"))
+		if got.ID != next.ID || got.RevokedAt != nil {
+			t.Fatalf("next token = %+v", got)
+		}
+
+		// 使用済みのトークンは再びローテーションできない
+		wantError(t, r.RefreshTokens.RotateRefreshToken(ctx, &current, &model.RefreshToken{TokenHash: "h3", FamilyID: "f", ExpiresAt: expiresAt, UserID: alice.ID}), repository.ErrTokenAlreadyRevoked)
+		wantKind(t, r.RefreshTokens.GetRefreshTokenByHash(ctx, &model.RefreshToken{}, "h3"), apperror.KindNotFound)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		bob := createUser(t, r, "bob@example.com")
+		for _, token := range []model.RefreshToken{
+			{TokenHash: "a1", FamilyID: "fa1", UserID: alice.ID},
+			{TokenHash: "a2", FamilyID: "fa2", UserID: alice.ID},
+			{TokenHash: "b1", FamilyID: "fb1", UserID: bob.ID},
+		} {
+			token := token
+			token.ExpiresAt = expiresAt
+			mustNil(t, r.RefreshTokens.CreateRefreshToken(ctx, &token))
+		}
+		mustNil(t, r.RefreshTokens.RevokeFamily(ctx, "fa1"))
+		wantRevoked(t, r, map[string]bool{"a1": true, "a2": false, "b1": false})
+		mustNil(t, r.RefreshTokens.RevokeAllForUser(ctx, alice.ID))
+		wantRevoked(t, r, map[string]bool{"a1": true, "a2": true, "b1": false})
+	})
+}
+
+// wantRevoked はハッシュごとにリフレッシュトークンが失効しているかを確認します。
+func wantRevoked(t *testing.T, r Repositories, revoked map[string]bool) {
+	t.Helper()
+	for hash, want := range revoked {
+		token := model.RefreshToken{}
+		mustNil(t, r.RefreshTokens.GetRefreshTokenByHash(ctx, &token, hash))
+		if (token.RevokedAt != nil) != want {
+			t.Fatalf("token %s revoked = %v, want %v", hash, token.RevokedAt != nil, want)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package repositorytest はリポジトリの実装が満たすべき振る舞いを確認する契約テストです。
+// GORM の実装とメモリ上の実装（repository/memory）に同じテストを実行し、両者の振る舞いが一致することを確認します。
+//
+//	func TestMemory(t *testing.T) { repositorytest.Run(t, repositorytest.Memory) }
+//	func TestSQLite(t *testing.T) { repositorytest.Run(t, repositorytest.SQLite) }
+package repositorytest
+
+import (
+	"context"
+	"errors"
+	"go-rest-api/apperror"
+	"go-rest-api/config"
+	"go-rest-api/db"
+	"go-rest-api/migration"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"go-rest-api/repository/memory"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// Repositories は契約テストの対象のリポジトリです。全てのリポジトリは同じデータを共有する必要があります。
+type Repositories struct {
+	Users         repository.IUserRepository
+	Tasks         repository.ITaskRepository
+	Blogs         repository.IBlogRepository
+	Shops         repository.IShopRepository
+	Menus         repository.IMenuRepository
+	Favorites     repository.IFavoriteRepository
+	Reservations  repository.IReservationRepository
+	Reviews       repository.IReviewRepository
+	RefreshTokens repository.IRefreshTokenRepository
+	Identities    repository.IIdentityRepository
+	UserTokens    repository.IUserTokenRepository
+	UnitOfWork    repository.IUnitOfWork
+}
+
+// ctx は契約テストでリポジトリに渡すコンテキストです。
+var ctx = context.Background()
+
+// Factory は空のデータのリポジトリを返します。テストごとに呼ぶため、前のテストのデータが残らないようにします。
+type Factory func(t *testing.T) Repositories
+
+// Run は全てのリポジトリの契約テストを実行します。
+func Run(t *testing.T, newRepositories Factory) {
+	t.Run("User", func(t *testing.T) { UserRepository(t, newRepositories) })
+	t.Run("Task", func(t *testing.T) { TaskRepository(t, newRepositories) })
+	t.Run("Blog", func(t *testing.T) { BlogRepository(t, newRepositories) })
+	t.Run("Shop", func(t *testing.T) { ShopRepository(t, newRepositories) })
+	t.Run("Favorite", func(t *testing.T) { FavoriteRepository(t, newRepositories) })
+	t.Run("Menu", func(t *testing.T) { MenuRepository(t, newRepositories) })
+	t.Run("Reservation", func(t *testing.T) { ReservationRepository(t, newRepositories) })
+	t.Run("Review", func(t *testing.T) { ReviewRepository(t, newRepositories) })
+	t.Run("RefreshToken", func(t *testing.T) { RefreshTokenRepository(t, newRepositories) })
+	t.Run("Identity", func(t *testing.T) { IdentityRepository(t, newRepositories) })
+	t.Run("UserToken", func(t *testing.T) { UserTokenRepository(t, newRepositories) })
+	t.Run("UnitOfWork", func(t *testing.T) { UnitOfWork(t, newRepositories) })
+}
+
+// Memory はメモリ上の実装のリポジトリを返します。
+func Memory(t *testing.T) Repositories {
+	s := memory.NewStore()
+	return Repositories{
+		Users:         memory.NewUserRepository(s),
+		Tasks:         memory.NewTaskRepository(s),
+		Blogs:         memory.NewBlogRepository(s),
+		Shops:         memory.NewShopRepository(s),
+		Menus:         memory.NewMenuRepository(s),
+		Favorites:     memory.NewFavoriteRepository(s),
+		Reservations:  memory.NewReservationRepository(s),
+		Reviews:       memory.NewReviewRepository(s),
+		RefreshTokens: memory.NewRefreshTokenRepository(s),
+		Identities:    memory.NewIdentityRepository(s),
+		UserTokens:    memory.NewUserTokenRepository(s),
+		UnitOfWork:    memory.NewUnitOfWork(s),
+	}
+}
+
+// GORM は db を使う GORM の実装のリポジトリを返します。
+func GORM(db *gorm.DB) Repositories {
+	return Repositories{
+		Users:         repository.NewUserRepository(db),
+		Tasks:         repository.NewTaskRepository(db),
+		Blogs:         repository.NewBlogRepository(db),
+		Shops:         repository.NewShopRepository(db),
+		Menus:         repository.NewMenuRepository(db),
+		Favorites:     repository.NewFavoriteRepository(db),
+		Reservations:  repository.NewReservationRepository(db),
+		Reviews:       repository.NewReviewRepository(db),
+		RefreshTokens: repository.NewRefreshTokenRepository(db),
+		Identities:    repository.NewIdentityRepository(db),
+		UserTokens:    repository.NewUserTokenRepository(db),
+		UnitOfWork:    repository.NewUnitOfWork(db),
+	}
+}
+
+// SQLite はマイグレーションを適用したメモリ上の SQLite を使う GORM の実装のリポジトリを返します。
+// データベースはテストの終了時に閉じます。
+func SQLite(t *testing.T) Repositories {
+	t.Helper()
+	conn, err := db.Open(config.Database{Driver: config.DriverSQLite, SQLitePath: ":memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.CloseDB(conn) })
+	migrator, err := migration.NewMigrator(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	return GORM(conn)
+}
+
+func mustNil(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// wantKind は err が指定した種類のドメインエラーであることを確認します。
+func wantKind(t *testing.T, err error, kind apperror.Kind) {
+	t.Helper()
+	if !apperror.Is(err, kind) {
+		t.Fatalf("want error of kind %s, got %v", kind.Slug(), err)
+	}
+}
+
+// wantError は err が target であることを確認します。
+func wantError(t *testing.T, err error, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("want %v, got %v", target, err)
+	}
+}
+
+func createUser(t *testing.T, r Repositories, email string) model.User {
+	t.Helper()
+	user := model.User{Email: email, Password: "password", Name: email}
+	mustNil(t, r.Users.CreateUser(ctx, &user))
+	return user
+}
+
+// madeBy は user が作成した予約の最初のステータスの変更履歴です。
+func madeBy(user model.User) *model.ReservationStatusChange {
+	return &model.ReservationStatusChange{ActorID: user.ID, ActorRole: model.RoleCustomer}
+}
+
+func createShop(t *testing.T, r Repositories, shop model.Shop) model.Shop {
+	t.Helper()
+	if shop.Address == "" {
+		shop.Address = "Tokyo"
+	}
+	if shop.Area == "" {
+		shop.Area = "東京都"
+	}
+	if shop.Genre == "" {
+		shop.Genre = "寿司"
+	}
+	mustNil(t, r.Shops.CreateShop(ctx, &shop))
+	return shop
+}
+
+// ids はページの行のIDを順に返します。
+func ids[T any](items []T, id func(T) uint) []uint {
+	result := make([]uint, 0, len(items))
+	for _, item := range items {
+		result = append(result, id(item))
+	}
+	return result
+}
+
+// allPages は q から NextCursor をたどって全てのページの行のIDを順に返します。
+func allPages[T any](t *testing.T, q model.ListQuery, list func(q model.ListQuery) (model.Page[T], error), id func(T) uint) []uint {
+	t.Helper()
+	all := []uint{}
+	for {
+		page, err := list(q)
+		mustNil(t, err)
+		all = append(all, ids(page.Items, id)...)
+		if page.NextCursor == "" || len(all) > int(page.Total) {
+			return all
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+// reversed は逆順にしたIDです。
+func reversed(a []uint) []uint {
+	r := make([]uint, len(a))
+	for i, id := range a {
+		r[len(a)-1-i] = id
+	}
+	return r
+}
+
+func equalIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
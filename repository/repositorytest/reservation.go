@@ -0,0 +1,183 @@
+package repositorytest
+
+import (
+	"fmt"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"testing"
+	"time"
+)
+
+// ReservationRepository は IReservationRepository の契約テストです。
+func ReservationRepository(t *testing.T, newRepositories Factory) {
+	date := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Capacity", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		shop := createShop(t, r, model.Shop{Name: "Sushi", Capacity: 4})
+		first := model.Reservation{Date: date, Time: "12:00", ShopID: shop.ID, UserID: alice.ID, Num: 3}
+		initial := madeBy(alice)
+		created, err := r.Reservations.MakeReservation(ctx, &first, initial)
+		mustNil(t, err)
+		if created.ID == 0 || created.Status != model.ReservationPending {
+			t.Fatalf("created reservation = %+v", created)
+		}
+		// 最初のステータスも変更履歴に記録する
+		if initial.ID == 0 || initial.ReservationID != created.ID || initial.FromStatus != "" || initial.ToStatus != model.ReservationPending {
+			t.Fatalf("initial status change = %+v", initial)
+		}
+		_, err = r.Reservations.MakeReservation(ctx, &model.Reservation{Date: date, Time: "12:00", ShopID: shop.ID, UserID: alice.ID, Num: 2, Status: model.ReservationPending}, madeBy(alice))
+		wantError(t, err, repository.ErrCapacityExceeded)
+		_, err = r.Reservations.MakeReservation(ctx, &model.Reservation{Date: date, Time: "13:00", ShopID: shop.ID, UserID: alice.ID, Num: 2, Status: model.ReservationPending}, madeBy(alice))
+		mustNil(t, err)
+		_, err = r.Reservations.MakeReservation(ctx, &model.Reservation{Date: date, Time: "12:00", ShopID: shop.ID + 1, UserID: alice.ID, Num: 1, Status: model.ReservationPending}, madeBy(alice))
+		wantKind(t, err, apperror.KindNotFound)
+
+		booked, err := r.Reservations.GetBookedSeats(ctx, shop.ID, date)
+		mustNil(t, err)
+		if len(booked) != 2 || booked["12:00"] != 3 || booked["13:00"] != 2 {
+			t.Fatalf("booked seats = %v", booked)
+		}
+
+		// キャンセルした予約の席は空く
+		change := model.ReservationStatusChange{ActorID: alice.ID, ActorRole: model.RoleCustomer}
+		mustNil(t, r.Reservations.ChangeStatus(ctx, &first, model.ReservationCancelledByUser, &change))
+		if first.Status != model.ReservationCancelledByUser || change.ID == 0 || change.FromStatus != model.ReservationPending {
+			t.Fatalf("after ChangeStatus: reservation %+v, change %+v", first, change)
+		}
+		changes, err := r.Reservations.GetStatusChanges(ctx, first.ID)
+		mustNil(t, err)
+		if len(changes) != 2 || changes[0].ID != initial.ID || changes[1].ID != change.ID || changes[1].ActorRole != model.RoleCustomer {
+			t.Fatalf("status changes = %+v", changes)
+		}
+		booked, err = r.Reservations.GetBookedSeats(ctx, shop.ID, date)
+		mustNil(t, err)
+		if booked["12:00"] != 0 {
+			t.Fatalf("booked seats after cancel = %v", booked)
+		}
+		_, err = r.Reservations.MakeReservation(ctx, &model.Reservation{Date: date, Time: "12:00", ShopID: shop.ID, UserID: alice.ID, Num: 4, Status: model.ReservationPending}, madeBy(alice))
+		mustNil(t, err)
+	})
+
+	t.Run("IgnoresStatusChangesOnCreate", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		shop := createShop(t, r, model.Shop{Name: "Sushi"})
+		reservation := model.Reservation{Date: date, Time: "12:00", ShopID: shop.ID, UserID: alice.ID, Num: 1, Status: model.ReservationPending,
+			StatusChanges: []model.ReservationStatusChange{{FromStatus: model.ReservationConfirmed, ToStatus: model.ReservationCompleted, ActorID: alice.ID, ActorRole: model.RoleAdmin}}}
+		_, err := r.Reservations.MakeReservation(ctx, &reservation, madeBy(alice))
+		mustNil(t, err)
+		// 変更履歴は change のみを記録し、予約の StatusChanges は保存しない
+		changes, err := r.Reservations.GetStatusChanges(ctx, reservation.ID)
+		mustNil(t, err)
+		if len(changes) != 1 || changes[0].ToStatus != model.ReservationPending || changes[0].ActorRole != model.RoleCustomer {
+			t.Fatalf("status changes = %+v", changes)
+		}
+	})
+
+	t.Run("ChangeStatusConflict", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		shop := createShop(t, r, model.Shop{Name: "Sushi"})
+		reservation := model.Reservation{Date: date, Time: "12:00", ShopID: shop.ID, UserID: alice.ID, Num: 1, Status: model.ReservationPending}
+		_, err := r.Reservations.MakeReservation(ctx, &reservation, madeBy(alice))
+		mustNil(t, err)
+		stale := reservation
+		mustNil(t, r.Reservations.ChangeStatus(ctx, &reservation, model.ReservationConfirmed, &model.ReservationStatusChange{ActorID: alice.ID, ActorRole: model.RoleCustomer}))
+		// 読み込んだ後に他のリクエストがステータスを変えていた場合
+		err = r.Reservations.ChangeStatus(ctx, &stale, model.ReservationCancelledByUser, &model.ReservationStatusChange{ActorID: alice.ID, ActorRole: model.RoleCustomer})
+		wantError(t, err, repository.ErrStatusConflict)
+		got := model.Reservation{}
+		mustNil(t, r.Reservations.GetReservation(ctx, &got, reservation.ID))
+		if got.Status != model.ReservationConfirmed {
+			t.Fatalf("status = %s", got.Status)
+		}
+	})
+
+	t.Run("UserScope", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		bob := createUser(t, r, "bob@example.com")
+		shop := createShop(t, r, model.Shop{Name: "Sushi", Capacity: 4})
+		reservation := model.Reservation{Date: date, Time: "12:00", ShopID: shop.ID, UserID: alice.ID, Num: 2, Status: model.ReservationPending}
+		_, err := r.Reservations.MakeReservation(ctx, &reservation, madeBy(alice))
+		mustNil(t, err)
+
+		got := model.Reservation{}
+		mustNil(t, r.Reservations.GetReservationById(ctx, &got, alice.ID, reservation.ID))
+		wantKind(t, r.Reservations.GetReservationById(ctx, &model.Reservation{}, bob.ID, reservation.ID), apperror.KindNotFound)
+		wantKind(t, r.Reservations.GetReservation(ctx, &model.Reservation{}, reservation.ID+1), apperror.KindNotFound)
+
+		update := reservation
+		update.Time = "18:00"
+		update.Num = 4
+		_, err = r.Reservations.UpdateReservation(ctx, &update, bob.ID, reservation.ID)
+		wantKind(t, err, apperror.KindNotFound)
+		_, err = r.Reservations.UpdateReservation(ctx, &update, alice.ID, reservation.ID)
+		mustNil(t, err)
+		mustNil(t, r.Reservations.GetReservation(ctx, &got, reservation.ID))
+		if got.Time != "18:00" || got.Num != 4 {
+			t.Fatalf("updated reservation = %+v", got)
+		}
+		// 変更前の自分の予約の席は数えない
+		update.Num = 5
+		_, err = r.Reservations.UpdateReservation(ctx, &update, alice.ID, reservation.ID)
+		wantError(t, err, repository.ErrCapacityExceeded)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		bob := createUser(t, r, "bob@example.com")
+		sushi := createShop(t, r, model.Shop{Name: "Sushi"})
+		ramen := createShop(t, r, model.Shop{Name: "Ramen"})
+		reserve := func(user model.User, shop model.Shop, day int, clock string) model.Reservation {
+			reservation := model.Reservation{Date: date.AddDate(0, 0, day), Time: clock, ShopID: shop.ID, UserID: user.ID, Num: 1, Status: model.ReservationPending}
+			_, err := r.Reservations.MakeReservation(ctx, &reservation, madeBy(user))
+			mustNil(t, err)
+			return reservation
+		}
+		later := reserve(alice, sushi, 1, "12:00")
+		evening := reserve(alice, ramen, 0, "18:00")
+		noon := reserve(alice, sushi, 0, "12:00")
+		other := reserve(bob, sushi, 2, "12:00")
+		reservationID := func(r model.Reservation) uint { return r.ID }
+
+		reservations, err := r.Reservations.GetReservationByUser(ctx, alice.ID)
+		mustNil(t, err)
+		if want := []uint{noon.ID, evening.ID, later.ID}; !equalIDs(ids(reservations, reservationID), want) {
+			t.Fatalf("alice's reservations = %v, want %v", ids(reservations, reservationID), want)
+		}
+		reservations, err = r.Reservations.GetReservationsByShop(ctx, sushi.ID)
+		mustNil(t, err)
+		if want := []uint{noon.ID, later.ID, other.ID}; !equalIDs(ids(reservations, reservationID), want) {
+			t.Fatalf("sushi's reservations = %v, want %v", ids(reservations, reservationID), want)
+		}
+
+		page, err := r.Reservations.GetAllReservations(ctx, model.ListQuery{Order: model.SortDesc, Filters: map[string]string{"shop_id": fmt.Sprint(sushi.ID)}})
+		mustNil(t, err)
+		if want := []uint{other.ID, later.ID, noon.ID}; !equalIDs(ids(page.Items, reservationID), want) {
+			t.Fatalf("reservations by date desc = %v, want %v", ids(page.Items, reservationID), want)
+		}
+		// 日付でカーソルをたどる（同じ日付の予約はIDの順）
+		list := func(q model.ListQuery) (model.Page[model.Reservation], error) {
+			return r.Reservations.GetAllReservations(ctx, q)
+		}
+		if got, want := allPages(t, model.ListQuery{Limit: 1}, list, reservationID), []uint{evening.ID, noon.ID, later.ID, other.ID}; !equalIDs(got, want) {
+			t.Fatalf("reservations by date cursor = %v, want %v", got, want)
+		}
+		from, to := date.AddDate(0, 0, 1), date.AddDate(0, 0, 1)
+		page, err = r.Reservations.GetAllReservations(ctx, model.ListQuery{From: &from, To: &to})
+		mustNil(t, err)
+		if page.Total != 1 || page.Items[0].ID != later.ID {
+			t.Fatalf("reservations on the next day = %v", ids(page.Items, reservationID))
+		}
+		page, err = r.Reservations.GetReservationsForBuild(ctx, model.ListQuery{Sort: "id", Limit: 3})
+		mustNil(t, err)
+		if page.Total != 4 || len(page.Items) != 3 || page.NextCursor == "" {
+			t.Fatalf("reservations for build = %+v", page)
+		}
+	})
+}
@@ -0,0 +1,123 @@
+package repositorytest
+
+import (
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"testing"
+	"time"
+)
+
+// ReviewRepository は IReviewRepository の契約テストです。
+func ReviewRepository(t *testing.T, newRepositories Factory) {
+	t.Run("RatingAggregate", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		bob := createUser(t, r, "bob@example.com")
+		shop := createShop(t, r, model.Shop{Name: "Sushi"})
+		good := model.Review{ShopID: shop.ID, UserID: alice.ID, Rating: 5, Comment: "good"}
+		mustNil(t, r.Reviews.CreateReview(ctx, &good))
+		bad := model.Review{ShopID: shop.ID, UserID: bob.ID, Rating: 2, Comment: "bad"}
+		mustNil(t, r.Reviews.CreateReview(ctx, &bad))
+		wantRating(t, r, shop.ID, 3.5, 2)
+
+		bad.Rating = 4
+		mustNil(t, r.Reviews.UpdateReview(ctx, &bad, bob.ID, bad.ID))
+		if bad.ShopID != shop.ID || bad.Comment != "bad" {
+			t.Fatalf("updated review = %+v", bad)
+		}
+		wantRating(t, r, shop.ID, 4.5, 2)
+		// 他のユーザーのレビューは変更・削除できない
+		wantKind(t, r.Reviews.UpdateReview(ctx, &model.Review{Rating: 1}, alice.ID, bad.ID), apperror.KindNotFound)
+		wantKind(t, r.Reviews.DeleteReview(ctx, alice.ID, bad.ID), apperror.KindNotFound)
+
+		// 非公開のレビューは一覧と集計から除く
+		hidden := model.Review{}
+		mustNil(t, r.Reviews.SetReviewHidden(ctx, &hidden, good.ID, true))
+		if !hidden.Hidden {
+			t.Fatalf("hidden review = %+v", hidden)
+		}
+		wantRating(t, r, shop.ID, 4, 1)
+		page, err := r.Reviews.GetShopReviews(ctx, shop.ID, model.ListQuery{})
+		mustNil(t, err)
+		if page.Total != 1 || page.Items[0].ID != bad.ID {
+			t.Fatalf("shop reviews = %+v", page)
+		}
+		page, err = r.Reviews.GetUserReviews(ctx, alice.ID, model.ListQuery{})
+		mustNil(t, err)
+		if page.Total != 1 || page.Items[0].ID != good.ID {
+			t.Fatalf("alice's reviews = %+v", page)
+		}
+
+		mustNil(t, r.Reviews.DeleteReview(ctx, bob.ID, bad.ID))
+		wantKind(t, r.Reviews.GetReviewById(ctx, &model.Review{}, bad.ID), apperror.KindNotFound)
+		wantRating(t, r, shop.ID, 0, 0)
+	})
+
+	t.Run("DuplicateReservation", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		shop := createShop(t, r, model.Shop{Name: "Sushi", Capacity: 4})
+		reservation, err := r.Reservations.MakeReservation(ctx, &model.Reservation{Date: time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC), Time: "12:00", ShopID: shop.ID, UserID: alice.ID, Num: 2}, madeBy(alice))
+		mustNil(t, err)
+		mustNil(t, r.Reviews.CreateReview(ctx, &model.Review{ShopID: shop.ID, UserID: alice.ID, ReservationID: &reservation.ID, Rating: 5, Verified: true}))
+		wantKind(t, r.Reviews.CreateReview(ctx, &model.Review{ShopID: shop.ID, UserID: alice.ID, ReservationID: &reservation.ID, Rating: 4}), apperror.KindConflict)
+	})
+
+	// 既定の並び替え（作成日時の降順）でカーソルをたどれる
+	t.Run("Cursor", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		shop := createShop(t, r, model.Shop{Name: "Sushi"})
+		base := time.Date(2030, 1, 7, 9, 0, 0, 0, time.UTC)
+		created := []uint{}
+		for i := 0; i < 5; i++ {
+			review := model.Review{ShopID: shop.ID, UserID: alice.ID, Rating: 3, CreatedAt: base.Add(time.Duration(i) * time.Minute)}
+			mustNil(t, r.Reviews.CreateReview(ctx, &review))
+			created = append(created, review.ID)
+		}
+		list := func(q model.ListQuery) (model.Page[model.Review], error) {
+			return r.Reviews.GetShopReviews(ctx, shop.ID, q)
+		}
+		got := allPages(t, model.ListQuery{Limit: 2}, list, func(r model.Review) uint { return r.ID })
+		if !equalIDs(got, reversed(created)) {
+			t.Fatalf("ids by created_at desc = %v, want %v", got, reversed(created))
+		}
+	})
+
+	t.Run("ReplyAndReport", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		bob := createUser(t, r, "bob@example.com")
+		shop := createShop(t, r, model.Shop{Name: "Sushi"})
+		review := model.Review{ShopID: shop.ID, UserID: alice.ID, Rating: 3, Comment: "ok"}
+		mustNil(t, r.Reviews.CreateReview(ctx, &review))
+
+		repliedAt := time.Now()
+		replied := model.Review{Reply: "thanks", RepliedAt: &repliedAt}
+		mustNil(t, r.Reviews.ReplyReview(ctx, &replied, shop.ID, review.ID))
+		if replied.Reply != "thanks" || replied.RepliedAt == nil || replied.Comment != "ok" {
+			t.Fatalf("replied review = %+v", replied)
+		}
+		wantKind(t, r.Reviews.ReplyReview(ctx, &model.Review{Reply: "x"}, shop.ID+1, review.ID), apperror.KindNotFound)
+
+		mustNil(t, r.Reviews.ReportReview(ctx, &model.ReviewReport{ReviewID: review.ID, UserID: bob.ID, Reason: "spam"}))
+		wantError(t, r.Reviews.ReportReview(ctx, &model.ReviewReport{ReviewID: review.ID, UserID: bob.ID, Reason: "spam"}), repository.ErrAlreadyReported)
+		mustNil(t, r.Reviews.ReportReview(ctx, &model.ReviewReport{ReviewID: review.ID, UserID: alice.ID, Reason: "spam"}))
+		page, err := r.Reviews.GetReportedReviews(ctx, model.ListQuery{})
+		mustNil(t, err)
+		if page.Total != 1 || page.Items[0].ReportCount != 2 {
+			t.Fatalf("reported reviews = %+v", page)
+		}
+	})
+}
+
+// wantRating はショップの平均評価と件数を確認します。
+func wantRating(t *testing.T, r Repositories, shopId uint, average float64, count int) {
+	t.Helper()
+	shop := model.Shop{}
+	mustNil(t, r.Shops.GetShopById(ctx, &shop, shopId))
+	if shop.RatingAverage != average || shop.RatingCount != count {
+		t.Fatalf("rating = %v (%d), want %v (%d)", shop.RatingAverage, shop.RatingCount, average, count)
+	}
+}
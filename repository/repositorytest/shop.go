@@ -0,0 +1,310 @@
+package repositorytest
+
+import (
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"testing"
+	"time"
+)
+
+// ShopRepository は IShopRepository の契約テストです。
+func ShopRepository(t *testing.T, newRepositories Factory) {
+	t.Run("CreateAndGet", func(t *testing.T) {
+		r := newRepositories(t)
+		shop := createShop(t, r, model.Shop{Name: "Sushi", Description: "fresh"})
+		// 値を指定しない項目はカラムの既定値になる
+		if shop.ID == 0 || shop.Capacity != 20 || shop.OpenTime != "11:00" || shop.CloseTime != "22:00" || shop.SlotMinutes != 60 {
+			t.Fatalf("created shop = %+v", shop)
+		}
+		got := model.Shop{}
+		mustNil(t, r.Shops.GetShopById(ctx, &got, shop.ID))
+		if got.Name != "Sushi" || got.Hours == nil || got.Closures == nil || got.Images == nil {
+			t.Fatalf("GetShopById = %+v", got)
+		}
+		wantKind(t, r.Shops.GetShopById(ctx, &model.Shop{}, shop.ID+1), apperror.KindNotFound)
+
+		externalID := "ext-1"
+		createShop(t, r, model.Shop{Name: "A", ExternalID: &externalID})
+		duplicate := model.Shop{Name: "B", Address: "Tokyo", Area: "東京都", Genre: "寿司", ExternalID: &externalID}
+		wantKind(t, r.Shops.CreateShop(ctx, &duplicate), apperror.KindConflict)
+	})
+
+	t.Run("UpdateAndDelete", func(t *testing.T) {
+		r := newRepositories(t)
+		shop := createShop(t, r, model.Shop{Name: "Sushi"})
+		lat, lng := 35.0, 139.0
+		updated := model.Shop{Name: "Ramen", Address: "Osaka", Area: "大阪府", Genre: "ラーメン", Capacity: 8, OpenTime: "10:00", CloseTime: "20:00", SlotMinutes: 30, Latitude: &lat, Longitude: &lng}
+		mustNil(t, r.Shops.UpdateShop(ctx, &updated, shop.ID))
+		if updated.ID != shop.ID || updated.Name != "Ramen" || updated.Capacity != 8 || *updated.Latitude != lat || updated.Hours == nil {
+			t.Fatalf("UpdateShop = %+v", updated)
+		}
+		wantKind(t, r.Shops.UpdateShop(ctx, &model.Shop{Name: "x"}, shop.ID+1), apperror.KindNotFound)
+
+		mustNil(t, r.Shops.DeleteShop(ctx, shop.ID))
+		wantKind(t, r.Shops.GetShopById(ctx, &model.Shop{}, shop.ID), apperror.KindNotFound)
+		wantKind(t, r.Shops.DeleteShop(ctx, shop.ID), apperror.KindNotFound)
+	})
+
+	t.Run("HoursAndClosures", func(t *testing.T) {
+		r := newRepositories(t)
+		shop := createShop(t, r, model.Shop{Name: "Sushi"})
+		mustNil(t, r.Shops.ReplaceShopHours(ctx, shop.ID, []model.ShopHour{{Weekday: 1, OpenTime: "11:00", CloseTime: "14:00"}}))
+		hours := []model.ShopHour{
+			{Weekday: 2, OpenTime: "17:00", CloseTime: "22:00"},
+			{Weekday: 1, OpenTime: "17:00", CloseTime: "22:00"},
+			{Weekday: 1, OpenTime: "11:00", CloseTime: "14:00"},
+		}
+		mustNil(t, r.Shops.ReplaceShopHours(ctx, shop.ID, hours))
+		got := model.Shop{}
+		mustNil(t, r.Shops.GetShopById(ctx, &got, shop.ID))
+		if len(got.Hours) != 3 || got.Hours[0].OpenTime != "11:00" || got.Hours[1].OpenTime != "17:00" || got.Hours[2].Weekday != 2 {
+			t.Fatalf("hours = %+v", got.Hours)
+		}
+
+		today := time.Now().UTC()
+		tomorrow := time.Date(today.Year(), today.Month(), today.Day()+1, 0, 0, 0, 0, time.UTC)
+		past := tomorrow.AddDate(0, 0, -10)
+		closure := model.ShopClosure{ShopID: shop.ID, Date: tomorrow, Reason: "holiday"}
+		mustNil(t, r.Shops.CreateShopClosure(ctx, &closure))
+		mustNil(t, r.Shops.CreateShopClosure(ctx, &model.ShopClosure{ShopID: shop.ID, Date: past, Reason: "past"}))
+		mustNil(t, r.Shops.GetShopById(ctx, &got, shop.ID))
+		// 前日より前の休業は読み込まない
+		if len(got.Closures) != 1 || got.Closures[0].ID != closure.ID {
+			t.Fatalf("closures = %+v", got.Closures)
+		}
+		wantKind(t, r.Shops.DeleteShopClosure(ctx, shop.ID+1, closure.ID), apperror.KindNotFound)
+		mustNil(t, r.Shops.DeleteShopClosure(ctx, shop.ID, closure.ID))
+		wantKind(t, r.Shops.DeleteShopClosure(ctx, shop.ID, closure.ID), apperror.KindNotFound)
+	})
+
+	t.Run("Images", func(t *testing.T) {
+		r := newRepositories(t)
+		shop := createShop(t, r, model.Shop{Name: "Sushi"})
+		images := []model.ShopImage{}
+		for _, key := range []string{"a", "b", "c"} {
+			image := model.ShopImage{ShopID: shop.ID, ImageFile: model.ImageFile{Key: key}}
+			mustNil(t, r.Shops.CreateShopImage(ctx, &image))
+			if image.Position != len(images) {
+				t.Fatalf("position of %s = %d", key, image.Position)
+			}
+			images = append(images, image)
+		}
+		imageID := func(img model.ShopImage) uint { return img.ID }
+		reversed := []uint{images[2].ID, images[1].ID, images[0].ID}
+		mustNil(t, r.Shops.ReorderShopImages(ctx, shop.ID, reversed))
+		got := model.Shop{}
+		mustNil(t, r.Shops.GetShopById(ctx, &got, shop.ID))
+		if !equalIDs(ids(got.Images, imageID), reversed) {
+			t.Fatalf("images after reorder = %v", ids(got.Images, imageID))
+		}
+		// 1つでも見つからない画像がある場合は並び順を変えない
+		wantKind(t, r.Shops.ReorderShopImages(ctx, shop.ID, []uint{images[0].ID, images[2].ID + 100}), apperror.KindNotFound)
+		mustNil(t, r.Shops.GetShopById(ctx, &got, shop.ID))
+		if !equalIDs(ids(got.Images, imageID), reversed) {
+			t.Fatalf("images after failed reorder = %v", ids(got.Images, imageID))
+		}
+
+		deleted := model.ShopImage{}
+		mustNil(t, r.Shops.DeleteShopImage(ctx, &deleted, shop.ID, images[1].ID))
+		if deleted.Key != "b" {
+			t.Fatalf("deleted image = %+v", deleted)
+		}
+		wantKind(t, r.Shops.DeleteShopImage(ctx, &model.ShopImage{}, shop.ID, images[1].ID), apperror.KindNotFound)
+		wantKind(t, r.Shops.DeleteShopImage(ctx, &model.ShopImage{}, shop.ID+1, images[0].ID), apperror.KindNotFound)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		r := newRepositories(t)
+		a := createShop(t, r, model.Shop{Name: "B shop", Area: "東京都"})
+		b := createShop(t, r, model.Shop{Name: "A shop", Area: "大阪府"})
+		c := createShop(t, r, model.Shop{Name: "C shop", Area: "東京都"})
+		shopID := func(s model.Shop) uint { return s.ID }
+		page, err := r.Shops.GetAllShops(ctx, model.ListQuery{Sort: "name"})
+		mustNil(t, err)
+		if want := []uint{b.ID, a.ID, c.ID}; !equalIDs(ids(page.Items, shopID), want) || page.Items[0].Hours == nil {
+			t.Fatalf("shops by name = %v, want %v", ids(page.Items, shopID), want)
+		}
+		page, err = r.Shops.GetAllShops(ctx, model.ListQuery{Sort: "id", Order: model.SortDesc, Filters: map[string]string{"area": "東京都"}})
+		mustNil(t, err)
+		if want := []uint{c.ID, a.ID}; !equalIDs(ids(page.Items, shopID), want) || page.Total != 2 {
+			t.Fatalf("shops in 東京都 = %v, want %v", ids(page.Items, shopID), want)
+		}
+		_, err = r.Shops.GetAllShops(ctx, model.ListQuery{Filters: map[string]string{"name": "A shop"}})
+		wantKind(t, err, apperror.KindValidation)
+	})
+
+	t.Run("Search", func(t *testing.T) {
+		r := newRepositories(t)
+		ginza := createShop(t, r, model.Shop{Name: "Sushi Ginza", Area: "東京都", Genre: "寿司"})
+		osaka := createShop(t, r, model.Shop{Name: "Ramen", Address: "Sushi street", Area: "大阪府", Genre: "ラーメン"})
+		createShop(t, r, model.Shop{Name: "Curry", Area: "東京都", Genre: "カレー"})
+		shopID := func(s model.Shop) uint { return s.ID }
+
+		page, facets, err := r.Shops.SearchShops(ctx, model.ShopSearchQuery{Text: "sushi"})
+		mustNil(t, err)
+		if page.Total != 2 || !sameIDs(ids(page.Items, shopID), []uint{ginza.ID, osaka.ID}) {
+			t.Fatalf("search sushi = %v", ids(page.Items, shopID))
+		}
+		if len(facets.Area) != 2 || len(facets.Genre) != 2 {
+			t.Fatalf("facets = %+v", facets)
+		}
+
+		// エリアの件数はエリアの絞り込みを除いた条件で数える
+		page, facets, err = r.Shops.SearchShops(ctx, model.ShopSearchQuery{Area: "東京都"})
+		mustNil(t, err)
+		if page.Total != 2 || len(facets.Area) != 2 || facets.Area[0] != (model.FacetCount{Value: "東京都", Count: 2}) {
+			t.Fatalf("search 東京都 = %d, facets %+v", page.Total, facets)
+		}
+		if len(facets.Genre) != 2 || facets.Genre[0].Count != 1 {
+			t.Fatalf("genre facets = %+v", facets.Genre)
+		}
+
+		page, _, err = r.Shops.SearchShops(ctx, model.ShopSearchQuery{Limit: 1, Offset: 1})
+		mustNil(t, err)
+		if page.Total != 3 || len(page.Items) != 1 || page.Items[0].ID != osaka.ID {
+			t.Fatalf("second page = %v", ids(page.Items, shopID))
+		}
+	})
+
+	t.Run("SearchOpenAt", func(t *testing.T) {
+		r := newRepositories(t)
+		lunch := createShop(t, r, model.Shop{Name: "Lunch"})
+		mustNil(t, r.Shops.ReplaceShopHours(ctx, lunch.ID, []model.ShopHour{{Weekday: 1, OpenTime: "10:00", CloseTime: "15:00"}}))
+		dinner := createShop(t, r, model.Shop{Name: "Dinner"})
+		mustNil(t, r.Shops.ReplaceShopHours(ctx, dinner.ID, []model.ShopHour{
+			{Weekday: 0, OpenTime: "17:00", CloseTime: "02:00"},
+			{Weekday: 1, OpenTime: "17:00", CloseTime: "02:00"},
+		}))
+		// 曜日ごとの営業時間がないショップは open_time〜close_time で判定する
+		allDay := createShop(t, r, model.Shop{Name: "All day", OpenTime: "11:00", CloseTime: "22:00"})
+		shopID := func(s model.Shop) uint { return s.ID }
+
+		monday := time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+		tuesday, wednesday, thursday := monday.AddDate(0, 0, 1), monday.AddDate(0, 0, 2), monday.AddDate(0, 0, 3)
+		// 日付をまたぐ営業時間帯は開店した日の休業で判定する
+		bar := createShop(t, r, model.Shop{Name: "Bar"})
+		mustNil(t, r.Shops.ReplaceShopHours(ctx, bar.ID, []model.ShopHour{
+			{Weekday: 2, OpenTime: "18:00", CloseTime: "03:00"},
+			{Weekday: 3, OpenTime: "18:00", CloseTime: "03:00"},
+		}))
+		for _, c := range []model.ShopClosure{
+			{Date: tuesday, Reason: "holiday"},
+			{Date: wednesday, StartTime: "20:00", EndTime: "22:00", Reason: "private"},
+			{Date: thursday, Reason: "holiday"},
+		} {
+			c.ShopID = bar.ID
+			mustNil(t, r.Shops.CreateShopClosure(ctx, &c))
+		}
+		for _, tc := range []struct {
+			at   time.Time
+			want []uint
+		}{
+			{monday.Add(12 * time.Hour), []uint{lunch.ID, allDay.ID}},
+			{monday.Add(18 * time.Hour), []uint{dinner.ID, allDay.ID}},
+			// 前日から日付をまたぐ営業時間
+			{monday.Add(time.Hour), []uint{dinner.ID}},
+			{monday.Add(8 * time.Hour), []uint{}},
+			{tuesday.Add(19 * time.Hour), []uint{allDay.ID}},
+			// 前日（火曜日）が終日休業のため、日付をまたいだ分も休み
+			{wednesday.Add(time.Hour), []uint{}},
+			{wednesday.Add(21 * time.Hour), []uint{allDay.ID}},
+			{wednesday.Add(23 * time.Hour), []uint{bar.ID}},
+			// 当日（木曜日）の休業は前日からの営業に影響しない
+			{thursday.Add(time.Hour), []uint{bar.ID}},
+			{thursday.Add(19 * time.Hour), []uint{allDay.ID}},
+		} {
+			at := tc.at
+			page, _, err := r.Shops.SearchShops(ctx, model.ShopSearchQuery{OpenAt: &at})
+			mustNil(t, err)
+			if !sameIDs(ids(page.Items, shopID), tc.want) {
+				t.Fatalf("open at %s = %v, want %v", at.Format("Mon 15:04"), ids(page.Items, shopID), tc.want)
+			}
+		}
+	})
+
+	t.Run("Nearby", func(t *testing.T) {
+		r := newRepositories(t)
+		point := func(lat, lng float64) (*float64, *float64) { return &lat, &lng }
+		nearLat, nearLng := point(35.6812, 139.7671)
+		farLat, farLng := point(35.6586, 139.7454)
+		near := createShop(t, r, model.Shop{Name: "Near", Latitude: nearLat, Longitude: nearLng})
+		far := createShop(t, r, model.Shop{Name: "Far", Latitude: farLat, Longitude: farLng})
+		createShop(t, r, model.Shop{Name: "Nowhere"})
+
+		page, err := r.Shops.GetNearbyShops(ctx, 35.6810, 139.7670, 5000, 10, 0)
+		mustNil(t, err)
+		if page.Total != 2 || len(page.Items) != 2 || page.Items[0].Shop.ID != near.ID || page.Items[1].Shop.ID != far.ID {
+			t.Fatalf("nearby = %+v", page.Items)
+		}
+		if d := page.Items[0].Distance; d <= 0 || d > 100 {
+			t.Fatalf("distance to near = %f", d)
+		}
+		if page.Items[0].Shop.Hours == nil {
+			t.Fatal("associations are not loaded")
+		}
+		page, err = r.Shops.GetNearbyShops(ctx, 35.6810, 139.7670, 1000, 10, 0)
+		mustNil(t, err)
+		if page.Total != 1 {
+			t.Fatalf("nearby within 1km = %d", page.Total)
+		}
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		r := newRepositories(t)
+		ext1, ext2 := "ext-1", "ext-2"
+		existing := createShop(t, r, model.Shop{Name: "Old", ExternalID: &ext1})
+		owner := createUser(t, r, "owner@example.com")
+		existing.OwnerID = &owner.ID
+		mustNil(t, r.Shops.UpdateShop(ctx, &existing, existing.ID))
+
+		update := existing
+		update.Name = "New"
+		update.OwnerID = nil
+		created := model.Shop{Name: "Created", Address: "Tokyo", Area: "東京都", Genre: "寿司", ExternalID: &ext2}
+		mustNil(t, r.Shops.SaveImportedShops(ctx, []*model.Shop{&update, &created}))
+		if created.ID == 0 {
+			t.Fatal("imported shop ID is not set")
+		}
+		shops, err := r.Shops.GetShopsByExternalIDs(ctx, []string{ext1, ext2, "unknown"})
+		mustNil(t, err)
+		if len(shops) != 2 {
+			t.Fatalf("GetShopsByExternalIDs = %+v", shops)
+		}
+		got := model.Shop{}
+		mustNil(t, r.Shops.GetShopById(ctx, &got, existing.ID))
+		// 取り込みではオーナーを変更しない
+		if got.Name != "New" || got.OwnerID == nil || *got.OwnerID != owner.ID {
+			t.Fatalf("updated by import = %+v", got)
+		}
+
+		// 1件でも失敗した場合は何も保存しない
+		ext3 := "ext-3"
+		failing := model.Shop{Name: "Failing", Address: "Tokyo", Area: "東京都", Genre: "寿司", ExternalID: &ext3}
+		missing := got
+		missing.ID = created.ID + 100
+		wantKind(t, r.Shops.SaveImportedShops(ctx, []*model.Shop{&failing, &missing}), apperror.KindNotFound)
+		shops, err = r.Shops.GetShopsForExport(ctx)
+		mustNil(t, err)
+		if len(shops) != 2 || shops[0].ID != existing.ID || shops[1].ID != created.ID {
+			t.Fatalf("shops after failed import = %+v", shops)
+		}
+	})
+}
+
+// sameIDs は順序を問わず同じIDの集まりかを返します。
+func sameIDs(a, b []uint) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	count := map[uint]int{}
+	for _, id := range a {
+		count[id]++
+	}
+	for _, id := range b {
+		count[id]--
+		if count[id] < 0 {
+			return false
+		}
+	}
+	return true
+}
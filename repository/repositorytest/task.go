@@ -0,0 +1,125 @@
+package repositorytest
+
+import (
+	"fmt"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"testing"
+	"time"
+)
+
+// TaskRepository は ITaskRepository の契約テストです。タスクはユーザーごとに分かれ、他のユーザーのタスクは存在しないものとして扱います。
+func TaskRepository(t *testing.T, newRepositories Factory) {
+	t.Run("UserScope", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		bob := createUser(t, r, "bob@example.com")
+		task := model.Task{Title: "a", UserId: alice.ID}
+		mustNil(t, r.Tasks.CreateTask(ctx, &task))
+		if task.ID == 0 {
+			t.Fatal("task ID is not set")
+		}
+
+		got := model.Task{}
+		mustNil(t, r.Tasks.GetTaskById(ctx, &got, alice.ID, task.ID))
+		if got.Title != "a" || got.User.Email != alice.Email {
+			t.Fatalf("GetTaskById = %+v", got)
+		}
+		wantKind(t, r.Tasks.GetTaskById(ctx, &model.Task{}, bob.ID, task.ID), apperror.KindNotFound)
+		wantKind(t, r.Tasks.UpdateTask(ctx, &model.Task{Title: "b"}, bob.ID, task.ID), apperror.KindNotFound)
+		wantKind(t, r.Tasks.DeleteTask(ctx, bob.ID, task.ID), apperror.KindNotFound)
+		page, err := r.Tasks.GetAllTasks(ctx, bob.ID, model.ListQuery{})
+		mustNil(t, err)
+		if page.Total != 0 || len(page.Items) != 0 {
+			t.Fatalf("bob's tasks = %+v", page)
+		}
+	})
+
+	t.Run("UpdateAndDelete", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		task := model.Task{Title: "a", UserId: alice.ID}
+		mustNil(t, r.Tasks.CreateTask(ctx, &task))
+
+		updated := model.Task{Title: "b"}
+		mustNil(t, r.Tasks.UpdateTask(ctx, &updated, alice.ID, task.ID))
+		if updated.ID != task.ID || updated.Title != "b" || updated.UserId != alice.ID {
+			t.Fatalf("UpdateTask = %+v", updated)
+		}
+		wantKind(t, r.Tasks.UpdateTask(ctx, &model.Task{Title: "c"}, alice.ID, task.ID+1), apperror.KindNotFound)
+
+		mustNil(t, r.Tasks.DeleteTask(ctx, alice.ID, task.ID))
+		wantKind(t, r.Tasks.GetTaskById(ctx, &model.Task{}, alice.ID, task.ID), apperror.KindNotFound)
+		wantKind(t, r.Tasks.DeleteTask(ctx, alice.ID, task.ID), apperror.KindNotFound)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		created := []uint{}
+		for i := 0; i < 5; i++ {
+			task := model.Task{Title: fmt.Sprintf("task %d", 5-i), UserId: alice.ID}
+			mustNil(t, r.Tasks.CreateTask(ctx, &task))
+			created = append(created, task.ID)
+		}
+		taskID := func(t model.Task) uint { return t.ID }
+
+		// カーソルで全てのページを順にたどる
+		all := []uint{}
+		q := model.ListQuery{Limit: 2, Sort: "id"}
+		for {
+			page, err := r.Tasks.GetAllTasks(ctx, alice.ID, q)
+			mustNil(t, err)
+			if page.Total != 5 {
+				t.Fatalf("total = %d", page.Total)
+			}
+			all = append(all, ids(page.Items, taskID)...)
+			if page.NextCursor == "" {
+				break
+			}
+			q.Cursor = page.NextCursor
+		}
+		if !equalIDs(all, created) {
+			t.Fatalf("ids by cursor = %v, want %v", all, created)
+		}
+
+		page, err := r.Tasks.GetAllTasks(ctx, alice.ID, model.ListQuery{Sort: "title", Order: model.SortDesc, Limit: 2, Offset: 1})
+		mustNil(t, err)
+		if want := []uint{created[1], created[2]}; !equalIDs(ids(page.Items, taskID), want) || page.Offset != 1 {
+			t.Fatalf("ids by title desc = %v, want %v", ids(page.Items, taskID), want)
+		}
+		if page.Items[0].User.Email != alice.Email {
+			t.Fatalf("task user = %+v", page.Items[0].User)
+		}
+
+		_, err = r.Tasks.GetAllTasks(ctx, alice.ID, model.ListQuery{Sort: "user_id"})
+		wantKind(t, err, apperror.KindValidation)
+		_, err = r.Tasks.GetAllTasks(ctx, alice.ID, model.ListQuery{Order: "up"})
+		wantKind(t, err, apperror.KindValidation)
+		_, err = r.Tasks.GetAllTasks(ctx, alice.ID, model.ListQuery{Filters: map[string]string{"title": "a"}})
+		wantKind(t, err, apperror.KindValidation)
+		_, err = r.Tasks.GetAllTasks(ctx, alice.ID, model.ListQuery{Sort: "title", Cursor: page.NextCursor})
+		wantKind(t, err, apperror.KindValidation)
+	})
+	t.Run("CursorOnTimestamp", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		base := time.Date(2030, 1, 7, 9, 0, 0, 123456000, time.UTC)
+		created := []uint{}
+		for i := 0; i < 5; i++ {
+			// 2件ずつ同じ作成日時にし、同じ値の行がIDで順序付けられることも確認する
+			task := model.Task{Title: fmt.Sprint(i), UserId: alice.ID, CreatedAt: base.Add(time.Duration(i/2) * time.Second)}
+			mustNil(t, r.Tasks.CreateTask(ctx, &task))
+			created = append(created, task.ID)
+		}
+		list := func(q model.ListQuery) (model.Page[model.Task], error) { return r.Tasks.GetAllTasks(ctx, alice.ID, q) }
+		taskID := func(t model.Task) uint { return t.ID }
+
+		if got := allPages(t, model.ListQuery{Limit: 2}, list, taskID); !equalIDs(got, created) {
+			t.Fatalf("ids by created_at = %v, want %v", got, created)
+		}
+		if got := allPages(t, model.ListQuery{Limit: 2, Order: model.SortDesc}, list, taskID); !equalIDs(got, reversed(created)) {
+			t.Fatalf("ids by created_at desc = %v, want %v", got, reversed(created))
+		}
+	})
+}
@@ -0,0 +1,132 @@
+package repositorytest
+
+import (
+	"errors"
+	"fmt"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"testing"
+)
+
+// UnitOfWork は IUnitOfWork の契約テストです。トランザクションの中の変更は成功した場合のみ残り、
+// 入れ子のトランザクションは失敗した場合に内側の変更のみ取り消します。
+func UnitOfWork(t *testing.T, newRepositories Factory) {
+	errAbort := errors.New("abort")
+	shopExists := func(t *testing.T, r Repositories, shopId uint) bool {
+		t.Helper()
+		err := r.Shops.GetShopById(ctx, &model.Shop{}, shopId)
+		if err != nil && !apperror.Is(err, apperror.KindNotFound) {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return err == nil
+	}
+
+	t.Run("Commit", func(t *testing.T) {
+		r := newRepositories(t)
+		user := model.User{Email: "alice@example.com", Password: "password", Name: "alice"}
+		shop := model.Shop{Name: "Sushi", Address: "Tokyo", Area: "東京都", Genre: "寿司"}
+		err := r.UnitOfWork.Do(ctx, func(tx repository.Repositories) error {
+			if err := tx.Users().CreateUser(ctx, &user); err != nil {
+				return err
+			}
+			if err := tx.Shops().CreateShop(ctx, &shop); err != nil {
+				return err
+			}
+			// トランザクションの中の変更はトランザクションの中から見える
+			return tx.Favorites().AddFavorite(ctx, &model.Favorite{ShopID: shop.ID, UserID: user.ID, IsFavorite: true})
+		})
+		mustNil(t, err)
+		mustNil(t, r.Users.GetUserById(ctx, &model.User{}, user.ID))
+		favorites := []model.Favorite{}
+		mustNil(t, r.Favorites.GetFavorites(ctx, fmt.Sprint(user.ID), &favorites))
+		if len(favorites) != 1 || favorites[0].ShopID != shop.ID {
+			t.Fatalf("favorites after commit = %+v", favorites)
+		}
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		r := newRepositories(t)
+		shop := model.Shop{Name: "Sushi", Address: "Tokyo", Area: "東京都", Genre: "寿司"}
+		err := r.UnitOfWork.Do(ctx, func(tx repository.Repositories) error {
+			if err := tx.Shops().CreateShop(ctx, &shop); err != nil {
+				return err
+			}
+			return errAbort
+		})
+		wantError(t, err, errAbort)
+		if shopExists(t, r, shop.ID) {
+			t.Fatal("shop created in a failed transaction exists")
+		}
+	})
+
+	t.Run("Panic", func(t *testing.T) {
+		r := newRepositories(t)
+		shop := model.Shop{Name: "Sushi", Address: "Tokyo", Area: "東京都", Genre: "寿司"}
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Fatal("panic is not propagated")
+				}
+			}()
+			r.UnitOfWork.Do(ctx, func(tx repository.Repositories) error {
+				mustNil(t, tx.Shops().CreateShop(ctx, &shop))
+				panic("abort")
+			})
+		}()
+		if shopExists(t, r, shop.ID) {
+			t.Fatal("shop created in a panicked transaction exists")
+		}
+		// パニックの後もリポジトリを使える
+		createShop(t, r, model.Shop{Name: "Ramen"})
+	})
+
+	t.Run("Savepoint", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		outer := model.Shop{Name: "Sushi", Address: "Tokyo", Area: "東京都", Genre: "寿司"}
+		inner := model.Shop{Name: "Ramen", Address: "Tokyo", Area: "東京都", Genre: "ラーメン"}
+		after := model.Shop{Name: "Soba", Address: "Tokyo", Area: "東京都", Genre: "そば"}
+		err := r.UnitOfWork.Do(ctx, func(tx repository.Repositories) error {
+			if err := tx.Shops().CreateShop(ctx, &outer); err != nil {
+				return err
+			}
+			err := tx.Do(ctx, func(tx repository.Repositories) error {
+				if err := tx.Shops().CreateShop(ctx, &inner); err != nil {
+					return err
+				}
+				return errAbort
+			})
+			wantError(t, err, errAbort)
+			if err := tx.Shops().GetShopById(ctx, &model.Shop{}, inner.ID); !apperror.Is(err, apperror.KindNotFound) {
+				t.Fatalf("shop of the rolled back savepoint: %v", err)
+			}
+			// 入れ子のトランザクションの中で失敗した文の後も、外側のトランザクションを続けられる
+			err = tx.Do(ctx, func(tx repository.Repositories) error {
+				return tx.Users().CreateUser(ctx, &model.User{Email: alice.Email, Password: "password", Name: "alice"})
+			})
+			wantKind(t, err, apperror.KindConflict)
+			if err := tx.Shops().CreateShop(ctx, &after); err != nil {
+				return err
+			}
+			// 成功した入れ子のトランザクションの変更は外側のトランザクションに含まれる
+			return tx.Do(ctx, func(tx repository.Repositories) error {
+				return tx.Favorites().AddFavorite(ctx, &model.Favorite{ShopID: after.ID, UserID: alice.ID, IsFavorite: true})
+			})
+		})
+		mustNil(t, err)
+		if !shopExists(t, r, outer.ID) || !shopExists(t, r, after.ID) {
+			t.Fatal("shops of the committed transaction do not exist")
+		}
+		shops := []model.Shop{}
+		mustNil(t, r.Favorites.GetFavoriteShops(ctx, fmt.Sprint(alice.ID), &shops))
+		if len(shops) != 1 || shops[0].ID != after.ID {
+			t.Fatalf("favorite shops = %+v", shops)
+		}
+		page, err := r.Shops.GetAllShops(ctx, model.ListQuery{})
+		mustNil(t, err)
+		if page.Total != 2 {
+			t.Fatalf("shops = %+v", page.Items)
+		}
+	})
+}
@@ -0,0 +1,56 @@
+package repositorytest
+
+import (
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"testing"
+)
+
+// UserRepository は IUserRepository の契約テストです。
+func UserRepository(t *testing.T, newRepositories Factory) {
+	t.Run("CreateAndGet", func(t *testing.T) {
+		r := newRepositories(t)
+		user := createUser(t, r, "a@example.com")
+		if user.ID == 0 || user.Role != model.RoleCustomer || user.CreatedAt.IsZero() {
+			t.Fatalf("created user = %+v", user)
+		}
+		got := model.User{}
+		mustNil(t, r.Users.GetUserByEmail(ctx, &got, "a@example.com"))
+		if got.ID != user.ID || got.Password != "password" {
+			t.Fatalf("GetUserByEmail = %+v", got)
+		}
+		got = model.User{}
+		mustNil(t, r.Users.GetUserById(ctx, &got, user.ID))
+		if got.Email != "a@example.com" {
+			t.Fatalf("GetUserById = %+v", got)
+		}
+	})
+
+	t.Run("DuplicateEmail", func(t *testing.T) {
+		r := newRepositories(t)
+		createUser(t, r, "a@example.com")
+		user := model.User{Email: "a@example.com", Password: "password"}
+		wantKind(t, r.Users.CreateUser(ctx, &user), apperror.KindConflict)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		r := newRepositories(t)
+		user := createUser(t, r, "a@example.com")
+		wantKind(t, r.Users.GetUserByEmail(ctx, &model.User{}, "b@example.com"), apperror.KindNotFound)
+		wantKind(t, r.Users.GetUserById(ctx, &model.User{}, user.ID+1), apperror.KindNotFound)
+		wantKind(t, r.Users.UpdateUserRole(ctx, user.ID+1, model.RoleAdmin), apperror.KindNotFound)
+		wantKind(t, r.Users.MarkEmailVerified(ctx, user.ID+1), apperror.KindNotFound)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		r := newRepositories(t)
+		user := createUser(t, r, "a@example.com")
+		mustNil(t, r.Users.UpdateUserRole(ctx, user.ID, model.RoleAdmin))
+		mustNil(t, r.Users.MarkEmailVerified(ctx, user.ID))
+		got := model.User{}
+		mustNil(t, r.Users.GetUserById(ctx, &got, user.ID))
+		if got.Role != model.RoleAdmin || !got.EmailVerified {
+			t.Fatalf("updated user = %+v", got)
+		}
+	})
+}
@@ -0,0 +1,63 @@
+package repositorytest
+
+import (
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"testing"
+	"time"
+)
+
+// UserTokenRepository は IUserTokenRepository の契約テストです。
+func UserTokenRepository(t *testing.T, newRepositories Factory) {
+	expiresAt := time.Now().Add(time.Hour)
+
+	t.Run("VerifyEmail", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		old := model.UserToken{Purpose: model.TokenPurposeVerifyEmail, TokenHash: "old", ExpiresAt: expiresAt, UserID: alice.ID}
+		mustNil(t, r.UserTokens.CreateUserToken(ctx, &old))
+		token := model.UserToken{Purpose: model.TokenPurposeVerifyEmail, TokenHash: "new", ExpiresAt: expiresAt, UserID: alice.ID}
+		mustNil(t, r.UserTokens.CreateUserToken(ctx, &token))
+
+		// 新しいトークンを作成すると、同じ用途の古いトークンは使えない
+		_, err := r.UserTokens.VerifyEmail(ctx, "old")
+		wantError(t, err, repository.ErrInvalidUserToken)
+		// 用途の違うトークンは使えない
+		_, err = r.UserTokens.ResetPassword(ctx, "new", "hash")
+		wantError(t, err, repository.ErrInvalidUserToken)
+
+		userId, err := r.UserTokens.VerifyEmail(ctx, "new")
+		mustNil(t, err)
+		if userId != alice.ID {
+			t.Fatalf("VerifyEmail user = %d", userId)
+		}
+		got := model.User{}
+		mustNil(t, r.Users.GetUserById(ctx, &got, alice.ID))
+		if !got.EmailVerified {
+			t.Fatalf("user = %+v", got)
+		}
+		// 一度だけ使える
+		_, err = r.UserTokens.VerifyEmail(ctx, "new")
+		wantError(t, err, repository.ErrInvalidUserToken)
+	})
+
+	t.Run("ResetPassword", func(t *testing.T) {
+		r := newRepositories(t)
+		alice := createUser(t, r, "alice@example.com")
+		mustNil(t, r.RefreshTokens.CreateRefreshToken(ctx, &model.RefreshToken{TokenHash: "rt", FamilyID: "f", ExpiresAt: expiresAt, UserID: alice.ID}))
+		mustNil(t, r.UserTokens.CreateUserToken(ctx, &model.UserToken{Purpose: model.TokenPurposeResetPassword, TokenHash: "reset", ExpiresAt: expiresAt, UserID: alice.ID}))
+		mustNil(t, r.UserTokens.CreateUserToken(ctx, &model.UserToken{Purpose: model.TokenPurposeResetPassword, TokenHash: "expired", ExpiresAt: time.Now().Add(-time.Minute), UserID: createUser(t, r, "bob@example.com").ID}))
+
+		_, err := r.UserTokens.ResetPassword(ctx, "expired", "hash")
+		wantError(t, err, repository.ErrInvalidUserToken)
+		userId, err := r.UserTokens.ResetPassword(ctx, "reset", "new-hash")
+		mustNil(t, err)
+		got := model.User{}
+		mustNil(t, r.Users.GetUserById(ctx, &got, userId))
+		if got.ID != alice.ID || got.Password != "new-hash" {
+			t.Fatalf("user = %+v", got)
+		}
+		// 既存のセッションは全て失効する
+		wantRevoked(t, r, map[string]bool{"rt": true})
+	})
+}
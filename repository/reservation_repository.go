@@ -1,17 +1,40 @@
 package repository
 
 import (
+	"context"
+	"go-rest-api/apperror"
 	"go-rest-api/model"
+	"time"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrCapacityExceeded は予約枠の残り席数が足りない場合に返されます。
+var ErrCapacityExceeded = apperror.Conflict("not enough remaining capacity for the requested slot")
+
+// ErrStatusConflict はステータス変更中に他のリクエストによってステータスが変わっていた場合に返されます。
+var ErrStatusConflict = apperror.Conflict("reservation status was changed by another request")
+
+// releasedStatuses は席を確保しないステータスです。残り席数の計算から除外します。
+var releasedStatuses = []string{
+    model.ReservationCancelledByUser,
+    model.ReservationCancelledByShop,
+    model.ReservationNoShow,
+}
+
 type IReservationRepository interface {
-    MakeReservation(reservation *model.Reservation) (model.Reservation, error)
-    CancelReservation(reservationId string) error
-    GetReservationByUser(userId string) ([]model.Reservation, error)
-    GetAllReservations() ([]model.Reservation, error)
-    UpdateReservation(reservation *model.Reservation) (model.Reservation, error)
-    GetReservationsForBuild() ([]model.Reservation, error)
+    MakeReservation(ctx context.Context, reservation *model.Reservation, change *model.ReservationStatusChange) (model.Reservation, error)
+    ChangeStatus(ctx context.Context, reservation *model.Reservation, status string, change *model.ReservationStatusChange) error
+    GetStatusChanges(ctx context.Context, reservationId uint) ([]model.ReservationStatusChange, error)
+    GetReservation(ctx context.Context, reservation *model.Reservation, reservationId uint) error
+    GetReservationById(ctx context.Context, reservation *model.Reservation, userId uint, reservationId uint) error
+    GetReservationByUser(ctx context.Context, userId uint) ([]model.Reservation, error)
+    GetReservationsByShop(ctx context.Context, shopId uint) ([]model.Reservation, error)
+    GetAllReservations(ctx context.Context, q model.ListQuery) (model.Page[model.Reservation], error)
+    UpdateReservation(ctx context.Context, reservation *model.Reservation, userId uint, reservationId uint) (model.Reservation, error)
+    GetReservationsForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.Reservation], error)
+    GetBookedSeats(ctx context.Context, shopId uint, date time.Time) (map[string]int, error)
 }
 
 type reservationRepository struct {
@@ -22,35 +45,170 @@ func NewReservationRepository(db *gorm.DB) IReservationRepository {
 	return &reservationRepository{db}
 }
 
-func (rr *reservationRepository) MakeReservation(reservation *model.Reservation) (model.Reservation, error) {
-    result := rr.db.Create(reservation)
-    return *reservation, result.Error
+// MakeReservation は予約を作成し、最初のステータスを変更履歴として同じトランザクションで記録します。
+func (rr *reservationRepository) MakeReservation(ctx context.Context, reservation *model.Reservation, change *model.ReservationStatusChange) (model.Reservation, error) {
+    err := rr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := checkCapacity(tx, reservation); err != nil {
+            return err
+        }
+        // コースや変更履歴の関連は作成しない（変更履歴は change のみを記録する）
+        if err := tx.Omit(clause.Associations).Create(reservation).Error; err != nil {
+            return translateError(err)
+        }
+        change.ReservationID = reservation.ID
+        change.FromStatus = ""
+        change.ToStatus = reservation.Status
+        return translateError(tx.Create(change).Error)
+    })
+    return *reservation, err
+}
+
+// ChangeStatus は予約のステータスを変更し、変更履歴を同じトランザクションで記録します。
+// 読み込み時のステータスを条件に更新するため、同時に変更された場合は ErrStatusConflict を返します。
+func (rr *reservationRepository) ChangeStatus(ctx context.Context, reservation *model.Reservation, status string, change *model.ReservationStatusChange) error {
+    return rr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        result := tx.Model(&model.Reservation{}).
+            Where("id=? AND status=?", reservation.ID, reservation.Status).
+            Update("status", status)
+        if result.Error != nil {
+            return translateError(result.Error)
+        }
+        if result.RowsAffected < 1 {
+            return ErrStatusConflict
+        }
+        change.ReservationID = reservation.ID
+        change.FromStatus = reservation.Status
+        change.ToStatus = status
+        if err := tx.Create(change).Error; err != nil {
+            return translateError(err)
+        }
+        reservation.Status = status
+        return nil
+    })
+}
+
+// GetStatusChanges は予約のステータスの変更履歴を古い順に返します。
+func (rr *reservationRepository) GetStatusChanges(ctx context.Context, reservationId uint) ([]model.ReservationStatusChange, error) {
+    changes := []model.ReservationStatusChange{}
+    result := rr.db.WithContext(ctx).Where("reservation_id = ?", reservationId).Order("id").Find(&changes)
+    return changes, translateError(result.Error)
+}
+
+func (rr *reservationRepository) GetReservation(ctx context.Context, reservation *model.Reservation, reservationId uint) error {
+    if err := rr.db.WithContext(ctx).First(reservation, reservationId).Error; err != nil {
+        return translateError(err)
+    }
+    return nil
 }
 
-func (rr *reservationRepository) CancelReservation(reservationId string) error {
-    result := rr.db.Delete(&model.Reservation{}, reservationId)
-    return result.Error
+func (rr *reservationRepository) GetReservationById(ctx context.Context, reservation *model.Reservation, userId uint, reservationId uint) error {
+    if err := rr.db.WithContext(ctx).Where("user_id=?", userId).First(reservation, reservationId).Error; err != nil {
+        return translateError(err)
+    }
+    return nil
 }
 
-func (rr *reservationRepository) GetReservationByUser(userId string) ([]model.Reservation, error) {
+func (rr *reservationRepository) GetReservationByUser(ctx context.Context, userId uint) ([]model.Reservation, error) {
     var reservations []model.Reservation
-    result := rr.db.Where("user_id = ?", userId).Find(&reservations)
-    return reservations, result.Error
+    result := rr.db.WithContext(ctx).Where("user_id = ?", userId).Order("date, time").Find(&reservations)
+    return reservations, translateError(result.Error)
 }
 
-func (rr *reservationRepository) GetAllReservations() ([]model.Reservation, error) {
+func (rr *reservationRepository) GetReservationsByShop(ctx context.Context, shopId uint) ([]model.Reservation, error) {
     var reservations []model.Reservation
-    result := rr.db.Find(&reservations)
-    return reservations, result.Error
+    result := rr.db.WithContext(ctx).Where("shop_id = ?", shopId).Order("date, time").Find(&reservations)
+    return reservations, translateError(result.Error)
 }
 
-func (rr *reservationRepository) UpdateReservation(reservation *model.Reservation) (model.Reservation, error) {
-    result := rr.db.Save(reservation)
-    return *reservation, result.Error
+// reservationListSpec は予約一覧で使える並び替え・絞り込みの項目です。
+var reservationListSpec = listSpec{
+    sorts: map[string]string{
+        "id":   "id",
+        "date": "date",
+    },
+    defaultSort: "date",
+    filters: map[string]string{
+        "status":  "status",
+        "shop_id": "shop_id",
+        "user_id": "user_id",
+    },
+    dateColumn: "date",
 }
 
-func (rr *reservationRepository) GetReservationsForBuild() ([]model.Reservation, error) {
-    var reservations []model.Reservation
-    result := rr.db.Preload("User").Find(&reservations) // ここでは関連するユーザー情報も取得します
-    return reservations, result.Error
+func (rr *reservationRepository) GetAllReservations(ctx context.Context, q model.ListQuery) (model.Page[model.Reservation], error) {
+    return paginate[model.Reservation](rr.db.WithContext(ctx), reservationListSpec, q)
+}
+
+func (rr *reservationRepository) UpdateReservation(ctx context.Context, reservation *model.Reservation, userId uint, reservationId uint) (model.Reservation, error) {
+    err := rr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+        if err := checkCapacity(tx, reservation); err != nil {
+            return err
+        }
+        result := tx.Model(reservation).Where("id=? AND user_id=?", reservationId, userId).Updates(map[string]interface{}{
+            "date":        reservation.Date,
+            "time":        reservation.Time,
+            "num":         reservation.Num,
+            "course_id":   reservation.CourseID,
+            "total_price": reservation.TotalPrice,
+        })
+        if result.Error != nil {
+            return translateError(result.Error)
+        }
+        if result.RowsAffected < 1 {
+            return apperror.NotFound("object does not exist")
+        }
+        return nil
+    })
+    return *reservation, err
+}
+
+func (rr *reservationRepository) GetReservationsForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.Reservation], error) {
+    // Reservation にはユーザーの関連がないため、予約の行のみを返します
+    return paginate[model.Reservation](rr.db.WithContext(ctx), reservationListSpec, q)
+}
+
+// GetBookedSeats は指定した日のショップの予約済み席数を時刻ごとに返します。
+func (rr *reservationRepository) GetBookedSeats(ctx context.Context, shopId uint, date time.Time) (map[string]int, error) {
+    var rows []struct {
+        Time   string
+        Booked int
+    }
+    err := rr.db.WithContext(ctx).Model(&model.Reservation{}).
+        Select("time, COALESCE(SUM(num), 0) AS booked").
+        Where("shop_id = ? AND date = ?", shopId, date).
+        Where("status NOT IN ?", releasedStatuses).
+        Group("time").
+        Scan(&rows).Error
+    if err != nil {
+        return nil, translateError(err)
+    }
+    booked := make(map[string]int, len(rows))
+    for _, row := range rows {
+        booked[row.Time] = row.Booked
+    }
+    return booked, nil
+}
+
+// checkCapacity はショップの行をロックした上で、予約枠の残り席数が足りるかを確認します。
+// ロックにより同じショップへの同時予約が直列化されるため、オーバーブッキングを防げます。
+func checkCapacity(tx *gorm.DB, reservation *model.Reservation) error {
+    shop := model.Shop{}
+    if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&shop, reservation.ShopID).Error; err != nil {
+        return translateError(err)
+    }
+    var booked int
+    query := tx.Model(&model.Reservation{}).
+        Select("COALESCE(SUM(num), 0)").
+        Where("shop_id = ? AND date = ? AND time = ?", reservation.ShopID, reservation.Date, reservation.Time).
+        Where("status NOT IN ?", releasedStatuses)
+    if reservation.ID != 0 {
+        query = query.Where("id <> ?", reservation.ID)
+    }
+    if err := query.Scan(&booked).Error; err != nil {
+        return translateError(err)
+    }
+    if booked+reservation.Num > shop.Capacity {
+        return ErrCapacityExceeded
+    }
+    return nil
 }
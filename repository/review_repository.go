@@ -0,0 +1,164 @@
+package repository
+
+import (
+	"context"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+
+	"gorm.io/gorm"
+)
+
+// ErrAlreadyReported は同じユーザーが同じレビューを再度通報した場合に返されます。
+var ErrAlreadyReported = apperror.Conflict("review already reported")
+
+type IReviewRepository interface {
+	GetShopReviews(ctx context.Context, shopId uint, q model.ListQuery) (model.Page[model.Review], error)
+	GetUserReviews(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.Review], error)
+	GetReportedReviews(ctx context.Context, q model.ListQuery) (model.Page[model.Review], error)
+	GetReviewById(ctx context.Context, review *model.Review, reviewId uint) error
+	CreateReview(ctx context.Context, review *model.Review) error
+	UpdateReview(ctx context.Context, review *model.Review, userId uint, reviewId uint) error
+	DeleteReview(ctx context.Context, userId uint, reviewId uint) error
+	ReplyReview(ctx context.Context, review *model.Review, shopId uint, reviewId uint) error
+	ReportReview(ctx context.Context, report *model.ReviewReport) error
+	SetReviewHidden(ctx context.Context, review *model.Review, reviewId uint, hidden bool) error
+}
+
+type reviewRepository struct {
+	db *gorm.DB
+}
+
+func NewReviewRepository(db *gorm.DB) IReviewRepository {
+	return &reviewRepository{db}
+}
+
+// reviewListSpec はレビュー一覧で使える並び替え・絞り込みの項目です。
+var reviewListSpec = listSpec{
+	sorts: map[string]string{
+		"id":           "id",
+		"rating":       "rating",
+		"created_at":   "created_at",
+		"report_count": "report_count",
+	},
+	defaultSort:  "created_at",
+	defaultOrder: model.SortDesc,
+	filters: map[string]string{
+		"rating":   "rating",
+		"verified": "verified",
+	},
+	dateColumn: "created_at",
+}
+
+// GetShopReviews はショップの公開中のレビューを返します。
+func (rr *reviewRepository) GetShopReviews(ctx context.Context, shopId uint, q model.ListQuery) (model.Page[model.Review], error) {
+	return paginate[model.Review](rr.db.WithContext(ctx).Where("shop_id=? AND hidden=?", shopId, false), reviewListSpec, q)
+}
+
+func (rr *reviewRepository) GetUserReviews(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.Review], error) {
+	return paginate[model.Review](rr.db.WithContext(ctx).Where("user_id=?", userId), reviewListSpec, q)
+}
+
+// GetReportedReviews は通報されたレビューを非公開のものも含めて返します。
+func (rr *reviewRepository) GetReportedReviews(ctx context.Context, q model.ListQuery) (model.Page[model.Review], error) {
+	return paginate[model.Review](rr.db.WithContext(ctx).Where("report_count > 0"), reviewListSpec, q)
+}
+
+func (rr *reviewRepository) GetReviewById(ctx context.Context, review *model.Review, reviewId uint) error {
+	if err := rr.db.WithContext(ctx).First(review, reviewId).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+func (rr *reviewRepository) CreateReview(ctx context.Context, review *model.Review) error {
+	err := rr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(review).Error; err != nil {
+			return err
+		}
+		return refreshShopRating(tx, review.ShopID)
+	})
+	return translateError(err)
+}
+
+func (rr *reviewRepository) UpdateReview(ctx context.Context, review *model.Review, userId uint, reviewId uint) error {
+	err := rr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := updateReturning(tx, review, map[string]interface{}{
+			"rating":  review.Rating,
+			"comment": review.Comment,
+		}, "id=? AND user_id=?", reviewId, userId)
+		if err != nil {
+			return err
+		}
+		return refreshShopRating(tx, review.ShopID)
+	})
+	return translateError(err)
+}
+
+func (rr *reviewRepository) DeleteReview(ctx context.Context, userId uint, reviewId uint) error {
+	err := rr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		review := model.Review{}
+		if err := deleteReturning(tx, &review, "id=? AND user_id=?", reviewId, userId); err != nil {
+			return err
+		}
+		return refreshShopRating(tx, review.ShopID)
+	})
+	return translateError(err)
+}
+
+// ReplyReview はショップのレビューにオーナーの返信を記録します。空の返信は返信の削除です。
+func (rr *reviewRepository) ReplyReview(ctx context.Context, review *model.Review, shopId uint, reviewId uint) error {
+	err := updateReturning(rr.db.WithContext(ctx), review, map[string]interface{}{
+		"reply":      review.Reply,
+		"replied_at": review.RepliedAt,
+	}, "id=? AND shop_id=?", reviewId, shopId)
+	if err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+// ReportReview は通報を記録し、レビューの通報回数を増やします。
+func (rr *reviewRepository) ReportReview(ctx context.Context, report *model.ReviewReport) error {
+	err := rr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(report).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.Review{}).Where("id=?", report.ReviewID).
+			UpdateColumn("report_count", gorm.Expr("report_count + 1")).Error
+	})
+	if apperror.Is(translateError(err), apperror.KindConflict) {
+		return ErrAlreadyReported
+	}
+	return translateError(err)
+}
+
+// SetReviewHidden はレビューの公開・非公開を切り替え、ショップの評価を集計し直します。
+func (rr *reviewRepository) SetReviewHidden(ctx context.Context, review *model.Review, reviewId uint, hidden bool) error {
+	err := rr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := updateReturning(tx, review, map[string]interface{}{"hidden": hidden}, "id=?", reviewId)
+		if err != nil {
+			return err
+		}
+		return refreshShopRating(tx, review.ShopID)
+	})
+	return translateError(err)
+}
+
+// refreshShopRating はショップの公開中のレビューから平均評価と件数を集計し直します。
+func refreshShopRating(tx *gorm.DB, shopId uint) error {
+	stats := struct {
+		Average float64
+		Count   int
+	}{}
+	err := tx.Model(&model.Review{}).
+		Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Where("shop_id=? AND hidden=?", shopId, false).
+		Scan(&stats).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&model.Shop{}).Where("id=?", shopId).UpdateColumns(map[string]interface{}{
+		"rating_average": stats.Average,
+		"rating_count":   stats.Count,
+	}).Error
+}
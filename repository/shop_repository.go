@@ -1,19 +1,34 @@
 package repository
 
 import (
-	"fmt"
+	"context"
+	"go-rest-api/apperror"
 	"go-rest-api/model"
+	"math"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
 )
 
 type IShopRepository interface {
-	GetAllShops(shops *[]model.Shop) error
-	GetShopById(shop *model.Shop, shopId uint) error
-	CreateShop(shop *model.Shop) error
-	UpdateShop(shop *model.Shop, shopId uint) error
-	DeleteShop(shopId uint) error
+	GetAllShops(ctx context.Context, q model.ListQuery) (model.Page[model.Shop], error)
+	SearchShops(ctx context.Context, q model.ShopSearchQuery) (model.Page[model.Shop], model.ShopFacets, error)
+	GetNearbyShops(ctx context.Context, lat, lng, radius float64, limit, offset int) (model.Page[model.NearbyShop], error)
+	ReplaceShopHours(ctx context.Context, shopId uint, hours []model.ShopHour) error
+	CreateShopClosure(ctx context.Context, closure *model.ShopClosure) error
+	DeleteShopClosure(ctx context.Context, shopId uint, closureId uint) error
+	CreateShopImage(ctx context.Context, image *model.ShopImage) error
+	ReorderShopImages(ctx context.Context, shopId uint, imageIds []uint) error
+	DeleteShopImage(ctx context.Context, image *model.ShopImage, shopId uint, imageId uint) error
+	GetShopById(ctx context.Context, shop *model.Shop, shopId uint) error
+	CreateShop(ctx context.Context, shop *model.Shop) error
+	UpdateShop(ctx context.Context, shop *model.Shop, shopId uint) error
+	DeleteShop(ctx context.Context, shopId uint) error
+	GetShopsByExternalIDs(ctx context.Context, externalIds []string) ([]model.Shop, error)
+	GetShopsForExport(ctx context.Context) ([]model.Shop, error)
+	SaveImportedShops(ctx context.Context, shops []*model.Shop) error
 }
 
 type shopRepository struct {
@@ -24,51 +39,431 @@ func NewShopRepository(db *gorm.DB) IShopRepository {
 	return &shopRepository{db}
 }
 
-func (sr *shopRepository) GetAllShops(shops *[]model.Shop) error {
-	if err := sr.db.Order("created_at").Find(shops).Error; err != nil {
-		return err
+// shopListSpec はショップ一覧で使える並び替え・絞り込みの項目です。
+var shopListSpec = listSpec{
+	sorts: map[string]string{
+		"id":         "id",
+		"name":       "name",
+		"area":       "area",
+		"genre":      "genre",
+		"created_at": "created_at",
+		"rating":     "rating_average",
+	},
+	defaultSort: "created_at",
+	filters: map[string]string{
+		"area":  "area",
+		"genre": "genre",
+	},
+	dateColumn: "created_at",
+}
+
+func (sr *shopRepository) GetAllShops(ctx context.Context, q model.ListQuery) (model.Page[model.Shop], error) {
+	page, err := paginate[model.Shop](sr.db.WithContext(ctx), shopListSpec, q)
+	if err != nil {
+		return page, err
+	}
+	return page, sr.loadAssociations(ctx, shopPointers(page.Items)...)
+}
+
+// shopSearchDocument は PostgreSQL の全文検索の対象です。
+// マイグレーション（migration/sql/postgres/0001_baseline.up.sql）の idx_shops_search と同じ式を使う必要があります。
+const shopSearchDocument = "to_tsvector('simple', name || ' ' || description || ' ' || address)"
+
+// SearchShops は検索語・エリア・ジャンル・営業時間で絞り込んだショップと、エリア・ジャンルごとの件数を返します。
+// 検索語を指定した場合は関連度の高い順、指定しない場合は登録順に並べます。
+func (sr *shopRepository) SearchShops(ctx context.Context, q model.ShopSearchQuery) (model.Page[model.Shop], model.ShopFacets, error) {
+	page := model.Page[model.Shop]{Items: []model.Shop{}, Limit: q.Limit, Offset: q.Offset}
+	facets := model.ShopFacets{Area: []model.FacetCount{}, Genre: []model.FacetCount{}}
+	if page.Limit <= 0 {
+		page.Limit = model.DefaultListLimit
+	}
+
+	if err := sr.searchQuery(ctx, q, "").Count(&page.Total).Error; err != nil {
+		return page, facets, translateError(err)
+	}
+
+	order := clause.OrderBy{Columns: []clause.OrderByColumn{{Column: clause.Column{Name: "created_at"}}, {Column: clause.Column{Name: "id"}}}}
+	if q.Text != "" && isPostgres(sr.db) {
+		order = clause.OrderBy{Expression: clause.Expr{
+			SQL:  "ts_rank(" + shopSearchDocument + ", plainto_tsquery('simple', ?)) + similarity(name, ?) DESC, id",
+			Vars: []interface{}{q.Text, q.Text},
+		}}
+	} else if q.Text != "" {
+		// 全文検索のない SQLite では店名に一致するショップを先に並べる
+		order = clause.OrderBy{Expression: clause.Expr{
+			SQL:  `CASE WHEN name LIKE ? ESCAPE '\' THEN 0 ELSE 1 END, id`,
+			Vars: []interface{}{"%" + escapeLike(q.Text) + "%"},
+		}}
+	}
+	err := sr.searchQuery(ctx, q, "").
+		Clauses(order).
+		Limit(page.Limit).
+		Offset(page.Offset).
+		Find(&page.Items).Error
+	if err != nil {
+		return page, facets, translateError(err)
+	}
+	if err := sr.loadAssociations(ctx, shopPointers(page.Items)...); err != nil {
+		return page, facets, err
+	}
+
+	if err := sr.facetCounts(ctx, q, "area", &facets.Area); err != nil {
+		return page, facets, err
+	}
+	if err := sr.facetCounts(ctx, q, "genre", &facets.Genre); err != nil {
+		return page, facets, err
+	}
+	return page, facets, nil
+}
+
+// searchQuery は検索条件を適用したクエリです。except に指定した項目の絞り込みは適用しません。
+func (sr *shopRepository) searchQuery(ctx context.Context, q model.ShopSearchQuery, except string) *gorm.DB {
+	query := sr.db.WithContext(ctx).Model(&model.Shop{})
+	if q.Text != "" && isPostgres(sr.db) {
+		pattern := "%" + escapeLike(q.Text) + "%"
+		query = query.Where(
+			"("+shopSearchDocument+" @@ plainto_tsquery('simple', ?) OR name ILIKE ? OR description ILIKE ? OR address ILIKE ?)",
+			q.Text, pattern, pattern, pattern,
+		)
+	} else if q.Text != "" {
+		// SQLite の LIKE は ASCII の大文字・小文字を区別しない
+		pattern := "%" + escapeLike(q.Text) + "%"
+		query = query.Where(
+			`(name LIKE ? ESCAPE '\' OR description LIKE ? ESCAPE '\' OR address LIKE ? ESCAPE '\')`,
+			pattern, pattern, pattern,
+		)
+	}
+	if q.Area != "" && except != "area" {
+		query = query.Where("area = ?", q.Area)
+	}
+	if q.Genre != "" && except != "genre" {
+		query = query.Where("genre = ?", q.Genre)
+	}
+	if q.OpenAt != nil {
+		query = query.Where(openAtCondition(*q.OpenAt))
+	}
+	return query
+}
+
+func (sr *shopRepository) facetCounts(ctx context.Context, q model.ShopSearchQuery, column string, counts *[]model.FacetCount) error {
+	err := sr.searchQuery(ctx, q, column).
+		Select(column + " AS value, COUNT(*) AS count").
+		Group(column).
+		Order("count DESC, value").
+		Scan(counts).Error
+	return translateError(err)
+}
+
+// escapeLike は LIKE のパターンで特別な意味を持つ文字をエスケープします。
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// earthRadius は地球の平均半径（メートル）です。
+const earthRadius = 6371000.0
+
+// GetNearbyShops は指定地点から radius メートル以内のショップを近い順に返します。
+// 緯度・経度の範囲でインデックスを使って候補を絞り込んだ後、球面上の距離で判定します。
+func (sr *shopRepository) GetNearbyShops(ctx context.Context, lat, lng, radius float64, limit, offset int) (model.Page[model.NearbyShop], error) {
+	page := model.Page[model.NearbyShop]{Items: []model.NearbyShop{}, Limit: limit, Offset: offset}
+	if page.Limit <= 0 {
+		page.Limit = model.DefaultListLimit
+	}
+
+	latDelta := radius / earthRadius * 180 / math.Pi
+	lngDelta := 180.0
+	if c := math.Cos(lat * math.Pi / 180); c > 1e-6 {
+		lngDelta = math.Min(latDelta/c, 180)
+	}
+	// 2引数の MIN は SQLite で LEAST と同じ意味になる
+	least := "MIN"
+	if isPostgres(sr.db) {
+		least = "LEAST"
+	}
+	distance := clause.Expr{
+		SQL:  "? * 2 * ASIN(SQRT(" + least + "(1, POWER(SIN(RADIANS(latitude - ?) / 2), 2) + COS(RADIANS(?)) * COS(RADIANS(latitude)) * POWER(SIN(RADIANS(longitude - ?) / 2), 2))))",
+		Vars: []interface{}{earthRadius, lat, lat, lng},
+	}
+	candidates := sr.db.WithContext(ctx).Model(&model.Shop{}).
+		Select("shops.*, ? AS distance", distance).
+		Where("latitude BETWEEN ? AND ?", lat-latDelta, lat+latDelta)
+	// 経度の範囲が日付変更線をまたぐ場合は経度で絞り込まない
+	if lng-lngDelta >= -180 && lng+lngDelta <= 180 {
+		candidates = candidates.Where("longitude BETWEEN ? AND ?", lng-lngDelta, lng+lngDelta)
+	}
+	query := func() *gorm.DB {
+		return sr.db.WithContext(ctx).Table("(?) AS shops", candidates).Where("distance <= ?", radius)
+	}
+
+	if err := query().Count(&page.Total).Error; err != nil {
+		return page, translateError(err)
+	}
+	err := query().
+		Order("distance, id").
+		Limit(page.Limit).
+		Offset(page.Offset).
+		Find(&page.Items).Error
+	if err != nil {
+		return page, translateError(err)
+	}
+	shops := make([]*model.Shop, 0, len(page.Items))
+	for i := range page.Items {
+		shops = append(shops, &page.Items[i].Shop)
+	}
+	return page, sr.loadAssociations(ctx, shops...)
+}
+
+// openAtCondition は指定した時刻に営業しているショップの条件です。model.Shop.IsOpenAt と同じ判定を行います。
+// 曜日ごとの営業時間がないショップは open_time〜close_time を毎日の営業時間として判定します。
+// 前日から日付をまたぐ営業時間帯は前日の営業として扱い、前日の終日休業の場合のみ休みです（当日の休業は影響しません）。
+func openAtCondition(t time.Time) clause.Expr {
+	clock := t.Format("15:04")
+	weekday := int(t.Weekday())
+	yesterday := (weekday + 6) % 7
+	date := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return clause.Expr{
+		SQL: `(((EXISTS (SELECT 1 FROM shop_hours h WHERE h.shop_id = shops.id
+					AND h.weekday = ? AND h.open_time <= ? AND (? < h.close_time OR h.close_time <= h.open_time))
+				OR (NOT EXISTS (SELECT 1 FROM shop_hours h WHERE h.shop_id = shops.id)
+					AND shops.open_time <= ? AND (? < shops.close_time OR shops.close_time <= shops.open_time)))
+			AND NOT EXISTS (SELECT 1 FROM shop_closures c WHERE c.shop_id = shops.id AND c.date = ?
+				AND (c.start_time = '' OR (c.start_time <= ? AND ? < c.end_time))))
+			OR ((EXISTS (SELECT 1 FROM shop_hours h WHERE h.shop_id = shops.id
+					AND h.weekday = ? AND h.close_time <= h.open_time AND ? < h.close_time)
+				OR (NOT EXISTS (SELECT 1 FROM shop_hours h WHERE h.shop_id = shops.id)
+					AND shops.close_time <= shops.open_time AND ? < shops.close_time))
+				AND NOT EXISTS (SELECT 1 FROM shop_closures c WHERE c.shop_id = shops.id AND c.date = ? AND c.start_time = '')))`,
+		Vars: []interface{}{weekday, clock, clock, clock, clock, date, clock, clock, yesterday, clock, clock, date.AddDate(0, 0, -1)},
+	}
+}
+
+// loadAssociations はショップの曜日ごとの営業時間、今日以降の休業、ギャラリーの画像をまとめて読み込みます。
+func (sr *shopRepository) loadAssociations(ctx context.Context, shops ...*model.Shop) error {
+	if len(shops) == 0 {
+		return nil
+	}
+	ids := make([]uint, 0, len(shops))
+	for _, shop := range shops {
+		ids = append(ids, shop.ID)
+	}
+	hours := []model.ShopHour{}
+	if err := sr.db.WithContext(ctx).Where("shop_id IN ?", ids).Order("weekday, open_time").Find(&hours).Error; err != nil {
+		return translateError(err)
+	}
+	// 前日から日付をまたぐ営業時間帯の判定のため、前日の休業から読み込む。
+	// ショップのタイムゾーンでの前日は UTC の2日前になることがあるため、2日前から読み込む
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day()-2, 0, 0, 0, 0, time.UTC)
+	closures := []model.ShopClosure{}
+	if err := sr.db.WithContext(ctx).Where("shop_id IN ? AND date >= ?", ids, from).Order("date, start_time").Find(&closures).Error; err != nil {
+		return translateError(err)
+	}
+	images := []model.ShopImage{}
+	if err := sr.db.WithContext(ctx).Where("shop_id IN ?", ids).Order("position, id").Find(&images).Error; err != nil {
+		return translateError(err)
+	}
+	for _, shop := range shops {
+		shop.Hours = []model.ShopHour{}
+		shop.Closures = []model.ShopClosure{}
+		shop.Images = []model.ShopImage{}
+		for _, h := range hours {
+			if h.ShopID == shop.ID {
+				shop.Hours = append(shop.Hours, h)
+			}
+		}
+		for _, c := range closures {
+			if c.ShopID == shop.ID {
+				shop.Closures = append(shop.Closures, c)
+			}
+		}
+		for _, img := range images {
+			if img.ShopID == shop.ID {
+				shop.Images = append(shop.Images, img)
+			}
+		}
+	}
+	return nil
+}
+
+func shopPointers(shops []model.Shop) []*model.Shop {
+	pointers := make([]*model.Shop, 0, len(shops))
+	for i := range shops {
+		pointers = append(pointers, &shops[i])
+	}
+	return pointers
+}
+
+// ReplaceShopHours はショップの曜日ごとの営業時間をまとめて置き換えます。
+func (sr *shopRepository) ReplaceShopHours(ctx context.Context, shopId uint, hours []model.ShopHour) error {
+	err := sr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("shop_id=?", shopId).Delete(&model.ShopHour{}).Error; err != nil {
+			return err
+		}
+		if len(hours) == 0 {
+			return nil
+		}
+		for i := range hours {
+			hours[i].ID = 0
+			hours[i].ShopID = shopId
+		}
+		return tx.Create(&hours).Error
+	})
+	return translateError(err)
+}
+
+func (sr *shopRepository) CreateShopClosure(ctx context.Context, closure *model.ShopClosure) error {
+	if err := sr.db.WithContext(ctx).Create(closure).Error; err != nil {
+		return translateError(err)
 	}
 	return nil
 }
 
-func (sr *shopRepository) GetShopById(shop *model.Shop, shopId uint) error {
-	if err := sr.db.First(shop, shopId).Error; err != nil {
-		return err
+func (sr *shopRepository) DeleteShopClosure(ctx context.Context, shopId uint, closureId uint) error {
+	result := sr.db.WithContext(ctx).Where("id=? AND shop_id=?", closureId, shopId).Delete(&model.ShopClosure{})
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected < 1 {
+		return apperror.NotFound("object does not exist")
 	}
 	return nil
 }
 
-func (sr *shopRepository) CreateShop(shop *model.Shop) error {
-	if err := sr.db.Create(shop).Error; err != nil {
-		return err
+func (sr *shopRepository) GetShopById(ctx context.Context, shop *model.Shop, shopId uint) error {
+	if err := sr.db.WithContext(ctx).First(shop, shopId).Error; err != nil {
+		return translateError(err)
+	}
+	return sr.loadAssociations(ctx, shop)
+}
+
+func (sr *shopRepository) CreateShop(ctx context.Context, shop *model.Shop) error {
+	if err := sr.db.WithContext(ctx).Create(shop).Error; err != nil {
+		return translateError(err)
 	}
 	return nil
 }
 
-func (sr *shopRepository) UpdateShop(shop *model.Shop, shopId uint) error {
-	result := sr.db.Model(shop).Clauses(clause.Returning{}).Where("id=?", shopId).Updates(map[string]interface{}{
+func (sr *shopRepository) UpdateShop(ctx context.Context, shop *model.Shop, shopId uint) error {
+	err := updateReturning(sr.db.WithContext(ctx), shop, map[string]interface{}{
 		"name":        shop.Name,
 		"address":     shop.Address,
+		"postal_code":  shop.PostalCode,
+		"latitude":     shop.Latitude,
+		"longitude":    shop.Longitude,
 		"area":        shop.Area,
 		"genre":       shop.Genre,
-		"description": shop.Description,
-	})
+		"description":  shop.Description,
+		"capacity":     shop.Capacity,
+		"open_time":    shop.OpenTime,
+		"close_time":   shop.CloseTime,
+		"slot_minutes": shop.SlotMinutes,
+		"owner_id":     shop.OwnerID,
+	}, "id=?", shopId)
+	if err != nil {
+		return translateError(err)
+	}
+	return sr.loadAssociations(ctx, shop)
+}
+
+func (sr *shopRepository) DeleteShop(ctx context.Context, shopId uint) error {
+	result := sr.db.WithContext(ctx).Where("id=?", shopId).Delete(&model.Shop{})
 	if result.Error != nil {
-		return result.Error
+		return translateError(result.Error)
 	}
 	if result.RowsAffected < 1 {
-		return fmt.Errorf("object does not exist")
+		return apperror.NotFound("object does not exist")
 	}
 	return nil
 }
 
-func (sr *shopRepository) DeleteShop(shopId uint) error {
-	result := sr.db.Where("id=?", shopId).Delete(&model.Shop{})
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected < 1 {
-		return fmt.Errorf("object does not exist")
+// CreateShopImage は画像をギャラリーの最後に追加します。
+func (sr *shopRepository) CreateShopImage(ctx context.Context, image *model.ShopImage) error {
+	err := sr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.ShopImage{}).Where("shop_id=?", image.ShopID).
+			Select("COALESCE(MAX(position), -1) + 1").Scan(&image.Position).Error
+		if err != nil {
+			return err
+		}
+		return tx.Create(image).Error
+	})
+	return translateError(err)
+}
+
+// ReorderShopImages はギャラリーの画像を imageIds の順に並べ替えます。imageIds にはショップの全ての画像を指定します。
+func (sr *shopRepository) ReorderShopImages(ctx context.Context, shopId uint, imageIds []uint) error {
+	err := sr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i, id := range imageIds {
+			result := tx.Model(&model.ShopImage{}).Where("id=? AND shop_id=?", id, shopId).Update("position", i)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected < 1 {
+				return apperror.NotFound("object does not exist")
+			}
+		}
+		return nil
+	})
+	return translateError(err)
+}
+
+func (sr *shopRepository) DeleteShopImage(ctx context.Context, image *model.ShopImage, shopId uint, imageId uint) error {
+	if err := deleteReturning(sr.db.WithContext(ctx), image, "id=? AND shop_id=?", imageId, shopId); err != nil {
+		return translateError(err)
 	}
 	return nil
 }
+
+// GetShopsByExternalIDs は外部IDが一致するショップを返します。
+func (sr *shopRepository) GetShopsByExternalIDs(ctx context.Context, externalIds []string) ([]model.Shop, error) {
+	shops := []model.Shop{}
+	if len(externalIds) == 0 {
+		return shops, nil
+	}
+	err := sr.db.WithContext(ctx).Where("external_id IN ?", externalIds).Find(&shops).Error
+	return shops, translateError(err)
+}
+
+// GetShopsForExport は書き出し用に全てのショップを ID 順に返します。
+func (sr *shopRepository) GetShopsForExport(ctx context.Context) ([]model.Shop, error) {
+	shops := []model.Shop{}
+	err := sr.db.WithContext(ctx).Order("id").Find(&shops).Error
+	return shops, translateError(err)
+}
+
+// SaveImportedShops は取り込んだショップを1つのトランザクションで保存します。
+// ID が設定されたショップは取り込みの対象項目のみを更新し、オーナーや評価の集計は変更しません。
+func (sr *shopRepository) SaveImportedShops(ctx context.Context, shops []*model.Shop) error {
+	err := sr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, shop := range shops {
+			if shop.ID == 0 {
+				if err := tx.Create(shop).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			result := tx.Model(shop).Updates(map[string]interface{}{
+				"external_id":  shop.ExternalID,
+				"name":         shop.Name,
+				"address":      shop.Address,
+				"postal_code":  shop.PostalCode,
+				"latitude":     shop.Latitude,
+				"longitude":    shop.Longitude,
+				"area":         shop.Area,
+				"genre":        shop.Genre,
+				"description":  shop.Description,
+				"capacity":     shop.Capacity,
+				"open_time":    shop.OpenTime,
+				"close_time":   shop.CloseTime,
+				"slot_minutes": shop.SlotMinutes,
+			})
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected < 1 {
+				return apperror.NotFound("object does not exist")
+			}
+		}
+		return nil
+	})
+	return translateError(err)
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"context"
+	"go-rest-api/apperror"
+	"go-rest-api/config"
+	"go-rest-api/db"
+	"go-rest-api/migration"
+	"go-rest-api/model"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+// SQLite で PostgreSQL 固有の機能の代わりに使う処理のテストです。
+// PostgreSQL の処理はデータベースのサーバーが必要なため、ここでは確認しません。
+
+func newSQLite(t *testing.T) *gorm.DB {
+	t.Helper()
+	conn, err := db.Open(config.Database{Driver: config.DriverSQLite, SQLitePath: ":memory:"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.CloseDB(conn) })
+	migrator, err := migration.NewMigrator(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestSQLiteIsNotPostgres(t *testing.T) {
+	if isPostgres(newSQLite(t)) {
+		t.Fatal("isPostgres(sqlite) = true")
+	}
+}
+
+func TestSQLiteSearchShopsText(t *testing.T) {
+	ctx := context.Background()
+	sr := NewShopRepository(newSQLite(t))
+	create := func(name, address string) model.Shop {
+		shop := model.Shop{Name: name, Address: address, Area: "東京都", Genre: "寿司"}
+		if err := sr.CreateShop(ctx, &shop); err != nil {
+			t.Fatal(err)
+		}
+		return shop
+	}
+	street := create("Ramen", "Sushi street")
+	bar := create("Sushi Bar", "Tokyo")
+	percent := create("100% Curry", "Tokyo")
+	create("1000 Curry", "Tokyo")
+	underscore := create("a_b", "Tokyo")
+	create("axb", "Tokyo")
+
+	for _, tc := range []struct {
+		text string
+		want []uint
+	}{
+		// 大文字・小文字を区別せず、店名に一致するショップを先に並べる
+		{"SUSHI", []uint{bar.ID, street.ID}},
+		// LIKE の % と _ は文字として扱う
+		{"100%", []uint{percent.ID}},
+		{"a_b", []uint{underscore.ID}},
+	} {
+		page, _, err := sr.SearchShops(ctx, model.ShopSearchQuery{Text: tc.text})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []uint{}
+		for _, shop := range page.Items {
+			got = append(got, shop.ID)
+		}
+		if len(got) != len(tc.want) || page.Total != int64(len(tc.want)) {
+			t.Fatalf("search %q = %v, want %v", tc.text, got, tc.want)
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Fatalf("search %q = %v, want %v", tc.text, got, tc.want)
+			}
+		}
+	}
+}
+
+func TestSQLiteNearbyShopsAcrossDateLine(t *testing.T) {
+	ctx := context.Background()
+	sr := NewShopRepository(newSQLite(t))
+	lat, lng := 0.0, -179.995
+	shop := model.Shop{Name: "Fiji", Address: "Pacific", Area: "海外", Genre: "寿司", Latitude: &lat, Longitude: &lng}
+	if err := sr.CreateShop(ctx, &shop); err != nil {
+		t.Fatal(err)
+	}
+	// 日付変更線の反対側からの距離も球面上の距離で判定する（MIN が LEAST の代わりになる）
+	page, err := sr.GetNearbyShops(ctx, 0, 179.995, 2000, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if page.Total != 1 || page.Items[0].Distance > 1200 {
+		t.Fatalf("nearby across the date line = %+v", page.Items)
+	}
+}
+
+func TestSQLiteUpdateAndDeleteReturning(t *testing.T) {
+	conn := newSQLite(t)
+	user := model.User{Email: "a@example.com", Password: "password"}
+	if err := conn.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	task := model.Task{Title: "a", UserId: user.ID}
+	if err := conn.Create(&task).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	updated := model.Task{}
+	if err := updateReturning(conn, &updated, map[string]interface{}{"title": "b"}, "id=? AND user_id=?", task.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if updated.ID != task.ID || updated.Title != "b" {
+		t.Fatalf("updated task = %+v", updated)
+	}
+	err := updateReturning(conn, &model.Task{}, map[string]interface{}{"title": "c"}, "id=? AND user_id=?", task.ID, user.ID+1)
+	if !apperror.Is(translateError(err), apperror.KindNotFound) {
+		t.Fatalf("update of another user's task = %v", err)
+	}
+
+	err = deleteReturning(conn, &model.Task{}, "id=? AND user_id=?", task.ID, user.ID+1)
+	if !apperror.Is(translateError(err), apperror.KindNotFound) {
+		t.Fatalf("delete of another user's task = %v", err)
+	}
+	deleted := model.Task{}
+	if err := deleteReturning(conn, &deleted, "id=? AND user_id=?", task.ID, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if deleted.ID != task.ID || deleted.Title != "b" {
+		t.Fatalf("deleted task = %+v", deleted)
+	}
+}
@@ -1,19 +1,19 @@
 package repository
 
 import (
-	"fmt"
+	"context"
+	"go-rest-api/apperror"
 	"go-rest-api/model"
 
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause"
 )
 
 type ITaskRepository interface {
-	GetAllTasks(tasks *[]model.Task, userId uint) error
-	GetTaskById(task *model.Task, userId uint, taskId uint) error
-	CreateTask(task *model.Task) error
-	UpdateTask(task *model.Task, userId uint, taskId uint) error
-	DeleteTask(userId uint, taskId uint) error
+	GetAllTasks(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.Task], error)
+	GetTaskById(ctx context.Context, task *model.Task, userId uint, taskId uint) error
+	CreateTask(ctx context.Context, task *model.Task) error
+	UpdateTask(ctx context.Context, task *model.Task, userId uint, taskId uint) error
+	DeleteTask(ctx context.Context, userId uint, taskId uint) error
 }
 
 type taskRepository struct {
@@ -24,45 +24,51 @@ func NewTaskRepository(db *gorm.DB) ITaskRepository {
 	return &taskRepository{db}
 }
 
-func (tr *taskRepository) GetAllTasks(tasks *[]model.Task, userId uint) error {
-	if err := tr.db.Joins("User").Where("user_id=?", userId).Order("created_at").Find(tasks).Error; err != nil {
-		return err
-	}
-	return nil
+// taskListSpec はタスク一覧で使える並び替え・絞り込みの項目です。
+var taskListSpec = listSpec{
+	sorts: map[string]string{
+		"id":         "id",
+		"title":      "title",
+		"created_at": "created_at",
+		"updated_at": "updated_at",
+	},
+	defaultSort: "created_at",
+	dateColumn:  "created_at",
+}
+
+func (tr *taskRepository) GetAllTasks(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.Task], error) {
+	return paginate[model.Task](tr.db.WithContext(ctx).Joins("User").Where("user_id=?", userId), taskListSpec, q)
 }
 
-func (tr *taskRepository) GetTaskById(task *model.Task, userId uint, taskId uint) error {
-	if err := tr.db.Joins("User").Where("user_id=?", userId).First(task, taskId).Error; err != nil {
-		return err
+func (tr *taskRepository) GetTaskById(ctx context.Context, task *model.Task, userId uint, taskId uint) error {
+	if err := tr.db.WithContext(ctx).Joins("User").Where("user_id=?", userId).First(task, taskId).Error; err != nil {
+		return translateError(err)
 	}
 	return nil
 }
 
-func (tr *taskRepository) CreateTask(task *model.Task) error {
-	if err := tr.db.Create(task).Error; err != nil {
-		return err
+func (tr *taskRepository) CreateTask(ctx context.Context, task *model.Task) error {
+	if err := tr.db.WithContext(ctx).Create(task).Error; err != nil {
+		return translateError(err)
 	}
 	return nil
 }
 
-func (tr *taskRepository) UpdateTask(task *model.Task, userId uint, taskId uint) error {
-	result := tr.db.Model(task).Clauses(clause.Returning{}).Where("id=? AND user_id=?", taskId, userId).Update("title", task.Title)
-	if result.Error != nil {
-		return result.Error
-	}
-	if result.RowsAffected < 1 {
-		return fmt.Errorf("object does not exist")
+func (tr *taskRepository) UpdateTask(ctx context.Context, task *model.Task, userId uint, taskId uint) error {
+	err := updateReturning(tr.db.WithContext(ctx), task, map[string]interface{}{"title": task.Title}, "id=? AND user_id=?", taskId, userId)
+	if err != nil {
+		return translateError(err)
 	}
 	return nil
 }
 
-func (tr *taskRepository) DeleteTask(userId uint, taskId uint) error {
-	result := tr.db.Where("id=? AND user_id=?", taskId, userId).Delete(&model.Task{})
+func (tr *taskRepository) DeleteTask(ctx context.Context, userId uint, taskId uint) error {
+	result := tr.db.WithContext(ctx).Where("id=? AND user_id=?", taskId, userId).Delete(&model.Task{})
 	if result.Error != nil {
-		return result.Error
+		return translateError(result.Error)
 	}
 	if result.RowsAffected < 1 {
-		return fmt.Errorf("object does not exist")
+		return apperror.NotFound("object does not exist")
 	}
 	return nil
 }
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// IUnitOfWork は複数のリポジトリの操作を1つのトランザクションで実行します。
+type IUnitOfWork interface {
+	// Do は fc をトランザクションの中で実行します。fc がエラーを返すかパニックした場合は fc の中の変更を全て取り消します。
+	// ctx が終了した場合もトランザクションを取り消すため、fc の中のリポジトリの操作には同じ ctx を渡します。
+	// fc の中では引数の Repositories から取得したリポジトリのみを使います。それ以外のリポジトリはトランザクションの外で実行されます。
+	// Repositories の Do を呼ぶとセーブポイントを使った入れ子のトランザクションになり、内側の fc が失敗した場合は内側の変更のみ取り消します。
+	Do(ctx context.Context, fc func(r Repositories) error) error
+}
+
+// Repositories はトランザクションの中で使うリポジトリです。
+type Repositories interface {
+	IUnitOfWork
+	Users() IUserRepository
+	Tasks() ITaskRepository
+	Blogs() IBlogRepository
+	Shops() IShopRepository
+	Menus() IMenuRepository
+	Favorites() IFavoriteRepository
+	Reservations() IReservationRepository
+}
+
+type unitOfWork struct {
+	db *gorm.DB
+}
+
+func NewUnitOfWork(db *gorm.DB) IUnitOfWork {
+	return &unitOfWork{db}
+}
+
+// Do はトランザクションの中で呼ばれた場合、GORM がセーブポイントを作成します。
+func (u *unitOfWork) Do(ctx context.Context, fc func(r Repositories) error) error {
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fc(&unitOfWork{tx})
+	})
+}
+
+func (u *unitOfWork) Users() IUserRepository {
+	return NewUserRepository(u.db)
+}
+
+func (u *unitOfWork) Tasks() ITaskRepository {
+	return NewTaskRepository(u.db)
+}
+
+func (u *unitOfWork) Blogs() IBlogRepository {
+	return NewBlogRepository(u.db)
+}
+
+func (u *unitOfWork) Shops() IShopRepository {
+	return NewShopRepository(u.db)
+}
+
+func (u *unitOfWork) Menus() IMenuRepository {
+	return NewMenuRepository(u.db)
+}
+
+func (u *unitOfWork) Favorites() IFavoriteRepository {
+	return NewFavoriteRepository(u.db)
+}
+
+func (u *unitOfWork) Reservations() IReservationRepository {
+	return NewReservationRepository(u.db)
+}
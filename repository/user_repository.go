@@ -1,15 +1,19 @@
 package repository
 
 import (
+	"context"
+	"go-rest-api/apperror"
 	"go-rest-api/model"
 
 	"gorm.io/gorm"
 )
 
 type IUserRepository interface {
-	GetUserByEmail(user *model.User, email string) error
-	CreateUser(user *model.User) error
-	GetUserById(user *model.User, userId uint) error  // 新しいメソッドをインターフェースに追加
+	GetUserByEmail(ctx context.Context, user *model.User, email string) error
+	CreateUser(ctx context.Context, user *model.User) error
+	GetUserById(ctx context.Context, user *model.User, userId uint) error  // 新しいメソッドをインターフェースに追加
+	UpdateUserRole(ctx context.Context, userId uint, role string) error
+	MarkEmailVerified(ctx context.Context, userId uint) error
 }
 
 type userRepository struct {
@@ -20,23 +24,46 @@ func NewUserRepository(db *gorm.DB) IUserRepository {
 	return &userRepository{db}
 }
 
-func (ur *userRepository) GetUserByEmail(user *model.User, email string) error {
-	if err := ur.db.Where("email=?", email).First(user).Error; err != nil {
-		return err
+func (ur *userRepository) GetUserByEmail(ctx context.Context, user *model.User, email string) error {
+	if err := ur.db.WithContext(ctx).Where("email=?", email).First(user).Error; err != nil {
+		return translateError(err)
 	}
 	return nil
 }
 
-func (ur *userRepository) CreateUser(user *model.User) error {
-	if err := ur.db.Create(user).Error; err != nil {
-		return err
+func (ur *userRepository) CreateUser(ctx context.Context, user *model.User) error {
+	if err := ur.db.WithContext(ctx).Create(user).Error; err != nil {
+		return translateError(err)
 	}
 	return nil
 }
 
-func (ur *userRepository) GetUserById(user *model.User, userId uint) error {
-	if err := ur.db.Where("id=?", userId).First(user).Error; err != nil {
-		return err
+func (ur *userRepository) GetUserById(ctx context.Context, user *model.User, userId uint) error {
+	if err := ur.db.WithContext(ctx).Where("id=?", userId).First(user).Error; err != nil {
+		return translateError(err)
+	}
+	return nil
+}
+
+
+func (ur *userRepository) UpdateUserRole(ctx context.Context, userId uint, role string) error {
+	result := ur.db.WithContext(ctx).Model(&model.User{}).Where("id=?", userId).Update("role", role)
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected < 1 {
+		return apperror.NotFound("object does not exist")
+	}
+	return nil
+}
+
+func (ur *userRepository) MarkEmailVerified(ctx context.Context, userId uint) error {
+	result := ur.db.WithContext(ctx).Model(&model.User{}).Where("id=?", userId).Update("email_verified", true)
+	if result.Error != nil {
+		return translateError(result.Error)
+	}
+	if result.RowsAffected < 1 {
+		return apperror.NotFound("object does not exist")
 	}
 	return nil
 }
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidUserToken はトークンが存在しない・期限切れ・使用済みの場合に返されます。
+var ErrInvalidUserToken = apperror.BadRequest("invalid, expired or already used token")
+
+type IUserTokenRepository interface {
+	// CreateUserToken は同じ用途の未使用トークンを無効にした上で新しいトークンを作成します。
+	CreateUserToken(ctx context.Context, token *model.UserToken) error
+	// VerifyEmail はトークンを使用済みにし、ユーザーのメールアドレスを確認済みにします。
+	VerifyEmail(ctx context.Context, tokenHash string) (uint, error)
+	// ResetPassword はトークンを使用済みにし、パスワードを更新してリフレッシュトークンを全て失効させます。
+	ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (uint, error)
+}
+
+type userTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewUserTokenRepository(db *gorm.DB) IUserTokenRepository {
+	return &userTokenRepository{db}
+}
+
+func (utr *userTokenRepository) CreateUserToken(ctx context.Context, token *model.UserToken) error {
+	return utr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&model.UserToken{}).
+			Where("user_id=? AND purpose=? AND used_at IS NULL", token.UserID, token.Purpose).
+			Update("used_at", time.Now()).Error
+		if err != nil {
+			return translateError(err)
+		}
+		return translateError(tx.Create(token).Error)
+	})
+}
+
+func (utr *userTokenRepository) VerifyEmail(ctx context.Context, tokenHash string) (uint, error) {
+	var userId uint
+	err := utr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, tokenHash, model.TokenPurposeVerifyEmail)
+		if err != nil {
+			return err
+		}
+		userId = token.UserID
+		return translateError(tx.Model(&model.User{}).Where("id=?", token.UserID).Update("email_verified", true).Error)
+	})
+	return userId, err
+}
+
+func (utr *userTokenRepository) ResetPassword(ctx context.Context, tokenHash string, passwordHash string) (uint, error) {
+	var userId uint
+	err := utr.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		token, err := consumeUserToken(tx, tokenHash, model.TokenPurposeResetPassword)
+		if err != nil {
+			return err
+		}
+		userId = token.UserID
+		if err := tx.Model(&model.User{}).Where("id=?", token.UserID).Update("password", passwordHash).Error; err != nil {
+			return translateError(err)
+		}
+		// 乗っ取られていた場合に備え、既存のセッションを全て失効させる
+		return translateError(tx.Model(&model.RefreshToken{}).
+			Where("user_id=? AND revoked_at IS NULL", token.UserID).
+			Update("revoked_at", time.Now()).Error)
+	})
+	return userId, err
+}
+
+// consumeUserToken は有効なトークンを行ロックして取得し、使用済みにします。
+func consumeUserToken(tx *gorm.DB, tokenHash string, purpose string) (model.UserToken, error) {
+	token := model.UserToken{}
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash=? AND purpose=? AND used_at IS NULL AND expires_at > ?", tokenHash, purpose, time.Now()).
+		First(&token).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.UserToken{}, ErrInvalidUserToken
+		}
+		return model.UserToken{}, translateError(err)
+	}
+	if err := tx.Model(&token).Update("used_at", time.Now()).Error; err != nil {
+		return model.UserToken{}, translateError(err)
+	}
+	return token, nil
+}
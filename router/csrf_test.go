@@ -0,0 +1,61 @@
+package router
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+)
+
+func TestSkipCSRF(t *testing.T) {
+	e := echo.New()
+	e.Use(middleware.CSRFWithConfig(middleware.CSRFConfig{
+		TokenLookup: "header:X-CSRF-Token",
+		Skipper:     skipCSRF,
+	}))
+	ok := func(c echo.Context) error { return c.NoContent(http.StatusOK) }
+	e.POST("/auth/login", ok)
+	e.POST("/auth/refresh", ok)
+	e.POST("/auth/logout", ok)
+	e.POST("/tasks", ok)
+
+	for _, tc := range []struct {
+		path   string
+		cookie bool
+		want   int
+	}{
+		{"/auth/login", false, http.StatusOK},
+		// リクエストボディのトークンのみの場合はCSRFチェックを行わない
+		{"/auth/refresh", false, http.StatusOK},
+		{"/auth/logout", false, http.StatusOK},
+		// refresh_tokenのCookieが付いている場合はCSRFトークンが必要
+		{"/auth/refresh", true, http.StatusBadRequest},
+		{"/auth/logout", true, http.StatusBadRequest},
+		{"/tasks", false, http.StatusBadRequest},
+	} {
+		req := httptest.NewRequest(http.MethodPost, tc.path, strings.NewReader(`{"refresh_token":"token"}`))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if tc.cookie {
+			req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "token"})
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		if rec.Code != tc.want {
+			t.Errorf("POST %s (cookie %v) = %d, want %d", tc.path, tc.cookie, rec.Code, tc.want)
+		}
+	}
+
+	// Cookieと同じCSRFトークンをヘッダーで送れば通る
+	req := httptest.NewRequest(http.MethodPost, "/auth/refresh", nil)
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: "token"})
+	req.AddCookie(&http.Cookie{Name: "_csrf", Value: "csrf-token"})
+	req.Header.Set(echo.HeaderXCSRFToken, "csrf-token")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("POST /auth/refresh with a CSRF token = %d", rec.Code)
+	}
+}
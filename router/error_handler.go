@@ -0,0 +1,112 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"go-rest-api/apperror"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// problemTypePrefix は problem+json の type に使うURIの接頭辞です。
+const problemTypePrefix = "urn:ecsite:problem:"
+
+// Problem は RFC 7807 のエラーレスポンスです。
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// ErrorHandler はハンドラーやミドルウェアが返したエラーを application/problem+json で返します。
+// 内部エラーの詳細はクライアントに返さず、アクセスログにのみ記録します。
+func ErrorHandler(err error, c echo.Context) {
+	if c.Response().Committed {
+		return
+	}
+	problem := newProblem(err)
+	problem.Instance = c.Request().URL.Path
+
+	c.Response().Header().Set(echo.HeaderContentType, "application/problem+json")
+	if c.Request().Method == http.MethodHead {
+		err = c.NoContent(problem.Status)
+	} else {
+		err = c.JSON(problem.Status, problem)
+	}
+	if err != nil {
+		c.Logger().Error(err)
+	}
+}
+
+func newProblem(err error) Problem {
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) && appErr.Kind != apperror.KindInternal {
+		status := appErr.Kind.Status()
+		return Problem{
+			Type:   problemTypePrefix + appErr.Kind.Slug(),
+			Title:  http.StatusText(status),
+			Status: status,
+			Detail: appErr.Message,
+			Errors: appErr.Fields,
+		}
+	}
+
+	// リポジトリを経由しない処理（トランザクションの開始など）で処理時間の上限を過ぎた場合
+	if errors.Is(err, context.DeadlineExceeded) {
+		return newProblem(apperror.Wrap(apperror.KindUnavailable, "request timed out", err))
+	}
+
+	// echoやJWTミドルウェアが返すエラー
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		problem := Problem{
+			Type:   problemTypePrefix + kindOfStatus(he.Code).Slug(),
+			Title:  http.StatusText(he.Code),
+			Status: he.Code,
+		}
+		// 種類に対応しないステータス（405など）はステータスコード自体が意味を表す
+		if kindOfStatus(he.Code) == apperror.KindInternal && he.Code < http.StatusInternalServerError {
+			problem.Type = "about:blank"
+		}
+		if message, ok := he.Message.(string); ok && he.Code < http.StatusInternalServerError {
+			problem.Detail = message
+		}
+		return problem
+	}
+
+	return Problem{
+		Type:   problemTypePrefix + apperror.KindInternal.Slug(),
+		Title:  http.StatusText(http.StatusInternalServerError),
+		Status: http.StatusInternalServerError,
+	}
+}
+
+// statusOf はエラーに対応するHTTPステータスコードです。アクセスログでも使います。
+func statusOf(err error) int {
+	return newProblem(err).Status
+}
+
+// kindOfStatus はHTTPステータスコードに対応するエラーの種類です。
+func kindOfStatus(status int) apperror.Kind {
+	switch status {
+	case http.StatusBadRequest:
+		return apperror.KindBadRequest
+	case http.StatusUnprocessableEntity:
+		return apperror.KindValidation
+	case http.StatusNotFound:
+		return apperror.KindNotFound
+	case http.StatusConflict:
+		return apperror.KindConflict
+	case http.StatusForbidden:
+		return apperror.KindForbidden
+	case http.StatusUnauthorized:
+		return apperror.KindUnauthorized
+	case http.StatusServiceUnavailable:
+		return apperror.KindUnavailable
+	}
+	return apperror.KindInternal
+}
@@ -0,0 +1,204 @@
+package router
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/labstack/echo/v4"
+)
+
+const redacted = "[REDACTED]"
+
+// RequestLoggerConfigはアクセスログの設定です。
+type RequestLoggerConfig struct {
+	Logger *slog.Logger
+	// RedactHeadersは値を伏せるヘッダー名です（大文字小文字を区別しない）。
+	RedactHeaders []string
+	// RedactFieldsは値を伏せるJSON・フォーム・クエリのキーです（大文字小文字を区別しない）。
+	RedactFields []string
+	// LogBodyがtrueの場合のみリクエストボディを記録します。
+	LogBody bool
+	// MaxBodyBytesを超えるボディは記録しません。
+	MaxBodyBytes int64
+}
+
+// DefaultRequestLoggerConfigは認証情報を伏せる既定の設定を返します。ボディは記録しません。
+func DefaultRequestLoggerConfig() RequestLoggerConfig {
+	return RequestLoggerConfig{
+		Logger: slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+		RedactHeaders: []string{
+			echo.HeaderAuthorization,
+			echo.HeaderCookie,
+			echo.HeaderSetCookie,
+			echo.HeaderXCSRFToken,
+			"X-BUILD-API-KEY",
+		},
+		RedactFields: []string{
+			"password",
+			"token",
+			"jwt",
+			"access_token",
+			"refresh_token",
+			"id_token",
+			"csrf_token",
+			"secret",
+		},
+		MaxBodyBytes: 4096,
+	}
+}
+
+// RequestLoggerはリクエストごとに1行のJSONアクセスログを出力するミドルウェアです。
+// リクエストIDはmiddleware.RequestIDで設定されたものを使うため、その後に適用してください。
+func RequestLogger(config RequestLoggerConfig) echo.MiddlewareFunc {
+	redactHeaders := toSet(config.RedactHeaders)
+	redactFields := toSet(config.RedactFields)
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			start := time.Now()
+
+			var body []slog.Attr
+			if config.LogBody {
+				body = readBody(req, config.MaxBodyBytes, redactFields)
+			}
+
+			err := next(c)
+
+			status := c.Response().Status
+			if err != nil {
+				status = statusOf(err)
+			}
+			attrs := []slog.Attr{
+				slog.String("request_id", c.Response().Header().Get(echo.HeaderXRequestID)),
+				slog.String("method", req.Method),
+				slog.String("route", c.Path()),
+				slog.String("path", req.URL.Path),
+				slog.String("query", redactQuery(req.URL.Query(), redactFields)),
+				slog.Int("status", status),
+				slog.Duration("latency", time.Since(start)),
+				slog.String("remote_ip", c.RealIP()),
+				slog.Any("headers", redactHeaderValues(req.Header, redactHeaders)),
+			}
+			if userId, ok := userIDFromContext(c); ok {
+				attrs = append(attrs, slog.Any("user_id", userId))
+			}
+			attrs = append(attrs, body...)
+			if err != nil {
+				attrs = append(attrs, slog.String("error", err.Error()))
+			}
+
+			level := slog.LevelInfo
+			if status >= http.StatusInternalServerError {
+				level = slog.LevelError
+			}
+			config.Logger.LogAttrs(req.Context(), level, "request", attrs...)
+			return err
+		}
+	}
+}
+
+// readBodyは上限までボディを読み込み、JSONとフォームの場合のみ伏せ字にして返します。
+// 読み込んだ内容はハンドラーが再度読めるようにリクエストに戻します。
+func readBody(req *http.Request, limit int64, fields map[string]bool) []slog.Attr {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	head, err := io.ReadAll(io.LimitReader(req.Body, limit+1))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), req.Body), req.Body}
+	if err != nil {
+		return nil
+	}
+	if int64(len(head)) > limit {
+		return []slog.Attr{slog.Bool("body_truncated", true)}
+	}
+
+	contentType := req.Header.Get(echo.HeaderContentType)
+	switch {
+	case strings.HasPrefix(contentType, echo.MIMEApplicationJSON):
+		var v interface{}
+		if err := json.Unmarshal(head, &v); err != nil {
+			return []slog.Attr{slog.String("body", "[invalid json]")}
+		}
+		return []slog.Attr{slog.Any("body", redactJSON(v, fields))}
+	case strings.HasPrefix(contentType, echo.MIMEApplicationForm):
+		values, err := url.ParseQuery(string(head))
+		if err != nil {
+			return []slog.Attr{slog.String("body", "[invalid form]")}
+		}
+		return []slog.Attr{slog.String("body", redactQuery(values, fields))}
+	}
+	return []slog.Attr{slog.Int("body_bytes", len(head))}
+}
+
+func redactJSON(v interface{}, fields map[string]bool) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if fields[strings.ToLower(k)] {
+				t[k] = redacted
+				continue
+			}
+			t[k] = redactJSON(child, fields)
+		}
+	case []interface{}:
+		for i, child := range t {
+			t[i] = redactJSON(child, fields)
+		}
+	}
+	return v
+}
+
+func redactQuery(values url.Values, fields map[string]bool) string {
+	for k := range values {
+		if fields[strings.ToLower(k)] {
+			values[k] = []string{redacted}
+		}
+	}
+	return values.Encode()
+}
+
+func redactHeaderValues(header http.Header, names map[string]bool) map[string]string {
+	out := make(map[string]string, len(header))
+	for k, v := range header {
+		if names[strings.ToLower(k)] {
+			out[k] = redacted
+			continue
+		}
+		out[k] = strings.Join(v, ", ")
+	}
+	return out
+}
+
+// userIDFromContextはJWTミドルウェアが検証したトークンからユーザーIDを取り出します。
+func userIDFromContext(c echo.Context) (interface{}, bool) {
+	token, ok := c.Get("user").(*jwt.Token)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, false
+	}
+	userId, ok := claims["user_id"]
+	return userId, ok
+}
+
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[strings.ToLower(v)] = true
+	}
+	return set
+}
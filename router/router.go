@@ -1,97 +1,155 @@
 package router
 
 import (
+	"crypto/subtle"
+	"go-rest-api/apperror"
+	"go-rest-api/config"
 	"go-rest-api/controller"
+	"go-rest-api/model"
 	"net/http"
-	"os"
-	"io"
-    "bytes"
-"fmt"
+	"strings"
+	"github.com/golang-jwt/jwt/v4"
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
-// LoggerMiddlewareは、リクエストのヘッダーとボディをログに出力するミドルウェアです。
-func LoggerMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
-    return func(c echo.Context) error {
-        // リクエストURLをログに出力
-        fmt.Printf("Request URL: %s\n", c.Request().URL)
-
-        // リクエストヘッダーをログに出力
-        fmt.Printf("Request Headers: %+v\n", c.Request().Header)
-
-        // リクエストボディを読み込む
-        body, err := io.ReadAll(c.Request().Body)
-        if err != nil {
-            // エラー処理
-            return err
-        }
-
-        // リクエストボディをログに出力
-        fmt.Printf("Request Body: %s\n", string(body))
-
-        // リクエストボディを再設定（重要）
-        c.Request().Body = io.NopCloser(bytes.NewBuffer(body))
-
-        return next(c)
-    }
+
+// APIキーを検証するカスタムミドルウェア
+// キーが設定されていない場合は全てのリクエストを拒否する
+func ValidateBuildAPIKey(buildAPIKey string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			apiKey := c.Request().Header.Get("X-BUILD-API-KEY")
+			if buildAPIKey == "" || subtle.ConstantTimeCompare([]byte(apiKey), []byte(buildAPIKey)) != 1 {
+				return apperror.Unauthorized("invalid build API key")
+			}
+			return next(c)
+		}
+	}
 }
 
+// tokenLookupはJWTを探す場所です。先に一致したものが使われます。
+const tokenLookup = "header:Authorization:Bearer ,header:Authorization,cookie:token"
 
-// APIキーを検証するカスタムミドルウェア
-func ValidateBuildAPIKey(next echo.HandlerFunc) echo.HandlerFunc {
-	return func(c echo.Context) error {
-		apiKey := c.Request().Header.Get("X-BUILD-API-KEY")
-		if apiKey != os.Getenv("BUILD_API_KEY") {
-			return echo.NewHTTPError(http.StatusUnauthorized, "Invalid build API key")
+// csrfExemptPathsはCSRFチェックを行わないパスです。
+// Cookieを使わずにリクエストボディのトークンで認証・検証するエンドポイントが対象です。
+var csrfExemptPaths = map[string]bool{
+	"/auth/login":                  true,
+	"/auth/signup":                 true,
+	"/auth/verify-email":           true,
+	"/auth/password-reset/request": true,
+	"/auth/password-reset":         true,
+}
+
+// refreshTokenPathsはリクエストボディまたはrefresh_tokenのCookieのリフレッシュトークンを使うパスです。
+// Cookieはブラウザが自動で送るため、Cookieが付いている場合のみCSRFチェックを行います。
+var refreshTokenPaths = map[string]bool{
+	"/auth/refresh": true,
+	"/auth/logout":  true,
+}
+
+// skipCSRFはCSRFチェックを行わないリクエストかを返します。
+func skipCSRF(c echo.Context) bool {
+	if refreshTokenPaths[c.Path()] {
+		_, err := c.Cookie("refresh_token")
+		return err != nil
+	}
+	return csrfExemptPaths[c.Path()]
+}
+
+// RequireRoleはJWTのroleクレームが指定されたロールのいずれかであることを検証するミドルウェアです。
+// echojwtミドルウェアの後に適用する必要があります。
+func RequireRole(roles ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token, ok := c.Get("user").(*jwt.Token)
+			if !ok {
+				return apperror.Unauthorized("missing or invalid token")
+			}
+			claims, ok := token.Claims.(jwt.MapClaims)
+			if !ok {
+				return apperror.Unauthorized("missing or invalid token")
+			}
+			role, _ := claims["role"].(string)
+			for _, r := range roles {
+				if r == role {
+					return next(c)
+				}
+			}
+			return apperror.Forbidden("insufficient role")
 		}
-		return next(c)
 	}
 }
 
 func NewRouter(
+    cfg config.Config,
     uc controller.IUserController, 
+    ac controller.IAccountController,
     tc controller.ITaskController, 
     bc controller.IBlogController, 
     sc controller.IShopController,
     fc controller.IFavoriteController,
     rc controller.IReservationController, 
+    rvc controller.IReviewController,
+    mc controller.IMenuController,
+    hc controller.IHealthController,
 ) *echo.Echo {
 	e := echo.New()
+	e.HTTPErrorHandler = ErrorHandler
+
+	// JWTミドルウェアは一度だけ設定し、全てのグループで共有する
+	// Authorizationヘッダー（Bearerの有無を問わない）とCookieのどちらからでもトークンを受け付ける
+	jwtAuth := echojwt.WithConfig(echojwt.Config{
+		SigningKey:  []byte(cfg.Secret),
+		TokenLookup: tokenLookup,
+	})
 
-	// LoggerMiddlewareを追加
-    e.Use(LoggerMiddleware)
+	// 構造化アクセスログ（認証情報は伏せ字にする。ボディはLOG_REQUEST_BODY=trueの場合のみ記録）
+	loggerConfig := DefaultRequestLoggerConfig()
+	loggerConfig.LogBody = cfg.Log.RequestBody
+	loggerConfig.MaxBodyBytes = cfg.Log.MaxBodyBytes
+	// 設定で指定したヘッダーとキーは既定のものに加えて伏せる
+	loggerConfig.RedactHeaders = append(loggerConfig.RedactHeaders, cfg.Log.RedactHeaders...)
+	loggerConfig.RedactFields = append(loggerConfig.RedactFields, cfg.Log.RedactFields...)
+	e.Use(middleware.RequestID())
+	e.Use(RequestLogger(loggerConfig))
+	// ルートのグループごとの処理時間の上限（REQUEST_TIMEOUT・REQUEST_TIMEOUTS）
+	e.Use(RequestTimeout(cfg.Timeout))
 
 	// CORSミドルウェアの設定
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
-		AllowOrigins: []string{"http://localhost:3000", "https://ecsite-front.vercel.app"},
+		AllowOrigins: cfg.AllowedOrigins,
 		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept,
 			echo.HeaderAccessControlAllowHeaders, echo.HeaderXCSRFToken,"Authorization" },
 		AllowMethods:     []string{"GET", "PUT", "POST", "DELETE"},
 		AllowCredentials: true,
-		AllowOriginFunc: func(origin string) (bool, error) {
-        return origin == "http://localhost:3000" || origin == "https://ecsite-front.vercel.app", nil
-    },
 	}))
 
+	// ヘルスチェック（/healthz は liveness、/readyz はデータベースとマイグレーションを確認する readiness）
+	e.GET("/healthz", hc.Healthz)
+	e.GET("/readyz", hc.Readyz)
+
     authGroup := e.Group("/auth")
     authGroup.POST("/login", uc.AuthLogin)
 		authGroup.POST("/signup", uc.AuthSignup)
 		authGroup.POST("/oauth/login", uc.HandleOAuthLogin)
+		authGroup.POST("/refresh", uc.RefreshToken)
+		authGroup.POST("/logout", uc.LogOut)
+		authGroup.POST("/verify-email", ac.VerifyEmail)
+		authGroup.POST("/password-reset/request", ac.RequestPasswordReset)
+		authGroup.POST("/password-reset", ac.ResetPassword)
 
 
     // CSRFミドルウェアの設定
 	e.Use(middleware.CSRFWithConfig(middleware.CSRFConfig{
 		CookiePath:     "/",
-		CookieDomain:   os.Getenv("API_DOMAIN"),
+		CookieDomain:   cfg.APIDomain,
 		CookieHTTPOnly: true,
 		CookieSameSite: http.SameSiteNoneMode,
 		CookieSecure:   true,  // これを追加
 		TokenLookup:    "header:X-CSRF-Token",
-		Skipper: func(c echo.Context) bool {
-        // `/auth/login` と `/auth/signup` へのリクエストをCSRFチェックから除外
-        return c.Path() == "/auth/login" || c.Path() == "/auth/signup"
-    },
+		// トークンで認証する `/auth` 配下の一部のエンドポイントをCSRFチェックから除外
+		Skipper:        skipCSRF,
 	}))
 
 	// ユーザー関連のエンドポイント
@@ -103,25 +161,39 @@ func NewRouter(
 
 	// 新しいエンドポイント '/user' を追加
     u := e.Group("/user")
-    u.Use(echojwt.WithConfig(echojwt.Config{
-        SigningKey:  []byte(os.Getenv("SECRET")),
-        TokenLookup: "cookie:token",
-    }))
+    u.Use(jwtAuth)
     u.GET("", uc.GetUser)
 		u.GET("/token", uc.GetToken)
+		u.POST("/verify-email", ac.RequestEmailVerification)
 
 
+	// アップロードした画像の配信（MEDIA_BASE_URL がパスの場合のみ。外部のURLの場合はそちらで配信する）
+	if strings.HasPrefix(cfg.Media.BaseURL, "/") {
+		e.Static(cfg.Media.BaseURL, cfg.Media.Dir)
+	}
+
 	// CSRFミドルウェアを適用しないエンドポイントのグループ
 	s := e.Group("")
 	s.GET("/shops", sc.GetAllShops)
+	s.GET("/shops/search", sc.SearchShops)
+	s.GET("/shops/nearby", sc.GetNearbyShops)
 	s.GET("/shops/:shopId", sc.GetShopById)
+	s.GET("/shops/:shopId/availability", rc.GetAvailability)
+	s.GET("/shops/:shopId/reviews", rvc.GetShopReviews)
+	s.GET("/shops/:shopId/menus", mc.GetMenus)
+	s.POST("/shops/:shopId/reviews", rvc.CreateReview, jwtAuth)
+
+	// レビューエンドポイントの設定（自分のレビューの一覧・更新・削除と、他人のレビューの通報）
+	rv := e.Group("/reviews")
+	rv.Use(jwtAuth)
+	rv.GET("", rvc.GetMyReviews)
+	rv.PUT("/:reviewId", rvc.UpdateReview)
+	rv.DELETE("/:reviewId", rvc.DeleteReview)
+	rv.POST("/:reviewId/reports", rvc.ReportReview)
 
 	// tasksエンドポイントの設定
 	t := e.Group("/tasks")
-	t.Use(echojwt.WithConfig(echojwt.Config{
-		SigningKey:  []byte(os.Getenv("SECRET")),
-		TokenLookup: "cookie:token",
-	}))
+	t.Use(jwtAuth)
 	t.GET("", tc.GetAllTasks)
 	t.GET("/:taskId", tc.GetTaskById)
 	t.POST("", tc.CreateTask)
@@ -130,41 +202,71 @@ func NewRouter(
 
 	// blogsエンドポイントの設定
 	b := e.Group("/blogs")
-	b.Use(echojwt.WithConfig(echojwt.Config{
-		SigningKey:  []byte(os.Getenv("SECRET")),
-		TokenLookup: "header:Authorization",
-	}))
+	b.Use(jwtAuth)
 	b.GET("", bc.GetAllBlogs)
 	b.GET("/:blogId", bc.GetBlogById)
 	b.POST("", bc.CreateBlog)
 	b.PUT("/:blogId", bc.UpdateBlog)
 	b.DELETE("/:blogId", bc.DeleteBlog)
+	b.PUT("/:blogId/cover", bc.SetBlogCover)
+	b.DELETE("/:blogId/cover", bc.DeleteBlogCover)
 
 	// お気に入りエンドポイントの設定
     f := e.Group("/favorites")
-    f.Use(echojwt.WithConfig(echojwt.Config{
-        SigningKey:  []byte(os.Getenv("SECRET")),
-        TokenLookup: "header:Authorization",
-    }))
+    f.Use(jwtAuth)
     f.POST("", fc.AddFavorite)  // お気に入りを追加
     f.DELETE("/:shopId/:userId", fc.RemoveFavorite)  // お気に入りを削除
     f.GET("", fc.GetFavorites)  // お気に入りを取得
 
 	// reserveエンドポイントの設定
 		r := e.Group("/reservations")
-    r.Use(echojwt.WithConfig(echojwt.Config{
-        SigningKey:  []byte(os.Getenv("SECRET")),
-        TokenLookup: "header:Authorization",
-    }))
+    r.Use(jwtAuth)
 		r.POST("/shop/:shopId", rc.MakeReservation)
     r.DELETE("/:reservationId", rc.CancelReservation)
+    r.GET("/me", rc.GetReservationByUser)
     r.GET("/user/:userId", rc.GetReservationByUser)
-    r.GET("", rc.GetAllReservations)
+    r.GET("/shop/:shopId", rc.GetReservationsByShop, RequireRole(model.RoleShopOwner, model.RoleAdmin))
+    r.GET("", rc.GetAllReservations, RequireRole(model.RoleAdmin))
+    // ショップスタッフによるステータス変更
+    staff := RequireRole(model.RoleShopOwner, model.RoleAdmin)
+    r.PUT("/:reservationId/confirm", rc.ConfirmReservation, staff)
+    r.PUT("/:reservationId/check-in", rc.CheckInReservation, staff)
+    r.PUT("/:reservationId/no-show", rc.MarkNoShow, staff)
+    r.PUT("/:reservationId/complete", rc.CompleteReservation, staff)
+    r.PUT("/:reservationId/cancel", rc.CancelReservationByShop, staff)
     r.PUT("/:reservationId", rc.UpdateReservation)
 
+	// 管理用エンドポイントの設定
+	a := e.Group("/admin")
+	a.Use(jwtAuth)
+	a.PUT("/users/:userId/role", uc.UpdateUserRole, RequireRole(model.RoleAdmin))
+	a.DELETE("/users/:userId/sessions", uc.RevokeUserSessions, RequireRole(model.RoleAdmin))
+	a.GET("/reviews/reported", rvc.GetReportedReviews, RequireRole(model.RoleAdmin))
+	a.PUT("/reviews/:reviewId/hidden", rvc.SetReviewHidden, RequireRole(model.RoleAdmin))
+
+	// ショップの作成・削除は管理者のみ、更新はショップオーナー（自分のショップのみ）と管理者
+	as := a.Group("/shops", RequireRole(model.RoleShopOwner, model.RoleAdmin))
+	as.POST("", sc.CreateShop, RequireRole(model.RoleAdmin))
+	as.PUT("/:shopId", sc.UpdateShop)
+	as.DELETE("/:shopId", sc.DeleteShop, RequireRole(model.RoleAdmin))
+	as.PUT("/:shopId/hours", sc.SetShopHours)
+	as.POST("/:shopId/closures", sc.AddShopClosure)
+	as.DELETE("/:shopId/closures/:closureId", sc.DeleteShopClosure)
+	as.PUT("/:shopId/reviews/:reviewId/reply", rvc.ReplyReview)
+	as.POST("/:shopId/images", sc.AddShopImage)
+	as.PUT("/:shopId/images/order", sc.ReorderShopImages)
+	as.DELETE("/:shopId/images/:imageId", sc.DeleteShopImage)
+	as.GET("/:shopId/menus", mc.GetMenusForOwner)
+	as.POST("/:shopId/menus", mc.CreateMenu)
+	as.PUT("/:shopId/menus/:menuId", mc.UpdateMenu)
+	as.DELETE("/:shopId/menus/:menuId", mc.DeleteMenu)
+	as.POST("/:shopId/menus/:menuId/courses", mc.CreateCourse)
+	as.PUT("/:shopId/courses/:courseId", mc.UpdateCourse)
+	as.DELETE("/:shopId/courses/:courseId", mc.DeleteCourse)
+
 	// ビルド専用のエンドポイント
 	build := e.Group("/build")
-	build.Use(ValidateBuildAPIKey)  // カスタムミドルウェアを適用
+	build.Use(ValidateBuildAPIKey(cfg.BuildAPIKey))  // カスタムミドルウェアを適用
 	build.GET("/blogs", bc.GetBlogsForBuild)
 	build.GET("/favorites", fc.GetFavoritesForBuild)
 	build.GET("/reservations", rc.GetReservationsForBuild)
@@ -0,0 +1,32 @@
+package router
+
+import (
+	"context"
+	"go-rest-api/config"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// RequestTimeout はリクエストのコンテキストに、ルートのグループごとの処理時間の上限を設定します。
+// 上限を過ぎるとリポジトリは実行中のクエリを中断し、503 を返します。上限が0のグループには設定しません。
+func RequestTimeout(timeout config.Timeout) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			d := timeout.For(routeGroup(c.Path()))
+			if d <= 0 {
+				return next(c)
+			}
+			ctx, cancel := context.WithTimeout(c.Request().Context(), d)
+			defer cancel()
+			c.SetRequest(c.Request().WithContext(ctx))
+			return next(c)
+		}
+	}
+}
+
+// routeGroup はルートのパスの最初の要素です（"/admin/shops/:shopId" は "admin"）。
+func routeGroup(path string) string {
+	group, _, _ := strings.Cut(strings.TrimPrefix(path, "/"), "/")
+	return group
+}
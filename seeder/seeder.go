@@ -1,10 +1,12 @@
 package main
 
 import (
+	"go-rest-api/config"
 	"go-rest-api/db"
 	"go-rest-api/model"
 	"log"
 	"math/rand"
+	"os"
 	"time"
 )
 
@@ -28,7 +30,11 @@ func main() {
 	rand.Seed(time.Now().UnixNano())
 
 	// データベース接続の取得
-	database := db.NewDB()
+	cfg, err := config.Load(os.Args[1:])
+	if err != nil {
+		log.Fatalln(err)
+	}
+	database := db.NewDB(cfg.Database)
 
 	// シードデータを生成
 	shops := make([]model.Shop, 10)
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"io"
+	"strconv"
+	"strings"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+// readCSV は見出し行付きの CSV を読み込みます。見出しは model.ShopImportColumns の名前で、順序は問いません。
+// 値を変換できない行はその行のエラーとし、CSV として読めない場合のみエラーを返します。
+func readCSV(r io.Reader) ([]model.ShopImportRow, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, errors.New("empty file")
+	}
+	if err != nil {
+		return nil, err
+	}
+	index := map[string]int{}
+	for i, name := range header {
+		name = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+		if !isImportColumn(name) {
+			return nil, fmt.Errorf("line 1: unknown column %q", name)
+		}
+		if _, ok := index[name]; ok {
+			return nil, fmt.Errorf("line 1: duplicate column %q", name)
+		}
+		index[name] = i
+	}
+	if _, ok := index["external_id"]; !ok {
+		return nil, errors.New("line 1: external_id column is required")
+	}
+
+	rows := []model.ShopImportRow{}
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := cr.FieldPos(0)
+		row := model.ShopImportRow{Line: line}
+		if len(record) != len(header) {
+			row.Err = apperror.BadRequest(fmt.Sprintf("expected %d fields, got %d", len(header), len(record)))
+			rows = append(rows, row)
+			continue
+		}
+		values := map[string]string{}
+		for name, i := range index {
+			values[name] = strings.TrimSpace(record[i])
+		}
+		row.Shop, row.Err = shopFromValues(values)
+		rows = append(rows, row)
+	}
+}
+
+// shopFromValues は項目名と値の組からショップを作ります。数値の項目は空の場合に未指定とします。
+func shopFromValues(values map[string]string) (model.Shop, error) {
+	shop := model.Shop{
+		Name:        values["name"],
+		Address:     values["address"],
+		PostalCode:  values["postal_code"],
+		Area:        values["area"],
+		Genre:       values["genre"],
+		Description: values["description"],
+		OpenTime:    values["open_time"],
+		CloseTime:   values["close_time"],
+	}
+	if v := values["external_id"]; v != "" {
+		shop.ExternalID = &v
+	}
+	errs := validation.Errors{}
+	shop.Latitude = parseFloat(values, "latitude", errs)
+	shop.Longitude = parseFloat(values, "longitude", errs)
+	shop.Capacity = parseInt(values, "capacity", errs)
+	shop.SlotMinutes = parseInt(values, "slot_minutes", errs)
+	if len(errs) > 0 {
+		return shop, apperror.Validation(errs)
+	}
+	return shop, nil
+}
+
+func parseFloat(values map[string]string, name string, errs validation.Errors) *float64 {
+	if values[name] == "" {
+		return nil
+	}
+	v, err := strconv.ParseFloat(values[name], 64)
+	if err != nil {
+		errs[name] = errors.New("must be a number")
+		return nil
+	}
+	return &v
+}
+
+func parseInt(values map[string]string, name string, errs validation.Errors) int {
+	if values[name] == "" {
+		return 0
+	}
+	v, err := strconv.Atoi(values[name])
+	if err != nil {
+		errs[name] = errors.New("must be an integer")
+	}
+	return v
+}
+
+func isImportColumn(name string) bool {
+	for _, column := range model.ShopImportColumns {
+		if column == name {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCSV は見出し行付きの CSV を書き出します。readCSV でそのまま取り込めます。
+func writeCSV(w io.Writer, shops []model.Shop) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(model.ShopImportColumns); err != nil {
+		return err
+	}
+	for _, shop := range shops {
+		if err := cw.Write(shop.ImportValues()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"io"
+)
+
+// shopRecord は JSON で取り込み・書き出しするショップです。項目は model.ShopImportColumns と同じです。
+type shopRecord struct {
+	ExternalID  *string  `json:"external_id"`
+	Name        string   `json:"name"`
+	Address     string   `json:"address"`
+	PostalCode  string   `json:"postal_code"`
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	Area        string   `json:"area"`
+	Genre       string   `json:"genre"`
+	Description string   `json:"description"`
+	Capacity    int      `json:"capacity"`
+	OpenTime    string   `json:"open_time"`
+	CloseTime   string   `json:"close_time"`
+	SlotMinutes int      `json:"slot_minutes"`
+}
+
+func (r shopRecord) shop() model.Shop {
+	return model.Shop{
+		ExternalID:  r.ExternalID,
+		Name:        r.Name,
+		Address:     r.Address,
+		PostalCode:  r.PostalCode,
+		Latitude:    r.Latitude,
+		Longitude:   r.Longitude,
+		Area:        r.Area,
+		Genre:       r.Genre,
+		Description: r.Description,
+		Capacity:    r.Capacity,
+		OpenTime:    r.OpenTime,
+		CloseTime:   r.CloseTime,
+		SlotMinutes: r.SlotMinutes,
+	}
+}
+
+func toShopRecord(shop model.Shop) shopRecord {
+	return shopRecord{
+		ExternalID:  shop.ExternalID,
+		Name:        shop.Name,
+		Address:     shop.Address,
+		PostalCode:  shop.PostalCode,
+		Latitude:    shop.Latitude,
+		Longitude:   shop.Longitude,
+		Area:        shop.Area,
+		Genre:       shop.Genre,
+		Description: shop.Description,
+		Capacity:    shop.Capacity,
+		OpenTime:    shop.OpenTime,
+		CloseTime:   shop.CloseTime,
+		SlotMinutes: shop.SlotMinutes,
+	}
+}
+
+// readJSON はショップの配列を読み込みます。各要素の行番号は要素が始まる行です。
+// 要素の型が合わない場合や未知の項目がある場合はその要素のエラーとし、JSON として読めない場合のみエラーを返します。
+func readJSON(r io.Reader) ([]model.ShopImportRow, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+		return nil, errors.New("line 1: expected an array of shops")
+	}
+
+	rows := []model.ShopImportRow{}
+	for dec.More() {
+		line := lineAt(data, dec.InputOffset())
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		row := model.ShopImportRow{Line: line}
+		record := shopRecord{}
+		rd := json.NewDecoder(bytes.NewReader(raw))
+		rd.DisallowUnknownFields()
+		if err := rd.Decode(&record); err != nil {
+			row.Err = apperror.Wrap(apperror.KindBadRequest, "invalid shop", err)
+		}
+		row.Shop = record.shop()
+		rows = append(rows, row)
+	}
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// lineAt は offset 以降で最初の値が始まる行番号を返します。区切りの空白やカンマは読み飛ばします。
+func lineAt(data []byte, offset int64) int {
+	i := int(offset)
+	for i < len(data) && bytes.IndexByte([]byte(" \t\r\n,"), data[i]) >= 0 {
+		i++
+	}
+	return bytes.Count(data[:i], []byte("\n")) + 1
+}
+
+// writeJSON はショップを配列として書き出します。readJSON でそのまま取り込めます。
+func writeJSON(w io.Writer, shops []model.Shop) error {
+	records := make([]shopRecord, len(shops))
+	for i, shop := range shops {
+		records[i] = toShopRecord(shop)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(records)
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go-rest-api/config"
+	"go-rest-api/db"
+	"go-rest-api/geocoder"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"go-rest-api/usecase"
+	"go-rest-api/validator"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const usage = `usage:
+  shopio import [-config file] [-format csv|json] [-dry-run] [-atomic] [-json-report] FILE
+  shopio export [-config file] [-format csv|json] [-o FILE]`
+
+// shopio はショップを CSV または JSON から取り込み、同じ形式で書き出すコマンドです。
+func main() {
+	if len(os.Args) < 2 {
+		log.Fatalln(usage)
+	}
+	// Ctrl-C で処理中のクエリを中断する（-atomic の取り込みは何も保存しない）
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	var err error
+	switch os.Args[1] {
+	case "import":
+		err = runImport(ctx, os.Args[2:])
+	case "export":
+		err = runExport(ctx, os.Args[2:])
+	default:
+		err = fmt.Errorf("unknown command %q\n%s", os.Args[1], usage)
+	}
+	if err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func runImport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a .env style config file")
+	format := fs.String("format", "", "csv or json (default: from the file extension)")
+	dryRun := fs.Bool("dry-run", false, "report the changes without saving them")
+	atomic := fs.Bool("atomic", false, "save all rows in one transaction, or nothing if any row has errors")
+	jsonReport := fs.Bool("json-report", false, "print the report as JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return fmt.Errorf("import requires exactly one FILE\n%s", usage)
+	}
+	path := fs.Arg(0)
+	f, err := resolveFormat(*format, path)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var rows []model.ShopImportRow
+	if f == "csv" {
+		rows, err = readCSV(file)
+	} else {
+		rows, err = readJSON(file)
+	}
+	if err != nil {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+
+	iu, closeDB, err := newImportUsecase(*configFile)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+	report, err := iu.ImportShops(ctx, rows, model.ShopImportOptions{DryRun: *dryRun, Atomic: *atomic})
+	if err != nil {
+		return err
+	}
+	if *jsonReport {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		printReport(os.Stdout, report)
+	}
+	if report.Failed > 0 {
+		return fmt.Errorf("%d of %d rows failed", report.Failed, len(report.Results))
+	}
+	return nil
+}
+
+func runExport(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	configFile := fs.String("config", "", "path to a .env style config file")
+	format := fs.String("format", "", "csv or json (default: from the output extension, or csv)")
+	out := fs.String("o", "", "output file (default: stdout)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return fmt.Errorf("export takes no arguments\n%s", usage)
+	}
+	f := *format
+	if f == "" && *out == "" {
+		f = "csv"
+	}
+	f, err := resolveFormat(f, *out)
+	if err != nil {
+		return err
+	}
+
+	iu, closeDB, err := newImportUsecase(*configFile)
+	if err != nil {
+		return err
+	}
+	defer closeDB()
+	shops, err := iu.ExportShops(ctx)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+	if f == "csv" {
+		return writeCSV(w, shops)
+	}
+	return writeJSON(w, shops)
+}
+
+// newImportUsecase は設定を読み込み、取り込み用のユースケースとDB接続を閉じる関数を返します。
+func newImportUsecase(configFile string) (usecase.IShopImportUsecase, func(), error) {
+	args := []string{}
+	if configFile != "" {
+		args = append(args, "-config", configFile)
+	}
+	cfg, err := config.Load(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	gc := geocoder.NewNopGeocoder()
+	if cfg.GeocoderFile != "" {
+		if gc, err = geocoder.NewFileGeocoder(cfg.GeocoderFile); err != nil {
+			return nil, nil, err
+		}
+	}
+	database := db.NewDB(cfg.Database)
+	iu := usecase.NewShopImportUsecase(repository.NewShopRepository(database), validator.NewShopValidator(), gc)
+	return iu, func() { db.CloseDB(database) }, nil
+}
+
+// resolveFormat は指定された形式か、ファイルの拡張子から形式を決めます。
+func resolveFormat(format, path string) (string, error) {
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+	}
+	if format != "csv" && format != "json" {
+		return "", fmt.Errorf("unknown format %q: use -format csv or -format json", format)
+	}
+	return format, nil
+}
+
+// printReport は行番号付きで取り込み結果を出力します。変更のない行は出力しません。
+func printReport(w io.Writer, report model.ShopImportReport) {
+	for _, result := range report.Results {
+		switch result.Action {
+		case model.ShopImportUnchanged:
+			continue
+		case model.ShopImportInvalid, model.ShopImportFailed:
+			fmt.Fprintf(w, "line %d (%s): %s: %s\n", result.Line, result.ExternalID, result.Action, result.Error)
+			fields := make([]string, 0, len(result.Errors))
+			for field := range result.Errors {
+				fields = append(fields, field)
+			}
+			sort.Strings(fields)
+			for _, field := range fields {
+				fmt.Fprintf(w, "    %s: %s\n", field, result.Errors[field])
+			}
+		default:
+			fmt.Fprintf(w, "line %d (%s): %s\n", result.Line, result.ExternalID, result.Action)
+			for _, change := range result.Changes {
+				fmt.Fprintf(w, "    %s: %q -> %q\n", change.Field, change.From, change.To)
+			}
+		}
+	}
+	status := "applied"
+	switch {
+	case report.DryRun:
+		status = "dry run, nothing saved"
+	case !report.Applied:
+		status = "nothing saved"
+	}
+	fmt.Fprintf(w, "created %d, updated %d, unchanged %d, failed %d (%s)\n",
+		report.Created, report.Updated, report.Unchanged, report.Failed, status)
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type localStorage struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalStorage はファイルを dir 以下に保存します。URL は baseURL にキーを付けたものです。
+// ファイルの配信はルーターの静的ファイル配信で行います。
+func NewLocalStorage(dir, baseURL string) IStorage {
+	return &localStorage{dir, strings.TrimRight(baseURL, "/")}
+}
+
+func (ls *localStorage) Put(key string, r io.Reader, contentType string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	path := filepath.Join(ls.dir, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// 書き込み途中のファイルが配信されないよう、一時ファイルに書いてから名前を変える
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+func (ls *localStorage) Delete(key string) error {
+	key, err := CleanKey(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(filepath.Join(ls.dir, filepath.FromSlash(key)))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (ls *localStorage) URL(key string) string {
+	return ls.baseURL + "/" + key
+}
@@ -0,0 +1,36 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+// ErrNotFound は指定したキーのファイルが存在しない場合に返されます。
+var ErrNotFound = errors.New("storage: object not found")
+
+// ErrInvalidKey はキーがストレージの外を指す場合などに返されます。
+var ErrInvalidKey = errors.New("storage: invalid key")
+
+// IStorage はアップロードしたファイルの保存先です。
+// キーは "shops/1/abc.jpg" のような "/" 区切りの相対パスです。
+// ローカルディスクのほか、S3互換のオブジェクトストレージなどに差し替えられるよう、インターフェースにしています。
+type IStorage interface {
+	Put(key string, r io.Reader, contentType string) error
+	Delete(key string) error
+	// URL はクライアントがファイルを取得するためのURLです。
+	URL(key string) string
+}
+
+// CleanKey はキーを正規化します。空のキーや、".." でストレージの外を指すキーは ErrInvalidKey です。
+func CleanKey(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	cleaned := path.Clean(key)
+	if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+		return "", ErrInvalidKey
+	}
+	return cleaned, nil
+}
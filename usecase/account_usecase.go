@@ -0,0 +1,149 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"go-rest-api/apperror"
+	"go-rest-api/mailer"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"go-rest-api/validator"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// トークンの有効期限
+const (
+	verifyEmailTokenTTL   = 24 * time.Hour
+	resetPasswordTokenTTL = time.Hour
+)
+
+// IAccountUsecase はメールアドレス確認とパスワード再設定を扱います。
+type IAccountUsecase interface {
+	RequestEmailVerification(ctx context.Context, userId uint) error
+	VerifyEmail(ctx context.Context, token string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token string, password string) error
+}
+
+type accountUsecase struct {
+	ur     repository.IUserRepository
+	utr    repository.IUserTokenRepository
+	uv     validator.IUserValidator
+	mailer mailer.IMailer
+	secret []byte
+	appURL string
+}
+
+func NewAccountUsecase(ur repository.IUserRepository, utr repository.IUserTokenRepository, uv validator.IUserValidator, mailer mailer.IMailer, secret []byte, appURL string) IAccountUsecase {
+	return &accountUsecase{ur, utr, uv, mailer, secret, appURL}
+}
+
+func (au *accountUsecase) RequestEmailVerification(ctx context.Context, userId uint) error {
+	user := model.User{}
+	if err := au.ur.GetUserById(ctx, &user, userId); err != nil {
+		return err
+	}
+	if user.EmailVerified {
+		return nil
+	}
+	token, err := au.issueUserToken(ctx, user.ID, model.TokenPurposeVerifyEmail, verifyEmailTokenTTL)
+	if err != nil {
+		return err
+	}
+	return au.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "メールアドレスの確認",
+		Body: fmt.Sprintf("%s 様\n\n以下のリンクからメールアドレスを確認してください（有効期限: 24時間）。\n%s\n",
+			user.Name, au.link("/verify-email", token)),
+	})
+}
+
+func (au *accountUsecase) VerifyEmail(ctx context.Context, token string) error {
+	if !au.checkSignature(token, model.TokenPurposeVerifyEmail) {
+		return repository.ErrInvalidUserToken
+	}
+	_, err := au.utr.VerifyEmail(ctx, hashToken(token))
+	return err
+}
+
+// RequestPasswordReset は登録済みのメールアドレスに再設定用のリンクを送信します。
+// アカウントの有無を推測されないよう、未登録のメールアドレスでもエラーにしません。
+func (au *accountUsecase) RequestPasswordReset(ctx context.Context, email string) error {
+	user := model.User{}
+	if err := au.ur.GetUserByEmail(ctx, &user, email); err != nil {
+		if apperror.Is(err, apperror.KindNotFound) {
+			return nil
+		}
+		return err
+	}
+	token, err := au.issueUserToken(ctx, user.ID, model.TokenPurposeResetPassword, resetPasswordTokenTTL)
+	if err != nil {
+		return err
+	}
+	return au.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "パスワードの再設定",
+		Body: fmt.Sprintf("%s 様\n\n以下のリンクからパスワードを再設定してください（有効期限: 1時間）。\n%s\n\n心当たりがない場合はこのメールを破棄してください。\n",
+			user.Name, au.link("/reset-password", token)),
+	})
+}
+
+func (au *accountUsecase) ResetPassword(ctx context.Context, token string, password string) error {
+	if err := au.uv.PasswordValidate(password); err != nil {
+		return err
+	}
+	if !au.checkSignature(token, model.TokenPurposeResetPassword) {
+		return repository.ErrInvalidUserToken
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+	if err != nil {
+		return err
+	}
+	_, err = au.utr.ResetPassword(ctx, hashToken(token), string(hash))
+	return err
+}
+
+// issueUserToken は署名付きのトークンを作成し、ハッシュをDBに保存します。
+// トークンは "<乱数>.<署名>" の形式で、署名が一致しないものはDBを参照せずに拒否できます。
+func (au *accountUsecase) issueUserToken(ctx context.Context, userId uint, purpose string, ttl time.Duration) (string, error) {
+	nonce, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+	token := nonce + "." + au.sign(purpose, nonce)
+	err = au.utr.CreateUserToken(ctx, &model.UserToken{
+		Purpose:   purpose,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+		UserID:    userId,
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (au *accountUsecase) sign(purpose string, nonce string) string {
+	mac := hmac.New(sha256.New, au.secret)
+	mac.Write([]byte(purpose + "." + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func (au *accountUsecase) checkSignature(token string, purpose string) bool {
+	nonce, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(au.sign(purpose, nonce)))
+}
+
+func (au *accountUsecase) link(path string, token string) string {
+	return strings.TrimRight(au.appURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
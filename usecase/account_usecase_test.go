@@ -0,0 +1,214 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"go-rest-api/mailer"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"go-rest-api/repository/memory"
+	"go-rest-api/usecase"
+	"go-rest-api/validator"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// accountTest はメモリ上のリポジトリと MemoryMailer を使うアカウントのユースケースです。
+type accountTest struct {
+	au     usecase.IAccountUsecase
+	mails  *mailer.MemoryMailer
+	users  repository.IUserRepository
+	tokens repository.IRefreshTokenRepository
+}
+
+func newAccountTest(t *testing.T, wrap func(repository.IUserTokenRepository) repository.IUserTokenRepository) accountTest {
+	t.Helper()
+	s := memory.NewStore()
+	utr := memory.NewUserTokenRepository(s)
+	if wrap != nil {
+		utr = wrap(utr)
+	}
+	at := accountTest{mails: mailer.NewMemoryMailer(), users: memory.NewUserRepository(s), tokens: memory.NewRefreshTokenRepository(s)}
+	at.au = usecase.NewAccountUsecase(at.users, utr, validator.NewUserValidator(), at.mails, []byte("secret"), "https://app.example.com/")
+	return at
+}
+
+func (at accountTest) createUser(t *testing.T, email string) model.User {
+	t.Helper()
+	user := model.User{Email: email, Password: "hash", Name: "Alice"}
+	if err := at.users.CreateUser(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+var tokenLink = regexp.MustCompile(`https://app\.example\.com(/[a-z-]+)\?token=(\S+)`)
+
+// lastToken は最後に送信したメールのリンクのパスとトークンを返します。
+func (at accountTest) lastToken(t *testing.T, to string) (string, string) {
+	t.Helper()
+	messages := at.mails.Messages()
+	if len(messages) == 0 {
+		t.Fatal("no mail was sent")
+	}
+	msg := messages[len(messages)-1]
+	if msg.To != to {
+		t.Fatalf("mail sent to %q, want %q", msg.To, to)
+	}
+	m := tokenLink.FindStringSubmatch(msg.Body)
+	if m == nil {
+		t.Fatalf("no link in mail: %q", msg.Body)
+	}
+	token, err := url.QueryUnescape(m[2])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m[1], token
+}
+
+// expiredTokens は有効期限が切れた状態でトークンを作成します。
+type expiredTokens struct {
+	repository.IUserTokenRepository
+}
+
+func (et expiredTokens) CreateUserToken(ctx context.Context, token *model.UserToken) error {
+	token.ExpiresAt = time.Now().Add(-time.Second)
+	return et.IUserTokenRepository.CreateUserToken(ctx, token)
+}
+
+func wantInvalidToken(t *testing.T, err error) {
+	t.Helper()
+	if !errors.Is(err, repository.ErrInvalidUserToken) {
+		t.Fatalf("want ErrInvalidUserToken, got %v", err)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	ctx := context.Background()
+	at := newAccountTest(t, nil)
+	user := at.createUser(t, "alice@example.com")
+
+	if err := at.au.RequestEmailVerification(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	path, token := at.lastToken(t, "alice@example.com")
+	if path != "/verify-email" {
+		t.Fatalf("link path = %q", path)
+	}
+	// 用途の違うトークンとしては使えない
+	wantInvalidToken(t, at.au.ResetPassword(ctx, token, "new-password-123"))
+
+	if err := at.au.VerifyEmail(ctx, token); err != nil {
+		t.Fatal(err)
+	}
+	got := model.User{}
+	if err := at.users.GetUserById(ctx, &got, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if !got.EmailVerified {
+		t.Fatal("email is not verified")
+	}
+	// 一度だけ使える
+	wantInvalidToken(t, at.au.VerifyEmail(ctx, token))
+
+	// 確認済みのユーザーにはメールを送らない
+	if err := at.au.RequestEmailVerification(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(at.mails.Messages()); n != 1 {
+		t.Fatalf("mails sent = %d, want 1", n)
+	}
+}
+
+func TestVerifyEmailReissued(t *testing.T) {
+	ctx := context.Background()
+	at := newAccountTest(t, nil)
+	user := at.createUser(t, "alice@example.com")
+	if err := at.au.RequestEmailVerification(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, first := at.lastToken(t, "alice@example.com")
+	if err := at.au.RequestEmailVerification(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, second := at.lastToken(t, "alice@example.com")
+	// 再送すると前のトークンは使えない
+	wantInvalidToken(t, at.au.VerifyEmail(ctx, first))
+	if err := at.au.VerifyEmail(ctx, second); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyEmailExpired(t *testing.T) {
+	ctx := context.Background()
+	at := newAccountTest(t, func(utr repository.IUserTokenRepository) repository.IUserTokenRepository { return expiredTokens{utr} })
+	user := at.createUser(t, "alice@example.com")
+	if err := at.au.RequestEmailVerification(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	_, token := at.lastToken(t, "alice@example.com")
+	wantInvalidToken(t, at.au.VerifyEmail(ctx, token))
+}
+
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+	at := newAccountTest(t, nil)
+	user := at.createUser(t, "alice@example.com")
+	session := model.RefreshToken{TokenHash: "session", FamilyID: "f", ExpiresAt: time.Now().Add(time.Hour), UserID: user.ID}
+	if err := at.tokens.CreateRefreshToken(ctx, &session); err != nil {
+		t.Fatal(err)
+	}
+
+	// 未登録のメールアドレスでもエラーにせず、メールも送らない
+	if err := at.au.RequestPasswordReset(ctx, "bob@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(at.mails.Messages()); n != 0 {
+		t.Fatalf("mails sent for an unknown address = %d", n)
+	}
+
+	if err := at.au.RequestPasswordReset(ctx, "alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	path, token := at.lastToken(t, "alice@example.com")
+	if path != "/reset-password" {
+		t.Fatalf("link path = %q", path)
+	}
+	// 用途の違うトークンとしては使えない
+	wantInvalidToken(t, at.au.VerifyEmail(ctx, token))
+	// 改ざんしたトークンは使えない
+	wantInvalidToken(t, at.au.ResetPassword(ctx, token+"x", "new-password-123"))
+
+	if err := at.au.ResetPassword(ctx, token, "new-password-123"); err != nil {
+		t.Fatal(err)
+	}
+	got := model.User{}
+	if err := at.users.GetUserById(ctx, &got, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	if bcrypt.CompareHashAndPassword([]byte(got.Password), []byte("new-password-123")) != nil {
+		t.Fatal("password was not updated")
+	}
+	// 既存のセッションは失効する
+	revoked := model.RefreshToken{}
+	if err := at.tokens.GetRefreshTokenByHash(ctx, &revoked, "session"); err != nil || revoked.RevokedAt == nil {
+		t.Fatalf("session after reset = %+v, %v", revoked, err)
+	}
+	// 一度だけ使える
+	wantInvalidToken(t, at.au.ResetPassword(ctx, token, "another-password-123"))
+}
+
+func TestResetPasswordExpired(t *testing.T) {
+	ctx := context.Background()
+	at := newAccountTest(t, func(utr repository.IUserTokenRepository) repository.IUserTokenRepository { return expiredTokens{utr} })
+	at.createUser(t, "alice@example.com")
+	if err := at.au.RequestPasswordReset(ctx, "alice@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	_, token := at.lastToken(t, "alice@example.com")
+	wantInvalidToken(t, at.au.ResetPassword(ctx, token, "new-password-123"))
+}
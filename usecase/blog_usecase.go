@@ -1,104 +1,141 @@
 package usecase
 
 import (
+	"context"
+	"fmt"
+	"go-rest-api/media"
 	"go-rest-api/model"
 	"go-rest-api/repository"
+	"go-rest-api/storage"
 	"go-rest-api/validator"
+	"io"
 )
 
 type IBlogUsecase interface {
-	GetAllBlogs(userId uint) ([]model.BlogResponse, error)
-	GetBlogById(userId uint, blogId uint) (model.BlogResponse, error)
-	CreateBlog(blog model.Blog) (model.BlogResponse, error)
-	UpdateBlog(blog model.Blog, userId uint, blogId uint) (model.BlogResponse, error)
-	DeleteBlog(userId uint, blogId uint) error
-	GetAllBlogsForBuild() ([]model.Blog, error)
+	GetAllBlogs(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.BlogResponse], error)
+	GetBlogById(ctx context.Context, userId uint, blogId uint) (model.BlogResponse, error)
+	CreateBlog(ctx context.Context, blog model.Blog) (model.BlogResponse, error)
+	UpdateBlog(ctx context.Context, blog model.Blog, userId uint, blogId uint) (model.BlogResponse, error)
+	SetBlogCover(ctx context.Context, userId uint, blogId uint, file io.Reader) (model.BlogResponse, error)
+	DeleteBlogCover(ctx context.Context, userId uint, blogId uint) (model.BlogResponse, error)
+	DeleteBlog(ctx context.Context, userId uint, blogId uint) error
+	GetAllBlogsForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.Blog], error)
 }
 
 type blogUsecase struct {
 	br repository.IBlogRepository
 	bv validator.IBlogValidator
+	st storage.IStorage
+	mp media.IProcessor
 }
 
-func NewBlogUsecase(br repository.IBlogRepository, bv validator.IBlogValidator) IBlogUsecase {
-	return &blogUsecase{br, bv}
+func NewBlogUsecase(br repository.IBlogRepository, bv validator.IBlogValidator, st storage.IStorage, mp media.IProcessor) IBlogUsecase {
+	return &blogUsecase{br, bv, st, mp}
 }
 
-func (bu *blogUsecase) GetAllBlogs(userId uint) ([]model.BlogResponse, error) {
-	blogs := []model.Blog{}
-	if err := bu.br.GetAllBlogs(&blogs, userId); err != nil {
-		return nil, err
+func (bu *blogUsecase) GetAllBlogs(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.BlogResponse], error) {
+	blogs, err := bu.br.GetAllBlogs(ctx, userId, q)
+	if err != nil {
+		return model.Page[model.BlogResponse]{}, err
 	}
-	resBlogs := []model.BlogResponse{}
-	for _, v := range blogs {
-		b := model.BlogResponse{
-			ID:        v.ID,
-			Title:     v.Title,
-			Content:   v.Content,
-			CreatedAt: v.CreatedAt,
-			UpdatedAt: v.UpdatedAt,
-		}
-		resBlogs = append(resBlogs, b)
-	}
-	return resBlogs, nil
+	return mapPage(blogs, toBlogResponse), nil
 }
 
-func (bu *blogUsecase) GetBlogById(userId uint, blogId uint) (model.BlogResponse, error) {
+func (bu *blogUsecase) GetBlogById(ctx context.Context, userId uint, blogId uint) (model.BlogResponse, error) {
 	blog := model.Blog{}
-	if err := bu.br.GetBlogById(&blog, userId, blogId); err != nil {
+	if err := bu.br.GetBlogById(ctx, &blog, userId, blogId); err != nil {
 		return model.BlogResponse{}, err
 	}
-	resBlog := model.BlogResponse{
-		ID:        blog.ID,
-		Title:     blog.Title,
-		Content:   blog.Content,
-		CreatedAt: blog.CreatedAt,
-		UpdatedAt: blog.UpdatedAt,
-	}
+	resBlog := toBlogResponse(blog)
 	return resBlog, nil
 }
 
-func (bu *blogUsecase) CreateBlog(blog model.Blog) (model.BlogResponse, error) {
+func (bu *blogUsecase) CreateBlog(ctx context.Context, blog model.Blog) (model.BlogResponse, error) {
 	if err := bu.bv.BlogValidate(blog); err != nil {
 		return model.BlogResponse{}, err
 	}
-	if err := bu.br.CreateBlog(&blog); err != nil {
+	// カバー画像はアップロード用のエンドポイントでのみ設定する
+	blog.Cover = model.ImageFile{}
+	if err := bu.br.CreateBlog(ctx, &blog); err != nil {
 		return model.BlogResponse{}, err
 	}
-	resBlog := model.BlogResponse{
-		ID:        blog.ID,
-		Title:     blog.Title,
-		Content:   blog.Content,
-		CreatedAt: blog.CreatedAt,
-		UpdatedAt: blog.UpdatedAt,
-	}
+	resBlog := toBlogResponse(blog)
 	return resBlog, nil
 }
 
-func (bu *blogUsecase) UpdateBlog(blog model.Blog, userId uint, blogId uint) (model.BlogResponse, error) {
+func (bu *blogUsecase) UpdateBlog(ctx context.Context, blog model.Blog, userId uint, blogId uint) (model.BlogResponse, error) {
 	if err := bu.bv.BlogValidate(blog); err != nil {
 		return model.BlogResponse{}, err
 	}
-	if err := bu.br.UpdateBlog(&blog, userId, blogId); err != nil {
+	if err := bu.br.UpdateBlog(ctx, &blog, userId, blogId); err != nil {
 		return model.BlogResponse{}, err
 	}
-	resBlog := model.BlogResponse{
-		ID:        blog.ID,
-		Title:     blog.Title,
-		Content:   blog.Content,
-		CreatedAt: blog.CreatedAt,
-		UpdatedAt: blog.UpdatedAt,
-	}
+	resBlog := toBlogResponse(blog)
 	return resBlog, nil
 }
 
-func (bu *blogUsecase) DeleteBlog(userId uint, blogId uint) error {
-	if err := bu.br.DeleteBlog(userId, blogId); err != nil {
+// SetBlogCover はブログのカバー画像をアップロードした画像に置き換え、以前の画像を削除します。
+func (bu *blogUsecase) SetBlogCover(ctx context.Context, userId uint, blogId uint, file io.Reader) (model.BlogResponse, error) {
+	blog := model.Blog{}
+	if err := bu.br.GetBlogById(ctx, &blog, userId, blogId); err != nil {
+		return model.BlogResponse{}, err
+	}
+	previous := blog.Cover
+	stored, err := storeImage(bu.st, bu.mp, fmt.Sprintf("blogs/%d", blogId), file)
+	if err != nil {
+		return model.BlogResponse{}, err
+	}
+	blog.Cover = stored
+	if err := bu.br.UpdateBlogCover(ctx, &blog, userId, blogId); err != nil {
+		removeImage(bu.st, stored)
+		return model.BlogResponse{}, err
+	}
+	removeImage(bu.st, previous)
+	return toBlogResponse(blog), nil
+}
+
+func (bu *blogUsecase) DeleteBlogCover(ctx context.Context, userId uint, blogId uint) (model.BlogResponse, error) {
+	blog := model.Blog{}
+	if err := bu.br.GetBlogById(ctx, &blog, userId, blogId); err != nil {
+		return model.BlogResponse{}, err
+	}
+	previous := blog.Cover
+	blog.Cover = model.ImageFile{}
+	if err := bu.br.UpdateBlogCover(ctx, &blog, userId, blogId); err != nil {
+		return model.BlogResponse{}, err
+	}
+	removeImage(bu.st, previous)
+	return toBlogResponse(blog), nil
+}
+
+func (bu *blogUsecase) DeleteBlog(ctx context.Context, userId uint, blogId uint) error {
+	blog := model.Blog{}
+	if err := bu.br.GetBlogById(ctx, &blog, userId, blogId); err != nil {
 		return err
 	}
+	if err := bu.br.DeleteBlog(ctx, userId, blogId); err != nil {
+		return err
+	}
+	removeImage(bu.st, blog.Cover)
 	return nil
 }
 
-func (bu *blogUsecase) GetAllBlogsForBuild() ([]model.Blog, error) {
-    return bu.br.GetAllBlogsForBuild()
+func (bu *blogUsecase) GetAllBlogsForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.Blog], error) {
+    return bu.br.GetAllBlogsForBuild(ctx, q)
+}
+
+// toBlogResponse はブログをレスポンス用の型に変換します。カバー画像がない場合は nil です。
+func toBlogResponse(blog model.Blog) model.BlogResponse {
+	res := model.BlogResponse{
+		ID:        blog.ID,
+		Title:     blog.Title,
+		Content:   blog.Content,
+		CreatedAt: blog.CreatedAt,
+		UpdatedAt: blog.UpdatedAt,
+	}
+	if blog.Cover.Key != "" {
+		cover := blog.Cover
+		res.Cover = &cover
+	}
+	return res
 }
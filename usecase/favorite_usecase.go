@@ -1,49 +1,53 @@
 package usecase
 
 import (
+	"context"
 	"go-rest-api/model"
 	"go-rest-api/repository"
 	"go-rest-api/validator"
+	"time"
 )
 
 type IFavoriteUsecase interface {
-	AddFavorite(favorite model.Favorite) (model.FavoriteResponse, error)
-	RemoveFavorite(fshopId, userId string) error
-	GetFavorites(userId string) ([]model.FavoriteResponse, error)
-	GetFavoriteShops(userId string) ([]model.ShopResponse, error)
-	GetFavoritesForBuild() ([]model.FavoriteResponse, error)
+	AddFavorite(ctx context.Context, favorite model.Favorite) (model.FavoriteResponse, error)
+	RemoveFavorite(ctx context.Context, fshopId, userId string) error
+	GetFavorites(ctx context.Context, userId string) ([]model.FavoriteResponse, error)
+	GetFavoriteShops(ctx context.Context, userId string) ([]model.ShopResponse, error)
+	GetFavoritesForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.FavoriteResponse], error)
 }
 
 type favoriteUsecase struct {
-	fr repository.IFavoriteRepository
-	sr repository.IShopRepository  
-	ur repository.IUserRepository  
-	fv validator.IFavoriteValidator 
+	fr  repository.IFavoriteRepository
+	sr  repository.IShopRepository  
+	ur  repository.IUserRepository  
+	uow repository.IUnitOfWork
+	fv  validator.IFavoriteValidator 
+	loc *time.Location
 }
 
-func NewFavoriteUsecase(fr repository.IFavoriteRepository, sr repository.IShopRepository, ur repository.IUserRepository, fv validator.IFavoriteValidator) IFavoriteUsecase {
-	return &favoriteUsecase{fr, sr, ur, fv}
+// NewFavoriteUsecase はお気に入りのユースケースを作成します。loc はショップが営業中かどうかを判定するタイムゾーンです。
+func NewFavoriteUsecase(fr repository.IFavoriteRepository, sr repository.IShopRepository, ur repository.IUserRepository, uow repository.IUnitOfWork, fv validator.IFavoriteValidator, loc *time.Location) IFavoriteUsecase {
+	return &favoriteUsecase{fr, sr, ur, uow, fv, loc}
 }
 
-func (fu *favoriteUsecase) AddFavorite(favorite model.Favorite) (model.FavoriteResponse, error) {
+// AddFavorite はショップとユーザーを確認した上でお気に入りを追加します。
+// 確認と追加は同じトランザクションで行うため、途中で失敗した場合にお気に入りだけが残ることはありません。
+func (fu *favoriteUsecase) AddFavorite(ctx context.Context, favorite model.Favorite) (model.FavoriteResponse, error) {
 	if err := fu.fv.FavoriteValidate(favorite); err != nil {
 		return model.FavoriteResponse{}, err
 	}
 
-	// ここで repository の AddFavorite メソッドを呼び出す
-	err := fu.fr.AddFavorite(&favorite)
-	if err != nil {
-		return model.FavoriteResponse{}, err
-	}
-
 	shop := model.Shop{}
-	err = fu.sr.GetShopById(&shop, favorite.ShopID)
-	if err != nil {
-		return model.FavoriteResponse{}, err
-	}
-
 	user := model.User{}
-	err = fu.ur.GetUserById(&user, favorite.UserID)
+	err := fu.uow.Do(ctx, func(r repository.Repositories) error {
+		if err := r.Shops().GetShopById(ctx, &shop, favorite.ShopID); err != nil {
+			return err
+		}
+		if err := r.Users().GetUserById(ctx, &user, favorite.UserID); err != nil {
+			return err
+		}
+		return r.Favorites().AddFavorite(ctx, &favorite)
+	})
 	if err != nil {
 		return model.FavoriteResponse{}, err
 	}
@@ -55,25 +59,25 @@ func (fu *favoriteUsecase) AddFavorite(favorite model.Favorite) (model.FavoriteR
 	}, nil
 }
 
-func (fu *favoriteUsecase) RemoveFavorite(shopId, userId string) error {
-    return fu.fr.RemoveFavorite(shopId, userId)
+func (fu *favoriteUsecase) RemoveFavorite(ctx context.Context, shopId, userId string) error {
+    return fu.fr.RemoveFavorite(ctx, shopId, userId)
 }
 
 
-func (fu *favoriteUsecase) GetFavorites(userId string) ([]model.FavoriteResponse, error) {
+func (fu *favoriteUsecase) GetFavorites(ctx context.Context, userId string) ([]model.FavoriteResponse, error) {
 	favorites := []model.Favorite{}
-	if err := fu.fr.GetFavorites(userId, &favorites); err != nil {
+	if err := fu.fr.GetFavorites(ctx, userId, &favorites); err != nil {
 		return nil, err
 	}
 	resFavorites := []model.FavoriteResponse{}
 	for _, v := range favorites {
 		shop := model.Shop{}
-		err := fu.sr.GetShopById(&shop, v.ShopID)
+		err := fu.sr.GetShopById(ctx, &shop, v.ShopID)
 		if err != nil {
 			return nil, err
 		}
 		user := model.User{}
-		err = fu.ur.GetUserById(&user, v.UserID)
+		err = fu.ur.GetUserById(ctx, &user, v.UserID)
 		if err != nil {
 			return nil, err
 		}
@@ -87,51 +91,32 @@ func (fu *favoriteUsecase) GetFavorites(userId string) ([]model.FavoriteResponse
 	return resFavorites, nil
 }
 
-func (fu *favoriteUsecase) GetFavoriteShops(userId string) ([]model.ShopResponse, error) {
+func (fu *favoriteUsecase) GetFavoriteShops(ctx context.Context, userId string) ([]model.ShopResponse, error) {
 	shops := []model.Shop{}
-	if err := fu.fr.GetFavoriteShops(userId, &shops); err != nil {
+	if err := fu.fr.GetFavoriteShops(ctx, userId, &shops); err != nil {
 		return nil, err
 	}
 	resShops := []model.ShopResponse{}
+	now := time.Now().In(fu.loc)
 	for _, v := range shops {
-		resShops = append(resShops, model.ShopResponse{
-			ID:          v.ID,
-			Name:        v.Name,
-			Address:     v.Address,
-			Area:        v.Area,
-			Genre:       v.Genre,
-			Description: v.Description,
-			CreatedAt:   v.CreatedAt,
-			UpdatedAt:   v.UpdatedAt,
-		})
+		resShops = append(resShops, toShopResponse(v, now))
 	}
 	return resShops, nil
 }
 
-func (fu *favoriteUsecase) GetFavoritesForBuild() ([]model.FavoriteResponse, error) {
-	favorites := []model.Favorite{}
-	if err := fu.fr.GetFavoritesForBuild(&favorites); err != nil {
-		return nil, err
+func (fu *favoriteUsecase) GetFavoritesForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.FavoriteResponse], error) {
+	favorites, err := fu.fr.GetFavoritesForBuild(ctx, q)
+	if err != nil {
+		return model.Page[model.FavoriteResponse]{}, err
 	}
-	resFavorites := []model.FavoriteResponse{}
-	for _, v := range favorites {
-		shop := model.Shop{}
-		err := fu.sr.GetShopById(&shop, v.ShopID)
-		if err != nil {
-			return nil, err
-		}
-		user := model.User{}
-		err = fu.ur.GetUserById(&user, v.UserID)
-		if err != nil {
-			return nil, err
-		}
-		resFavorites = append(resFavorites, model.FavoriteResponse{
+	// ショップとユーザーはリポジトリで一緒に読み込み済み
+	return mapPage(favorites, func(v model.Favorite) model.FavoriteResponse {
+		return model.FavoriteResponse{
 			ID:     v.ID,
-			Shop:   shop,
-			User:   user,
+			Shop:   v.Shop,
+			User:   v.User,
 			IsFavorite: true,
-		})
-	}
-	return resFavorites, nil
+		}
+	}), nil
 }
 
@@ -0,0 +1,46 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+)
+
+type IHealthUsecase interface {
+	// Readiness はデータベースに接続でき、全てのマイグレーションが適用済みかを確認します。
+	// 準備ができていない場合は、その原因をエラーとしても返します。
+	Readiness(ctx context.Context) (model.Readiness, error)
+}
+
+type healthUsecase struct {
+	hr repository.IHealthRepository
+}
+
+func NewHealthUsecase(hr repository.IHealthRepository) IHealthUsecase {
+	return &healthUsecase{hr}
+}
+
+func (hu *healthUsecase) Readiness(ctx context.Context) (model.Readiness, error) {
+	readiness := model.Readiness{
+		Status:     model.HealthUnavailable,
+		Database:   model.HealthUnavailable,
+		Migrations: model.MigrationsReadiness{Status: model.HealthUnavailable},
+	}
+	if err := hu.hr.Ping(ctx); err != nil {
+		return readiness, fmt.Errorf("database: %w", err)
+	}
+	readiness.Database = model.HealthOK
+	pending, err := hu.hr.PendingMigrations(ctx)
+	if err != nil {
+		return readiness, fmt.Errorf("migrations: %w", err)
+	}
+	readiness.Migrations.Pending = pending
+	if pending > 0 {
+		readiness.Migrations.Status = model.HealthPending
+		return readiness, fmt.Errorf("migrations: %d pending", pending)
+	}
+	readiness.Migrations.Status = model.HealthOK
+	readiness.Status = model.HealthOK
+	return readiness, nil
+}
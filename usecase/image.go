@@ -0,0 +1,58 @@
+package usecase
+
+import (
+	"bytes"
+	"errors"
+	"go-rest-api/apperror"
+	"go-rest-api/media"
+	"go-rest-api/model"
+	"go-rest-api/storage"
+	"io"
+	"log/slog"
+)
+
+// storeImage はアップロードされた画像を検証して縮小画像を作り、両方をストレージの prefix 以下に保存します。
+func storeImage(st storage.IStorage, mp media.IProcessor, prefix string, r io.Reader) (model.ImageFile, error) {
+	img, err := mp.Process(r)
+	if errors.Is(err, media.ErrTooLarge) || errors.Is(err, media.ErrUnsupportedType) || errors.Is(err, media.ErrInvalidImage) {
+		return model.ImageFile{}, apperror.Field("file", err)
+	}
+	if err != nil {
+		return model.ImageFile{}, err
+	}
+	name, err := randomToken(16)
+	if err != nil {
+		return model.ImageFile{}, err
+	}
+	file := model.ImageFile{
+		Key:          prefix + "/" + name + img.Ext,
+		ThumbnailKey: prefix + "/" + name + "_thumb" + img.ThumbnailExt,
+		ContentType:  img.ContentType,
+		Size:         int64(len(img.Data)),
+		Width:        img.Width,
+		Height:       img.Height,
+	}
+	if err := st.Put(file.Key, bytes.NewReader(img.Data), img.ContentType); err != nil {
+		return model.ImageFile{}, err
+	}
+	if err := st.Put(file.ThumbnailKey, bytes.NewReader(img.Thumbnail), img.ThumbnailContentType); err != nil {
+		removeImage(st, model.ImageFile{Key: file.Key})
+		return model.ImageFile{}, err
+	}
+	file.URL = st.URL(file.Key)
+	file.ThumbnailURL = st.URL(file.ThumbnailKey)
+	return file, nil
+}
+
+// removeImage はストレージから画像と縮小画像を削除します。
+// DBの更新後に呼ぶため、削除に失敗しても処理は続け、ログに残すのみとします。
+func removeImage(st storage.IStorage, file model.ImageFile) {
+	for _, key := range []string{file.Key, file.ThumbnailKey} {
+		if key == "" {
+			continue
+		}
+		if err := st.Delete(key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			slog.Warn("failed to delete stored image", "key", key, "error", err)
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"go-rest-api/validator"
+)
+
+type IMenuUsecase interface {
+	GetMenus(ctx context.Context, shopId uint) ([]model.Menu, error)
+	GetMenusForOwner(ctx context.Context, shopId uint, userId uint, role string) ([]model.Menu, error)
+	CreateMenu(ctx context.Context, menu model.Menu, userId uint, role string) (model.Menu, error)
+	UpdateMenu(ctx context.Context, menu model.Menu, shopId uint, menuId uint, userId uint, role string) (model.Menu, error)
+	DeleteMenu(ctx context.Context, shopId uint, menuId uint, userId uint, role string) error
+	CreateCourse(ctx context.Context, course model.Course, userId uint, role string) (model.Course, error)
+	UpdateCourse(ctx context.Context, course model.Course, shopId uint, courseId uint, userId uint, role string) (model.Course, error)
+	DeleteCourse(ctx context.Context, shopId uint, courseId uint, userId uint, role string) error
+}
+
+type menuUsecase struct {
+	mr repository.IMenuRepository
+	sr repository.IShopRepository
+	mv validator.IMenuValidator
+}
+
+func NewMenuUsecase(mr repository.IMenuRepository, sr repository.IShopRepository, mv validator.IMenuValidator) IMenuUsecase {
+	return &menuUsecase{mr, sr, mv}
+}
+
+// GetMenus はショップのメニューを提供中のコースとともに返します。
+func (mu *menuUsecase) GetMenus(ctx context.Context, shopId uint) ([]model.Menu, error) {
+	shop := model.Shop{}
+	if err := mu.sr.GetShopById(ctx, &shop, shopId); err != nil {
+		return nil, err
+	}
+	return mu.mr.GetMenusByShop(ctx, shopId, true)
+}
+
+// GetMenusForOwner はショップのメニューを提供を停止したコースも含めて返します。
+func (mu *menuUsecase) GetMenusForOwner(ctx context.Context, shopId uint, userId uint, role string) ([]model.Menu, error) {
+	if _, err := authorizeShop(ctx, mu.sr, shopId, userId, role); err != nil {
+		return nil, err
+	}
+	return mu.mr.GetMenusByShop(ctx, shopId, false)
+}
+
+func (mu *menuUsecase) CreateMenu(ctx context.Context, menu model.Menu, userId uint, role string) (model.Menu, error) {
+	if err := mu.mv.MenuValidate(menu); err != nil {
+		return model.Menu{}, err
+	}
+	if _, err := authorizeShop(ctx, mu.sr, menu.ShopID, userId, role); err != nil {
+		return model.Menu{}, err
+	}
+	// コースはコース用のエンドポイントで追加する
+	menu.Courses = nil
+	if err := mu.mr.CreateMenu(ctx, &menu); err != nil {
+		return model.Menu{}, err
+	}
+	menu.Courses = []model.Course{}
+	return menu, nil
+}
+
+func (mu *menuUsecase) UpdateMenu(ctx context.Context, menu model.Menu, shopId uint, menuId uint, userId uint, role string) (model.Menu, error) {
+	if err := mu.mv.MenuValidate(menu); err != nil {
+		return model.Menu{}, err
+	}
+	if _, err := authorizeShop(ctx, mu.sr, shopId, userId, role); err != nil {
+		return model.Menu{}, err
+	}
+	menu.Courses = nil
+	if err := mu.mr.UpdateMenu(ctx, &menu, shopId, menuId); err != nil {
+		return model.Menu{}, err
+	}
+	return menu, nil
+}
+
+// DeleteMenu はメニューをコースとともに削除します。コースを選んだ予約はコースなしの予約として残ります。
+func (mu *menuUsecase) DeleteMenu(ctx context.Context, shopId uint, menuId uint, userId uint, role string) error {
+	if _, err := authorizeShop(ctx, mu.sr, shopId, userId, role); err != nil {
+		return err
+	}
+	return mu.mr.DeleteMenu(ctx, shopId, menuId)
+}
+
+// CreateCourse はメニューにコースを追加します。
+func (mu *menuUsecase) CreateCourse(ctx context.Context, course model.Course, userId uint, role string) (model.Course, error) {
+	if err := mu.mv.CourseValidate(course); err != nil {
+		return model.Course{}, err
+	}
+	if _, err := authorizeShop(ctx, mu.sr, course.ShopID, userId, role); err != nil {
+		return model.Course{}, err
+	}
+	menu := model.Menu{}
+	if err := mu.mr.GetMenuById(ctx, &menu, course.ShopID, course.MenuID); err != nil {
+		return model.Course{}, err
+	}
+	for i := range course.Windows {
+		course.Windows[i].ID = 0
+	}
+	if err := mu.mr.CreateCourse(ctx, &course); err != nil {
+		return model.Course{}, err
+	}
+	return course, nil
+}
+
+// UpdateCourse はコースを更新し、予約できる時間帯を置き換えます。価格の変更は既存の予約の合計金額には影響しません。
+func (mu *menuUsecase) UpdateCourse(ctx context.Context, course model.Course, shopId uint, courseId uint, userId uint, role string) (model.Course, error) {
+	if err := mu.mv.CourseValidate(course); err != nil {
+		return model.Course{}, err
+	}
+	if _, err := authorizeShop(ctx, mu.sr, shopId, userId, role); err != nil {
+		return model.Course{}, err
+	}
+	if course.Windows == nil {
+		course.Windows = []model.CourseWindow{}
+	}
+	if err := mu.mr.UpdateCourse(ctx, &course, shopId, courseId); err != nil {
+		return model.Course{}, err
+	}
+	return course, nil
+}
+
+func (mu *menuUsecase) DeleteCourse(ctx context.Context, shopId uint, courseId uint, userId uint, role string) error {
+	if _, err := authorizeShop(ctx, mu.sr, shopId, userId, role); err != nil {
+		return err
+	}
+	return mu.mr.DeleteCourse(ctx, shopId, courseId)
+}
@@ -0,0 +1,18 @@
+package usecase
+
+import "go-rest-api/model"
+
+// mapPage はページング情報を保ったまま、一覧の要素をレスポンス用の型に変換します。
+func mapPage[T any, R any](page model.Page[T], f func(T) R) model.Page[R] {
+	items := make([]R, 0, len(page.Items))
+	for _, v := range page.Items {
+		items = append(items, f(v))
+	}
+	return model.Page[R]{
+		Items:      items,
+		Total:      page.Total,
+		Limit:      page.Limit,
+		Offset:     page.Offset,
+		NextCursor: page.NextCursor,
+	}
+}
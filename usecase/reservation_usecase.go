@@ -1,59 +1,340 @@
 package usecase
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"go-rest-api/apperror"
 	"go-rest-api/model"
 	"go-rest-api/repository"
 	"go-rest-api/validator"
+	"time"
 )
 
+// ErrSlotUnavailable は予約時刻がショップの予約枠に一致しない場合に返されます。
+var ErrSlotUnavailable = apperror.BadRequest("requested time is not a bookable slot for this shop")
+
+// ErrInvalidTransition は現在のステータスから指定されたステータスへ変更できない場合に返されます。
+var ErrInvalidTransition = apperror.Conflict("invalid reservation status transition")
+
+// reservationTransitions は各ステータスから変更可能なステータスの一覧です。
+// 一覧にないステータス（キャンセル・無断キャンセル・完了）は終了状態です。
+var reservationTransitions = map[string][]string{
+    model.ReservationPending: {
+        model.ReservationConfirmed,
+        model.ReservationCancelledByUser,
+        model.ReservationCancelledByShop,
+    },
+    model.ReservationConfirmed: {
+        model.ReservationCancelledByUser,
+        model.ReservationCancelledByShop,
+        model.ReservationCheckedIn,
+        model.ReservationNoShow,
+    },
+    model.ReservationCheckedIn: {
+        model.ReservationCompleted,
+    },
+}
+
+// canTransition は from から to へのステータス変更が許可されているかを返します。
+func canTransition(from, to string) bool {
+    for _, s := range reservationTransitions[from] {
+        if s == to {
+            return true
+        }
+    }
+    return false
+}
+
 type IReservationUsecase interface {
-    MakeReservation(reservation model.Reservation) (model.Reservation, error)
-    CancelReservation(reservationId string) error
-    GetReservationByUser(userId string) ([]model.Reservation, error)
-    GetAllReservations() ([]model.Reservation, error)
-    UpdateReservation(reservation model.Reservation) (model.Reservation, error)
-    GetReservationsForBuild() ([]model.Reservation, error)
+    MakeReservation(ctx context.Context, reservation model.Reservation, role string) (model.Reservation, error)
+    CancelReservation(ctx context.Context, userId uint, reservationId uint, role string) error
+    ChangeStatusByShop(ctx context.Context, reservationId uint, status string, userId uint, role string) (model.Reservation, error)
+    GetReservationByUser(ctx context.Context, userId uint) ([]model.Reservation, error)
+    GetReservationsByShop(ctx context.Context, shopId uint, userId uint, role string) ([]model.Reservation, error)
+    GetAllReservations(ctx context.Context, q model.ListQuery) (model.Page[model.Reservation], error)
+    UpdateReservation(ctx context.Context, reservation model.Reservation, userId uint, reservationId uint, role string) (model.Reservation, error)
+    GetReservationsForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.Reservation], error)
+    GetAvailability(ctx context.Context, shopId uint, date time.Time) ([]model.SlotAvailability, error)
 }
 
 type reservationUsecase struct {
     rr repository.IReservationRepository
+    sr repository.IShopRepository
+    uow repository.IUnitOfWork
 	rv validator.IReservationValidator // バリデータのインスタンス
 }
 
-func NewReservationUsecase(rr repository.IReservationRepository, rv validator.IReservationValidator) IReservationUsecase {
-	return &reservationUsecase{rr, rv}
+func NewReservationUsecase(rr repository.IReservationRepository, sr repository.IShopRepository, uow repository.IUnitOfWork, rv validator.IReservationValidator) IReservationUsecase {
+	return &reservationUsecase{rr, sr, uow, rv}
+}
+
+// MakeReservation はショップとコースを読み込んで予約を確認し、作成します。
+// 読み込みから作成までを同じトランザクションで行うため、確認した時点のショップやコースの設定で予約が作成されます。
+// 最初のステータス（pending）は予約したユーザーと role を変更者として履歴に記録します。
+func (ru *reservationUsecase) MakeReservation(ctx context.Context, reservation model.Reservation, role string) (model.Reservation, error) {
+    var created model.Reservation
+    var course *model.Course
+    err := ru.uow.Do(ctx, func(r repository.Repositories) error {
+        shop := model.Shop{}
+        if err := r.Shops().GetShopById(ctx, &shop, reservation.ShopID); err != nil {
+            return err
+        }
+        var err error
+        course, err = getCourse(ctx, r.Menus(), reservation.CourseID)
+        if err != nil {
+            return err
+        }
+        // 入力データのバリデーションを行う（営業時間外や休業中の時刻、コースの条件に合わない予約は受け付けない）
+        if err := ru.rv.ReservationValidate(reservation, shop, course); err != nil {
+            return err
+        }
+        reservation.TotalPrice = totalPrice(course, reservation.Num)
+        if err := checkSlot(&reservation, shop); err != nil {
+            return err
+        }
+        reservation.Status = model.ReservationPending
+        // コースはリクエストボディの内容で作成・更新されないよう、保存後に設定する
+        reservation.Course = nil
+        // バリデーションが成功したら、予約を作成（残り席数はリポジトリ側で確認）
+        change := model.ReservationStatusChange{
+            ActorID:   reservation.UserID,
+            ActorRole: role,
+        }
+        created, err = r.Reservations().MakeReservation(ctx, &reservation, &change)
+        return err
+    })
+    if err != nil {
+        return model.Reservation{}, err
+    }
+    created.Course = course
+    return created, nil
+}
+
+// CancelReservation は利用者自身による予約のキャンセルです。予約は削除せずステータスを変更します。
+// 変更者のロールには利用者の role を記録します。
+func (ru *reservationUsecase) CancelReservation(ctx context.Context, userId uint, reservationId uint, role string) error {
+    reservation := model.Reservation{}
+    if err := ru.rr.GetReservationById(ctx, &reservation, userId, reservationId); err != nil {
+        return err
+    }
+    return ru.changeStatus(ctx, &reservation, model.ReservationCancelledByUser, userId, role)
 }
 
-func (ru *reservationUsecase) MakeReservation(reservation model.Reservation) (model.Reservation, error) {
-    // 入力データのバリデーションを行う
-    if err := ru.rv.ReservationValidate(reservation); err != nil {
+// ChangeStatusByShop はショップスタッフによるステータス変更（確定・来店・無断キャンセルなど）です。
+// 管理者以外はショップのオーナーのみ変更できます。
+func (ru *reservationUsecase) ChangeStatusByShop(ctx context.Context, reservationId uint, status string, userId uint, role string) (model.Reservation, error) {
+    reservation := model.Reservation{}
+    if err := ru.rr.GetReservation(ctx, &reservation, reservationId); err != nil {
+        return model.Reservation{}, err
+    }
+    if role != model.RoleAdmin {
+        shop := model.Shop{}
+        if err := ru.sr.GetShopById(ctx, &shop, reservation.ShopID); err != nil {
+            return model.Reservation{}, err
+        }
+        if shop.OwnerID == nil || *shop.OwnerID != userId {
+            return model.Reservation{}, ErrForbidden
+        }
+    }
+    if err := ru.changeStatus(ctx, &reservation, status, userId, role); err != nil {
         return model.Reservation{}, err
     }
-    // バリデーションが成功したら、予約を作成
-    return ru.rr.MakeReservation(&reservation)
+    return reservation, nil
+}
+
+// changeStatus は遷移が許可されているかを確認し、変更者とともにステータスを記録します。
+func (ru *reservationUsecase) changeStatus(ctx context.Context, reservation *model.Reservation, status string, actorId uint, actorRole string) error {
+    if !canTransition(reservation.Status, status) {
+        return ErrInvalidTransition
+    }
+    change := model.ReservationStatusChange{
+        ActorID:   actorId,
+        ActorRole: actorRole,
+    }
+    return ru.rr.ChangeStatus(ctx, reservation, status, &change)
 }
 
-func (ru *reservationUsecase) CancelReservation(reservationId string) error {
-    return ru.rr.CancelReservation(reservationId)
+func (ru *reservationUsecase) GetReservationByUser(ctx context.Context, userId uint) ([]model.Reservation, error) {
+    return ru.rr.GetReservationByUser(ctx, userId)
 }
 
-func (ru *reservationUsecase) GetReservationByUser(userId string) ([]model.Reservation, error) {
-    return ru.rr.GetReservationByUser(userId)
+// GetReservationsByShop はショップの予約一覧を返します。管理者以外はショップのオーナーのみ閲覧できます。
+func (ru *reservationUsecase) GetReservationsByShop(ctx context.Context, shopId uint, userId uint, role string) ([]model.Reservation, error) {
+    if role != model.RoleAdmin {
+        shop := model.Shop{}
+        if err := ru.sr.GetShopById(ctx, &shop, shopId); err != nil {
+            return nil, err
+        }
+        if shop.OwnerID == nil || *shop.OwnerID != userId {
+            return nil, ErrForbidden
+        }
+    }
+    return ru.rr.GetReservationsByShop(ctx, shopId)
 }
 
-func (ru *reservationUsecase) GetAllReservations() ([]model.Reservation, error) {
-    return ru.rr.GetAllReservations()
+func (ru *reservationUsecase) GetAllReservations(ctx context.Context, q model.ListQuery) (model.Page[model.Reservation], error) {
+    return ru.rr.GetAllReservations(ctx, q)
 }
 
-func (ru *reservationUsecase) UpdateReservation(reservation model.Reservation) (model.Reservation, error) {
-    // 更新前にもバリデーションを行う
-    if err := ru.rv.ReservationValidate(reservation); err != nil {
+// UpdateReservation は予約の日時・人数・コースを変更します。確認から更新までを同じトランザクションで行います。
+// 確定済みの予約の日付または時刻を変更した場合は、ショップが改めて確認できるよう確定前（pending）に戻します。
+func (ru *reservationUsecase) UpdateReservation(ctx context.Context, reservation model.Reservation, userId uint, reservationId uint, role string) (model.Reservation, error) {
+    var updated model.Reservation
+    var course *model.Course
+    err := ru.uow.Do(ctx, func(r repository.Repositories) error {
+        // 自分の予約であることを確認し、ショップやユーザーはリクエストボディではなく既存の予約から引き継ぐ
+        current := model.Reservation{}
+        if err := r.Reservations().GetReservationById(ctx, &current, userId, reservationId); err != nil {
+            return err
+        }
+        // 日時や人数を変更できるのは確定前または確定済みの予約のみ
+        if current.Status != model.ReservationPending && current.Status != model.ReservationConfirmed {
+            return ErrInvalidTransition
+        }
+        reservation.ID = current.ID
+        reservation.ShopID = current.ShopID
+        reservation.UserID = current.UserID
+        reservation.Status = current.Status
+        shop := model.Shop{}
+        if err := r.Shops().GetShopById(ctx, &shop, reservation.ShopID); err != nil {
+            return err
+        }
+        var err error
+        course, err = getCourse(ctx, r.Menus(), reservation.CourseID)
+        if err != nil {
+            return err
+        }
+        // 更新前にもバリデーションを行う
+        if err := ru.rv.ReservationValidate(reservation, shop, course); err != nil {
+            return err
+        }
+        reservation.TotalPrice = totalPrice(course, reservation.Num)
+        if err := checkSlot(&reservation, shop); err != nil {
+            return err
+        }
+        reservation.Course = nil
+        updated, err = r.Reservations().UpdateReservation(ctx, &reservation, userId, reservationId)
+        if err != nil {
+            return err
+        }
+        // 利用者の変更による差し戻しのため、ショップの操作の遷移の一覧は確認しない
+        if current.Status == model.ReservationConfirmed && (!reservation.Date.Equal(current.Date) || reservation.Time != current.Time) {
+            change := model.ReservationStatusChange{
+                ActorID:   userId,
+                ActorRole: role,
+            }
+            if err := r.Reservations().ChangeStatus(ctx, &current, model.ReservationPending, &change); err != nil {
+                return err
+            }
+            updated.Status = current.Status
+        }
+        return nil
+    })
+    if err != nil {
         return model.Reservation{}, err
     }
-    return ru.rr.UpdateReservation(&reservation)
+    updated.Course = course
+    return updated, nil
 }
 
-func (ru *reservationUsecase) GetReservationsForBuild() ([]model.Reservation, error) {
-    return ru.rr.GetReservationsForBuild()
+func (ru *reservationUsecase) GetReservationsForBuild(ctx context.Context, q model.ListQuery) (model.Page[model.Reservation], error) {
+    return ru.rr.GetReservationsForBuild(ctx, q)
 }
 
+// GetAvailability は指定した日の空いている予約枠を返します。
+func (ru *reservationUsecase) GetAvailability(ctx context.Context, shopId uint, date time.Time) ([]model.SlotAvailability, error) {
+    shop := model.Shop{}
+    if err := ru.sr.GetShopById(ctx, &shop, shopId); err != nil {
+        return nil, err
+    }
+    booked, err := ru.rr.GetBookedSeats(ctx, shopId, normalizeDate(date))
+    if err != nil {
+        return nil, err
+    }
+    slots := []model.SlotAvailability{}
+    for _, t := range shopSlots(shop, date) {
+        remaining := shop.Capacity - booked[t]
+        if remaining <= 0 {
+            continue
+        }
+        slots = append(slots, model.SlotAvailability{
+            Time:      t,
+            Capacity:  shop.Capacity,
+            Booked:    booked[t],
+            Remaining: remaining,
+        })
+    }
+    return slots, nil
+}
+
+// checkSlot は予約日を正規化し、予約時刻と人数がショップの設定に合っているかを確認します。
+func checkSlot(reservation *model.Reservation, shop model.Shop) error {
+    reservation.Date = normalizeDate(reservation.Date)
+    if reservation.Num > shop.Capacity {
+        return repository.ErrCapacityExceeded
+    }
+    for _, t := range shopSlots(shop, reservation.Date) {
+        if t == reservation.Time {
+            return nil
+        }
+    }
+    return ErrSlotUnavailable
+}
+
+// getCourse は予約で選ばれたコースを予約できる時間帯とともに返します。コースを選んでいない場合は nil です。
+func getCourse(ctx context.Context, mr repository.IMenuRepository, courseId *uint) (*model.Course, error) {
+    if courseId == nil {
+        return nil, nil
+    }
+    course := model.Course{}
+    err := mr.GetCourseById(ctx, &course, *courseId)
+    if apperror.Is(err, apperror.KindNotFound) {
+        return nil, apperror.Field("course_id", errors.New("course does not exist"))
+    }
+    if err != nil {
+        return nil, err
+    }
+    return &course, nil
+}
+
+// totalPrice は予約の合計金額です。コースを選んでいない場合は0です。
+func totalPrice(course *model.Course, num int) int64 {
+    if course == nil {
+        return 0
+    }
+    return course.TotalPrice(num)
+}
+
+// normalizeDate は日付を UTC の 0 時に揃えます。予約枠は日付と時刻の文字列で識別するためです。
+func normalizeDate(date time.Time) time.Time {
+    return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// shopSlots はその日の営業時間帯と枠の長さから、予約枠の開始時刻を "HH:MM" 形式で返します。
+// 休業の時間帯は除きます。前日から日付をまたぐ営業時間帯のうち0時以降に始まる枠は、その日の枠として含めます。
+func shopSlots(shop model.Shop, date time.Time) []string {
+    const day = 24 * 60
+    slots := []string{}
+    if shop.SlotMinutes <= 0 {
+        return slots
+    }
+    for _, r := range shop.OpenRanges(date.AddDate(0, 0, -1)) {
+        for m := r.Start; m+shop.SlotMinutes <= r.End; m += shop.SlotMinutes {
+            if m >= day {
+                slots = append(slots, formatClock(m-day))
+            }
+        }
+    }
+    for _, r := range shop.OpenRanges(date) {
+        for m := r.Start; m+shop.SlotMinutes <= r.End && m < day; m += shop.SlotMinutes {
+            slots = append(slots, formatClock(m))
+        }
+    }
+    return slots
+}
+
+// formatClock は0時からの分を "HH:MM" 形式にします。
+func formatClock(m int) string {
+    return fmt.Sprintf("%02d:%02d", m/60, m%60)
+}
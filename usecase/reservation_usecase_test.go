@@ -0,0 +1,153 @@
+package usecase_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"go-rest-api/repository/memory"
+	"go-rest-api/usecase"
+	"go-rest-api/validator"
+	"testing"
+	"time"
+)
+
+// reservationTest はメモリ上のリポジトリを使う予約のユースケースです。
+type reservationTest struct {
+	ru   usecase.IReservationUsecase
+	rr   repository.IReservationRepository
+	shop model.Shop
+}
+
+func newReservationTest(t *testing.T) reservationTest {
+	t.Helper()
+	s := memory.NewStore()
+	sr := memory.NewShopRepository(s)
+	shop := model.Shop{Name: "Sushi", Address: "Tokyo", Area: "東京都", Genre: "寿司", Capacity: 10, OpenTime: "11:00", CloseTime: "22:00", SlotMinutes: 60}
+	if err := sr.CreateShop(context.Background(), &shop); err != nil {
+		t.Fatal(err)
+	}
+	rt := reservationTest{rr: memory.NewReservationRepository(s), shop: shop}
+	rt.ru = usecase.NewReservationUsecase(rt.rr, sr, memory.NewUnitOfWork(s), validator.NewReservationValidator())
+	return rt
+}
+
+var reservationDate = time.Date(2030, 1, 7, 0, 0, 0, 0, time.UTC)
+
+func (rt reservationTest) reserve(t *testing.T, userId uint, role string) model.Reservation {
+	t.Helper()
+	reservation, err := rt.ru.MakeReservation(context.Background(), model.Reservation{Date: reservationDate, Time: "12:00", ShopID: rt.shop.ID, UserID: userId, Num: 2}, role)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return reservation
+}
+
+// wantChanges は予約の変更履歴の遷移と変更者のロールを確認します。
+func (rt reservationTest) wantChanges(t *testing.T, reservationId uint, want ...model.ReservationStatusChange) {
+	t.Helper()
+	changes, err := rt.rr.GetStatusChanges(context.Background(), reservationId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("status changes = %+v, want %+v", changes, want)
+	}
+	for i, c := range changes {
+		w := want[i]
+		if c.FromStatus != w.FromStatus || c.ToStatus != w.ToStatus || c.ActorID != w.ActorID || c.ActorRole != w.ActorRole {
+			t.Fatalf("status changes = %+v, want %+v", changes, want)
+		}
+	}
+}
+
+func TestMakeReservationRecordsPending(t *testing.T) {
+	rt := newReservationTest(t)
+	reservation := rt.reserve(t, 1, model.RoleCustomer)
+	if reservation.Status != model.ReservationPending {
+		t.Fatalf("status = %q", reservation.Status)
+	}
+	rt.wantChanges(t, reservation.ID, model.ReservationStatusChange{ToStatus: model.ReservationPending, ActorID: 1, ActorRole: model.RoleCustomer})
+}
+
+func TestMakeReservationIgnoresStatusChangesInBody(t *testing.T) {
+	rt := newReservationTest(t)
+	body := fmt.Sprintf(`{"date":"2030-01-07T00:00:00Z","time":"12:00","shop_id":%d,"num":2,
+		"status_changes":[{"from_status":"confirmed","to_status":"completed","actor_id":9,"actor_role":"admin"}]}`, rt.shop.ID)
+	reservation := model.Reservation{}
+	if err := json.Unmarshal([]byte(body), &reservation); err != nil {
+		t.Fatal(err)
+	}
+	reservation.UserID = 1
+	created, err := rt.ru.MakeReservation(context.Background(), reservation, model.RoleCustomer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// リクエストボディの変更履歴は記録しない
+	rt.wantChanges(t, created.ID, model.ReservationStatusChange{ToStatus: model.ReservationPending, ActorID: 1, ActorRole: model.RoleCustomer})
+}
+
+func TestCancelReservationRecordsRole(t *testing.T) {
+	ctx := context.Background()
+	rt := newReservationTest(t)
+	// ショップのオーナーが利用者として予約した場合も、変更者のロールはそのユーザーのロール
+	reservation := rt.reserve(t, 2, model.RoleShopOwner)
+	if err := rt.ru.CancelReservation(ctx, 2, reservation.ID, model.RoleShopOwner); err != nil {
+		t.Fatal(err)
+	}
+	rt.wantChanges(t, reservation.ID,
+		model.ReservationStatusChange{ToStatus: model.ReservationPending, ActorID: 2, ActorRole: model.RoleShopOwner},
+		model.ReservationStatusChange{FromStatus: model.ReservationPending, ToStatus: model.ReservationCancelledByUser, ActorID: 2, ActorRole: model.RoleShopOwner},
+	)
+}
+
+func TestUpdateConfirmedReservation(t *testing.T) {
+	ctx := context.Background()
+	rt := newReservationTest(t)
+	reservation := rt.reserve(t, 1, model.RoleCustomer)
+	if _, err := rt.ru.ChangeStatusByShop(ctx, reservation.ID, model.ReservationConfirmed, 9, model.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+
+	// 人数のみの変更では確定済みのまま
+	updated, err := rt.ru.UpdateReservation(ctx, model.Reservation{Date: reservationDate, Time: "12:00", Num: 3}, 1, reservation.ID, model.RoleCustomer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != model.ReservationConfirmed {
+		t.Fatalf("status after changing the party size = %q", updated.Status)
+	}
+
+	// 時刻を変更すると確定前に戻る
+	updated, err = rt.ru.UpdateReservation(ctx, model.Reservation{Date: reservationDate, Time: "13:00", Num: 3}, 1, reservation.ID, model.RoleCustomer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := model.Reservation{}
+	if err := rt.rr.GetReservation(ctx, &got, reservation.ID); err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != model.ReservationPending || got.Status != model.ReservationPending || got.Time != "13:00" {
+		t.Fatalf("after changing the time: response %q, stored %+v", updated.Status, got)
+	}
+
+	// 日付の変更も同じ
+	if _, err := rt.ru.ChangeStatusByShop(ctx, reservation.ID, model.ReservationConfirmed, 9, model.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	updated, err = rt.ru.UpdateReservation(ctx, model.Reservation{Date: reservationDate.AddDate(0, 0, 1), Time: "13:00", Num: 3}, 1, reservation.ID, model.RoleCustomer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Status != model.ReservationPending {
+		t.Fatalf("status after changing the date = %q", updated.Status)
+	}
+	rt.wantChanges(t, reservation.ID,
+		model.ReservationStatusChange{ToStatus: model.ReservationPending, ActorID: 1, ActorRole: model.RoleCustomer},
+		model.ReservationStatusChange{FromStatus: model.ReservationPending, ToStatus: model.ReservationConfirmed, ActorID: 9, ActorRole: model.RoleAdmin},
+		model.ReservationStatusChange{FromStatus: model.ReservationConfirmed, ToStatus: model.ReservationPending, ActorID: 1, ActorRole: model.RoleCustomer},
+		model.ReservationStatusChange{FromStatus: model.ReservationPending, ToStatus: model.ReservationConfirmed, ActorID: 9, ActorRole: model.RoleAdmin},
+		model.ReservationStatusChange{FromStatus: model.ReservationConfirmed, ToStatus: model.ReservationPending, ActorID: 1, ActorRole: model.RoleCustomer},
+	)
+}
@@ -0,0 +1,203 @@
+package usecase
+
+import (
+	"context"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"go-rest-api/validator"
+	"time"
+)
+
+// ErrVisitNotCompleted は来店済みのレビューに指定した予約が、自分のそのショップでの完了した予約でない場合に返されます。
+var ErrVisitNotCompleted = apperror.Forbidden("reservation is not a completed visit to this shop")
+
+// ErrReportOwnReview は自分のレビューを通報しようとした場合に返されます。
+var ErrReportOwnReview = apperror.BadRequest("cannot report your own review")
+
+type IReviewUsecase interface {
+	GetShopReviews(ctx context.Context, shopId uint, q model.ListQuery) (model.Page[model.ReviewResponse], error)
+	GetUserReviews(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.ReviewResponse], error)
+	GetReportedReviews(ctx context.Context, q model.ListQuery) (model.Page[model.ReviewModerationResponse], error)
+	CreateReview(ctx context.Context, review model.Review) (model.ReviewResponse, error)
+	UpdateReview(ctx context.Context, review model.Review, userId uint, reviewId uint) (model.ReviewResponse, error)
+	DeleteReview(ctx context.Context, userId uint, reviewId uint) error
+	ReplyReview(ctx context.Context, review model.Review, shopId uint, reviewId uint, userId uint, role string) (model.ReviewResponse, error)
+	ReportReview(ctx context.Context, report model.ReviewReport) error
+	SetReviewHidden(ctx context.Context, reviewId uint, hidden bool) (model.ReviewModerationResponse, error)
+}
+
+type reviewUsecase struct {
+	rvr repository.IReviewRepository
+	rr  repository.IReservationRepository
+	sr  repository.IShopRepository
+	rv  validator.IReviewValidator
+}
+
+func NewReviewUsecase(rvr repository.IReviewRepository, rr repository.IReservationRepository, sr repository.IShopRepository, rv validator.IReviewValidator) IReviewUsecase {
+	return &reviewUsecase{rvr, rr, sr, rv}
+}
+
+func (ru *reviewUsecase) GetShopReviews(ctx context.Context, shopId uint, q model.ListQuery) (model.Page[model.ReviewResponse], error) {
+	shop := model.Shop{}
+	if err := ru.sr.GetShopById(ctx, &shop, shopId); err != nil {
+		return model.Page[model.ReviewResponse]{}, err
+	}
+	reviews, err := ru.rvr.GetShopReviews(ctx, shopId, q)
+	if err != nil {
+		return model.Page[model.ReviewResponse]{}, err
+	}
+	return mapPage(reviews, toReviewResponse), nil
+}
+
+func (ru *reviewUsecase) GetUserReviews(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.ReviewResponse], error) {
+	reviews, err := ru.rvr.GetUserReviews(ctx, userId, q)
+	if err != nil {
+		return model.Page[model.ReviewResponse]{}, err
+	}
+	return mapPage(reviews, toReviewResponse), nil
+}
+
+func (ru *reviewUsecase) GetReportedReviews(ctx context.Context, q model.ListQuery) (model.Page[model.ReviewModerationResponse], error) {
+	reviews, err := ru.rvr.GetReportedReviews(ctx, q)
+	if err != nil {
+		return model.Page[model.ReviewModerationResponse]{}, err
+	}
+	return mapPage(reviews, toReviewModerationResponse), nil
+}
+
+// CreateReview はレビューを投稿します。予約を指定した場合は、その予約が完了していれば来店済みのレビューになります。
+func (ru *reviewUsecase) CreateReview(ctx context.Context, review model.Review) (model.ReviewResponse, error) {
+	if err := ru.rv.ReviewValidate(review); err != nil {
+		return model.ReviewResponse{}, err
+	}
+	shop := model.Shop{}
+	if err := ru.sr.GetShopById(ctx, &shop, review.ShopID); err != nil {
+		return model.ReviewResponse{}, err
+	}
+	review.Verified = false
+	if review.ReservationID != nil {
+		reservation := model.Reservation{}
+		err := ru.rr.GetReservationById(ctx, &reservation, review.UserID, *review.ReservationID)
+		if apperror.Is(err, apperror.KindNotFound) {
+			return model.ReviewResponse{}, ErrVisitNotCompleted
+		}
+		if err != nil {
+			return model.ReviewResponse{}, err
+		}
+		if reservation.ShopID != review.ShopID || reservation.Status != model.ReservationCompleted {
+			return model.ReviewResponse{}, ErrVisitNotCompleted
+		}
+		review.Verified = true
+	}
+	// 返信と通報の状態はリクエストの値を使わない
+	review.Reply = ""
+	review.RepliedAt = nil
+	review.ReportCount = 0
+	review.Hidden = false
+	if err := ru.rvr.CreateReview(ctx, &review); err != nil {
+		return model.ReviewResponse{}, err
+	}
+	return toReviewResponse(review), nil
+}
+
+// UpdateReview は自分のレビューの評価とコメントを更新します。
+func (ru *reviewUsecase) UpdateReview(ctx context.Context, review model.Review, userId uint, reviewId uint) (model.ReviewResponse, error) {
+	current := model.Review{}
+	if err := ru.rvr.GetReviewById(ctx, &current, reviewId); err != nil {
+		return model.ReviewResponse{}, err
+	}
+	if current.UserID != userId {
+		return model.ReviewResponse{}, apperror.NotFound("object does not exist")
+	}
+	review.UserID = current.UserID
+	review.ShopID = current.ShopID
+	if err := ru.rv.ReviewValidate(review); err != nil {
+		return model.ReviewResponse{}, err
+	}
+	if err := ru.rvr.UpdateReview(ctx, &review, userId, reviewId); err != nil {
+		return model.ReviewResponse{}, err
+	}
+	return toReviewResponse(review), nil
+}
+
+func (ru *reviewUsecase) DeleteReview(ctx context.Context, userId uint, reviewId uint) error {
+	return ru.rvr.DeleteReview(ctx, userId, reviewId)
+}
+
+// ReplyReview はショップのオーナーとしてレビューに返信します。管理者以外はショップのオーナーのみ返信できます。
+func (ru *reviewUsecase) ReplyReview(ctx context.Context, review model.Review, shopId uint, reviewId uint, userId uint, role string) (model.ReviewResponse, error) {
+	if err := ru.rv.ReviewReplyValidate(review); err != nil {
+		return model.ReviewResponse{}, err
+	}
+	if role != model.RoleAdmin {
+		shop := model.Shop{}
+		if err := ru.sr.GetShopById(ctx, &shop, shopId); err != nil {
+			return model.ReviewResponse{}, err
+		}
+		if shop.OwnerID == nil || *shop.OwnerID != userId {
+			return model.ReviewResponse{}, ErrForbidden
+		}
+	}
+	review.RepliedAt = nil
+	if review.Reply != "" {
+		now := time.Now()
+		review.RepliedAt = &now
+	}
+	if err := ru.rvr.ReplyReview(ctx, &review, shopId, reviewId); err != nil {
+		return model.ReviewResponse{}, err
+	}
+	return toReviewResponse(review), nil
+}
+
+// ReportReview はレビューを通報します。公開中の他人のレビューのみ通報できます。
+func (ru *reviewUsecase) ReportReview(ctx context.Context, report model.ReviewReport) error {
+	if err := ru.rv.ReviewReportValidate(report); err != nil {
+		return err
+	}
+	review := model.Review{}
+	if err := ru.rvr.GetReviewById(ctx, &review, report.ReviewID); err != nil {
+		return err
+	}
+	if review.Hidden {
+		return apperror.NotFound("object does not exist")
+	}
+	if review.UserID == report.UserID {
+		return ErrReportOwnReview
+	}
+	return ru.rvr.ReportReview(ctx, &report)
+}
+
+// SetReviewHidden は管理者がレビューを非公開にする、または公開に戻します。
+func (ru *reviewUsecase) SetReviewHidden(ctx context.Context, reviewId uint, hidden bool) (model.ReviewModerationResponse, error) {
+	review := model.Review{}
+	if err := ru.rvr.SetReviewHidden(ctx, &review, reviewId, hidden); err != nil {
+		return model.ReviewModerationResponse{}, err
+	}
+	return toReviewModerationResponse(review), nil
+}
+
+// toReviewResponse はレビューをレスポンス用の型に変換します。
+func toReviewResponse(review model.Review) model.ReviewResponse {
+	return model.ReviewResponse{
+		ID:            review.ID,
+		ShopID:        review.ShopID,
+		UserID:        review.UserID,
+		ReservationID: review.ReservationID,
+		Rating:        review.Rating,
+		Comment:       review.Comment,
+		Verified:      review.Verified,
+		Reply:         review.Reply,
+		RepliedAt:     review.RepliedAt,
+		CreatedAt:     review.CreatedAt,
+		UpdatedAt:     review.UpdatedAt,
+	}
+}
+
+func toReviewModerationResponse(review model.Review) model.ReviewModerationResponse {
+	return model.ReviewModerationResponse{
+		ReviewResponse: toReviewResponse(review),
+		ReportCount:    review.ReportCount,
+		Hidden:         review.Hidden,
+	}
+}
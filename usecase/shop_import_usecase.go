@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-rest-api/apperror"
+	"go-rest-api/geocoder"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"go-rest-api/validator"
+)
+
+type IShopImportUsecase interface {
+	ImportShops(ctx context.Context, rows []model.ShopImportRow, opts model.ShopImportOptions) (model.ShopImportReport, error)
+	ExportShops(ctx context.Context) ([]model.Shop, error)
+}
+
+type shopImportUsecase struct {
+	sr repository.IShopRepository
+	sv validator.IShopValidator
+	gc geocoder.IGeocoder
+}
+
+func NewShopImportUsecase(sr repository.IShopRepository, sv validator.IShopValidator, gc geocoder.IGeocoder) IShopImportUsecase {
+	return &shopImportUsecase{sr, sv, gc}
+}
+
+// ImportShops は外部IDをキーにショップを作成・更新し、行ごとの結果を返します。
+// 行のエラーは結果に記録し、保存に失敗した場合など取り込み全体を続けられない場合のみエラーを返します。
+func (iu *shopImportUsecase) ImportShops(ctx context.Context, rows []model.ShopImportRow, opts model.ShopImportOptions) (model.ShopImportReport, error) {
+	report := model.ShopImportReport{
+		DryRun:  opts.DryRun,
+		Atomic:  opts.Atomic,
+		Results: make([]model.ShopImportResult, len(rows)),
+	}
+	shops := make([]*model.Shop, len(rows))
+	seen := map[string]int{}
+	for i := range rows {
+		result := &report.Results[i]
+		result.Line = rows[i].Line
+		shop := rows[i].Shop
+		if shop.ExternalID != nil {
+			result.ExternalID = *shop.ExternalID
+		}
+		if err := iu.prepare(&shop, rows[i].Err); err != nil {
+			setImportError(result, err)
+			continue
+		}
+		if line, ok := seen[result.ExternalID]; ok {
+			setImportError(result, apperror.Field("external_id", fmt.Errorf("external id is already used in line %d", line)))
+			continue
+		}
+		seen[result.ExternalID] = result.Line
+		shops[i] = &shop
+	}
+
+	externalIds := make([]string, 0, len(seen))
+	for id := range seen {
+		externalIds = append(externalIds, id)
+	}
+	existing, err := iu.sr.GetShopsByExternalIDs(ctx, externalIds)
+	if err != nil {
+		return model.ShopImportReport{}, err
+	}
+	current := make(map[string]model.Shop, len(existing))
+	for _, shop := range existing {
+		current[*shop.ExternalID] = shop
+	}
+
+	// 変更のある行だけを保存する
+	pending := []int{}
+	for i, shop := range shops {
+		if shop == nil {
+			continue
+		}
+		result := &report.Results[i]
+		old, ok := current[result.ExternalID]
+		if !ok {
+			result.Action = model.ShopImportCreate
+			pending = append(pending, i)
+			continue
+		}
+		shop.ID = old.ID
+		result.ShopID = old.ID
+		result.Changes = shopChanges(old, *shop)
+		if len(result.Changes) == 0 {
+			result.Action = model.ShopImportUnchanged
+			continue
+		}
+		result.Action = model.ShopImportUpdate
+		pending = append(pending, i)
+	}
+
+	switch {
+	case opts.DryRun:
+	case opts.Atomic:
+		if countImportFailures(report.Results) > 0 {
+			break
+		}
+		batch := make([]*model.Shop, len(pending))
+		for j, i := range pending {
+			batch[j] = shops[i]
+		}
+		if err := iu.sr.SaveImportedShops(ctx, batch); err != nil {
+			return model.ShopImportReport{}, err
+		}
+		for _, i := range pending {
+			report.Results[i].ShopID = shops[i].ID
+		}
+		report.Applied = true
+	default:
+		for _, i := range pending {
+			if err := iu.sr.SaveImportedShops(ctx, []*model.Shop{shops[i]}); err != nil {
+				report.Results[i].Action = model.ShopImportFailed
+				report.Results[i].Error = err.Error()
+				continue
+			}
+			report.Results[i].ShopID = shops[i].ID
+		}
+		report.Applied = true
+	}
+
+	for _, result := range report.Results {
+		switch result.Action {
+		case model.ShopImportCreate:
+			report.Created++
+		case model.ShopImportUpdate:
+			report.Updated++
+		case model.ShopImportUnchanged:
+			report.Unchanged++
+		default:
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// prepare は行のショップに既定値と座標を設定し、バリデーションを行います。
+func (iu *shopImportUsecase) prepare(shop *model.Shop, rowErr error) error {
+	if rowErr != nil {
+		return rowErr
+	}
+	if shop.ExternalID == nil || *shop.ExternalID == "" {
+		return apperror.Field("external_id", errors.New("external id is required"))
+	}
+	// オーナーと評価は取り込みの対象外
+	shop.ID = 0
+	shop.OwnerID = nil
+	shop.RatingAverage = 0
+	shop.RatingCount = 0
+	applyShopDefaults(shop)
+	if err := applyShopLocation(iu.gc, shop); err != nil {
+		return err
+	}
+	return iu.sv.ShopValidate(*shop)
+}
+
+func (iu *shopImportUsecase) ExportShops(ctx context.Context) ([]model.Shop, error) {
+	return iu.sr.GetShopsForExport(ctx)
+}
+
+// setImportError は行をエラーとして記録します。バリデーションエラーは項目ごとのメッセージも記録します。
+func setImportError(result *model.ShopImportResult, err error) {
+	result.Action = model.ShopImportInvalid
+	result.Error = err.Error()
+	var appErr *apperror.Error
+	if errors.As(err, &appErr) && len(appErr.Fields) > 0 {
+		result.Error = appErr.Message
+		result.Errors = appErr.Fields
+	}
+}
+
+func countImportFailures(results []model.ShopImportResult) int {
+	n := 0
+	for _, result := range results {
+		if result.Action == model.ShopImportInvalid || result.Action == model.ShopImportFailed {
+			n++
+		}
+	}
+	return n
+}
+
+// shopChanges は取り込みの対象項目のうち、current から next で変わる項目を返します。
+func shopChanges(current, next model.Shop) []model.FieldChange {
+	from := current.ImportValues()
+	to := next.ImportValues()
+	changes := []model.FieldChange{}
+	for i, column := range model.ShopImportColumns {
+		if from[i] != to[i] {
+			changes = append(changes, model.FieldChange{Field: column, From: from[i], To: to[i]})
+		}
+	}
+	return changes
+}
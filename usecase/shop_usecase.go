@@ -1,111 +1,356 @@
 package usecase
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"go-rest-api/apperror"
+	"go-rest-api/geocoder"
+	"go-rest-api/media"
 	"go-rest-api/model"
 	"go-rest-api/repository"
+	"go-rest-api/storage"
 	"go-rest-api/validator"
+	"io"
+	"time"
 )
 
 type IShopUsecase interface {
-	GetAllShops() ([]model.ShopResponse, error)
-	GetShopById(shopId uint) (model.ShopResponse, error)
-	CreateShop(shop model.Shop) (model.ShopResponse, error)
-	UpdateShop(shop model.Shop, shopId uint) (model.ShopResponse, error)
-	DeleteShop(shopId uint) error
+	GetAllShops(ctx context.Context, q model.ListQuery) (model.Page[model.ShopResponse], error)
+	SearchShops(ctx context.Context, q model.ShopSearchQuery, openNow bool) (model.ShopSearchResponse, error)
+	GetNearbyShops(ctx context.Context, lat, lng, radius float64, limit, offset int) (model.Page[model.NearbyShopResponse], error)
+	GetShopById(ctx context.Context, shopId uint) (model.ShopResponse, error)
+	CreateShop(ctx context.Context, shop model.Shop) (model.ShopResponse, error)
+	UpdateShop(ctx context.Context, shop model.Shop, shopId uint, userId uint, role string, updateOwner bool) (model.ShopResponse, error)
+	DeleteShop(ctx context.Context, shopId uint) error
+	SetShopHours(ctx context.Context, shopId uint, hours []model.ShopHour, userId uint, role string) (model.ShopResponse, error)
+	AddShopClosure(ctx context.Context, closure model.ShopClosure, userId uint, role string) (model.ShopClosure, error)
+	DeleteShopClosure(ctx context.Context, shopId uint, closureId uint, userId uint, role string) error
+	AddShopImage(ctx context.Context, shopId uint, file io.Reader, caption string, userId uint, role string) (model.ShopImage, error)
+	ReorderShopImages(ctx context.Context, shopId uint, imageIds []uint, userId uint, role string) ([]model.ShopImage, error)
+	DeleteShopImage(ctx context.Context, shopId uint, imageId uint, userId uint, role string) error
 }
 
+// ErrInvalidImageOrder は並べ替えの指定がショップの画像の一覧と一致しない場合に返されます。
+var ErrInvalidImageOrder = apperror.Field("image_ids", errors.New("image_ids must list every image of the shop exactly once"))
+
+// ErrForbidden は操作対象に対する権限がない場合に返されます。
+var ErrForbidden = apperror.Forbidden("forbidden")
+
+// 予約枠の設定が省略された場合の既定値
+const (
+	defaultShopCapacity    = 20
+	defaultShopOpenTime    = "11:00"
+	defaultShopCloseTime   = "22:00"
+	defaultShopSlotMinutes = 60
+)
+
 type shopUsecase struct {
-	sr repository.IShopRepository
-	sv validator.IShopValidator
+	sr  repository.IShopRepository
+	sv  validator.IShopValidator
+	gc  geocoder.IGeocoder
+	st  storage.IStorage
+	mp  media.IProcessor
+	loc *time.Location
 }
 
-func NewShopUsecase(sr repository.IShopRepository, sv validator.IShopValidator) IShopUsecase {
-	return &shopUsecase{sr, sv}
+// NewShopUsecase はショップのユースケースを作成します。営業中かどうかは loc（ショップのタイムゾーン）の現在時刻で判定します。
+func NewShopUsecase(sr repository.IShopRepository, sv validator.IShopValidator, gc geocoder.IGeocoder, st storage.IStorage, mp media.IProcessor, loc *time.Location) IShopUsecase {
+	return &shopUsecase{sr, sv, gc, st, mp, loc}
 }
 
-func (su *shopUsecase) GetAllShops() ([]model.ShopResponse, error) {
-	shops := []model.Shop{}
-	if err := su.sr.GetAllShops(&shops); err != nil {
-		return nil, err
+// now はショップのタイムゾーンでの現在時刻です。
+func (su *shopUsecase) now() time.Time {
+	return time.Now().In(su.loc)
+}
+
+// toResponse はショップのタイムゾーンの現在時刻でレスポンスに変換します。
+func (su *shopUsecase) toResponse(shop model.Shop) model.ShopResponse {
+	return toShopResponse(shop, su.now())
+}
+
+func (su *shopUsecase) GetAllShops(ctx context.Context, q model.ListQuery) (model.Page[model.ShopResponse], error) {
+	shops, err := su.sr.GetAllShops(ctx, q)
+	if err != nil {
+		return model.Page[model.ShopResponse]{}, err
 	}
-	resShops := []model.ShopResponse{}
-	for _, v := range shops {
-		s := model.ShopResponse{
-			ID:          v.ID,
-			Name:        v.Name,
-			Address:     v.Address,
-			Area:        v.Area,
-			Genre:       v.Genre,
-			Description: v.Description,
-			CreatedAt:   v.CreatedAt,
-			UpdatedAt:   v.UpdatedAt,
-		}
-		resShops = append(resShops, s)
+	return mapPage(shops, su.toResponse), nil
+}
+
+// SearchShops はショップを検索します。openNow を指定した場合は現在営業中のショップに絞り込みます。
+func (su *shopUsecase) SearchShops(ctx context.Context, q model.ShopSearchQuery, openNow bool) (model.ShopSearchResponse, error) {
+	if openNow {
+		now := su.now()
+		q.OpenAt = &now
 	}
-	return resShops, nil
+	shops, facets, err := su.sr.SearchShops(ctx, q)
+	if err != nil {
+		return model.ShopSearchResponse{}, err
+	}
+	return model.ShopSearchResponse{
+		Page:   mapPage(shops, su.toResponse),
+		Facets: facets,
+	}, nil
+}
+
+// GetNearbyShops は指定地点から radius メートル以内のショップを近い順に返します。
+func (su *shopUsecase) GetNearbyShops(ctx context.Context, lat, lng, radius float64, limit, offset int) (model.Page[model.NearbyShopResponse], error) {
+	shops, err := su.sr.GetNearbyShops(ctx, lat, lng, radius, limit, offset)
+	if err != nil {
+		return model.Page[model.NearbyShopResponse]{}, err
+	}
+	return mapPage(shops, func(v model.NearbyShop) model.NearbyShopResponse {
+		return model.NearbyShopResponse{
+			ShopResponse: su.toResponse(v.Shop),
+			Distance:     v.Distance,
+		}
+	}), nil
 }
 
-func (su *shopUsecase) GetShopById(shopId uint) (model.ShopResponse, error) {
+func (su *shopUsecase) GetShopById(ctx context.Context, shopId uint) (model.ShopResponse, error) {
 	shop := model.Shop{}
-	if err := su.sr.GetShopById(&shop, shopId); err != nil {
+	if err := su.sr.GetShopById(ctx, &shop, shopId); err != nil {
 		return model.ShopResponse{}, err
 	}
-	resShop := model.ShopResponse{
-		ID:          shop.ID,
-		Name:        shop.Name,
-		Address:     shop.Address,
-		Area:        shop.Area,
-		Genre:       shop.Genre,
-		Description: shop.Description,
-		CreatedAt:   shop.CreatedAt,
-		UpdatedAt:   shop.UpdatedAt,
-	}
+	resShop := su.toResponse(shop)
 	return resShop, nil
 }
 
-func (su *shopUsecase) CreateShop(shop model.Shop) (model.ShopResponse, error) {
-	if err := su.sv.ShopValidate(shop); err != nil {
+func (su *shopUsecase) CreateShop(ctx context.Context, shop model.Shop) (model.ShopResponse, error) {
+	// 評価はレビューから集計するため、リクエストの値は使わない
+	shop.RatingAverage = 0
+	shop.RatingCount = 0
+	applyShopDefaults(&shop)
+	if err := applyShopLocation(su.gc, &shop); err != nil {
 		return model.ShopResponse{}, err
 	}
-	if err := su.sr.CreateShop(&shop); err != nil {
+	if err := su.sv.ShopValidate(shop); err != nil {
 		return model.ShopResponse{}, err
 	}
-	resShop := model.ShopResponse{
-		ID:          shop.ID,
-		Name:        shop.Name,
-		Address:     shop.Address,
-		Area:        shop.Area,
-		Genre:       shop.Genre,
-		Description: shop.Description,
-		CreatedAt:   shop.CreatedAt,
-		UpdatedAt:   shop.UpdatedAt,
+	if err := su.sr.CreateShop(ctx, &shop); err != nil {
+		return model.ShopResponse{}, err
 	}
+	resShop := su.toResponse(shop)
 	return resShop, nil
 }
 
-func (su *shopUsecase) UpdateShop(shop model.Shop, shopId uint) (model.ShopResponse, error) {
-	if err := su.sv.ShopValidate(shop); err != nil {
+// UpdateShop はショップを更新します。オーナーは管理者が updateOwner を指定した場合のみ shop.OwnerID に変更し、
+// それ以外は現在のオーナーのままにします。
+func (su *shopUsecase) UpdateShop(ctx context.Context, shop model.Shop, shopId uint, userId uint, role string, updateOwner bool) (model.ShopResponse, error) {
+	applyShopDefaults(&shop)
+	if err := applyShopLocation(su.gc, &shop); err != nil {
 		return model.ShopResponse{}, err
 	}
-	if err := su.sr.UpdateShop(&shop, shopId); err != nil {
+	if err := su.sv.ShopValidate(shop); err != nil {
 		return model.ShopResponse{}, err
 	}
-	resShop := model.ShopResponse{
-		ID:          shop.ID,
-		Name:        shop.Name,
-		Address:     shop.Address,
-		Area:        shop.Area,
-		Genre:       shop.Genre,
-		Description: shop.Description,
-		CreatedAt:   shop.CreatedAt,
-		UpdatedAt:   shop.UpdatedAt,
+	// 管理者以外は自分が所有するショップのみ更新でき、オーナーの変更もできない
+	if role != model.RoleAdmin || !updateOwner {
+		current, err := authorizeShop(ctx, su.sr, shopId, userId, role)
+		if err != nil {
+			return model.ShopResponse{}, err
+		}
+		shop.OwnerID = current.OwnerID
+	}
+	if err := su.sr.UpdateShop(ctx, &shop, shopId); err != nil {
+		return model.ShopResponse{}, err
 	}
+	resShop := su.toResponse(shop)
 	return resShop, nil
 }
 
-func (su *shopUsecase) DeleteShop(shopId uint) error {
-	if err := su.sr.DeleteShop(shopId); err != nil {
+func (su *shopUsecase) DeleteShop(ctx context.Context, shopId uint) error {
+	// 画像のレコードはショップとともに削除されるため、ファイルを消せるよう先に読み込む
+	shop := model.Shop{}
+	if err := su.sr.GetShopById(ctx, &shop, shopId); err != nil {
+		return err
+	}
+	if err := su.sr.DeleteShop(ctx, shopId); err != nil {
+		return err
+	}
+	for _, image := range shop.Images {
+		removeImage(su.st, image.ImageFile)
+	}
+	return nil
+}
+
+// SetShopHours はショップの曜日ごとの営業時間を置き換えます。空の場合は OpenTime〜CloseTime を毎日の営業時間とします。
+func (su *shopUsecase) SetShopHours(ctx context.Context, shopId uint, hours []model.ShopHour, userId uint, role string) (model.ShopResponse, error) {
+	if err := su.sv.ShopHoursValidate(hours); err != nil {
+		return model.ShopResponse{}, err
+	}
+	if _, err := authorizeShop(ctx, su.sr, shopId, userId, role); err != nil {
+		return model.ShopResponse{}, err
+	}
+	if err := su.sr.ReplaceShopHours(ctx, shopId, hours); err != nil {
+		return model.ShopResponse{}, err
+	}
+	return su.GetShopById(ctx, shopId)
+}
+
+// AddShopClosure はショップに祝日や貸切などの休業を登録します。
+func (su *shopUsecase) AddShopClosure(ctx context.Context, closure model.ShopClosure, userId uint, role string) (model.ShopClosure, error) {
+	if err := su.sv.ShopClosureValidate(closure); err != nil {
+		return model.ShopClosure{}, err
+	}
+	if _, err := authorizeShop(ctx, su.sr, closure.ShopID, userId, role); err != nil {
+		return model.ShopClosure{}, err
+	}
+	// 予約と同じく日付は UTC の 0 時に揃える
+	closure.Date = normalizeDate(closure.Date)
+	if err := su.sr.CreateShopClosure(ctx, &closure); err != nil {
+		return model.ShopClosure{}, err
+	}
+	return closure, nil
+}
+
+func (su *shopUsecase) DeleteShopClosure(ctx context.Context, shopId uint, closureId uint, userId uint, role string) error {
+	if _, err := authorizeShop(ctx, su.sr, shopId, userId, role); err != nil {
+		return err
+	}
+	return su.sr.DeleteShopClosure(ctx, shopId, closureId)
+}
+
+// AddShopImage はショップのギャラリーの最後に画像を追加します。
+func (su *shopUsecase) AddShopImage(ctx context.Context, shopId uint, file io.Reader, caption string, userId uint, role string) (model.ShopImage, error) {
+	image := model.ShopImage{ShopID: shopId, Caption: caption}
+	if err := su.sv.ShopImageValidate(image); err != nil {
+		return model.ShopImage{}, err
+	}
+	if _, err := authorizeShop(ctx, su.sr, shopId, userId, role); err != nil {
+		return model.ShopImage{}, err
+	}
+	stored, err := storeImage(su.st, su.mp, fmt.Sprintf("shops/%d", shopId), file)
+	if err != nil {
+		return model.ShopImage{}, err
+	}
+	image.ImageFile = stored
+	if err := su.sr.CreateShopImage(ctx, &image); err != nil {
+		removeImage(su.st, stored)
+		return model.ShopImage{}, err
+	}
+	return image, nil
+}
+
+// ReorderShopImages はギャラリーの画像を imageIds の順に並べ替えます。imageIds にはショップの全ての画像を1回ずつ指定します。
+func (su *shopUsecase) ReorderShopImages(ctx context.Context, shopId uint, imageIds []uint, userId uint, role string) ([]model.ShopImage, error) {
+	shop, err := authorizeShop(ctx, su.sr, shopId, userId, role)
+	if err != nil {
+		return nil, err
+	}
+	if len(imageIds) != len(shop.Images) {
+		return nil, ErrInvalidImageOrder
+	}
+	images := map[uint]model.ShopImage{}
+	for _, image := range shop.Images {
+		images[image.ID] = image
+	}
+	ordered := make([]model.ShopImage, 0, len(imageIds))
+	for i, id := range imageIds {
+		image, ok := images[id]
+		if !ok {
+			return nil, ErrInvalidImageOrder
+		}
+		delete(images, id)
+		image.Position = i
+		ordered = append(ordered, image)
+	}
+	if err := su.sr.ReorderShopImages(ctx, shopId, imageIds); err != nil {
+		return nil, err
+	}
+	return ordered, nil
+}
+
+func (su *shopUsecase) DeleteShopImage(ctx context.Context, shopId uint, imageId uint, userId uint, role string) error {
+	if _, err := authorizeShop(ctx, su.sr, shopId, userId, role); err != nil {
+		return err
+	}
+	image := model.ShopImage{}
+	if err := su.sr.DeleteShopImage(ctx, &image, shopId, imageId); err != nil {
+		return err
+	}
+	removeImage(su.st, image.ImageFile)
+	return nil
+}
+
+// authorizeShop はショップを取得し、管理者以外はショップのオーナーであることを確認します。
+func authorizeShop(ctx context.Context, sr repository.IShopRepository, shopId uint, userId uint, role string) (model.Shop, error) {
+	shop := model.Shop{}
+	if err := sr.GetShopById(ctx, &shop, shopId); err != nil {
+		return model.Shop{}, err
+	}
+	if role != model.RoleAdmin && (shop.OwnerID == nil || *shop.OwnerID != userId) {
+		return model.Shop{}, ErrForbidden
+	}
+	return shop, nil
+}
+
+// applyShopDefaults は未指定の予約枠設定に既定値を設定します。
+func applyShopDefaults(shop *model.Shop) {
+	if shop.Capacity == 0 {
+		shop.Capacity = defaultShopCapacity
+	}
+	if shop.OpenTime == "" {
+		shop.OpenTime = defaultShopOpenTime
+	}
+	if shop.CloseTime == "" {
+		shop.CloseTime = defaultShopCloseTime
+	}
+	if shop.SlotMinutes == 0 {
+		shop.SlotMinutes = defaultShopSlotMinutes
+	}
+}
+
+// applyShopLocation は郵便番号を正規化し、座標が未指定の場合は郵便番号から求めます。
+// 郵便番号が未指定の場合は住所に含まれるものを使います。座標が見つからない場合は未設定のままにします。
+func applyShopLocation(gc geocoder.IGeocoder, shop *model.Shop) error {
+	if shop.PostalCode == "" {
+		shop.PostalCode, _ = geocoder.FindPostalCode(shop.Address)
+	} else if normalized, ok := geocoder.NormalizePostalCode(shop.PostalCode); ok {
+		shop.PostalCode = normalized
+	}
+	if shop.PostalCode == "" || shop.Latitude != nil || shop.Longitude != nil {
+		return nil
+	}
+	location, err := gc.Geocode(shop.PostalCode)
+	if errors.Is(err, geocoder.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
 		return err
 	}
+	shop.Latitude = &location.Latitude
+	shop.Longitude = &location.Longitude
 	return nil
 }
+
+// toShopResponse はショップをレスポンス用の型に変換します。営業中かどうかは now で判定します。
+// now はショップのタイムゾーンの時刻を渡します。
+func toShopResponse(shop model.Shop, now time.Time) model.ShopResponse {
+	return model.ShopResponse{
+		ID:            shop.ID,
+		ExternalID:    shop.ExternalID,
+		Name:          shop.Name,
+		Address:       shop.Address,
+		PostalCode:    shop.PostalCode,
+		Latitude:      shop.Latitude,
+		Longitude:     shop.Longitude,
+		Area:          shop.Area,
+		Genre:         shop.Genre,
+		Description:   shop.Description,
+		Capacity:      shop.Capacity,
+		OpenTime:      shop.OpenTime,
+		CloseTime:     shop.CloseTime,
+		SlotMinutes:   shop.SlotMinutes,
+		Hours:         shop.Hours,
+		Closures:      shop.Closures,
+		Images:        shop.Images,
+		IsOpenNow:     shop.IsOpenAt(now),
+		NextOpeningAt: shop.NextOpeningAt(now),
+		OwnerID:       shop.OwnerID,
+		RatingAverage: shop.RatingAverage,
+		RatingCount:   shop.RatingCount,
+		CreatedAt:     shop.CreatedAt,
+		UpdatedAt:     shop.UpdatedAt,
+	}
+}
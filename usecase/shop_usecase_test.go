@@ -0,0 +1,108 @@
+package usecase_test
+
+import (
+	"context"
+	"go-rest-api/model"
+	"go-rest-api/repository/memory"
+	"go-rest-api/usecase"
+	"go-rest-api/validator"
+	"testing"
+	"time"
+)
+
+func loadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestShopOpenNowUsesShopTimezone(t *testing.T) {
+	ctx := context.Background()
+	tokyo := loadLocation(t, "Asia/Tokyo")
+	newYork := loadLocation(t, "America/New_York")
+	sr := memory.NewShopRepository(memory.NewStore())
+
+	// 東京の現在時刻の前後1時間だけ営業するショップ
+	now := time.Now().In(tokyo)
+	shop := model.Shop{
+		Name:      "Sushi",
+		Address:   "Tokyo",
+		Area:      "東京都",
+		Genre:     "寿司",
+		OpenTime:  now.Add(-time.Hour).Format("15:04"),
+		CloseTime: now.Add(time.Hour).Format("15:04"),
+	}
+	if err := sr.CreateShop(ctx, &shop); err != nil {
+		t.Fatal(err)
+	}
+
+	// サーバーのタイムゾーンに関係なく、ショップのタイムゾーンで判定する
+	for _, tc := range []struct {
+		loc  *time.Location
+		open bool
+	}{
+		{tokyo, true},
+		{newYork, false},
+	} {
+		su := usecase.NewShopUsecase(sr, validator.NewShopValidator(), nil, nil, nil, tc.loc)
+		res, err := su.GetShopById(ctx, shop.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.IsOpenNow != tc.open {
+			t.Fatalf("%s: is_open_now = %v, want %v", tc.loc, res.IsOpenNow, tc.open)
+		}
+		if !tc.open {
+			next := res.NextOpeningAt
+			if next == nil || next.Location() != tc.loc || next.Format("15:04") != shop.OpenTime {
+				t.Fatalf("%s: next_opening_at = %v, want %s", tc.loc, next, shop.OpenTime)
+			}
+		}
+
+		found, err := su.SearchShops(ctx, model.ShopSearchQuery{}, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := len(found.Page.Items) == 1; got != tc.open {
+			t.Fatalf("%s: open_now search = %+v", tc.loc, found.Page.Items)
+		}
+	}
+}
+
+func TestUpdateShopOwner(t *testing.T) {
+	ctx := context.Background()
+	sr := memory.NewShopRepository(memory.NewStore())
+	su := usecase.NewShopUsecase(sr, validator.NewShopValidator(), nil, nil, nil, time.UTC)
+	owner, other := uint(10), uint(20)
+	shop := model.Shop{Name: "Sushi", Address: "Tokyo", Area: "東京都", Genre: "寿司", OwnerID: &owner}
+	if err := sr.CreateShop(ctx, &shop); err != nil {
+		t.Fatal(err)
+	}
+	update := func(ownerID *uint, userId uint, role string, updateOwner bool) *uint {
+		t.Helper()
+		res, err := su.UpdateShop(ctx, model.Shop{Name: "Sushi", Address: "Tokyo", Area: "東京都", Genre: "寿司", Description: "Sushi bar", OwnerID: ownerID}, shop.ID, userId, role, updateOwner)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res.OwnerID
+	}
+	wantOwner := func(got *uint, want *uint) {
+		t.Helper()
+		if (got == nil) != (want == nil) || (got != nil && *got != *want) {
+			t.Fatalf("owner = %v, want %v", got, want)
+		}
+	}
+
+	// owner_id を省略した管理者の更新ではオーナーを変更しない
+	wantOwner(update(nil, 1, model.RoleAdmin, false), &owner)
+	// 指定した場合は変更し、null の場合はオーナーを外す
+	wantOwner(update(&other, 1, model.RoleAdmin, true), &other)
+	wantOwner(update(nil, 1, model.RoleAdmin, true), nil)
+	wantOwner(update(&owner, 1, model.RoleAdmin, true), &owner)
+
+	// ショップのオーナーはオーナーを変更できない
+	wantOwner(update(&other, owner, model.RoleShopOwner, true), &owner)
+}
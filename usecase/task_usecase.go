@@ -1,17 +1,18 @@
 package usecase
 
 import (
+	"context"
 	"go-rest-api/model"
 	"go-rest-api/repository"
 	"go-rest-api/validator"
 )
 
 type ITaskUsecase interface {
-	GetAllTasks(userId uint) ([]model.TaskResponse, error)
-	GetTaskById(userId uint, taskId uint) (model.TaskResponse, error)
-	CreateTask(task model.Task) (model.TaskResponse, error)
-	UpdateTask(task model.Task, userId uint, taskId uint) (model.TaskResponse, error)
-	DeleteTask(userId uint, taskId uint) error
+	GetAllTasks(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.TaskResponse], error)
+	GetTaskById(ctx context.Context, userId uint, taskId uint) (model.TaskResponse, error)
+	CreateTask(ctx context.Context, task model.Task) (model.TaskResponse, error)
+	UpdateTask(ctx context.Context, task model.Task, userId uint, taskId uint) (model.TaskResponse, error)
+	DeleteTask(ctx context.Context, userId uint, taskId uint) error
 }
 
 type taskUsecase struct {
@@ -23,27 +24,24 @@ func NewTaskUsecase(tr repository.ITaskRepository, tv validator.ITaskValidator)
 	return &taskUsecase{tr, tv}
 }
 
-func (tu *taskUsecase) GetAllTasks(userId uint) ([]model.TaskResponse, error) {
-	tasks := []model.Task{}
-	if err := tu.tr.GetAllTasks(&tasks, userId); err != nil {
-		return nil, err
+func (tu *taskUsecase) GetAllTasks(ctx context.Context, userId uint, q model.ListQuery) (model.Page[model.TaskResponse], error) {
+	tasks, err := tu.tr.GetAllTasks(ctx, userId, q)
+	if err != nil {
+		return model.Page[model.TaskResponse]{}, err
 	}
-	resTasks := []model.TaskResponse{}
-	for _, v := range tasks {
-		t := model.TaskResponse{
+	return mapPage(tasks, func(v model.Task) model.TaskResponse {
+		return model.TaskResponse{
 			ID:        v.ID,
 			Title:     v.Title,
 			CreatedAt: v.CreatedAt,
 			UpdatedAt: v.UpdatedAt,
 		}
-		resTasks = append(resTasks, t)
-	}
-	return resTasks, nil
+	}), nil
 }
 
-func (tu *taskUsecase) GetTaskById(userId uint, taskId uint) (model.TaskResponse, error) {
+func (tu *taskUsecase) GetTaskById(ctx context.Context, userId uint, taskId uint) (model.TaskResponse, error) {
 	task := model.Task{}
-	if err := tu.tr.GetTaskById(&task, userId, taskId); err != nil {
+	if err := tu.tr.GetTaskById(ctx, &task, userId, taskId); err != nil {
 		return model.TaskResponse{}, err
 	}
 	resTask := model.TaskResponse{
@@ -55,11 +53,11 @@ func (tu *taskUsecase) GetTaskById(userId uint, taskId uint) (model.TaskResponse
 	return resTask, nil
 }
 
-func (tu *taskUsecase) CreateTask(task model.Task) (model.TaskResponse, error) {
+func (tu *taskUsecase) CreateTask(ctx context.Context, task model.Task) (model.TaskResponse, error) {
 	if err := tu.tv.TaskValidate(task); err != nil {
 		return model.TaskResponse{}, err
 	}
-	if err := tu.tr.CreateTask(&task); err != nil {
+	if err := tu.tr.CreateTask(ctx, &task); err != nil {
 		return model.TaskResponse{}, err
 	}
 	resTask := model.TaskResponse{
@@ -71,11 +69,11 @@ func (tu *taskUsecase) CreateTask(task model.Task) (model.TaskResponse, error) {
 	return resTask, nil
 }
 
-func (tu *taskUsecase) UpdateTask(task model.Task, userId uint, taskId uint) (model.TaskResponse, error) {
+func (tu *taskUsecase) UpdateTask(ctx context.Context, task model.Task, userId uint, taskId uint) (model.TaskResponse, error) {
 	if err := tu.tv.TaskValidate(task); err != nil {
 		return model.TaskResponse{}, err
 	}
-	if err := tu.tr.UpdateTask(&task, userId, taskId); err != nil {
+	if err := tu.tr.UpdateTask(ctx, &task, userId, taskId); err != nil {
 		return model.TaskResponse{}, err
 	}
 	resTask := model.TaskResponse{
@@ -87,8 +85,8 @@ func (tu *taskUsecase) UpdateTask(task model.Task, userId uint, taskId uint) (mo
 	return resTask, nil
 }
 
-func (tu *taskUsecase) DeleteTask(userId uint, taskId uint) error {
-	if err := tu.tr.DeleteTask(userId, taskId); err != nil {
+func (tu *taskUsecase) DeleteTask(ctx context.Context, userId uint, taskId uint) error {
+	if err := tu.tr.DeleteTask(ctx, userId, taskId); err != nil {
 		return err
 	}
 	return nil
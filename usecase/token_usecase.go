@@ -0,0 +1,179 @@
+package usecase
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+	"go-rest-api/repository"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// ErrInvalidRefreshToken はリフレッシュトークンが存在しない・期限切れ・失効済みの場合に返されます。
+var ErrInvalidRefreshToken = apperror.Unauthorized("invalid or expired refresh token")
+
+// ITokenUsecase はログイン方法に関係なく同じクレームのJWTを発行します。
+// アクセストークンは短命にし、リフレッシュトークンをローテーションして更新します。
+type ITokenUsecase interface {
+	IssueToken(ctx context.Context, user model.UserResponse) (model.TokenResponse, error)
+	RefreshToken(ctx context.Context, refreshToken string) (model.TokenResponse, model.UserResponse, error)
+	RevokeToken(ctx context.Context, refreshToken string) error
+	RevokeAllTokens(ctx context.Context, userId uint) error
+}
+
+type tokenUsecase struct {
+	rtr        repository.IRefreshTokenRepository
+	ur         repository.IUserRepository
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+func NewTokenUsecase(rtr repository.IRefreshTokenRepository, ur repository.IUserRepository, secret []byte, accessTTL time.Duration, refreshTTL time.Duration) ITokenUsecase {
+	return &tokenUsecase{rtr, ur, secret, accessTTL, refreshTTL}
+}
+
+// IssueToken はログイン時にアクセストークンと新しいファミリーのリフレッシュトークンを発行します。
+func (tu *tokenUsecase) IssueToken(ctx context.Context, user model.UserResponse) (model.TokenResponse, error) {
+	familyId, err := randomToken(16)
+	if err != nil {
+		return model.TokenResponse{}, err
+	}
+	refreshToken, next, err := tu.newRefreshToken(user.ID, familyId)
+	if err != nil {
+		return model.TokenResponse{}, err
+	}
+	if err := tu.rtr.CreateRefreshToken(ctx, &next); err != nil {
+		return model.TokenResponse{}, err
+	}
+	return tu.tokenResponse(user, refreshToken, next)
+}
+
+// RefreshToken はリフレッシュトークンをローテーションし、新しいトークンの組を発行します。
+// 使用済みのトークンが再度使われた場合は盗用とみなし、同じファミリーのトークンを全て失効させます。
+func (tu *tokenUsecase) RefreshToken(ctx context.Context, refreshToken string) (model.TokenResponse, model.UserResponse, error) {
+	current := model.RefreshToken{}
+	if err := tu.rtr.GetRefreshTokenByHash(ctx, &current, hashToken(refreshToken)); err != nil {
+		if apperror.Is(err, apperror.KindNotFound) {
+			return model.TokenResponse{}, model.UserResponse{}, ErrInvalidRefreshToken
+		}
+		return model.TokenResponse{}, model.UserResponse{}, err
+	}
+	if current.RevokedAt != nil {
+		if err := tu.rtr.RevokeFamily(ctx, current.FamilyID); err != nil {
+			return model.TokenResponse{}, model.UserResponse{}, err
+		}
+		return model.TokenResponse{}, model.UserResponse{}, ErrInvalidRefreshToken
+	}
+	if time.Now().After(current.ExpiresAt) {
+		return model.TokenResponse{}, model.UserResponse{}, ErrInvalidRefreshToken
+	}
+
+	// ロールなどの変更を反映するため、クレームは毎回最新のユーザー情報から作成する
+	user := model.User{}
+	if err := tu.ur.GetUserById(ctx, &user, current.UserID); err != nil {
+		return model.TokenResponse{}, model.UserResponse{}, err
+	}
+	resUser := model.UserResponse{
+		ID:            user.ID,
+		Email:         user.Email,
+		Name:          user.Name,
+		Role:          user.Role,
+		EmailVerified: user.EmailVerified,
+	}
+
+	newToken, next, err := tu.newRefreshToken(current.UserID, current.FamilyID)
+	if err != nil {
+		return model.TokenResponse{}, model.UserResponse{}, err
+	}
+	if err := tu.rtr.RotateRefreshToken(ctx, &current, &next); err != nil {
+		if errors.Is(err, repository.ErrTokenAlreadyRevoked) {
+			if err := tu.rtr.RevokeFamily(ctx, current.FamilyID); err != nil {
+				return model.TokenResponse{}, model.UserResponse{}, err
+			}
+			return model.TokenResponse{}, model.UserResponse{}, ErrInvalidRefreshToken
+		}
+		return model.TokenResponse{}, model.UserResponse{}, err
+	}
+	tokenRes, err := tu.tokenResponse(resUser, newToken, next)
+	if err != nil {
+		return model.TokenResponse{}, model.UserResponse{}, err
+	}
+	return tokenRes, resUser, nil
+}
+
+// RevokeToken はログアウト時にリフレッシュトークンのファミリーを失効させます。
+func (tu *tokenUsecase) RevokeToken(ctx context.Context, refreshToken string) error {
+	current := model.RefreshToken{}
+	if err := tu.rtr.GetRefreshTokenByHash(ctx, &current, hashToken(refreshToken)); err != nil {
+		if apperror.Is(err, apperror.KindNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+	return tu.rtr.RevokeFamily(ctx, current.FamilyID)
+}
+
+// RevokeAllTokens はユーザーの全てのセッションを失効させます。
+func (tu *tokenUsecase) RevokeAllTokens(ctx context.Context, userId uint) error {
+	return tu.rtr.RevokeAllForUser(ctx, userId)
+}
+
+// newRefreshToken は新しいリフレッシュトークンと、保存用のレコードを作成します。
+func (tu *tokenUsecase) newRefreshToken(userId uint, familyId string) (string, model.RefreshToken, error) {
+	refreshToken, err := randomToken(32)
+	if err != nil {
+		return "", model.RefreshToken{}, err
+	}
+	return refreshToken, model.RefreshToken{
+		TokenHash: hashToken(refreshToken),
+		FamilyID:  familyId,
+		ExpiresAt: time.Now().Add(tu.refreshTTL),
+		UserID:    userId,
+	}, nil
+}
+
+// tokenResponse はアクセストークンに署名し、リフレッシュトークンと組にして返します。
+func (tu *tokenUsecase) tokenResponse(user model.UserResponse, refreshToken string, stored model.RefreshToken) (model.TokenResponse, error) {
+	now := time.Now()
+	expiresAt := now.Add(tu.accessTTL)
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": user.ID,
+		"role":    user.Role,
+		"name":    user.Name,
+		"email":   user.Email,
+		"iat":     now.Unix(),
+		"exp":     expiresAt.Unix(),
+	})
+	tokenString, err := token.SignedString(tu.secret)
+	if err != nil {
+		return model.TokenResponse{}, err
+	}
+	return model.TokenResponse{
+		AccessToken:           tokenString,
+		AccessTokenExpiresAt:  expiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: stored.ExpiresAt,
+	}, nil
+}
+
+// randomToken は暗号論的に安全な乱数から URL セーフな文字列を作成します。
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken はトークンを保存用の SHA-256 ハッシュに変換します。
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,38 +1,48 @@
 package usecase
 
 import (
+	"context"
+	"errors"
+	"go-rest-api/apperror"
 	"go-rest-api/model"
+	"go-rest-api/oidc"
 	"go-rest-api/repository"
 	"go-rest-api/validator"
-	"os"
-	"log"
-	"time"
-	"errors" 
-  "gorm.io/gorm"
 
-	"github.com/golang-jwt/jwt/v4"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type IUserUsecase interface {
-	SignUp(user model.User) (model.UserResponse, error)
-	Login(user model.User) (string, model.UserResponse, error)
-	GetUserByID(userID uint) (model.UserResponse, error)
-	AuthenticateUser(user model.User) (model.UserResponse, error)
-	FindOrCreateUser(email, name string) (model.UserResponse, error)
-	GenerateJWT(userID uint) (string, error)
+	SignUp(ctx context.Context, user model.User) (model.UserResponse, error)
+	Login(ctx context.Context, user model.User) (model.UserResponse, error)
+	GetUserByID(ctx context.Context, userID uint) (model.UserResponse, error)
+	OAuthLogin(ctx context.Context, idToken string) (model.UserResponse, error)
+	UpdateUserRole(ctx context.Context, userID uint, role string) (model.UserResponse, error)
 }
 
+// ErrEmailNotVerified はIDプロバイダーがメールアドレスを確認していない場合に返されます。
+var ErrEmailNotVerified = apperror.Forbidden("email address is not verified by the identity provider")
+
+// ErrAccountNotVerified はIDプロバイダーのメールアドレスと同じメールアドレスの既存ユーザーが、メールアドレスを確認していない場合に返されます。
+// 他人がそのメールアドレスで先に登録したアカウントを乗っ取れないよう、自動では紐付けません。
+var ErrAccountNotVerified = apperror.Conflict("an account with this email address exists but its email address is not verified; log in with the password and verify it first")
+
+// ErrInvalidCredentials はメールアドレスまたはパスワードが一致しない場合に返されます。
+// どちらが誤っているかは区別しません。
+var ErrInvalidCredentials = apperror.Unauthorized("invalid email or password")
+
 type userUsecase struct {
 	ur repository.IUserRepository
+	ir repository.IIdentityRepository
 	uv validator.IUserValidator
+	ov oidc.IVerifier
 }
 
-func NewUserUsecase(ur repository.IUserRepository, uv validator.IUserValidator) IUserUsecase {
-	return &userUsecase{ur, uv}
+func NewUserUsecase(ur repository.IUserRepository, ir repository.IIdentityRepository, uv validator.IUserValidator, ov oidc.IVerifier) IUserUsecase {
+	return &userUsecase{ur, ir, uv, ov}
 }
 
-func (uu *userUsecase) SignUp(user model.User) (model.UserResponse, error) {
+func (uu *userUsecase) SignUp(ctx context.Context, user model.User) (model.UserResponse, error) {
 	if err := uu.uv.UserValidate(user); err != nil {
 		return model.UserResponse{}, err
 	}
@@ -44,51 +54,51 @@ func (uu *userUsecase) SignUp(user model.User) (model.UserResponse, error) {
 		Email: user.Email,
 		Password: string(hash),
 		Name:     user.Name,
+		Role:     model.RoleCustomer,
 	}
-	if err := uu.ur.CreateUser(&newUser); err != nil {
+	if err := uu.ur.CreateUser(ctx, &newUser); err != nil {
 		return model.UserResponse{}, err
 	}
 	resUser := model.UserResponse{
 		ID:    newUser.ID,
 		Email: newUser.Email,
 		Name:  newUser.Name,
+		Role:  newUser.Role,
+		EmailVerified: newUser.EmailVerified,
 	}
 	return resUser, nil
 }
 
-func (uu *userUsecase) Login(user model.User) (string, model.UserResponse, error) {
+func (uu *userUsecase) Login(ctx context.Context, user model.User) (model.UserResponse, error) {
 	// ログインバリデーション関数を呼び出す（UserLoginValidateは新しく作成する必要があります）
 	if err := uu.uv.UserLoginValidate(user); err != nil {
-		return "", model.UserResponse{}, err
+		return model.UserResponse{}, err
 	}
 	storedUser := model.User{}
-	if err := uu.ur.GetUserByEmail(&storedUser, user.Email); err != nil {
-		return "", model.UserResponse{}, err
+	if err := uu.ur.GetUserByEmail(ctx, &storedUser, user.Email); err != nil {
+		if apperror.Is(err, apperror.KindNotFound) {
+			return model.UserResponse{}, ErrInvalidCredentials
+		}
+		return model.UserResponse{}, err
 	}
 	err := bcrypt.CompareHashAndPassword([]byte(storedUser.Password), []byte(user.Password))
 	if err != nil {
-		return "", model.UserResponse{}, err
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id": storedUser.ID,
-		"exp":     time.Now().Add(time.Hour * 12).Unix(),
-	})
-	tokenString, err := token.SignedString([]byte(os.Getenv("SECRET")))
-	if err != nil {
-		return "", model.UserResponse{}, err
+		return model.UserResponse{}, ErrInvalidCredentials
 	}
 	resUser := model.UserResponse{
 		ID:    storedUser.ID,
 		Email: storedUser.Email,
 		Name:  storedUser.Name,
+		Role:  storedUser.Role,
+		EmailVerified: storedUser.EmailVerified,
 	}
-	return tokenString, resUser, nil
+	return resUser, nil
 }
 
 // GetUserByID メソッドを追加
-func (uu *userUsecase) GetUserByID(userID uint) (model.UserResponse, error) {
+func (uu *userUsecase) GetUserByID(ctx context.Context, userID uint) (model.UserResponse, error) {
     user := model.User{}
-    err := uu.ur.GetUserById(&user, userID)
+    err := uu.ur.GetUserById(ctx, &user, userID)
     if err != nil {
         return model.UserResponse{}, err
     }
@@ -96,75 +106,89 @@ func (uu *userUsecase) GetUserByID(userID uint) (model.UserResponse, error) {
         ID:    user.ID,
         Email: user.Email,
         Name:  user.Name,
+        Role:  user.Role,
+        EmailVerified: user.EmailVerified,
     }, nil
 }
 
-func (uu *userUsecase) AuthenticateUser(user model.User) (model.UserResponse, error) {
-    log.Println("AuthenticateUser method called") // この行を追加
-
-    // ユーザーが提供したメールアドレスでDBを検索
-    storedUser := model.User{}
-    log.Println("Searching user by email") // この行を追加
-    if err := uu.ur.GetUserByEmail(&storedUser, user.Email); err != nil {
-        log.Printf("User not found: %v", err) // この行を追加
-        return model.UserResponse{}, err
-    }
-
-    // パスワードの検証
-    log.Println("Validating password") // この行を追加
-    if err := bcrypt.CompareHashAndPassword([]byte(storedUser.Password), []byte(user.Password)); err != nil {
-        log.Printf("Password validation failed: %v", err) // この行を追加
-        return model.UserResponse{}, err
-    }
-
-    log.Println("User authenticated successfully") // この行を追加
+// OAuthLogin はIDトークンを検証し、プロバイダーのアカウントに紐付いたユーザーを返します。
+// 未登録の場合は、確認済みのメールアドレスを持つ既存ユーザーに紐付けるか、新規ユーザーを作成します。
+// 同じメールアドレスの既存ユーザーがメールアドレスを確認していない場合は ErrAccountNotVerified を返します。
+func (uu *userUsecase) OAuthLogin(ctx context.Context, idToken string) (model.UserResponse, error) {
+	claims, err := uu.ov.Verify(idToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, oidc.ErrNotConfigured):
+			return model.UserResponse{}, apperror.Wrap(apperror.KindUnavailable, "OpenID Connect login is not available", err)
+		case errors.Is(err, oidc.ErrKeySetUnavailable):
+			return model.UserResponse{}, apperror.Wrap(apperror.KindUnavailable, "OpenID Connect provider is unavailable", err)
+		case errors.Is(err, oidc.ErrInvalidIDToken):
+			return model.UserResponse{}, apperror.Wrap(apperror.KindUnauthorized, "invalid ID token", err)
+		}
+		return model.UserResponse{}, err
+	}
 
-    // 認証が成功した場合、ユーザー情報を返す
-    return model.UserResponse{
-        ID:    storedUser.ID,
-        Email: storedUser.Email,
-        Name:  storedUser.Name,
-    }, nil
-}
+	identity := model.Identity{}
+	err = uu.ir.GetIdentity(ctx, &identity, claims.Issuer, claims.Subject)
+	if err == nil {
+		return uu.GetUserByID(ctx, identity.UserID)
+	}
+	if !apperror.Is(err, apperror.KindNotFound) {
+		return model.UserResponse{}, err
+	}
 
-func (uu *userUsecase) FindOrCreateUser(email, name string) (model.UserResponse, error) {
-    var user model.User
-    err := uu.ur.GetUserByEmail(&user, email)
-    if err != nil {
-        if errors.Is(err, gorm.ErrRecordNotFound) {
-            // ユーザーが存在しない場合、新規作成
-            user = model.User{
-                Email: email,
-                Name:  name,
-                // パスワードはOAuthの場合、空またはランダムに生成した値を設定
-            }
-            if err := uu.ur.CreateUser(&user); err != nil {
-                return model.UserResponse{}, err
-            }
-        } else {
-            // データベースエラー
-            return model.UserResponse{}, err
-        }
-    }
+	// メールアドレスで既存ユーザーと紐付けるため、プロバイダーが確認済みのものに限る
+	if claims.Email == "" || !claims.EmailVerified {
+		return model.UserResponse{}, ErrEmailNotVerified
+	}
+	identity = model.Identity{
+		Provider: claims.Issuer,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+	user := model.User{}
+	err = uu.ur.GetUserByEmail(ctx, &user, claims.Email)
+	switch {
+	case err == nil:
+		// 既存ユーザーがメールアドレスの所有を確認済みの場合のみ紐付ける
+		if !user.EmailVerified {
+			return model.UserResponse{}, ErrAccountNotVerified
+		}
+		identity.UserID = user.ID
+		if err := uu.ir.CreateIdentity(ctx, &identity); err != nil {
+			return model.UserResponse{}, err
+		}
+	case apperror.Is(err, apperror.KindNotFound):
+		// パスワードを持たないユーザーとして作成し、IDプロバイダー経由でのみログインできる
+		user = model.User{
+			Email: claims.Email,
+			Name:  claims.Name,
+			Role:  model.RoleCustomer,
+			EmailVerified: true,
+		}
+		if err := uu.ir.CreateUserWithIdentity(ctx, &user, &identity); err != nil {
+			return model.UserResponse{}, err
+		}
+	default:
+		return model.UserResponse{}, err
+	}
 
-    // ユーザーが既に存在するか、新規作成された場合のレスポンス
-    return model.UserResponse{
-        ID:    user.ID,
-        Email: user.Email,
-        Name:  user.Name,
-    }, nil
+	return model.UserResponse{
+		ID:    user.ID,
+		Email: user.Email,
+		Name:  user.Name,
+		Role:  user.Role,
+		EmailVerified: user.EmailVerified,
+	}, nil
 }
 
-func (uu *userUsecase) GenerateJWT(userID uint) (string, error) {
-    token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-        "user_id": userID,
-        "exp":     time.Now().Add(24 * time.Hour).Unix(),
-    })
-
-    tokenString, err := token.SignedString([]byte(os.Getenv("SECRET")))
-    if err != nil {
-        return "", err
-    }
-
-    return tokenString, nil
+// UpdateUserRole は管理者がユーザーのロールを変更するためのメソッドです。
+func (uu *userUsecase) UpdateUserRole(ctx context.Context, userID uint, role string) (model.UserResponse, error) {
+	if err := uu.uv.UserRoleValidate(role); err != nil {
+		return model.UserResponse{}, err
+	}
+	if err := uu.ur.UpdateUserRole(ctx, userID, role); err != nil {
+		return model.UserResponse{}, err
+	}
+	return uu.GetUserByID(ctx, userID)
 }
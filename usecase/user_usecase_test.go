@@ -0,0 +1,65 @@
+package usecase_test
+
+import (
+	"context"
+	"errors"
+	"go-rest-api/model"
+	"go-rest-api/oidc"
+	"go-rest-api/repository"
+	"go-rest-api/repository/memory"
+	"go-rest-api/usecase"
+	"go-rest-api/validator"
+	"testing"
+)
+
+// fixedVerifier は検証せずに決まったクレームを返す IVerifier です。
+type fixedVerifier struct {
+	claims oidc.Claims
+}
+
+func (fv fixedVerifier) Verify(rawIDToken string) (oidc.Claims, error) {
+	return fv.claims, nil
+}
+
+func newOAuthTest(t *testing.T, verified bool) (usecase.IUserUsecase, repository.IIdentityRepository, model.User) {
+	t.Helper()
+	s := memory.NewStore()
+	ur, ir := memory.NewUserRepository(s), memory.NewIdentityRepository(s)
+	user := model.User{Email: "alice@example.com", Password: "hash", Name: "Alice"}
+	if err := ur.CreateUser(context.Background(), &user); err != nil {
+		t.Fatal(err)
+	}
+	if verified {
+		if err := ur.MarkEmailVerified(context.Background(), user.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	claims := oidc.Claims{Issuer: "https://idp.example.com", Subject: "alice", Email: "alice@example.com", EmailVerified: true}
+	return usecase.NewUserUsecase(ur, ir, validator.NewUserValidator(), fixedVerifier{claims}), ir, user
+}
+
+func TestOAuthLoginLinksVerifiedAccount(t *testing.T) {
+	uu, ir, user := newOAuthTest(t, true)
+	res, err := uu.OAuthLogin(context.Background(), "id-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.ID != user.ID {
+		t.Fatalf("logged in as %+v, want user %d", res, user.ID)
+	}
+	identity := model.Identity{}
+	if err := ir.GetIdentity(context.Background(), &identity, "https://idp.example.com", "alice"); err != nil || identity.UserID != user.ID {
+		t.Fatalf("identity = %+v, %v", identity, err)
+	}
+}
+
+func TestOAuthLoginRefusesUnverifiedAccount(t *testing.T) {
+	uu, ir, _ := newOAuthTest(t, false)
+	// 確認していないメールアドレスで先に登録されたアカウントには紐付けない
+	if _, err := uu.OAuthLogin(context.Background(), "id-token"); !errors.Is(err, usecase.ErrAccountNotVerified) {
+		t.Fatalf("want ErrAccountNotVerified, got %v", err)
+	}
+	if err := ir.GetIdentity(context.Background(), &model.Identity{}, "https://idp.example.com", "alice"); err == nil {
+		t.Fatal("identity was linked to an unverified account")
+	}
+}
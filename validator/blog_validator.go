@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"go-rest-api/apperror"
 	"go-rest-api/model"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -17,7 +18,7 @@ func NewBlogValidator() IBlogValidator {
 }
 
 func (bv *blogValidator) BlogValidate(blog model.Blog) error {
-	return validation.ValidateStruct(&blog,
+	return apperror.Validation(validation.ValidateStruct(&blog,
 		validation.Field(
 			&blog.Title,
 			validation.Required.Error("title is required"),
@@ -28,5 +29,5 @@ func (bv *blogValidator) BlogValidate(blog model.Blog) error {
 			validation.Required.Error("content is required"),
 			validation.RuneLength(1, 5000).Error("limited max 5000 char"), // Assuming a longer content for blogs
 		),
-	)
+	))
 }
@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"go-rest-api/apperror"
 	"go-rest-api/model"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -17,7 +18,7 @@ func NewFavoriteValidator() IFavoriteValidator {
 }
 
 func (fv *favoriteValidator) FavoriteValidate(favorite model.Favorite) error {
-	return validation.ValidateStruct(&favorite,
+	return apperror.Validation(validation.ValidateStruct(&favorite,
 		validation.Field(
 			&favorite.UserID,
 			validation.Required.Error("user ID is required"),
@@ -26,5 +27,5 @@ func (fv *favoriteValidator) FavoriteValidate(favorite model.Favorite) error {
 			&favorite.ShopID,
 			validation.Required.Error("shop ID is required"),
 		),
-	)
+	))
 }
\ No newline at end of file
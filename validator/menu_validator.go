@@ -0,0 +1,120 @@
+package validator
+
+import (
+	"errors"
+	"fmt"
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type IMenuValidator interface {
+	MenuValidate(menu model.Menu) error
+	CourseValidate(course model.Course) error
+}
+
+type menuValidator struct{}
+
+func NewMenuValidator() IMenuValidator {
+	return &menuValidator{}
+}
+
+func (mv *menuValidator) MenuValidate(menu model.Menu) error {
+	return apperror.Validation(validation.ValidateStruct(&menu,
+		validation.Field(
+			&menu.Name,
+			validation.Required.Error("name is required"),
+			validation.RuneLength(1, 100).Error("limited max 100 char"),
+		),
+		validation.Field(
+			&menu.Description,
+			validation.RuneLength(0, 500).Error("limited max 500 char"),
+		),
+		validation.Field(
+			&menu.Position,
+			validation.Min(0).Error("position must not be negative"),
+		),
+	))
+}
+
+func (mv *menuValidator) CourseValidate(course model.Course) error {
+	err := validation.ValidateStruct(&course,
+		validation.Field(
+			&course.Name,
+			validation.Required.Error("name is required"),
+			validation.RuneLength(1, 100).Error("limited max 100 char"),
+		),
+		validation.Field(
+			&course.Description,
+			validation.RuneLength(0, 1000).Error("limited max 1000 char"),
+		),
+		validation.Field(
+			&course.Price,
+			validation.Min(int64(0)).Error("price must not be negative"),
+		),
+		validation.Field(
+			&course.DurationMinutes,
+			validation.Required.Error("duration minutes is required"),
+			validation.Min(5).Error("duration minutes must be at least 5"),
+			validation.Max(24*60).Error("duration minutes must be at most 1440"),
+		),
+		validation.Field(
+			&course.MinPartySize,
+			validation.Required.Error("min party size is required"),
+			validation.Min(1).Error("min party size must be at least 1"),
+		),
+		validation.Field(
+			&course.MaxPartySize,
+			validation.Min(0).Error("max party size must not be negative"),
+			validation.When(course.MaxPartySize != 0, validation.Min(course.MinPartySize).Error("max party size must be at least min party size")),
+		),
+		validation.Field(
+			&course.EndDate,
+			validation.By(func(interface{}) error {
+				if course.StartDate != nil && course.EndDate != nil && course.EndDate.Before(*course.StartDate) {
+					return errors.New("end date must not be before start date")
+				}
+				return nil
+			}),
+		),
+	)
+	if err != nil {
+		return apperror.Validation(err)
+	}
+	errs := validation.Errors{}
+	for i, w := range course.Windows {
+		err := validation.ValidateStruct(&w,
+			validation.Field(
+				&w.Weekday,
+				validation.Min(0).Error("weekday must be between 0 and 6"),
+				validation.Max(6).Error("weekday must be between 0 and 6"),
+			),
+			validation.Field(
+				&w.StartTime,
+				validation.Required.Error("start time is required"),
+				validation.Match(clockPattern).Error("start time must be HH:MM"),
+			),
+			validation.Field(
+				&w.EndTime,
+				validation.Required.Error("end time is required"),
+				validation.Match(clockPattern).Error("end time must be HH:MM"),
+				validation.By(func(interface{}) error {
+					start, ok1 := model.ClockMinutes(w.StartTime)
+					end, ok2 := model.ClockMinutes(w.EndTime)
+					if ok1 && ok2 && end <= start {
+						return errors.New("end time must be after start time")
+					}
+					return nil
+				}),
+			),
+		)
+		if err != nil {
+			errs[fmt.Sprintf("windows[%d]", i)] = err
+		}
+	}
+	if len(errs) > 0 {
+		return apperror.Validation(errs)
+	}
+	return nil
+}
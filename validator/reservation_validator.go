@@ -1,12 +1,16 @@
 package validator
 
 import (
+	"errors"
+	"fmt"
+	"go-rest-api/apperror"
 	"go-rest-api/model"
+
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 )
 
 type IReservationValidator interface {
-	ReservationValidate(reservation model.Reservation) error
+	ReservationValidate(reservation model.Reservation, shop model.Shop, course *model.Course) error
 }
 
 type reservationValidator struct{}
@@ -15,8 +19,10 @@ func NewReservationValidator() IReservationValidator {
 	return &reservationValidator{}
 }
 
-func (rv *reservationValidator) ReservationValidate(reservation model.Reservation) error {
-	return validation.ValidateStruct(&reservation,
+// ReservationValidate は予約を検証します。予約時刻はショップの営業時間内で、枠の終わりまで休業と重ならない必要があります。
+// コースを選んだ場合は、コースの提供期間・時間帯・人数の条件を満たし、コースの所要時間の間も営業している必要があります。
+func (rv *reservationValidator) ReservationValidate(reservation model.Reservation, shop model.Shop, course *model.Course) error {
+	return apperror.Validation(validation.ValidateStruct(&reservation,
 		// UserIDは必須
 		validation.Field(&reservation.UserID, validation.Required.Error("user ID is required")),
 		// ShopIDも必須
@@ -24,10 +30,54 @@ func (rv *reservationValidator) ReservationValidate(reservation model.Reservatio
 		// Dateは必須
 		validation.Field(&reservation.Date, validation.Required.Error("date is required")),
 		// Timeは必須
-		validation.Field(&reservation.Time, validation.Required.Error("time is required")),
+		validation.Field(&reservation.Time,
+			validation.Required.Error("time is required"),
+			validation.Match(clockPattern).Error("time must be HH:MM"),
+			validation.By(func(interface{}) error {
+				if !reservation.Date.IsZero() && !shop.IsBookable(reservation.Date, reservation.Time) {
+					return errors.New("shop is closed at the requested time")
+				}
+				return nil
+			}),
+		),
 		// Num (予約人数) も必須
-		validation.Field(&reservation.Num, validation.Required.Error("number of people is required")),
+		validation.Field(&reservation.Num,
+			validation.Required.Error("number of people is required"),
+			validation.Min(1).Error("number of people must be at least 1"),
+			validation.By(func(interface{}) error {
+				if course == nil {
+					return nil
+				}
+				if reservation.Num < course.MinPartySize {
+					return fmt.Errorf("this course requires at least %d people", course.MinPartySize)
+				}
+				if course.MaxPartySize > 0 && reservation.Num > course.MaxPartySize {
+					return fmt.Errorf("this course allows at most %d people", course.MaxPartySize)
+				}
+				return nil
+			}),
+		),
+		validation.Field(&reservation.CourseID,
+			validation.By(func(interface{}) error {
+				if course == nil {
+					return nil
+				}
+				if course.ShopID != reservation.ShopID {
+					return errors.New("course is not offered by this shop")
+				}
+				if reservation.Date.IsZero() || !clockPattern.MatchString(reservation.Time) {
+					return nil
+				}
+				if !course.IsAvailableAt(reservation.Date, reservation.Time) {
+					return errors.New("course is not available at the requested time")
+				}
+				if !shop.IsOpenFor(reservation.Date, reservation.Time, course.DurationMinutes) {
+					return errors.New("shop closes before the course ends")
+				}
+				return nil
+			}),
+		),
 		// ここで他のバリデーションルールを追加できます。例えば、予約日が未来であることを確認するなど。
-	)
+	))
 }
 
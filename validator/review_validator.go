@@ -0,0 +1,62 @@
+package validator
+
+import (
+	"go-rest-api/apperror"
+	"go-rest-api/model"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+)
+
+type IReviewValidator interface {
+	ReviewValidate(review model.Review) error
+	ReviewReplyValidate(review model.Review) error
+	ReviewReportValidate(report model.ReviewReport) error
+}
+
+type reviewValidator struct{}
+
+func NewReviewValidator() IReviewValidator {
+	return &reviewValidator{}
+}
+
+func (rv *reviewValidator) ReviewValidate(review model.Review) error {
+	return apperror.Validation(validation.ValidateStruct(&review,
+		validation.Field(
+			&review.UserID,
+			validation.Required.Error("user ID is required"),
+		),
+		validation.Field(
+			&review.ShopID,
+			validation.Required.Error("shop ID is required"),
+		),
+		validation.Field(
+			&review.Rating,
+			validation.Required.Error("rating is required"),
+			validation.Min(model.MinRating).Error("rating must be between 1 and 5"),
+			validation.Max(model.MaxRating).Error("rating must be between 1 and 5"),
+		),
+		validation.Field(
+			&review.Comment,
+			validation.RuneLength(0, 2000).Error("limited max 2000 char"),
+		),
+	))
+}
+
+func (rv *reviewValidator) ReviewReplyValidate(review model.Review) error {
+	return apperror.Validation(validation.ValidateStruct(&review,
+		validation.Field(
+			&review.Reply,
+			validation.RuneLength(0, 1000).Error("limited max 1000 char"),
+		),
+	))
+}
+
+func (rv *reviewValidator) ReviewReportValidate(report model.ReviewReport) error {
+	return apperror.Validation(validation.ValidateStruct(&report,
+		validation.Field(
+			&report.Reason,
+			validation.Required.Error("reason is required"),
+			validation.RuneLength(1, 500).Error("limited max 500 char"),
+		),
+	))
+}
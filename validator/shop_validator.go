@@ -1,15 +1,29 @@
 package validator
 
 import (
+	"errors"
+	"fmt"
+	"go-rest-api/apperror"
 	"go-rest-api/model"
+	"regexp"
+	"time"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
 )
 
 type IShopValidator interface {
 	ShopValidate(shop model.Shop) error
+	ShopHoursValidate(hours []model.ShopHour) error
+	ShopClosureValidate(closure model.ShopClosure) error
+	ShopImageValidate(image model.ShopImage) error
 }
 
+// clockPattern は "HH:MM" 形式の時刻にマッチします。
+var clockPattern = regexp.MustCompile(`^([01][0-9]|2[0-3]):[0-5][0-9]$`)
+
+// postalCodePattern は正規化済みの郵便番号にマッチします。
+var postalCodePattern = regexp.MustCompile(`^[0-9]{3}-[0-9]{4}$`)
+
 type shopValidator struct{}
 
 func NewShopValidator() IShopValidator {
@@ -17,7 +31,12 @@ func NewShopValidator() IShopValidator {
 }
 
 func (sv *shopValidator) ShopValidate(shop model.Shop) error {
-	return validation.ValidateStruct(&shop,
+	return apperror.Validation(validation.ValidateStruct(&shop,
+		validation.Field(
+			&shop.ExternalID,
+			validation.NilOrNotEmpty.Error("external id must not be empty"),
+			validation.RuneLength(1, 100).Error("limited max 100 char"),
+		),
 		validation.Field(
 			&shop.Name,
 			validation.Required.Error("name is required"),
@@ -28,6 +47,22 @@ func (sv *shopValidator) ShopValidate(shop model.Shop) error {
 			validation.Required.Error("address is required"),
 			validation.RuneLength(1, 255).Error("limited max 255 char"),
 		),
+		validation.Field(
+			&shop.PostalCode,
+			validation.Match(postalCodePattern).Error("postal code must be like 100-0001"),
+		),
+		validation.Field(
+			&shop.Latitude,
+			validation.Min(-90.0).Error("latitude must be between -90 and 90"),
+			validation.Max(90.0).Error("latitude must be between -90 and 90"),
+			validation.When(shop.Longitude != nil, validation.NotNil.Error("latitude and longitude must be set together")),
+		),
+		validation.Field(
+			&shop.Longitude,
+			validation.Min(-180.0).Error("longitude must be between -180 and 180"),
+			validation.Max(180.0).Error("longitude must be between -180 and 180"),
+			validation.When(shop.Latitude != nil, validation.NotNil.Error("latitude and longitude must be set together")),
+		),
 		validation.Field(
 			&shop.Area,
 			validation.Required.Error("area is required"),
@@ -43,5 +78,152 @@ func (sv *shopValidator) ShopValidate(shop model.Shop) error {
 			validation.Required.Error("description is required"),
 			validation.RuneLength(1, 500).Error("limited max 500 char"),
 		),
-	)
+		validation.Field(
+			&shop.Capacity,
+			validation.Required.Error("capacity is required"),
+			validation.Min(1).Error("capacity must be at least 1"),
+		),
+		validation.Field(
+			&shop.OpenTime,
+			validation.Required.Error("open time is required"),
+			validation.Match(clockPattern).Error("open time must be HH:MM"),
+		),
+		validation.Field(
+			&shop.CloseTime,
+			validation.Required.Error("close time is required"),
+			validation.Match(clockPattern).Error("close time must be HH:MM"),
+			validation.By(func(interface{}) error {
+				openAt, err1 := time.Parse("15:04", shop.OpenTime)
+				closeAt, err2 := time.Parse("15:04", shop.CloseTime)
+				if err1 == nil && err2 == nil && !closeAt.After(openAt) {
+					return errors.New("close time must be after open time")
+				}
+				return nil
+			}),
+		),
+		validation.Field(
+			&shop.SlotMinutes,
+			validation.Required.Error("slot minutes is required"),
+			validation.Min(5).Error("slot minutes must be at least 5"),
+			validation.Max(24*60).Error("slot minutes must be at most 1440"),
+		),
+	))
+}
+
+// ShopHoursValidate は曜日ごとの営業時間を検証します。同じ曜日の時間帯が重なっている場合はエラーです。
+func (sv *shopValidator) ShopHoursValidate(hours []model.ShopHour) error {
+	errs := validation.Errors{}
+	for i, h := range hours {
+		err := validation.ValidateStruct(&h,
+			validation.Field(
+				&h.Weekday,
+				validation.Min(0).Error("weekday must be between 0 and 6"),
+				validation.Max(6).Error("weekday must be between 0 and 6"),
+			),
+			validation.Field(
+				&h.OpenTime,
+				validation.Required.Error("open time is required"),
+				validation.Match(clockPattern).Error("open time must be HH:MM"),
+			),
+			validation.Field(
+				&h.CloseTime,
+				validation.Required.Error("close time is required"),
+				validation.Match(clockPattern).Error("close time must be HH:MM"),
+				validation.By(func(interface{}) error {
+					if h.CloseTime == h.OpenTime {
+						return errors.New("close time must differ from open time")
+					}
+					return nil
+				}),
+			),
+		)
+		if err != nil {
+			errs[fmt.Sprintf("hours[%d]", i)] = err
+		}
+	}
+	if len(errs) > 0 {
+		return apperror.Validation(errs)
+	}
+	// 日付をまたぐ時間帯も含め、週の分に換算して重なりを確認する
+	for i, a := range hours {
+		for j := i + 1; j < len(hours); j++ {
+			if hoursOverlap(a, hours[j]) {
+				return apperror.Field(fmt.Sprintf("hours[%d]", j), errors.New("hours must not overlap"))
+			}
+		}
+	}
+	return nil
+}
+
+// ShopClosureValidate は休業を検証します。時間帯は開始・終了の両方を指定するか、両方を省略します。
+func (sv *shopValidator) ShopClosureValidate(closure model.ShopClosure) error {
+	return apperror.Validation(validation.ValidateStruct(&closure,
+		validation.Field(
+			&closure.Date,
+			validation.Required.Error("date is required"),
+		),
+		validation.Field(
+			&closure.StartTime,
+			validation.Match(clockPattern).Error("start time must be HH:MM"),
+			validation.When(closure.EndTime != "", validation.Required.Error("start time and end time must be set together")),
+		),
+		validation.Field(
+			&closure.EndTime,
+			validation.Match(clockPattern).Error("end time must be HH:MM"),
+			validation.When(closure.StartTime != "", validation.Required.Error("start time and end time must be set together")),
+			validation.By(func(interface{}) error {
+				start, ok1 := model.ClockMinutes(closure.StartTime)
+				end, ok2 := model.ClockMinutes(closure.EndTime)
+				if ok1 && ok2 && end <= start {
+					return errors.New("end time must be after start time")
+				}
+				return nil
+			}),
+		),
+		validation.Field(
+			&closure.Reason,
+			validation.Required.Error("reason is required"),
+			validation.RuneLength(1, 100).Error("limited max 100 char"),
+		),
+	))
+}
+
+func (sv *shopValidator) ShopImageValidate(image model.ShopImage) error {
+	return apperror.Validation(validation.ValidateStruct(&image,
+		validation.Field(
+			&image.Caption,
+			validation.RuneLength(0, 200).Error("limited max 200 char"),
+		),
+	))
+}
+
+// hoursOverlap は2つの営業時間帯が重なっているかを返します。
+func hoursOverlap(a, b model.ShopHour) bool {
+	ra, ok1 := weekRange(a)
+	rb, ok2 := weekRange(b)
+	if !ok1 || !ok2 {
+		return false
+	}
+	const week = 7 * 24 * 60
+	// 土曜日から日曜日にまたぐ時間帯のため、1週間ずらした場合も確認する
+	for _, shift := range []int{-week, 0, week} {
+		if ra.Start < rb.End+shift && rb.Start+shift < ra.End {
+			return true
+		}
+	}
+	return false
+}
+
+// weekRange は営業時間帯を日曜日0時からの分に換算します。
+func weekRange(h model.ShopHour) (model.TimeRange, bool) {
+	start, ok1 := model.ClockMinutes(h.OpenTime)
+	end, ok2 := model.ClockMinutes(h.CloseTime)
+	if !ok1 || !ok2 {
+		return model.TimeRange{}, false
+	}
+	if end <= start {
+		end += 24 * 60
+	}
+	offset := h.Weekday * 24 * 60
+	return model.TimeRange{Start: offset + start, End: offset + end}, true
 }
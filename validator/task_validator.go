@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"go-rest-api/apperror"
 	"go-rest-api/model"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -17,11 +18,11 @@ func NewTaskValidator() ITaskValidator {
 }
 
 func (tv *taskValidator) TaskValidate(task model.Task) error {
-	return validation.ValidateStruct(&task,
+	return apperror.Validation(validation.ValidateStruct(&task,
 		validation.Field(
 			&task.Title,
 			validation.Required.Error("title is required"),
 			validation.RuneLength(1, 10).Error("limited max 10 char"),
 		),
-	)
+	))
 }
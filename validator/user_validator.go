@@ -1,6 +1,7 @@
 package validator
 
 import (
+	"go-rest-api/apperror"
 	"go-rest-api/model"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -10,6 +11,8 @@ import (
 type IUserValidator interface {
 	UserValidate(user model.User) error
 	UserLoginValidate(user model.User) error  // 新しいバリデーション関数のインターフェース
+	UserRoleValidate(role string) error
+	PasswordValidate(password string) error
 }
 
 type userValidator struct{}
@@ -19,7 +22,7 @@ func NewUserValidator() IUserValidator {
 }
 
 func (uv *userValidator) UserValidate(user model.User) error {
-	return validation.ValidateStruct(&user,
+	return apperror.Validation(validation.ValidateStruct(&user,
 		// emailのバリデーション
 		validation.Field(
 			&user.Email,
@@ -39,12 +42,12 @@ func (uv *userValidator) UserValidate(user model.User) error {
 			validation.Required.Error("name is required"), // 名前が必須であることを示す
 			validation.RuneLength(1, 50).Error("name must be between 1 and 50 characters"), // 文字数の制限
 		),
-	)
+	))
 }
 
 // UserLoginValidateはログイン時のバリデーションを行います。ここではnameは検証しません。
 func (uv *userValidator) UserLoginValidate(user model.User) error {
-	return validation.ValidateStruct(&user,
+	return apperror.Validation(validation.ValidateStruct(&user,
 		// Emailのバリデーション
 		validation.Field(
 			&user.Email,
@@ -58,6 +61,23 @@ func (uv *userValidator) UserLoginValidate(user model.User) error {
 			validation.RuneLength(6, 100).Error("password must be between 6 and 100 characters"),
 		),
 		// Nameのバリデーションはここでは行いません。
-	)
+	))
 }
 
+
+
+// UserRoleValidateはロールが定義済みの値であることを検証します。
+func (uv *userValidator) UserRoleValidate(role string) error {
+	return apperror.Field("role", validation.Validate(role,
+		validation.Required.Error("role is required"),
+		validation.In(model.RoleCustomer, model.RoleShopOwner, model.RoleAdmin).Error("invalid role"),
+	))
+}
+
+// PasswordValidateはパスワード再設定時の新しいパスワードを検証します。
+func (uv *userValidator) PasswordValidate(password string) error {
+	return apperror.Field("password", validation.Validate(password,
+		validation.Required.Error("password is required"),
+		validation.RuneLength(6, 30).Error("limited min 6 max 30 char"),
+	))
+}